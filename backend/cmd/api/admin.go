@@ -17,7 +17,7 @@ import (
 func (app *application) getAdminProfileHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 
-	err := app.writeJSON(w, http.StatusOK, envelope{"admin": user}, nil)
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"admin": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -65,7 +65,7 @@ func (app *application) updateAdminProfileHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"admin": user}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"admin": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -123,7 +123,7 @@ func (app *application) changeAdminPasswordHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "password successfully updated"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "password successfully updated"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -148,8 +148,9 @@ func (app *application) listAllUsersHandler(w http.ResponseWriter, r *http.Reque
 	input.Search = app.readString(qs, "search", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "name", "email", "created_at", "-id", "-name", "-email", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortID)
+	input.Filters.SortSafelist = data.UserSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -162,7 +163,7 @@ func (app *application) listAllUsersHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"users": users, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"users": users, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -187,7 +188,7 @@ func (app *application) viewUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -273,7 +274,7 @@ func (app *application) updateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -298,7 +299,7 @@ func (app *application) deleteUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -323,8 +324,9 @@ func (app *application) listAllAgentsHandler(w http.ResponseWriter, r *http.Requ
 	input.Search = app.readString(qs, "search", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "name", "created_at", "-id", "-name", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortID)
+	input.Filters.SortSafelist = data.AgentSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -337,7 +339,7 @@ func (app *application) listAllAgentsHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"agents": agents, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"agents": agents, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -368,12 +370,85 @@ func (app *application) approveAgentVerificationHandler(w http.ResponseWriter, r
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"agent": agent}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"agent": agent}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// maxBulkVerification caps the number of agents verified in one request
+const maxBulkVerification = 100
+
+// bulkApproveAgentVerificationHandler verifies a batch of agents in one transaction
+func (app *application) bulkApproveAgentVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	admin := app.contextGetUser(r)
+
+	var input struct {
+		AgentIDs []int64 `json:"agent_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.AgentIDs) > 0, "agent_ids", "must contain at least 1 id")
+	v.Check(len(input.AgentIDs) <= maxBulkVerification, "agent_ids", fmt.Sprintf("must not contain more than %d ids", maxBulkVerification))
+
+	seen := make(map[int64]bool, len(input.AgentIDs))
+	for _, id := range input.AgentIDs {
+		v.Check(id > 0, "agent_ids", "must contain only positive ids")
+		v.Check(!seen[id], "agent_ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Agents.ApproveVerificationBulk(input.AgentIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	verifiedIDs := results.Succeeded
+
+	app.recordAudit(admin.ID, "agents.verify_bulk", "agent", 0, map[string]interface{}{
+		"requested": input.AgentIDs,
+		"verified":  verifiedIDs,
+	})
+
+	// Notify each verified agent
+	app.background(func() {
+		for _, id := range verifiedIDs {
+			agent, err := app.models.Users.GetByID(id)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"agent_id": fmt.Sprintf("%d", id),
+				})
+				continue
+			}
+
+			emailData := map[string]interface{}{
+				"agentName": agent.Name,
+			}
+
+			err = app.mailer.Send(agent.Email, "agent_verification_approved.tmpl", emailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"agent_id": fmt.Sprintf("%d", agent.ID),
+				})
+			}
+		}
+	})
+
+	app.writeBulkResult(w, r, results)
+}
+
 // rejectAgentVerificationHandler rejects an agent verification request with a reason
 func (app *application) rejectAgentVerificationHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
@@ -436,7 +511,7 @@ func (app *application) rejectAgentVerificationHandler(w http.ResponseWriter, r
 		}
 	})
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "agent verification rejected",
 		"agent":   agent,
 	}, nil)
@@ -464,7 +539,9 @@ func (app *application) suspendAgentHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "agent successfully suspended"}, nil)
+	app.invalidateStats(id)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "agent successfully suspended"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -489,7 +566,9 @@ func (app *application) activateAgentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "agent successfully activated"}, nil)
+	app.invalidateStats(id)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "agent successfully activated"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -516,8 +595,9 @@ func (app *application) listAllPropertiesHandler(w http.ResponseWriter, r *http.
 	input.PropertyType = app.readString(qs, "property_type", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-id")
-	input.Filters.SortSafelist = []string{"id", "title", "price", "created_at", "-id", "-title", "-price", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortIDDesc)
+	input.Filters.SortSafelist = data.AdminPropertySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -530,7 +610,150 @@ func (app *application) listAllPropertiesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"properties": properties, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"properties": properties, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// ADMIN INQUIRY MANAGEMENT
+// =============================================================================
+
+// listAllInquiriesHandler returns inquiries across all agents, filterable by agent, status and type
+func (app *application) listAllInquiriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AgentID     int64
+		Status      string
+		InquiryType string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.AgentID = int64(app.readInt(qs, "agent_id", 0, v))
+	input.Status = app.readString(qs, "status", "")
+	input.InquiryType = app.readString(qs, "inquiry_type", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.AdminInquirySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	v.Check(input.AgentID >= 0, "agent_id", "must not be negative")
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, data.InquiryStatuses...), "status", "must be one of: new, contacted, scheduled, closed, spam")
+	}
+	if input.InquiryType != "" {
+		v.Check(validator.In(input.InquiryType, data.InquiryTypes...), "inquiry_type", "must be one of: general, viewing, purchase, rent, more_info")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	inquiries, metadata, err := app.models.Inquiries.GetAllForAdmin(input.AgentID, input.Status, input.InquiryType, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiries": inquiries, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// approvePropertyHandler approves a pending listing; it must have a primary image
+func (app *application) approvePropertyHandler(w http.ResponseWriter, r *http.Request) {
+	admin := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Properties.ApproveProperty(id, admin.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoPrimaryImage):
+			v := validator.New()
+			v.AddError("images", "property must have a primary image before it can be approved")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.addModerationNote(id, admin, data.PropertyNoteApproval, "Listing approved")
+	app.invalidateStats(0)
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rejectPropertyHandler rejects a pending listing, posting the reason to its notes thread
+func (app *application) rejectPropertyHandler(w http.ResponseWriter, r *http.Request) {
+	admin := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Reason != "", "reason", "must be provided")
+	v.Check(len(input.Reason) <= 2000, "reason", "must not exceed 2000 characters")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Properties.RejectProperty(id, admin.ID, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.addModerationNote(id, admin, data.PropertyNoteRejection, input.Reason)
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -544,7 +767,7 @@ func (app *application) adminDeletePropertyHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.models.Properties.Delete(id)
+	agentID, err := app.models.Properties.Delete(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrPropertyNotFound):
@@ -555,25 +778,132 @@ func (app *application) adminDeletePropertyHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "property successfully deleted"}, nil)
+	app.invalidateListingStats(agentID)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "property successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// adminRestorePropertyHandler undeletes a soft-deleted property
+func (app *application) adminRestorePropertyHandler(w http.ResponseWriter, r *http.Request) {
+	admin := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.RestoreProperty(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(admin.ID, "properties.restore", "property", property.ID, nil)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// maxBulkFeature caps the number of properties featured or unfeatured in one request
+const maxBulkFeature = 100
+
+// bulkFeaturePropertiesHandler features many properties at once
+func (app *application) bulkFeaturePropertiesHandler(w http.ResponseWriter, r *http.Request) {
+	app.bulkSetFeatured(w, r, true)
+}
+
+// bulkUnfeaturePropertiesHandler unfeatures many properties at once
+func (app *application) bulkUnfeaturePropertiesHandler(w http.ResponseWriter, r *http.Request) {
+	app.bulkSetFeatured(w, r, false)
+}
+
+// bulkSetFeatured applies a bulk feature or unfeature and reports the outcome of each id
+func (app *application) bulkSetFeatured(w http.ResponseWriter, r *http.Request, feature bool) {
+	admin := app.contextGetUser(r)
+
+	var input struct {
+		PropertyIDs []int64 `json:"property_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.PropertyIDs) > 0, "property_ids", "must contain at least 1 id")
+	v.Check(len(input.PropertyIDs) <= maxBulkFeature, "property_ids", fmt.Sprintf("must not contain more than %d ids", maxBulkFeature))
+
+	seen := make(map[int64]bool, len(input.PropertyIDs))
+	for _, id := range input.PropertyIDs {
+		v.Check(id > 0, "property_ids", "must contain only positive ids")
+		v.Check(!seen[id], "property_ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	policy := app.featurePolicy()
+
+	results, err := app.models.Properties.FeatureBulk(input.PropertyIDs, feature, policy)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateStats(0)
+
+	// One entry per property actually changed, so each listing's history shows it
+	action, details := "properties.unfeature_bulk", map[string]interface{}(nil)
+	if feature {
+		action = "properties.feature_bulk"
+		if policy.Duration > 0 {
+			details = map[string]interface{}{"featured_for": policy.Duration.String()}
+		}
+	}
+
+	for _, id := range results.Succeeded {
+		app.recordAudit(admin.ID, action, "property", id, details)
+	}
+
+	app.writeBulkResult(w, r, results)
+}
+
 // =============================================================================
 // ADMIN PLATFORM STATISTICS
 // =============================================================================
 
-// getPlatformStatsHandler returns comprehensive platform statistics
+// getPlatformStatsHandler returns comprehensive platform statistics. They are served from
+// a cache the background refresher renews every stats-cache-ttl; a missed refresh or an
+// invalidation falls back to computing them here.
 func (app *application) getPlatformStatsHandler(w http.ResponseWriter, r *http.Request) {
-	stats, err := app.models.Admin.GetPlatformStats()
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+	stats, ok := app.platformStats.get(2 * app.config.stats.cacheTTL)
+	if !ok {
+		var err error
+		stats, err = app.models.Admin.GetPlatformStats()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.platformStats.set(stats)
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -590,8 +920,29 @@ func (app *application) getGrowthMetricsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"metrics": metrics}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"metrics": metrics}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// =============================================================================
+// AUDIT LOG
+// =============================================================================
+
+// recordAudit writes an audit log entry, logging rather than failing the request on error
+func (app *application) recordAudit(actorID int64, action, entityType string, entityID int64, details map[string]interface{}) {
+	err := app.models.AuditLog.Insert(&data.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Details:    details,
+	})
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"action":      action,
+			"entity_type": entityType,
+		})
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/validator"
@@ -21,7 +25,7 @@ func (app *application) getAgentProfileHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err := app.writeJSON(w, http.StatusOK, envelope{"agent": user}, nil)
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"agent": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -37,8 +41,13 @@ func (app *application) updateAgentProfileHandler(w http.ResponseWriter, r *http
 	}
 
 	var input struct {
-		Name  *string `json:"name"`
-		Email *string `json:"email"`
+		Name        *string `json:"name"`
+		Email       *string `json:"email"`
+		SlotMinutes *int    `json:"slot_minutes"`
+		Timezone    *string `json:"timezone"`
+		Phone       *string `json:"phone"`
+		ShowPhone   *bool   `json:"show_phone"`
+		ShowEmail   *bool   `json:"show_email"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -53,6 +62,9 @@ func (app *application) updateAgentProfileHandler(w http.ResponseWriter, r *http
 	if input.Email != nil {
 		user.Email = *input.Email
 	}
+	if input.Timezone != nil {
+		user.Timezone = *input.Timezone
+	}
 
 	v := validator.New()
 	if input.Name != nil {
@@ -62,12 +74,37 @@ func (app *application) updateAgentProfileHandler(w http.ResponseWriter, r *http
 	if input.Email != nil {
 		data.ValidateEmail(v, *input.Email)
 	}
+	if input.SlotMinutes != nil {
+		data.ValidateSlotMinutes(v, *input.SlotMinutes)
+	}
+	if input.Timezone != nil {
+		data.ValidateTimezone(v, *input.Timezone)
+	}
+	if input.Phone != nil {
+		data.ValidatePhone(v, *input.Phone)
+	}
 
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	if input.SlotMinutes != nil {
+		err = app.models.Agents.UpdateSlotMinutes(user.ID, *input.SlotMinutes)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.Phone != nil || input.ShowPhone != nil || input.ShowEmail != nil {
+		err = app.models.Agents.UpdateContactSettings(user.ID, input.Phone, input.ShowPhone, input.ShowEmail)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	err = app.models.Users.Update(user)
 	if err != nil {
 		switch {
@@ -82,7 +119,7 @@ func (app *application) updateAgentProfileHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"agent": user}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"agent": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -109,7 +146,7 @@ func (app *application) deleteAgentAccountHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "account successfully deactivated"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "account successfully deactivated"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -169,7 +206,7 @@ func (app *application) changeAgentPasswordHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "password successfully changed"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "password successfully changed"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -197,8 +234,9 @@ func (app *application) listAgentPropertiesHandler(w http.ResponseWriter, r *htt
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "title", "year_built", "price", "-id", "-title", "-year_built", "-price"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortID)
+	input.Filters.SortSafelist = data.AgentPropertySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -211,12 +249,37 @@ func (app *application) listAgentPropertiesHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"properties": properties, "metadata": metadata}, nil)
+	err = app.setListingQuality(properties...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"properties": properties, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// setListingQuality scores the agent's listings so they can see what to improve
+func (app *application) setListingQuality(properties ...*data.Property) error {
+	ids := make([]int64, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+	}
+
+	media, err := app.models.Media.GetImageSummaries(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, property := range properties {
+		property.Quality = data.ComputeListingQuality(property, media[property.ID], app.config.listings.qualityWeights)
+	}
+
+	return nil
+}
+
 // getAgentPropertyHandler retrieves a specific property belonging to the authenticated agent
 func (app *application) getAgentPropertyHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
@@ -248,7 +311,191 @@ func (app *application) getAgentPropertyHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"property": property}, nil)
+	property.SetDaysUntilExpiry(time.Now())
+
+	err = app.setListingQuality(property)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// renewAgentPropertyHandler extends the lifetime of one of the agent's listings
+func (app *application) renewAgentPropertyHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	expiresAt, err := app.models.Properties.Renew(id, user.ID, app.config.listings.lifetime)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	property.ExpiresAt = expiresAt
+	property.SetDaysUntilExpiry(time.Now())
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// bumpAgentPropertyHandler moves one of the agent's live listings to the top of the
+// newest sort, at most once per cooldown
+func (app *application) bumpAgentPropertyHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	bumpedAt, err := app.models.Properties.Bump(id, user.ID, app.config.listings.bumpCooldown)
+	if err != nil {
+		var cooldown *data.BumpCooldownError
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrBumpNotLive):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		case errors.As(err, &cooldown):
+			retryAfter := int(math.Ceil(time.Until(cooldown.NextBumpAt).Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			app.errorResponse(w, r, http.StatusTooManyRequests, envelope{
+				"message":      "this listing was bumped recently",
+				"next_bump_at": cooldown.NextBumpAt,
+			})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	property.BumpedAt = bumpedAt
+	property.SetDaysUntilExpiry(time.Now())
+
+	env := envelope{
+		"property":     property,
+		"next_bump_at": bumpedAt.Add(app.config.listings.bumpCooldown),
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// maxBulkPropertyImport caps the number of properties imported in one request
+const maxBulkPropertyImport = 100
+
+// importAgentPropertiesHandler creates many listings for the user at once. Every
+// property is validated first; if any fail nothing is inserted, otherwise they are
+// all inserted in one transaction. Properties that fail validation are reported in
+// the bulk result by their position in the request, since they have no id yet.
+func (app *application) importAgentPropertiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input []propertyInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case len(input) == 0:
+		app.badRequestResponse(w, r, errors.New("body must contain at least one property"))
+		return
+	case len(input) > maxBulkPropertyImport:
+		app.badRequestResponse(w, r, fmt.Errorf("body must not contain more than %d properties", maxBulkPropertyImport))
+		return
+	}
+
+	properties := make([]*data.Property, len(input))
+	results := data.NewBulkResult(len(input))
+	limits := app.propertyLimits()
+
+	for i, item := range input {
+		property := item.property(user.ID)
+
+		v := validator.New()
+		if item.validate(v, property, limits); !v.Valid() {
+			results.FailWithErrors(int64(i), data.BulkErrorInvalid, "property failed validation", v.Errors)
+			continue
+		}
+
+		app.setListingExpiry(property)
+
+		err := app.applyListingTrust(user, property, app.models.Agents.IsVerified)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		properties[i] = property
+	}
+
+	if results.Counts.Failed > 0 {
+		env := envelope{
+			"error":  fmt.Sprintf("%d of %d properties failed validation; none were imported", results.Counts.Failed, len(input)),
+			"result": results,
+		}
+
+		err = app.writeJSON(w, r, http.StatusUnprocessableEntity, env, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Properties.InsertBatch(properties)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, property := range properties {
+		results.Succeed(property.ID)
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"result": results, "properties": properties}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -269,7 +516,7 @@ func (app *application) getAgentPropertyStatsHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -297,21 +544,22 @@ func (app *application) listAgentReviewsHandler(w http.ResponseWriter, r *http.R
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{"id", "rating", "created_at", "-id", "-rating", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.AgentReviewSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	reviews, metadata, err := app.models.Reviews.GetAllForAgent(user.ID, input.Filters)
+	reviews, metadata, err := app.models.Reviews.GetAllForAgent(user.ID, "", input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"reviews": reviews, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"reviews": reviews, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -335,8 +583,9 @@ func (app *application) listAgentPendingReviewsHandler(w http.ResponseWriter, r
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{"id", "created_at", "-id", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.AgentPendingReviewSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -349,7 +598,76 @@ func (app *application) listAgentPendingReviewsHandler(w http.ResponseWriter, r
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"reviews": reviews, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"reviews": reviews, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listPublicAgentReviewsHandler lists an agent's approved reviews for buyers, with their average rating
+func (app *application) listPublicAgentReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.AgentReviewSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	agent, err := app.models.Users.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUserNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if agent.Role != "agent" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForAgent(agent.ID, "approved", input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	average, count, err := app.models.Reviews.GetAverageRatingForAgent(agent.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	public := make([]*data.PublicReview, 0, len(reviews))
+	for _, review := range reviews {
+		public = append(public, review.Public())
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"reviews":        public,
+		"average_rating": average,
+		"review_count":   count,
+		"metadata":       app.withPageLinks(r, metadata),
+	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -422,20 +740,22 @@ func (app *application) createFeaturePaymentHandler(w http.ResponseWriter, r *ht
 		Status:          "completed",
 	}
 
-	err = app.models.Payments.Create(payment)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
-	// Feature the property immediately for this legacy endpoint
-	err = app.models.Properties.Feature(id)
+	// This legacy endpoint features the property immediately, so the payment is only
+	// recorded if a featured slot is free for it
+	err = app.models.Payments.CreateFeatured(payment, app.featurePolicy())
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrFeaturedSlotsFull):
+			app.featuredSlotsFullResponse(w, r)
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"payment": payment}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"payment": payment}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -459,8 +779,9 @@ func (app *application) listPaymentHistoryHandler(w http.ResponseWriter, r *http
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{"id", "amount", "created_at", "-id", "-amount", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.PaymentSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -473,7 +794,7 @@ func (app *application) listPaymentHistoryHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"payments": payments, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"payments": payments, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -510,7 +831,7 @@ func (app *application) getPaymentStatusHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"payment": payment}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"payment": payment}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -520,7 +841,8 @@ func (app *application) getPaymentStatusHandler(w http.ResponseWriter, r *http.R
 // E. AGENT DASHBOARD
 // =============================================================================
 
-// getAgentDashboardStatsHandler returns comprehensive dashboard metrics for the agent
+// getAgentDashboardStatsHandler returns comprehensive dashboard metrics for the agent,
+// cached for stats-cache-ttl
 func (app *application) getAgentDashboardStatsHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 
@@ -529,13 +851,18 @@ func (app *application) getAgentDashboardStatsHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	stats, err := app.models.Agents.GetDashboardStats(user.ID)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+	stats, ok := app.dashboardStats.get(user.ID)
+	if !ok {
+		var err error
+		stats, err = app.models.Agents.GetDashboardStats(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.dashboardStats.set(user.ID, stats, app.config.stats.cacheTTL)
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// AGENT: WEEKLY AVAILABILITY
+// =============================================================================
+
+// listAgentAvailabilityHandler returns the agent's weekly availability windows
+func (app *application) listAgentAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	windows, err := app.models.Availability.GetAllForAgent(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"availability": windows}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createAgentAvailabilityHandler adds a weekly window in which viewings may be booked
+func (app *application) createAgentAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		DayOfWeek *int   `json:"day_of_week"`
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.DayOfWeek != nil, "day_of_week", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	availability := &data.AgentAvailability{
+		AgentID:   user.ID,
+		DayOfWeek: *input.DayOfWeek,
+		StartTime: input.StartTime,
+		EndTime:   input.EndTime,
+	}
+
+	if data.ValidateAgentAvailability(v, availability); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Availability.Insert(availability)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAvailabilityOverlap):
+			v.AddError("start_time", "overlaps another window on the same day")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"availability": availability}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAgentAvailabilityHandler changes the day or times of one availability window
+func (app *application) updateAgentAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	availability, err := app.models.Availability.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAvailabilityNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		DayOfWeek *int    `json:"day_of_week"`
+		StartTime *string `json:"start_time"`
+		EndTime   *string `json:"end_time"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.DayOfWeek != nil {
+		availability.DayOfWeek = *input.DayOfWeek
+	}
+	if input.StartTime != nil {
+		availability.StartTime = *input.StartTime
+	}
+	if input.EndTime != nil {
+		availability.EndTime = *input.EndTime
+	}
+
+	v := validator.New()
+	if data.ValidateAgentAvailability(v, availability); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Availability.Update(availability)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAvailabilityOverlap):
+			v.AddError("start_time", "overlaps another window on the same day")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"availability": availability}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAgentAvailabilityHandler removes one of the agent's availability windows
+func (app *application) deleteAgentAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Availability.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAvailabilityNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "availability successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
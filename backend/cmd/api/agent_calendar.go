@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// maxCalendarRange caps how far apart from and to may be on a calendar request
+const maxCalendarRange = 92 * 24 * time.Hour
+
+// calendarEvent is a single entry in an agent's merged calendar feed
+type calendarEvent struct {
+	Type     string                    `json:"type"`
+	StartsAt time.Time                 `json:"starts_at"`
+	EndsAt   time.Time                 `json:"ends_at"`
+	Schedule *data.ScheduleWithDetails `json:"schedule,omitempty"`
+	Blackout *data.Blackout            `json:"blackout,omitempty"`
+}
+
+// =============================================================================
+// AGENT CALENDAR
+// =============================================================================
+
+// getAgentCalendarHandler returns the agent's schedules and blackouts as one feed
+func (app *application) getAgentCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	from, to := app.readCalendarRange(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, err := app.agentCalendarEvents(user.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"events": events, "from": from, "to": to}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportAgentCalendarHandler returns the agent's merged calendar feed as an ICS file
+func (app *application) exportAgentCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	from, to := app.readCalendarRange(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	events, err := app.agentCalendarEvents(user.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="calendar.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(buildICS(events)))
+}
+
+// createBlackoutHandler blocks out a period in the agent's calendar
+func (app *application) createBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+		Reason   string    `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	blackout := &data.Blackout{
+		AgentID:  user.ID,
+		StartsAt: input.StartsAt,
+		EndsAt:   input.EndsAt,
+		Reason:   input.Reason,
+	}
+
+	v := validator.New()
+	if data.ValidateBlackout(v, blackout); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Blackouts.Insert(blackout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"blackout": blackout}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteBlackoutHandler removes one of the agent's blackout periods
+func (app *application) deleteBlackoutHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Blackouts.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrBlackoutNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "blackout successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// readCalendarRange parses the from/to query parameters, defaulting to the next 30 days
+func (app *application) readCalendarRange(qs url.Values, v *validator.Validator) (time.Time, time.Time) {
+	now := time.Now().UTC().Truncate(24 * time.Hour)
+
+	from := app.readTime(qs, "from", now, v)
+	to := app.readTime(qs, "to", from.Add(30*24*time.Hour), v)
+
+	v.Check(to.After(from), "to", "must be after from")
+	v.Check(to.Sub(from) <= maxCalendarRange, "to", "range must not exceed 92 days")
+
+	return from, to
+}
+
+// agentCalendarEvents merges the agent's schedules and blackouts into a single ordered list
+func (app *application) agentCalendarEvents(agentID int64, from, to time.Time) ([]calendarEvent, error) {
+	schedules, err := app.models.Schedules.GetAllForAgentInRange(agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	blackouts, err := app.models.Blackouts.GetAllForAgentInRange(agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]calendarEvent, 0, len(schedules)+len(blackouts))
+
+	for _, s := range schedules {
+		events = append(events, calendarEvent{
+			Type:     "schedule",
+			StartsAt: s.ScheduledAt,
+			EndsAt:   s.ScheduledAt.Add(time.Duration(s.DurationMinutes) * time.Minute),
+			Schedule: s,
+		})
+	}
+
+	for _, b := range blackouts {
+		events = append(events, calendarEvent{
+			Type:     "blackout",
+			StartsAt: b.StartsAt,
+			EndsAt:   b.EndsAt,
+			Blackout: b,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartsAt.Before(events[j].StartsAt)
+	})
+
+	return events, nil
+}
+
+// buildICS renders calendar events as an iCalendar document
+func buildICS(events []calendarEvent) string {
+	const layout = "20060102T150405Z"
+
+	var b strings.Builder
+	stamp := time.Now().UTC().Format(layout)
+
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//propertyown//agent calendar//EN\r\n")
+	b.WriteString("CALSCALE:GREGORIAN\r\n")
+
+	for _, e := range events {
+		var uid, summary, description string
+
+		switch e.Type {
+		case "schedule":
+			uid = fmt.Sprintf("schedule-%d@propertyown.api", e.Schedule.ID)
+			summary = fmt.Sprintf("Viewing: %s", e.Schedule.PropertyTitle)
+			description = fmt.Sprintf("Status: %s\nClient: %s <%s>\nAddress: %s",
+				e.Schedule.Status, e.Schedule.UserName, e.Schedule.UserEmail, e.Schedule.PropertyAddr)
+		case "blackout":
+			uid = fmt.Sprintf("blackout-%d@propertyown.api", e.Blackout.ID)
+			summary = "Unavailable"
+			description = e.Blackout.Reason
+		}
+
+		b.WriteString("BEGIN:VEVENT\r\n")
+		b.WriteString("UID:" + uid + "\r\n")
+		b.WriteString("DTSTAMP:" + stamp + "\r\n")
+		b.WriteString("DTSTART:" + e.StartsAt.UTC().Format(layout) + "\r\n")
+		b.WriteString("DTEND:" + e.EndsAt.UTC().Format(layout) + "\r\n")
+		b.WriteString("SUMMARY:" + escapeICSText(summary) + "\r\n")
+		if description != "" {
+			b.WriteString("DESCRIPTION:" + escapeICSText(description) + "\r\n")
+		}
+		if e.Type == "schedule" && e.Schedule.PropertyAddr != "" {
+			b.WriteString("LOCATION:" + escapeICSText(e.Schedule.PropertyAddr) + "\r\n")
+		}
+		b.WriteString("END:VEVENT\r\n")
+	}
+
+	b.WriteString("END:VCALENDAR\r\n")
+
+	return b.String()
+}
+
+// escapeICSText escapes characters with special meaning in iCalendar text values
+func escapeICSText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// AGENT: OUT OF OFFICE DELEGATION
+// =============================================================================
+
+// getAgentDelegationHandler returns the agent's current delegation and the agents
+// they are acting for
+func (app *application) getAgentDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	delegation, err := app.models.Delegations.GetActive(user.ID)
+	if err != nil && !errors.Is(err, data.ErrDelegationNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	actingFor, err := app.models.Delegations.GetActiveForDelegate(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"delegation": delegation, "acting_for": actingFor}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// setAgentDelegationHandler hands the agent's inquiries and schedules to another
+// active agent until the given time, replacing any current delegation
+func (app *application) setAgentDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		DelegateID int64     `json:"delegate_id"`
+		Until      time.Time `json:"until"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	delegation := &data.Delegation{
+		AgentID:    user.ID,
+		DelegateID: input.DelegateID,
+		Until:      input.Until,
+	}
+
+	v := validator.New()
+	if data.ValidateDelegation(v, delegation, time.Now()); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	active, err := app.models.Agents.IsActive(delegation.DelegateID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !active {
+		v.AddError("delegate_id", "must be an active agent")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Delegations.Set(delegation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	delegation, err = app.models.Delegations.GetActive(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordAudit(user.ID, "delegations.set", "agent", user.ID, map[string]interface{}{
+		"delegate_id": delegation.DelegateID,
+		"until":       delegation.Until,
+	})
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"delegation": delegation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// clearAgentDelegationHandler ends the agent's delegation immediately
+func (app *application) clearAgentDelegationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err := app.models.Delegations.Delete(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDelegationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(user.ID, "delegations.clear", "agent", user.ID, nil)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "delegation successfully cleared"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// canActForAgent reports whether the user may handle the agent's inquiries and
+// schedules: they are the agent, or the agent's current delegate
+func (app *application) canActForAgent(user *data.User, agentID int64) (bool, error) {
+	if user.ID == agentID {
+		return true, nil
+	}
+
+	return app.models.Delegations.IsActiveDelegate(agentID, user.ID)
+}
+
+// readActingAgentID reads the optional agent_id query parameter a delegate uses to
+// list another agent's records, defaulting to the user's own ID
+func (app *application) readActingAgentID(qs url.Values, user *data.User, v *validator.Validator) int64 {
+	agentID := int64(app.readInt(qs, "agent_id", int(user.ID), v))
+	v.Check(agentID > 0, "agent_id", "must be a positive integer")
+	return agentID
+}
+
+// recordDelegateAction audits an action a delegate took on another agent's behalf;
+// actions by the agent themselves are not logged
+func (app *application) recordDelegateAction(user *data.User, agentID int64, action, entityType string, entityID int64, details map[string]interface{}) {
+	if user.ID == agentID {
+		return
+	}
+
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["on_behalf_of"] = agentID
+
+	app.recordAudit(user.ID, action, entityType, entityID, details)
+}
@@ -19,8 +19,8 @@ func (app *application) uploadAgentProfilePhotoHandler(w http.ResponseWriter, r
 		return
 	}
 
-	// Parse multipart form (max 10MB in memory)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form (max 10MB in memory), capped at the configured upload size
+	if err := app.parseMultipartForm(w, r, 10<<20); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -41,9 +41,10 @@ func (app *application) uploadAgentProfilePhotoHandler(w http.ResponseWriter, r
 
 	// Create upload struct
 	upload := &data.ProfilePhotoUpload{
-		File:   file,
-		Header: header,
-		UserID: user.ID,
+		File:    file,
+		Header:  header,
+		UserID:  user.ID,
+		MaxSize: app.config.media.avatarMaxBytes,
 	}
 
 	// Validate upload
@@ -54,6 +55,23 @@ func (app *application) uploadAgentProfilePhotoHandler(w http.ResponseWriter, r
 		return
 	}
 
+	// Crop and resize to the standard avatar before replacing the old photo
+	avatar, ext, err := app.processAvatar(file)
+	if err != nil {
+		var tooLarge *imageTooLargeError
+		switch {
+		case errors.Is(err, errNotAnImage):
+			v.AddError("photo", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.As(err, &tooLarge):
+			v.AddError("photo", "image dimensions are too large")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	// Delete old profile photo if exists
 	if user.ProfilePhoto != "" {
 		if err := data.DeleteProfilePhoto(user.ProfilePhoto); err != nil {
@@ -66,7 +84,7 @@ func (app *application) uploadAgentProfilePhotoHandler(w http.ResponseWriter, r
 	}
 
 	// Save new photo to disk
-	photoURL, err := data.SaveProfilePhoto(file, header, user.ID)
+	photoURL, err := data.SaveProfilePhoto(avatar, ext, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -90,7 +108,7 @@ func (app *application) uploadAgentProfilePhotoHandler(w http.ResponseWriter, r
 	user.ProfilePhoto = photoURL
 
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message":       "agent profile photo uploaded successfully",
 		"profile_photo": photoURL,
 		"agent":         user,
@@ -121,7 +139,7 @@ func (app *application) getAgentProfilePhotoHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"profile_photo": photoURL,
 	}, nil)
 	if err != nil {
@@ -173,7 +191,7 @@ func (app *application) deleteAgentProfilePhotoHandler(w http.ResponseWriter, r
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "agent profile photo deleted successfully",
 	}, nil)
 	if err != nil {
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// maxReportRange caps the date range of a property report
+const maxReportRange = 366 * 24 * time.Hour
+
+// exportPropertyReportHandler streams a CSV report of a property's activity for its agent
+func (app *application) exportPropertyReportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !property.AgentID.Valid || property.AgentID.Int64 != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// Default to the last 30 days, ending at the start of tomorrow
+	v := validator.New()
+	qs := r.URL.Query()
+
+	end := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	from := app.readTime(qs, "from", end.Add(-30*24*time.Hour), v)
+	to := app.readTime(qs, "to", end, v)
+
+	v.Check(to.After(from), "to", "must be after from")
+	v.Check(to.Sub(from) <= maxReportRange, "to", "range must not exceed 366 days")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	summary, err := app.models.Analytics.GetPropertySummary(property.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	activity, err := app.models.Analytics.GetPropertyDailyActivity(property.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("property-%d-report-%s-%s.csv", property.ID, from.Format("20060102"), to.Format("20060102"))
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+
+	// Summary block
+	cw.Write([]string{"property_id", strconv.FormatInt(property.ID, 10)})
+	cw.Write([]string{"title", property.Title})
+	cw.Write([]string{"from", from.Format(time.RFC3339)})
+	cw.Write([]string{"to", to.Format(time.RFC3339)})
+	cw.Write([]string{"total_views", strconv.Itoa(summary.Views)})
+	cw.Write([]string{"total_inquiries", strconv.Itoa(summary.Inquiries)})
+	cw.Write([]string{"total_schedules", strconv.Itoa(summary.Schedules)})
+	cw.Write([]string{"total_favourites", strconv.Itoa(summary.Favourites)})
+	cw.Write([]string{"total_contact_reveals", strconv.Itoa(summary.ContactReveals)})
+	cw.Write([]string{"average_rating", strconv.FormatFloat(summary.AverageRating, 'f', 2, 64)})
+	cw.Write([]string{"review_count", strconv.Itoa(summary.ReviewCount)})
+	cw.Write(nil)
+
+	// Daily time series
+	cw.Write([]string{"date", "views", "inquiries", "schedules", "favourites"})
+	for _, day := range activity {
+		cw.Write([]string{
+			day.Date.Format("2006-01-02"),
+			strconv.Itoa(day.Views),
+			strconv.Itoa(day.Inquiries),
+			strconv.Itoa(day.Schedules),
+			strconv.Itoa(day.Favourites),
+		})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"property_id": strconv.FormatInt(property.ID, 10),
+		})
+	}
+}
+
+// getPropertyBenchmarkHandler compares a listing's views and inquiries per day with
+// those of comparable listings, with plain-language insights for the agent
+func (app *application) getPropertyBenchmarkHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !property.AgentID.Valid || property.AgentID.Int64 != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	// Default to the last 30 days, ending at the start of tomorrow
+	v := validator.New()
+	qs := r.URL.Query()
+
+	end := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	from := app.readTime(qs, "from", end.Add(-30*24*time.Hour), v)
+	to := app.readTime(qs, "to", end, v)
+
+	v.Check(to.After(from), "to", "must be after from")
+	v.Check(to.Sub(from) <= maxReportRange, "to", "range must not exceed 366 days")
+	v.Check(from.Before(time.Now()), "from", "must be in the past")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	benchmark, err := app.models.Analytics.GetBenchmark(property, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"benchmark": benchmark,
+		"insights":  benchmarkInsights(benchmark),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// benchmarkInsights describes where a listing falls among its comparables
+func benchmarkInsights(benchmark *data.PropertyBenchmark) []string {
+	insights := []string{}
+
+	if benchmark.Comparables == 0 {
+		return append(insights, "There are no comparable listings to benchmark against yet.")
+	}
+
+	metrics := []struct {
+		name   string
+		metric data.BenchmarkMetric
+	}{
+		{"views", benchmark.Views},
+		{"inquiries", benchmark.Inquiries},
+	}
+
+	for _, m := range metrics {
+		switch percentile := *m.metric.Percentile; {
+		case percentile < 25:
+			insights = append(insights, fmt.Sprintf("Your %s are below the 25th percentile of comparable listings.", m.name))
+		case percentile >= 75:
+			insights = append(insights, fmt.Sprintf("Your %s are above the 75th percentile of comparable listings.", m.name))
+		default:
+			insights = append(insights, fmt.Sprintf("Your %s are in line with comparable listings.", m.name))
+		}
+	}
+
+	if benchmark.LowConfidence {
+		insights = append(insights, fmt.Sprintf("Only %d comparable listings were found, so treat this benchmark with caution.", benchmark.Comparables))
+	}
+
+	return insights
+}
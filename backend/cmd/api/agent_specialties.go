@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// AGENT SPECIALTIES & SERVICE AREAS
+// =============================================================================
+
+// getAgentSpecialtiesHandler returns the property types and areas the agent covers
+func (app *application) getAgentSpecialtiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	specialties, err := app.models.Agents.GetSpecialties(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"specialties": specialties}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAgentSpecialtiesHandler replaces the agent's specialties and service areas.
+// Values must be property types and locations that appear on listings.
+func (app *application) updateAgentSpecialtiesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PropertyTypes []string `json:"property_types"`
+		ServiceAreas  []string `json:"service_areas"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	specialties := &data.AgentSpecialties{
+		PropertyTypes: input.PropertyTypes,
+		ServiceAreas:  input.ServiceAreas,
+	}
+	if specialties.PropertyTypes == nil {
+		specialties.PropertyTypes = []string{}
+	}
+	if specialties.ServiceAreas == nil {
+		specialties.ServiceAreas = []string{}
+	}
+
+	known, err := app.models.Properties.GetAvailableFilters()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateAgentSpecialties(v, specialties, known.PropertyTypes, known.Locations); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Agents.ReplaceSpecialties(user.ID, specialties)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"specialties": specialties}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// searchAgentsHandler finds verified agents serving an area and/or specialising in a
+// property type, ranked by their matching listings and then their rating
+func (app *application) searchAgentsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Area         string
+		PropertyType string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Area = strings.TrimSpace(app.readString(qs, "area", ""))
+	input.PropertyType = strings.TrimSpace(app.readString(qs, "type", ""))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = defaultSortID
+	input.Filters.SortSafelist = data.AgentSearchSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	v.Check(input.Area != "" || input.PropertyType != "", "area", "area or type must be provided")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	agents, metadata, err := app.models.Agents.Search(input.Area, input.PropertyType, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"agents": agents, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,10 +1,30 @@
 package main
 
 import (
+	"expvar"
 	"strconv"
 	"time"
 )
 
+// backgroundOverflow counts tasks run outside the worker pool because the queue stayed full
+var backgroundOverflow = expvar.NewInt("background_overflow")
+
+// startWorkers launches the fixed pool of goroutines that run app.background tasks
+func (app *application) startWorkers() {
+	app.logger.PrintInfo("starting background workers", map[string]string{
+		"workers":    strconv.Itoa(app.config.workers.size),
+		"queue_size": strconv.Itoa(app.config.workers.queueSize),
+	})
+
+	for i := 0; i < app.config.workers.size; i++ {
+		go func() {
+			for task := range app.jobs {
+				task()
+			}
+		}()
+	}
+}
+
 // startBackgroundJobs starts all background maintenance jobs
 func (app *application) startBackgroundJobs() {
 	app.logger.PrintInfo("starting background jobs", nil)
@@ -15,11 +35,337 @@ func (app *application) startBackgroundJobs() {
 
 		// Run once on startup
 		app.cleanupExpiredRevokedTokens()
+		app.cleanupExpiredComparisons()
+		app.expireFeaturedListings()
+		app.autoCompleteSchedules()
 
 		for range ticker.C {
 			app.cleanupExpiredRevokedTokens()
+			app.cleanupExpiredComparisons()
+			app.expireFeaturedListings()
+			app.autoCompleteSchedules()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.processWebhookDeliveries()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(app.config.stats.cacheTTL)
+		defer ticker.Stop()
+
+		app.refreshPlatformStats()
+
+		for range ticker.C {
+			app.refreshPlatformStats()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		lastRun := time.Now()
+		app.processListingExpiry(lastRun.Add(-1 * time.Hour))
+
+		for range ticker.C {
+			app.processListingExpiry(lastRun)
+			lastRun = time.Now()
 		}
 	}()
+
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.sendScheduleReminders()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.publishDueProperties()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.sendSavedSearchAlerts()
+		}
+	}()
+
+	// Checked often so hourly digests go out close to on time
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			app.sendInquiryDigests()
+		}
+	}()
+
+	if app.config.inquiries.autoCloseAfter > 0 {
+		go func() {
+			ticker := time.NewTicker(1 * time.Hour)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				app.autoCloseStaleInquiries()
+			}
+		}()
+	}
+}
+
+// autoCloseStaleInquiries closes inquiries left without activity, optionally asking the
+// inquirer whether they are still interested first
+func (app *application) autoCloseStaleInquiries() {
+	cfg := app.config.inquiries
+
+	if cfg.autoClosePrompt {
+		stale, err := app.models.Inquiries.MarkStaleForPrompt(cfg.autoCloseAfter)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job": "auto_close_stale_inquiries",
+			})
+			return
+		}
+
+		for _, inquiry := range stale {
+			emailData := map[string]interface{}{
+				"name":          inquiry.Name,
+				"propertyTitle": inquiry.PropertyTitle,
+				"graceDays":     int(cfg.autoCloseGrace.Hours() / 24),
+			}
+
+			err := app.mailer.Send(inquiry.Email, "inquiry_still_interested.tmpl", emailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"job":        "auto_close_stale_inquiries",
+					"inquiry_id": strconv.FormatInt(inquiry.ID, 10),
+				})
+			}
+		}
+	}
+
+	count, err := app.models.Inquiries.AutoCloseStale(cfg.autoCloseAfter, cfg.autoClosePrompt, cfg.autoCloseGrace)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "auto_close_stale_inquiries",
+		})
+		return
+	}
+
+	if count > 0 {
+		app.logger.PrintInfo("stale inquiries closed", map[string]string{
+			"job":    "auto_close_stale_inquiries",
+			"closed": strconv.FormatInt(count, 10),
+		})
+	}
+}
+
+// expireFeaturedListings returns listings whose featured period has ended to standard
+func (app *application) expireFeaturedListings() {
+	ids, err := app.models.Properties.ExpireFeatured()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "expire_featured_listings",
+		})
+		return
+	}
+
+	if len(ids) > 0 {
+		app.invalidateStats(0)
+		app.logger.PrintInfo("featured listings expired", map[string]string{
+			"job":     "expire_featured_listings",
+			"expired": strconv.Itoa(len(ids)),
+		})
+	}
+}
+
+// publishDueProperties releases scheduled listings whose publish time has passed
+func (app *application) publishDueProperties() {
+	ids, err := app.models.Properties.PublishDue()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "publish_due_properties",
+		})
+		return
+	}
+
+	if len(ids) > 0 {
+		app.logger.PrintInfo("scheduled properties published", map[string]string{
+			"job":       "publish_due_properties",
+			"published": strconv.Itoa(len(ids)),
+		})
+	}
+}
+
+// autoCompleteSchedules marks confirmed viewings that have ended as completed
+func (app *application) autoCompleteSchedules() {
+	count, schedules, err := app.models.Schedules.MarkPastAsCompleted(app.config.schedules.completionGrace)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "auto_complete_schedules",
+		})
+		return
+	}
+
+	app.notifyCompletedSchedules(schedules)
+
+	if count > 0 {
+		app.logger.PrintInfo("schedules auto-completed", map[string]string{
+			"job":       "auto_complete_schedules",
+			"completed": strconv.FormatInt(count, 10),
+		})
+	}
+}
+
+// sendScheduleReminders emails the user and agent of each pending or confirmed viewing
+// starting within the reminder lead time, once per viewing. A viewing is marked as
+// reminded when either email goes out, so a mailer outage retries it on the next run.
+func (app *application) sendScheduleReminders() {
+	reminders, err := app.models.Schedules.GetUpcomingNeedingReminder(app.config.schedules.reminderLead)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "send_schedule_reminders",
+		})
+		return
+	}
+
+	sent := 0
+
+	for _, reminder := range reminders {
+		scheduleID := strconv.FormatInt(reminder.ScheduleID, 10)
+		delivered := false
+
+		recipients := []struct {
+			email, name, timezone, withName string
+			isAgent                         bool
+		}{
+			{reminder.UserEmail, reminder.UserName, reminder.UserTimezone, reminder.AgentName, false},
+			{reminder.AgentEmail, reminder.AgentName, reminder.AgentTimezone, reminder.UserName, true},
+		}
+
+		for _, recipient := range recipients {
+			emailData := map[string]interface{}{
+				"name":          recipient.name,
+				"withName":      recipient.withName,
+				"isAgent":       recipient.isAgent,
+				"scheduleID":    reminder.ScheduleID,
+				"propertyID":    reminder.PropertyID,
+				"propertyTitle": reminder.PropertyTitle,
+				"location":      reminder.Location,
+				"scheduledAt":   reminder.ScheduledAt.In(loadLocation(recipient.timezone)).Format("Monday, January 2, 2006 at 15:04 MST"),
+				"duration":      reminder.DurationMinutes,
+				"confirmed":     reminder.Status == "confirmed",
+			}
+
+			err := app.mailer.Send(recipient.email, "schedule_reminder.tmpl", emailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"job":         "send_schedule_reminders",
+					"schedule_id": scheduleID,
+				})
+				continue
+			}
+			delivered = true
+		}
+
+		if !delivered {
+			continue
+		}
+
+		err = app.models.Schedules.MarkReminderSent(reminder.ScheduleID)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":         "send_schedule_reminders",
+				"schedule_id": scheduleID,
+			})
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		app.logger.PrintInfo("schedule reminders sent", map[string]string{
+			"job":       "send_schedule_reminders",
+			"schedules": strconv.Itoa(sent),
+		})
+	}
+}
+
+// loadLocation returns the named timezone, falling back to UTC if it cannot be loaded
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// processListingExpiry reminds agents about listings that are about to expire and
+// reports listings that have expired (and so dropped out of public results) since the last run
+func (app *application) processListingExpiry(since time.Time) {
+	listings, err := app.models.Properties.GetExpiringForReminder(app.config.listings.reminderWindow)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "process_listing_expiry",
+		})
+		return
+	}
+
+	for _, listing := range listings {
+		emailData := map[string]interface{}{
+			"agentName":     listing.AgentName,
+			"propertyID":    listing.PropertyID,
+			"propertyTitle": listing.Title,
+			"expiresAt":     listing.ExpiresAt.Format("Monday, January 2, 2006"),
+		}
+
+		err := app.mailer.Send(listing.AgentEmail, "property_expiring.tmpl", emailData)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":         "process_listing_expiry",
+				"property_id": strconv.FormatInt(listing.PropertyID, 10),
+			})
+			continue
+		}
+
+		err = app.models.Properties.MarkExpiryReminded(listing.PropertyID)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":         "process_listing_expiry",
+				"property_id": strconv.FormatInt(listing.PropertyID, 10),
+			})
+		}
+	}
+
+	expired, err := app.models.Properties.CountExpiredSince(since)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "process_listing_expiry",
+		})
+		return
+	}
+
+	app.logger.PrintInfo("listing expiry processed", map[string]string{
+		"job":              "process_listing_expiry",
+		"reminders_sent":   strconv.Itoa(len(listings)),
+		"listings_expired": strconv.FormatInt(expired, 10),
+	})
 }
 
 // cleanupExpiredRevokedTokens removes expired revoked tokens from the database
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/jsonlog"
+)
+
+func TestBackgroundRunsTaskWhenQueueStaysFull(t *testing.T) {
+	app := &application{
+		logger: jsonlog.New(io.Discard, jsonlog.LevelInfo),
+		jobs:   make(chan func(), 1),
+	}
+	app.config.workers.enqueueTimeout = 10 * time.Millisecond
+
+	// No workers are running, so the first task fills the queue
+	app.background(func() {})
+
+	ran := make(chan struct{}, 1)
+	overflow := backgroundOverflow.Value()
+
+	start := time.Now()
+	app.background(func() { ran <- struct{}{} })
+
+	if elapsed := time.Since(start); elapsed < app.config.workers.enqueueTimeout {
+		t.Errorf("returned after %s; want it to wait for the enqueue timeout", elapsed)
+	}
+	if got := backgroundOverflow.Value() - overflow; got != 1 {
+		t.Errorf("got %d tasks run outside the pool; want 1", got)
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("task was dropped when the queue stayed full")
+	}
+
+	// Both tasks are tracked for graceful shutdown
+	(<-app.jobs)()
+	app.wg.Wait()
+}
+
+func TestBackgroundWaitsForRoomInTheQueue(t *testing.T) {
+	app := &application{
+		logger: jsonlog.New(io.Discard, jsonlog.LevelInfo),
+		jobs:   make(chan func(), 1),
+	}
+	app.config.workers.enqueueTimeout = time.Second
+
+	app.background(func() {})
+
+	// Free the queue shortly after the second task starts waiting
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		(<-app.jobs)()
+	}()
+
+	ran := make(chan struct{}, 1)
+	app.background(func() { ran <- struct{}{} })
+	(<-app.jobs)()
+
+	select {
+	case <-ran:
+	default:
+		t.Fatal("queued task did not run")
+	}
+	app.wg.Wait()
+}
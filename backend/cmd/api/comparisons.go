@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// =============================================================================
+// SAVED COMPARISONS
+// =============================================================================
+
+// createComparisonHandler saves a set of properties to compare and returns a share
+// token for it. Anyone may save a comparison; ones saved without signing in expire.
+func (app *application) createComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		PropertyIDs []int64 `json:"property_ids"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comparison := &data.Comparison{PropertyIDs: input.PropertyIDs}
+
+	v := validator.New()
+	if data.ValidateComparison(v, comparison); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	properties, err := app.models.Properties.GetBatch(comparison.PropertyIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Listings the requester can't see are reported as missing, as showPropertyHandler does
+	properties = app.visibleProperties(r, properties)
+
+	found := make(map[int64]bool, len(properties))
+	for _, property := range properties {
+		found[property.ID] = true
+	}
+	for _, id := range comparison.PropertyIDs {
+		if !found[id] {
+			v.AddError("property_ids", fmt.Sprintf("property %d does not exist", id))
+			break
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if user.IsAnonymous() {
+		expiresAt := time.Now().Add(app.config.comparisons.anonymousTTL)
+		comparison.ExpiresAt = &expiresAt
+	} else {
+		comparison.UserID = &user.ID
+	}
+
+	err = app.models.Comparisons.Insert(comparison)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", comparisonLocation(comparison.ShareToken))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"comparison": comparison, "properties": properties}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showComparisonHandler returns a shared comparison with its properties resolved.
+// Properties deleted since it was saved are left out.
+func (app *application) showComparisonHandler(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	comparison, err := app.models.Comparisons.GetByToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrComparisonNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	properties, err := app.models.Properties.GetBatch(comparison.PropertyIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// A listing may have been unpublished since the comparison was made
+	properties = app.visibleProperties(r, properties)
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"comparison": comparison, "properties": properties}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// cleanupExpiredComparisons removes anonymous comparisons past their expiry
+func (app *application) cleanupExpiredComparisons() {
+	count, err := app.models.Comparisons.DeleteExpired()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "cleanup_expired_comparisons",
+		})
+		return
+	}
+
+	if count > 0 {
+		app.logger.PrintInfo("expired comparisons removed", map[string]string{
+			"job":     "cleanup_expired_comparisons",
+			"removed": strconv.FormatInt(count, 10),
+		})
+	}
+}
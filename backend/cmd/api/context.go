@@ -28,3 +28,22 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// prettyJSONContextKey is the key used to store a per-request JSON formatting choice
+const prettyJSONContextKey = contextKey("prettyJSON")
+
+// contextSetPrettyJSON records whether JSON responses to the request should be indented
+func (app *application) contextSetPrettyJSON(r *http.Request, pretty bool) *http.Request {
+	ctx := context.WithValue(r.Context(), prettyJSONContextKey, pretty)
+	return r.WithContext(ctx)
+}
+
+// contextGetPrettyJSON reports whether JSON responses to the request should be indented,
+// falling back to the configured default when the request didn't choose
+func (app *application) contextGetPrettyJSON(r *http.Request) bool {
+	pretty, ok := r.Context().Value(prettyJSONContextKey).(bool)
+	if !ok {
+		return app.config.json.pretty
+	}
+	return pretty
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -16,7 +17,7 @@ func (app *application) logError(r *http.Request, err error) {
 // errorResponse sends a JSON-formatted error message error and status code
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
 	env := envelope{"error": message}
-	err := app.writeJSON(w, status, env, nil)
+	err := app.writeJSON(w, r, status, env, nil)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
@@ -44,10 +45,26 @@ func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.
 }
 
 // badRequestResponse sends a 400 Bad Request error as a JSON response.
+// Bodies over the size limit get a 413 and non-JSON bodies a 415 instead.
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *requestTooLargeError
+	if errors.As(err, &tooLarge) {
+		app.requestTooLargeResponse(w, r, tooLarge)
+		return
+	}
+	var unsupported *unsupportedMediaTypeError
+	if errors.As(err, &unsupported) {
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, unsupported.Error())
+		return
+	}
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
+// requestTooLargeResponse sends a 413 Request Entity Too Large response
+func (app *application) requestTooLargeResponse(w http.ResponseWriter, r *http.Request, err *requestTooLargeError) {
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, err.Error())
+}
+
 // failedValidationResponse sends a 422 JSON response with validation error
 func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
@@ -59,6 +76,12 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// featuredSlotsFullResponse sends a 409 Conflict when no featured slot is free
+func (app *application) featuredSlotsFullResponse(w http.ResponseWriter, r *http.Request) {
+	message := "all featured slots are currently taken, please try again later"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
 // rateLimitExceededResponse sends a 429 Too Many Requests response
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// readFields parses the ?fields= list for a partial response, checking each name
+// against the JSON fields of resource. A nil slice means the full resource was requested.
+func (app *application) readFields(qs url.Values, resource interface{}) ([]string, error) {
+	csv := strings.TrimSpace(qs.Get("fields"))
+	if csv == "" {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(resource))
+
+	var fields []string
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Split(csv, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !known[field] {
+			return nil, fmt.Errorf("fields: unknown field %q", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("fields: must contain at least one field name")
+	}
+
+	return fields, nil
+}
+
+// jsonFieldNames returns the set of top-level keys a struct type serializes to
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	names := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+
+	return names
+}
+
+// selectFields keeps only the given keys of a JSON object, or of each object in a
+// JSON array. Values are returned unchanged when fields is empty.
+func selectFields(value interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return value, nil
+	}
+
+	js, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	switch d := decoded.(type) {
+	case map[string]interface{}:
+		return pickKeys(d, fields), nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(d))
+		for _, item := range d {
+			if obj, ok := item.(map[string]interface{}); ok {
+				out = append(out, pickKeys(obj, fields))
+				continue
+			}
+			out = append(out, item)
+		}
+		return out, nil
+	default:
+		return decoded, nil
+	}
+}
+
+// pickKeys returns a copy of obj with only the given keys that are present
+func pickKeys(obj map[string]interface{}, keys []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := obj[key]; ok {
+			out[key] = value
+		}
+	}
+	return out
+}
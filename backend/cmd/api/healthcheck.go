@@ -16,7 +16,7 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	//Send the JSON response
-	err := app.writeJSON(w, http.StatusOK, env, nil)
+	err := app.writeJSON(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/validator"
 	"github.com/julienschmidt/httprouter"
 )
@@ -34,9 +38,26 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 }
 
 // Sends a JSON response with optional headers and a status code use type envelope
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	//Encode the data to JSON with indentation
-	js, err := json.MarshalIndent(data, "", "\t")
+// The envelope is shaped by the serializer for the API version negotiated on the request
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data envelope, headers http.Header) error {
+	negotiated := contextGetAPIVersion(r)
+	serialize, ok := responseVersions[negotiated.version]
+	if !ok {
+		return fmt.Errorf("no serializer for API version %d", negotiated.version)
+	}
+
+	body, err := serialize(data)
+	if err != nil {
+		return err
+	}
+
+	//Encode the data to JSON, indented when configured or requested with ?pretty=true
+	var js []byte
+	if app.contextGetPrettyJSON(r) {
+		js, err = json.MarshalIndent(body, "", "\t")
+	} else {
+		js, err = json.Marshal(body)
+	}
 	if err != nil {
 		return err
 	}
@@ -50,8 +71,11 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 
 	}
 
-	//Set Content-Type, write status code, and send JSON response
+	//Set Content-Type to the negotiated media type, write status code, and send JSON response
 	w.Header().Set("Content-Type", "application/json")
+	if negotiated.vendor {
+		w.Header().Set("Content-Type", vendorMediaType(negotiated.version))
+	}
 	w.WriteHeader(status)
 	w.Write(js)
 
@@ -59,22 +83,52 @@ func (app *application) writeJSON(w http.ResponseWriter, status int, data envelo
 
 }
 
+// nullable is an optional JSON field that tells an explicit null apart from a missing
+// field, so partial updates can clear a value. Set is true whenever the field was sent.
+type nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// UnmarshalJSON records that the field was sent and decodes it, leaving Value nil for null
+func (n *nullable[T]) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+
+	return nil
+}
+
 // readJSON decodes the request body into dst and provides detailed JSON error handling
 func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
-	//Limit request body to 1MB
-	maxBytes := 1_048_576
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	//Reject bodies that are not declared as JSON
+	err := app.checkJSONContentType(r)
+	if err != nil {
+		return err
+	}
+
+	//Limit request body to the configured size
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.limits.maxJSONBytes)
 
 	//Set up decoder and forbid unknown fields
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 
 	//Decode into destination
-	err := dec.Decode(dst)
+	err = dec.Decode(dst)
 	if err != nil {
 		var syntaxError *json.SyntaxError
 		var unmarshalTypeError *json.UnmarshalTypeError
 		var invalidUnmarshalErro *json.InvalidUnmarshalError
+		var maxBytesError *http.MaxBytesError
 
 		switch {
 		//Bad JSON sytnax
@@ -92,6 +146,10 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 			}
 			return fmt.Errorf("wrong JSON type (at character %d)", unmarshalTypeError.Offset)
 
+			//Body over the size limit
+		case errors.As(err, &maxBytesError):
+			return &requestTooLargeError{limit: maxBytesError.Limit}
+
 			//Empty body
 		case errors.Is(err, io.EOF):
 			return errors.New("body must not be empty")
@@ -116,6 +174,73 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst int
 	return nil
 }
 
+// requestTooLargeError is returned when a request body exceeds its size limit.
+// badRequestResponse turns it into a 413 rather than a 400.
+type requestTooLargeError struct {
+	limit int64
+}
+
+func (e *requestTooLargeError) Error() string {
+	return fmt.Sprintf("body must not be larger than %d bytes", e.limit)
+}
+
+// unsupportedMediaTypeError is returned when a JSON endpoint receives another content type.
+// badRequestResponse turns it into a 415 rather than a 400.
+type unsupportedMediaTypeError struct {
+	contentType string
+}
+
+func (e *unsupportedMediaTypeError) Error() string {
+	if e.contentType == "" {
+		return "Content-Type header must be application/json"
+	}
+	return fmt.Sprintf("Content-Type %q is not supported, use application/json", e.contentType)
+}
+
+// checkJSONContentType accepts application/json and +json media types. A missing
+// Content-Type is allowed only when lenient clients are enabled.
+func (app *application) checkJSONContentType(r *http.Request) error {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		if app.config.limits.allowMissingContentType {
+			return nil
+		}
+		return &unsupportedMediaTypeError{}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return &unsupportedMediaTypeError{contentType: contentType}
+	}
+
+	return nil
+}
+
+// parseMultipartForm parses a multipart upload, capping the whole body at the configured
+// upload limit. Up to maxMemory bytes of file parts are held in memory, the rest on disk.
+func (app *application) parseMultipartForm(w http.ResponseWriter, r *http.Request, maxMemory int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.limits.maxUploadBytes)
+
+	err := r.ParseMultipartForm(maxMemory)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return &requestTooLargeError{limit: maxBytesError.Limit}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// truncateRunes shortens s to at most n characters without splitting a multi-byte character
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 // readString returns the query string value for a key or default if missing
 // eg: ?name=Collins = "Collins" or if missing default "Guest"
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -136,6 +261,24 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 	return strings.Split(csv, ",")
 }
 
+// readMultiValue returns the trimmed, non-empty, de-duplicated values of a comma-separated query string
+// eg: ?location=Kilimani, Lavington = []string{"Kilimani", "Lavington"}
+func (app *application) readMultiValue(qs url.Values, key string) []string {
+	var values []string
+	seen := make(map[string]bool)
+
+	for _, value := range app.readCSV(qs, key, []string{}) {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		values = append(values, value)
+	}
+
+	return values
+}
+
 // readInt returns the query string value as an int or a default if missing/invalid, recording errors
 // eg: ?age25 = 25 or if invalid default 0
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
@@ -151,18 +294,75 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
-// background runs the given function in a safe background goroutine
+// readIDs returns the de-duplicated ids of a comma-separated query string, recording an
+// error if any is not a positive integer
+// eg: ?ids=1,2,3 = []int64{1, 2, 3}
+func (app *application) readIDs(qs url.Values, key string, v *validator.Validator) []int64 {
+	var ids []int64
+	seen := make(map[int64]bool)
+
+	for _, value := range app.readCSV(qs, key, []string{}) {
+		id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || id < 1 {
+			v.AddError(key, "must contain only positive integer ids")
+			return nil
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// readFloat returns the query string value as a float64 or the default, recording errors
+// eg: ?lat=-1.2921 = -1.2921
+func (app *application) readFloat(qs url.Values, key string, defaultValue float64, v *validator.Validator) float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return defaultValue
+	}
+	return f
+}
+
+// readTime returns the query string value as a time, accepting RFC3339 or YYYY-MM-DD, recording errors
+// eg: ?from=2024-01-31 = 2024-01-31T00:00:00Z or if invalid the default
+func (app *application) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t
+	}
+
+	v.AddError(key, "must be an RFC3339 timestamp or a YYYY-MM-DD date")
+	return defaultValue
+}
+
+// background queues the given function for the worker pool. If the queue is full the
+// caller waits up to the enqueue timeout for room; after that the task runs in its own
+// goroutine instead, still tracked for graceful shutdown, so no work such as mail is lost.
 func (app *application) background(fn func()) {
 
 	//Increament the WaitGroup counter
 	app.wg.Add(1)
 
-	go func() {
-
-		//Lauch the background goroutine
+	task := func() {
 		defer app.wg.Done()
 
-		//Recover and log any panic in the goroutine
+		//Recover and log any panic in the task
 		defer func() {
 			if err := recover(); err != nil {
 				app.logger.PrintError(fmt.Errorf("%s", err), nil)
@@ -170,7 +370,28 @@ func (app *application) background(fn func()) {
 		}()
 		//Execute the provided function
 		fn()
-	}()
+	}
+
+	//Queue straight away when there is room
+	select {
+	case app.jobs <- task:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(app.config.workers.enqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case app.jobs <- task:
+	case <-timer.C:
+		//Queue stayed full (or the pool is not running); run it outside the pool rather than lose it
+		backgroundOverflow.Add(1)
+		app.logger.PrintError(errors.New("background queue full, running task outside the pool"), map[string]string{
+			"queue_size": strconv.Itoa(cap(app.jobs)),
+		})
+		go task()
+	}
 }
 
 // invalidAuthenticationTokenResponse sends a 401 response when the auth token is missing or invalid
@@ -182,3 +403,39 @@ func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	message := "invalid or missing authentication token"
 	app.errorResponse(w, r, http.StatusUnauthorized, message)
 }
+
+// writeBulkResult sends the shared partial-success response of a bulk endpoint
+func (app *application) writeBulkResult(w http.ResponseWriter, r *http.Request, result *data.BulkResult) {
+	err := app.writeJSON(w, r, http.StatusOK, envelope{"result": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// withPageLinks adds first/prev/next/last URLs to the metadata, built from the request
+// path and query with only the page changed. Empty result sets get no links.
+func (app *application) withPageLinks(r *http.Request, metadata data.Metadata) data.Metadata {
+	if metadata.LastPage == 0 {
+		return metadata
+	}
+
+	pageURL := func(page int) string {
+		qs := r.URL.Query()
+		qs.Set("page", strconv.Itoa(page))
+		return r.URL.Path + "?" + qs.Encode()
+	}
+
+	links := &data.PageLinks{
+		First: pageURL(metadata.FirstPage),
+		Last:  pageURL(metadata.LastPage),
+	}
+	if metadata.CurrentPage > metadata.FirstPage {
+		links.Prev = pageURL(min(metadata.CurrentPage-1, metadata.LastPage))
+	}
+	if metadata.CurrentPage < metadata.LastPage {
+		links.Next = pageURL(metadata.CurrentPage + 1)
+	}
+
+	metadata.Links = links
+	return metadata
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestNullableDistinguishesNullFromMissing(t *testing.T) {
+	var input struct {
+		LotSize       nullable[int32]  `json:"lot_size"`
+		HeatingType   nullable[string] `json:"heating_type"`
+		ParkingSpaces nullable[int32]  `json:"parking_spaces"`
+	}
+
+	err := json.Unmarshal([]byte(`{"lot_size": null, "heating_type": "gas"}`), &input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !input.LotSize.Set || input.LotSize.Value != nil {
+		t.Errorf("lot_size: got set=%v value=%v; want an explicit null", input.LotSize.Set, input.LotSize.Value)
+	}
+	if !input.HeatingType.Set || input.HeatingType.Value == nil || *input.HeatingType.Value != "gas" {
+		t.Errorf("heating_type: got set=%v value=%v; want \"gas\"", input.HeatingType.Set, input.HeatingType.Value)
+	}
+	if input.ParkingSpaces.Set {
+		t.Error("parking_spaces: got set for a missing field")
+	}
+}
+
+func TestReadIDs(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		want      []int64
+		wantValid bool
+	}{
+		{"several", "ids=3,1,2", []int64{3, 1, 2}, true},
+		{"duplicates dropped", "ids=3,1,3", []int64{3, 1}, true},
+		{"spaces trimmed", "ids=3,%201", []int64{3, 1}, true},
+		{"missing", "", nil, true},
+		{"not a number", "ids=3,x", nil, false},
+		{"zero", "ids=0", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+			got := app.readIDs(qs, "ids", v)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+			if v.Valid() != tt.wantValid {
+				t.Errorf("got valid %v; want %v (errors %v)", v.Valid(), tt.wantValid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"habari yako 👋", 13, "habari yako 👋"},
+		{"naïve café", 4, "naïv"},
+		{"👋👋👋", 2, "👋👋"},
+	}
+
+	for _, tt := range tests {
+		got := truncateRunes(tt.s, tt.n)
+		if got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q; want %q", tt.s, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateRunes(%q, %d) returned invalid UTF-8", tt.s, tt.n)
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured bool
+		query      string
+		wantPretty bool
+	}{
+		{"compact in production", false, "", false},
+		{"pretty in development", true, "", true},
+		{"pretty requested in production", false, "?pretty=true", true},
+		{"compact requested in development", true, "?pretty=false", false},
+		{"unparseable override ignored", false, "?pretty=yes-please", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.json.pretty = tt.configured
+
+			handler := app.jsonFormat(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				app.writeJSON(w, r, http.StatusOK, envelope{"property": envelope{"id": 1}}, nil)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/properties/1"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			want := "{\"property\":{\"id\":1}}\n"
+			if tt.wantPretty {
+				want = "{\n\t\"property\": {\n\t\t\"id\": 1\n\t}\n}\n"
+			}
+			if got := rr.Body.String(); got != want {
+				t.Errorf("got body %q; want %q", got, want)
+			}
+		})
+	}
+}
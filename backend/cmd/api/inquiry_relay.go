@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/mailer"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// MASKED EMAIL RELAY
+// =============================================================================
+
+// relaySecretHeader carries the shared secret on inbound mail webhooks
+const relaySecretHeader = "X-Relay-Secret"
+
+// inquiryReplyTo returns the address replies about an inquiry should go to: the
+// inquiry's relay address when the relay is enabled, otherwise the direct address
+func (app *application) inquiryReplyTo(inquiryID int64, direct string) string {
+	if app.config.smtp.relayDomain == "" {
+		return direct
+	}
+	return data.InquiryRelayAddress(app.config.smtp.relaySecret, app.config.smtp.relayDomain, inquiryID)
+}
+
+// inboundMailHandler ingests a reply sent to an inquiry's relay address, as posted
+// by the mail provider. The message is added to the inquiry's conversation and
+// forwarded to the other party, keeping both email addresses private.
+func (app *application) inboundMailHandler(w http.ResponseWriter, r *http.Request) {
+	if app.config.smtp.relayDomain == "" {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	secret := r.Header.Get(relaySecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(app.config.smtp.relaySecret)) != 1 {
+		app.invalidCredentialsResponse(w, r)
+		return
+	}
+
+	var input struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Text string `json:"text"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	inquiryID, err := data.ParseInquiryRelayAddress(app.config.smtp.relaySecret, app.config.smtp.relayDomain, input.To)
+	if err != nil {
+		v.AddError("to", "must be an inquiry relay address")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	body := strings.TrimSpace(input.Text)
+	v.Check(body != "", "text", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	body = truncateRunes(body, data.MaxInquiryMessageLength)
+
+	inquiry, err := app.models.Inquiries.Get(inquiryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	agent, err := app.models.Users.GetByID(inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Only the two parties to the inquiry may post to its conversation
+	sender, recipient, recipientName := "", "", ""
+	switch from := senderAddress(input.From); {
+	case strings.EqualFold(from, inquiry.Email):
+		sender, recipient, recipientName = data.InquirySenderInquirer, agent.Email, agent.Name
+	case strings.EqualFold(from, agent.Email):
+		sender, recipient, recipientName = data.InquirySenderAgent, inquiry.Email, inquiry.Name
+	default:
+		err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "sender is not part of this inquiry; message ignored"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	message := &data.InquiryMessage{
+		InquiryID: inquiry.ID,
+		Sender:    sender,
+		Body:      body,
+		Via:       data.InquiryViaEmail,
+	}
+
+	err = app.models.Inquiries.InsertMessage(message)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		senderName := inquiry.Name
+		if sender == data.InquirySenderAgent {
+			senderName = agent.Name
+		}
+
+		emailData := map[string]interface{}{
+			"recipientName": recipientName,
+			"senderName":    senderName,
+			"propertyTitle": inquiry.PropertyTitle,
+			"message":       body,
+		}
+
+		err := app.mailer.Send(recipient, "inquiry_relay_message.tmpl", emailData, mailer.WithReplyTo(app.inquiryReplyTo(inquiry.ID, "")))
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"inquiry_id": strconv.FormatInt(inquiry.ID, 10),
+			})
+		}
+	})
+
+	err = app.writeJSON(w, r, http.StatusAccepted, envelope{"inquiry_message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// senderAddress extracts the bare address from a From header value
+func senderAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.Index(from[start:], ">"); end > 0 {
+			return strings.TrimSpace(from[start+1 : start+end])
+		}
+	}
+	return strings.TrimSpace(from)
+}
+
+// listAgentInquiryMessagesHandler returns the conversation on one of the agent's inquiries
+func (app *application) listAgentInquiryMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	inquiry, err := app.models.Inquiries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canActForAgent(user, inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	messages, err := app.models.Inquiries.GetMessages(inquiry.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiry_messages": messages}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOversizedHeadersRejected(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limits.maxHeaderBytes = 4096
+
+	srv := app.newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config = srv
+	ts.Start()
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"within the limit", strings.Repeat("a", 1024), http.StatusOK},
+		// net/http allows 4096 bytes of slack over MaxHeaderBytes
+		{"over the limit", strings.Repeat("a", 16<<10), http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Padding", tt.header)
+
+			res, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != tt.want {
+				t.Errorf("got status %d; want %d", res.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestOversizedJSONBodyRejected(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limits.maxJSONBytes = 1024
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			Title string `json:"title"`
+		}
+		if err := app.readJSON(w, r, &input); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name  string
+		title string
+		want  int
+	}{
+		{"within the limit", strings.Repeat("a", 512), http.StatusNoContent},
+		{"over the limit", strings.Repeat("a", 2048), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(map[string]string{"title": tt.title})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/properties", bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d (body %s)", rr.Code, tt.want, rr.Body)
+			}
+		})
+	}
+}
+
+func TestOversizedMultipartBodyRejected(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limits.maxUploadBytes = 4096
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := app.parseMultipartForm(w, r, 1024); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"within the limit", 1024, http.StatusNoContent},
+		{"over the limit", 8192, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			part, err := mw.CreateFormFile("file", "photo.jpg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write(bytes.Repeat([]byte{0xff}, tt.size))
+			mw.Close()
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/properties/1/media", &body)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d (body %s)", rr.Code, tt.want, rr.Body)
+			}
+		})
+	}
+}
+
+func TestNonJSONContentTypeRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		allowMissing bool
+		want         int
+	}{
+		{"text/plain", "text/plain", true, http.StatusUnsupportedMediaType},
+		{"form encoded", "application/x-www-form-urlencoded", true, http.StatusUnsupportedMediaType},
+		{"application/json", "application/json", false, http.StatusNoContent},
+		{"json with charset", "application/json; charset=utf-8", false, http.StatusNoContent},
+		{"vendor json", vendorMediaType(1), false, http.StatusNoContent},
+		{"missing when lenient", "", true, http.StatusNoContent},
+		{"missing when strict", "", false, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.limits.maxJSONBytes = 1024
+			app.config.limits.allowMissingContentType = tt.allowMissing
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var input struct {
+					Title string `json:"title"`
+				}
+				if err := app.readJSON(w, r, &input); err != nil {
+					app.badRequestResponse(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/properties", strings.NewReader(`{"title": "Flat"}`))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d (body %s)", rr.Code, tt.want, rr.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "fmt"
+
+// Location header values for created resources. Each points at the GET route that
+// returns the resource, or its list where the resource has no route of its own.
+
+// propertyLocation is the single property route
+func propertyLocation(id int64) string {
+	return fmt.Sprintf("/v1/property/%d", id)
+}
+
+// propertyMediaLocation is the property's media list; media has no single-item route
+func propertyMediaLocation(propertyID int64) string {
+	return fmt.Sprintf("/v1/property/%d/media", propertyID)
+}
+
+// propertyReviewsLocation is the property's review list; reviews have no single-review route
+func propertyReviewsLocation(propertyID int64) string {
+	return fmt.Sprintf("/v1/property/%d/reviews", propertyID)
+}
+
+// userInquiryLocation is the inquiry as seen by the user who sent it
+func userInquiryLocation(id int64) string {
+	return fmt.Sprintf("/v1/users/me/inquiries/%d", id)
+}
+
+// agentInquiryLocation is the inquiry as seen by the agent who received it
+func agentInquiryLocation(id int64) string {
+	return fmt.Sprintf("/v1/agents/me/inquiries/%d", id)
+}
+
+// userScheduleLocation is the viewing as seen by the user who booked it
+func userScheduleLocation(id int64) string {
+	return fmt.Sprintf("/v1/users/me/schedules/%d", id)
+}
+
+// savedSearchLocation is the user's saved search
+func savedSearchLocation(id int64) string {
+	return fmt.Sprintf("/v1/users/me/saved-searches/%d", id)
+}
+
+// comparisonLocation is the shared comparison
+func comparisonLocation(shareToken string) string {
+	return "/v1/comparisons/" + shareToken
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLocationsResolveToGetRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	router := app.router()
+
+	locations := []string{
+		propertyLocation(12),
+		propertyMediaLocation(12),
+		propertyReviewsLocation(12),
+		userInquiryLocation(34),
+		agentInquiryLocation(34),
+		userScheduleLocation(56),
+		savedSearchLocation(78),
+		comparisonLocation("a1b2c3"),
+	}
+
+	for _, location := range locations {
+		t.Run(location, func(t *testing.T) {
+			handle, _, _ := router.Lookup(http.MethodGet, location)
+			if handle == nil {
+				t.Errorf("no GET route serves %s", location)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/jsonlog"
 	"github.com/codercollo/property/backend/internal/mailer"
+	"github.com/codercollo/property/backend/internal/validator"
 	_ "github.com/lib/pq"
 )
 
@@ -34,24 +35,37 @@ type config struct {
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
+		maxLifetime  string
 	}
 	limiter struct {
-		rps     float64
-		burst   int
-		enabled bool
+		rps          float64
+		burst        int
+		enabled      bool
+		exemptAdmins bool
+		internalKeys []string
 	}
 	smtp struct {
-		host     string
-		port     int
-		username string
-		password string
-		sender   string
+		host        string
+		port        int
+		username    string
+		password    string
+		sender      string
+		replyTo     string
+		relayDomain string
+		relaySecret string
 	}
 	cors struct {
 		trustedOrigins []string
 	}
+	tls struct {
+		certFile     string
+		keyFile      string
+		redirectPort int
+	}
 	jwt struct {
-		secret string
+		secret   string
+		issuer   string
+		audience string
 	}
 	mpesa struct {
 		consumerKey    string
@@ -61,15 +75,103 @@ type config struct {
 		environment    string
 	}
 	baseURL string
+	sort    struct {
+		properties string
+	}
+	search struct {
+		fuzzyLocations     bool
+		locationSimilarity float64
+	}
+	workers struct {
+		size           int
+		queueSize      int
+		enqueueTimeout time.Duration
+	}
+	listings struct {
+		lifetime       time.Duration
+		reminderWindow time.Duration
+		bumpCooldown   time.Duration
+
+		autoApproveVerified bool
+
+		maxFeatures int
+		maxImages   int
+
+		maxFeatured            int
+		maxFeaturedPerLocation int
+		featuredDuration       time.Duration
+
+		qualityWeights map[string]int
+	}
+	schedules struct {
+		completionGrace time.Duration
+		defaultDuration int
+		maxDuration     int
+		reminderLead    time.Duration
+		workingHours    data.WorkingHours
+	}
+	comparisons struct {
+		anonymousTTL time.Duration
+	}
+	marketStats struct {
+		cacheTTL time.Duration
+	}
+	stats struct {
+		cacheTTL time.Duration
+	}
+	inquiries struct {
+		limit       int
+		limitWindow time.Duration
+
+		autoCloseAfter  time.Duration
+		autoClosePrompt bool
+		autoCloseGrace  time.Duration
+
+		slaHours int
+	}
+	contact struct {
+		gatePhone   bool
+		revealLimit int
+	}
+	pagination struct {
+		maxPage int
+	}
+	accounts struct {
+		requireActivation bool
+	}
+	media struct {
+		maxImageDimension int
+		oversizedImages   string
+		avatarSize        int
+		avatarMaxBytes    int64
+		maxPerProperty    int
+	}
+	limits struct {
+		maxHeaderBytes int
+		maxJSONBytes   int64
+		maxUploadBytes int64
+
+		allowMissingContentType bool
+	}
+	json struct {
+		pretty bool
+	}
 }
 
 // Application dependencies
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config      config
+	logger      *jsonlog.Logger
+	models      data.Models
+	mailer      mailer.Mailer
+	wg          sync.WaitGroup
+	jobs        chan func()
+	qrCodes     *renderCache
+	brochures   *renderCache
+	marketStats *marketStatsCache
+
+	platformStats  *platformStatsCache
+	dashboardStats *dashboardStatsCache
 }
 
 func main() {
@@ -83,25 +185,89 @@ func main() {
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	flag.StringVar(&cfg.db.maxLifetime, "db-max-lifetime", "1h", "PostgreSQL max connection lifetime (0 keeps connections forever)")
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.BoolVar(&cfg.limiter.exemptAdmins, "limiter-exempt-admins", true, "Exempt authenticated admins from the rate limiter")
+	flag.Func("limiter-internal-keys", "API keys exempt from the rate limiter via the X-Internal-Key header (space separated)", func(val string) error {
+		cfg.limiter.internalKeys = strings.Fields(val)
+		return nil
+	})
 	flag.StringVar(&cfg.smtp.host, "smtp-host", "sandbox.smtp.mailtrap.io", "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 2525, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", "7c529b35aca45a", "SMTP username")
 	flag.StringVar(&cfg.smtp.password, "smtp-password", "e6cd237eff9652", "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Greenlight <itscollinsmaina@gmail.com>", "SMTP sender")
+	flag.StringVar(&cfg.smtp.replyTo, "smtp-reply-to", "", "Default Reply-To address (defaults to the sender)")
+	flag.StringVar(&cfg.smtp.relayDomain, "smtp-relay-domain", "", "Domain for masked inquiry relay addresses (empty disables the relay)")
+	flag.StringVar(&cfg.smtp.relaySecret, "smtp-relay-secret", "", "Secret signing relay addresses and authenticating inbound mail webhooks")
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
 	})
+	flag.StringVar(&cfg.tls.certFile, "tls-cert", "", "TLS certificate file; serves HTTPS and HTTP/2 when set with tls-key")
+	flag.StringVar(&cfg.tls.keyFile, "tls-key", "", "TLS private key file")
+	flag.IntVar(&cfg.tls.redirectPort, "redirect-port", 80, "Port for the HTTP to HTTPS redirect listener when TLS is enabled (0 disables it)")
 	flag.StringVar(&cfg.jwt.secret, "jwt-secret", "", "JWT secret")
+	flag.StringVar(&cfg.jwt.issuer, "jwt-issuer", "propertyown.api", "JWT issuer claim")
+	flag.StringVar(&cfg.jwt.audience, "jwt-audience", "propertyown.api", "JWT audience claim")
 	flag.StringVar(&cfg.mpesa.consumerKey, "mpesa-consumer-key", "", "M-Pesa consumer key")
 	flag.StringVar(&cfg.mpesa.consumerSecret, "mpesa-consumer-secret", "", "M-Pesa consumer secret")
 	flag.StringVar(&cfg.mpesa.passkey, "mpesa-passkey", "", "M-Pesa passkey")
 	flag.StringVar(&cfg.mpesa.shortCode, "mpesa-shortcode", "", "M-Pesa business short code")
 	flag.StringVar(&cfg.mpesa.environment, "mpesa-env", "sandbox", "M-Pesa environment (sandbox|production)")
 	flag.StringVar(&cfg.baseURL, "base-url", "http://localhost:4000", "Base URL for callbacks")
+	flag.IntVar(&cfg.workers.size, "background-workers", 10, "Number of background worker goroutines")
+	flag.IntVar(&cfg.workers.queueSize, "background-queue-size", 1000, "Background task queue size")
+	flag.DurationVar(&cfg.workers.enqueueTimeout, "background-enqueue-timeout", 250*time.Millisecond, "How long to wait for room in a full background queue before running the task outside the worker pool")
+	flag.DurationVar(&cfg.listings.lifetime, "listing-lifetime", 90*24*time.Hour, "Default lifetime of a property listing before it expires")
+	flag.DurationVar(&cfg.listings.reminderWindow, "listing-expiry-reminder", 7*24*time.Hour, "How long before expiry agents are reminded to renew a listing")
+	flag.DurationVar(&cfg.listings.bumpCooldown, "listing-bump-cooldown", 7*24*time.Hour, "How often an agent may bump a listing to the top of the newest sort")
+	flag.IntVar(&cfg.listings.maxFeatures, "listing-max-features", 10, "Most features a listing may have")
+	flag.IntVar(&cfg.listings.maxImages, "listing-max-images", 10, "Most images a listing may have")
+	flag.IntVar(&cfg.listings.maxFeatured, "max-featured-listings", 0, "Most properties that may be featured at once across the platform (0 = unlimited)")
+	flag.IntVar(&cfg.listings.maxFeaturedPerLocation, "max-featured-per-location", 0, "Most properties that may be featured at once in one location (0 = unlimited)")
+	flag.DurationVar(&cfg.listings.featuredDuration, "featured-duration", 30*24*time.Hour, "How long a listing stays featured (0 = until unfeatured)")
+	cfg.listings.qualityWeights, _ = data.ParseQualityWeights(data.DefaultQualityWeights)
+	flag.Func("listing-quality-weights", "Weights of the listing quality signals, as signal=weight pairs (default \""+data.DefaultQualityWeights+"\")", func(val string) error {
+		weights, err := data.ParseQualityWeights(val)
+		cfg.listings.qualityWeights = weights
+		return err
+	})
+	flag.BoolVar(&cfg.listings.autoApproveVerified, "listing-auto-approve-verified", false, "Approve new listings from verified agents without admin moderation")
+	flag.DurationVar(&cfg.schedules.completionGrace, "schedule-completion-grace", 2*time.Hour, "How long after a confirmed viewing ends before it is marked completed")
+	flag.IntVar(&cfg.schedules.defaultDuration, "schedule-default-duration", 60, "Viewing length in minutes when a booking does not give one")
+	flag.IntVar(&cfg.schedules.maxDuration, "schedule-max-duration", 480, "Longest viewing in minutes that may be booked")
+	flag.IntVar(&cfg.schedules.workingHours.StartHour, "schedule-workday-start", data.DefaultWorkdayStartHour, "Local hour from which viewings may be booked in the agent's timezone, for agents without configured availability")
+	flag.IntVar(&cfg.schedules.workingHours.EndHour, "schedule-workday-end", data.DefaultWorkdayEndHour, "Local hour by which viewings must end in the agent's timezone (24 = midnight), for agents without configured availability")
+	flag.DurationVar(&cfg.schedules.reminderLead, "schedule-reminder-lead", 24*time.Hour, "How long before a viewing the user and agent are emailed a reminder")
+	flag.DurationVar(&cfg.comparisons.anonymousTTL, "comparison-anonymous-ttl", 30*24*time.Hour, "How long comparisons saved without signing in stay shareable")
+	flag.DurationVar(&cfg.marketStats.cacheTTL, "market-stats-cache-ttl", 15*time.Minute, "How long market stats are cached before being recomputed")
+	flag.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", time.Minute, "How often cached platform stats are refreshed and how long agent dashboard stats are cached")
+	flag.BoolVar(&cfg.accounts.requireActivation, "require-activation", true, "Require an activated account to send inquiries, book viewings, RSVP and pay")
+	flag.IntVar(&cfg.inquiries.limit, "inquiry-limit", 1, "Inquiries a user may send about one property per inquiry-limit-window; admins are exempt (0 disables the limit)")
+	flag.DurationVar(&cfg.inquiries.limitWindow, "inquiry-limit-window", 24*time.Hour, "Window for the per-user, per-property inquiry limit")
+	flag.DurationVar(&cfg.inquiries.autoCloseAfter, "inquiry-auto-close-after", 30*24*time.Hour, "Close new or contacted inquiries with no activity for this long (0 disables)")
+	flag.BoolVar(&cfg.inquiries.autoClosePrompt, "inquiry-auto-close-prompt", false, "Email inquirers a still-interested prompt before their stale inquiry is closed")
+	flag.DurationVar(&cfg.inquiries.autoCloseGrace, "inquiry-auto-close-grace", 7*24*time.Hour, "How long after the still-interested prompt a stale inquiry is closed")
+	flag.IntVar(&cfg.inquiries.slaHours, "inquiry-sla-hours", 24, "Hours an agent has to respond before a new inquiry is flagged overdue")
+	flag.BoolVar(&cfg.contact.gatePhone, "contact-reveal-gating", true, "Hide agent phone numbers from anonymous visitors until revealed by a signed-in user")
+	flag.IntVar(&cfg.contact.revealLimit, "contact-reveal-limit", 30, "Maximum agent contact reveals per user per hour")
+	flag.IntVar(&cfg.media.maxImageDimension, "media-max-image-dimension", 4096, "Maximum width or height in pixels of uploaded images (0 disables the limit)")
+	flag.StringVar(&cfg.media.oversizedImages, "media-oversized-images", oversizedImageDownscale, "What to do with images over the maximum dimension (reject|downscale)")
+	flag.IntVar(&cfg.media.maxPerProperty, "media-max-per-property", 30, "Maximum number of media items a single property may have")
+	flag.IntVar(&cfg.media.avatarSize, "avatar-size", 256, "Width and height in pixels of processed profile photos")
+	flag.Int64Var(&cfg.media.avatarMaxBytes, "avatar-max-bytes", data.MaxProfilePhotoSize, "Maximum size of an uploaded profile photo in bytes")
+	flag.IntVar(&cfg.limits.maxHeaderBytes, "max-header-bytes", 1<<20, "Maximum size of request headers in bytes")
+	flag.Int64Var(&cfg.limits.maxJSONBytes, "max-json-bytes", 1<<20, "Maximum size of a JSON request body in bytes")
+	flag.Int64Var(&cfg.limits.maxUploadBytes, "max-upload-bytes", 50<<20, "Maximum size of a multipart upload request body in bytes")
+	flag.BoolVar(&cfg.limits.allowMissingContentType, "json-allow-missing-content-type", true, "Accept JSON request bodies sent without a Content-Type header")
+	flag.BoolVar(&cfg.json.pretty, "json-pretty", false, "Indent JSON responses (defaults to on in development; ?pretty= overrides per request)")
+	flag.IntVar(&cfg.pagination.maxPage, "pagination-max-page", 1000, "Deepest page offset pagination will serve")
+	flag.StringVar(&cfg.sort.properties, "default-property-sort", defaultSortProperties, "Default sort for property listings (comma separated keys)")
+	flag.BoolVar(&cfg.search.fuzzyLocations, "search-fuzzy-locations", true, "Let location filters match misspelt locations (requires the pg_trgm extension)")
+	flag.Float64Var(&cfg.search.locationSimilarity, "search-location-similarity", 0.3, "Word similarity (0-1] a location needs to match a misspelt location filter")
 
 	// Create a new version boolean flag with the default value of false.
 	displayVersion := flag.Bool("version", false, "Display version and exit")
@@ -117,6 +283,148 @@ func main() {
 	//Init JSON logger at INFO level
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
+	//Responses are indented in development and compact elsewhere unless set explicitly
+	prettySet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "json-pretty" {
+			prettySet = true
+		}
+	})
+	if !prettySet {
+		cfg.json.pretty = cfg.env == "development"
+	}
+
+	//Tokens can't be signed or verified without an issuer and audience
+	if strings.TrimSpace(cfg.jwt.issuer) == "" || strings.TrimSpace(cfg.jwt.audience) == "" {
+		logger.PrintFatal(fmt.Errorf("jwt-issuer and jwt-audience must be set"), nil)
+	}
+
+	//Reject worker pool settings that would leave background tasks unserviced
+	if cfg.workers.size < 1 || cfg.workers.queueSize < 0 || cfg.workers.enqueueTimeout < 0 {
+		logger.PrintFatal(fmt.Errorf("background-workers must be at least 1, and background-queue-size and background-enqueue-timeout must not be negative"), nil)
+	}
+
+	//Reject a listing lifetime that would expire listings immediately
+	if cfg.listings.lifetime < 24*time.Hour || cfg.listings.reminderWindow <= 0 || cfg.listings.reminderWindow >= cfg.listings.lifetime {
+		logger.PrintFatal(fmt.Errorf("listing-lifetime must be at least 24h and listing-expiry-reminder must be positive and shorter than it"), nil)
+	}
+
+	//A zero cooldown would let agents keep a listing permanently on top
+	if cfg.listings.bumpCooldown < time.Hour {
+		logger.PrintFatal(fmt.Errorf("listing-bump-cooldown must be at least 1h"), nil)
+	}
+
+	//The default viewing length must itself be bookable, and no viewing may run past a day
+	if cfg.schedules.maxDuration < 1 || cfg.schedules.maxDuration > 24*60 || cfg.schedules.defaultDuration < 1 || cfg.schedules.defaultDuration > cfg.schedules.maxDuration {
+		logger.PrintFatal(fmt.Errorf("schedule-max-duration must be between 1 and 1440 and schedule-default-duration between 1 and schedule-max-duration"), nil)
+	}
+
+	//Working hours must leave part of the day bookable
+	v := validator.New()
+	if data.ValidateWorkingHours(v, cfg.schedules.workingHours); !v.Valid() {
+		logger.PrintFatal(fmt.Errorf("schedule-workday-start must be between 0 and 23 and schedule-workday-end between it and 24"), nil)
+	}
+
+	//The reminder job runs every 15 minutes, so shorter leads could miss viewings entirely
+	if cfg.schedules.reminderLead < 30*time.Minute {
+		logger.PrintFatal(fmt.Errorf("schedule-reminder-lead must be at least 30m"), nil)
+	}
+
+	//Listings need at least one feature and image, so the maxima can't go below that
+	if cfg.listings.maxFeatures < data.MinPropertyFeatures || cfg.listings.maxImages < data.MinPropertyImages {
+		logger.PrintFatal(fmt.Errorf("listing-max-features and listing-max-images must be at least 1"), nil)
+	}
+
+	//Featured slots caps are optional, so zero turns them off
+	if cfg.listings.maxFeatured < 0 || cfg.listings.maxFeaturedPerLocation < 0 {
+		logger.PrintFatal(fmt.Errorf("max-featured-listings and max-featured-per-location must not be negative"), nil)
+	}
+
+	//Featured periods are checked hourly, so shorter ones would overrun
+	if cfg.listings.featuredDuration != 0 && cfg.listings.featuredDuration < time.Hour {
+		logger.PrintFatal(fmt.Errorf("featured-duration must be 0 or at least 1h"), nil)
+	}
+
+	//Market stats are aggregated over every listing, so they must be cached for a while
+	if cfg.marketStats.cacheTTL < time.Second {
+		logger.PrintFatal(fmt.Errorf("market-stats-cache-ttl must be at least 1s"), nil)
+	}
+
+	//Platform stats are refreshed in the background every TTL, so it can't be too short
+	if cfg.stats.cacheTTL < time.Second {
+		logger.PrintFatal(fmt.Errorf("stats-cache-ttl must be at least 1s"), nil)
+	}
+
+	//Anonymous comparisons must stay shareable for a while before cleanup removes them
+	if cfg.comparisons.anonymousTTL < time.Hour {
+		logger.PrintFatal(fmt.Errorf("comparison-anonymous-ttl must be at least 1h"), nil)
+	}
+
+	//A negative limit or an empty window would make the inquiry limit meaningless
+	if cfg.inquiries.limit < 0 || (cfg.inquiries.limit > 0 && cfg.inquiries.limitWindow <= 0) {
+		logger.PrintFatal(fmt.Errorf("inquiry-limit must not be negative and inquiry-limit-window must be positive"), nil)
+	}
+
+	//Stale inquiries are closed after a non-negative window, with a positive grace when prompting
+	if cfg.inquiries.autoCloseAfter < 0 || (cfg.inquiries.autoClosePrompt && cfg.inquiries.autoCloseGrace <= 0) {
+		logger.PrintFatal(fmt.Errorf("inquiry-auto-close-after must not be negative and inquiry-auto-close-grace must be positive"), nil)
+	}
+
+	//The response SLA must leave agents some time to respond
+	if cfg.inquiries.slaHours < 1 {
+		logger.PrintFatal(fmt.Errorf("inquiry-sla-hours must be at least 1"), nil)
+	}
+
+	//Reveals are rate limited per user, so the limit must allow at least one
+	if cfg.contact.revealLimit < 1 {
+		logger.PrintFatal(fmt.Errorf("contact-reveal-limit must be at least 1"), nil)
+	}
+
+	//Uploaded images are either rejected or downscaled past the maximum dimension
+	if cfg.media.maxImageDimension < 0 || (cfg.media.oversizedImages != oversizedImageReject && cfg.media.oversizedImages != oversizedImageDownscale) {
+		logger.PrintFatal(fmt.Errorf("media-max-image-dimension must not be negative and media-oversized-images must be reject or downscale"), nil)
+	}
+
+	//Every property must be able to hold at least one media item
+	if cfg.media.maxPerProperty < 1 {
+		logger.PrintFatal(fmt.Errorf("media-max-per-property must be at least 1"), nil)
+	}
+
+	//The mail relay signs its addresses, so it needs a secret
+	if cfg.smtp.relayDomain != "" && len(cfg.smtp.relaySecret) < 16 {
+		logger.PrintFatal(fmt.Errorf("smtp-relay-secret must be at least 16 characters when smtp-relay-domain is set"), nil)
+	}
+
+	//Avatars must be a usable size
+	if cfg.media.avatarSize < 32 || cfg.media.avatarSize > 2048 || cfg.media.avatarMaxBytes < 1024 {
+		logger.PrintFatal(fmt.Errorf("avatar-size must be between 32 and 2048 and avatar-max-bytes at least 1024"), nil)
+	}
+
+	//Request size limits must leave room for a real request
+	if cfg.limits.maxHeaderBytes < 4096 || cfg.limits.maxJSONBytes < 1024 || cfg.limits.maxUploadBytes < 1024 {
+		logger.PrintFatal(fmt.Errorf("max-header-bytes must be at least 4096, and max-json-bytes and max-upload-bytes at least 1024"), nil)
+	}
+
+	//Deep paging is capped to protect the database from large OFFSET scans
+	if cfg.pagination.maxPage < 1 {
+		logger.PrintFatal(fmt.Errorf("pagination-max-page must be at least 1"), nil)
+	}
+
+	//TLS needs both halves of the key pair, and both must be readable
+	if err := validateTLSFiles(cfg.tls.certFile, cfg.tls.keyFile); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	//Reject a default sort that would fail validation on every request
+	if err := validateDefaultSort(cfg.sort.properties, data.PropertySortSafelist); err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	//A zero threshold would match every location
+	if cfg.search.locationSimilarity <= 0 || cfg.search.locationSimilarity > 1 {
+		logger.PrintFatal(fmt.Errorf("search-location-similarity must be greater than 0 and at most 1"), nil)
+	}
+
 	//Open database connection pool
 	db, err := openDB(cfg)
 	if err != nil {
@@ -125,6 +433,22 @@ func main() {
 	defer db.Close()
 	logger.PrintInfo("database connection pool established", nil)
 
+	//Fuzzy location matching needs pg_trgm; without it location filters stay exact
+	locationSearch := data.LocationSearch{SimilarityThreshold: cfg.search.locationSimilarity}
+	if cfg.search.fuzzyLocations {
+		available, err := data.TrigramAvailable(db)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		if !available {
+			logger.PrintInfo("pg_trgm is not installed, fuzzy location matching is disabled", nil)
+		}
+		locationSearch.Fuzzy = available
+	}
+
+	models := data.NewModels(db)
+	models.Properties.Locations = locationSearch
+
 	//Publish version
 	expvar.NewString("version").Set(version)
 
@@ -145,16 +469,36 @@ func main() {
 	app := &application{
 		config: cfg,
 		logger: logger,
-		models: data.NewModels(db),
+		models: models,
 		mailer: mailer.New(
 			cfg.smtp.host,
 			cfg.smtp.port,
 			cfg.smtp.username,
 			cfg.smtp.password,
 			cfg.smtp.sender,
+			cfg.smtp.replyTo,
 		),
+		jobs:        make(chan func(), cfg.workers.queueSize),
+		qrCodes:     newRenderCache(maxQRCacheLen),
+		brochures:   newRenderCache(maxBrochureCacheLen),
+		marketStats: newMarketStatsCache(),
+
+		platformStats:  &platformStatsCache{},
+		dashboardStats: newDashboardStatsCache(),
 	}
 
+	// Publish the background task queue depth and capacity.
+	expvar.Publish("background_queue", expvar.Func(func() interface{} {
+		return map[string]int{
+			"depth":    len(app.jobs),
+			"capacity": cap(app.jobs),
+			"workers":  cfg.workers.size,
+		}
+	}))
+
+	//Start the background worker pool
+	app.startWorkers()
+
 	//Start background jobs
 	app.startBackgroundJobs()
 
@@ -185,6 +529,16 @@ func openDB(cfg config) (*sql.DB, error) {
 	}
 	db.SetConnMaxIdleTime(duration)
 
+	//Recycle connections periodically so they follow failovers and pooler restarts
+	lifetime, err := time.ParseDuration(cfg.db.maxLifetime)
+	if err != nil {
+		return nil, err
+	}
+	if lifetime < 0 {
+		return nil, fmt.Errorf("db-max-lifetime must not be negative")
+	}
+	db.SetConnMaxLifetime(lifetime)
+
 	//Verify connection to the database with a 5-sec timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// maxMarketStatsCacheLen bounds the market stats cache
+const maxMarketStatsCacheLen = 500
+
+// marketStatsEntry is a cached market stats result and when it stops being served
+type marketStatsEntry struct {
+	stats     []*data.MarketStats
+	expiresAt time.Time
+}
+
+// marketStatsCache holds market stats keyed by location and property type
+type marketStatsCache struct {
+	mu    sync.Mutex
+	items map[string]marketStatsEntry
+}
+
+// newMarketStatsCache creates an empty market stats cache
+func newMarketStatsCache() *marketStatsCache {
+	return &marketStatsCache{items: make(map[string]marketStatsEntry)}
+}
+
+// get returns cached stats if present and not yet expired
+func (c *marketStatsCache) get(key string) ([]*data.MarketStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.items[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+// set stores stats for ttl, clearing the cache once it grows past its bound
+func (c *marketStatsCache) set(key string, stats []*data.MarketStats, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.items) >= maxMarketStatsCacheLen {
+		c.items = make(map[string]marketStatsEntry)
+	}
+	c.items[key] = marketStatsEntry{stats: stats, expiresAt: time.Now().Add(ttl)}
+}
+
+// showMarketStatsHandler returns average and median prices, overall and per square
+// metre, for approved listings grouped by location and property type. Results are
+// cached for the configured TTL.
+func (app *application) showMarketStatsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	location := strings.TrimSpace(app.readString(qs, "location", ""))
+	propertyType := strings.TrimSpace(app.readString(qs, "property_type", ""))
+
+	v := validator.New()
+	v.Check(len(location) <= 100, "location", "must not be more than 100 bytes long")
+	v.Check(len(propertyType) <= 50, "property_type", "must not be more than 50 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key := strings.ToLower(location) + "\x00" + strings.ToLower(propertyType)
+
+	stats, ok := app.marketStats.get(key)
+	if !ok {
+		var err error
+		stats, err = app.models.Properties.GetMarketStats(location, propertyType)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.marketStats.set(key, stats, app.config.marketStats.cacheTTL)
+	}
+
+	env := envelope{
+		"market_stats": stats,
+		"min_listings": data.MinMarketStatsListings,
+	}
+
+	err := app.writeJSON(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
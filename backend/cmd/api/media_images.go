@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Policies for uploaded images larger than the configured maximum dimension
+const (
+	oversizedImageReject    = "reject"
+	oversizedImageDownscale = "downscale"
+)
+
+// maxDecodePixels guards against decompression bombs; larger images are always rejected
+const maxDecodePixels = 100_000_000
+
+// imageTooLargeError is returned when an image exceeds the maximum dimension and
+// the policy is to reject it
+type imageTooLargeError struct {
+	width, height, max int
+}
+
+func (e *imageTooLargeError) Error() string {
+	return fmt.Sprintf("image must not be larger than %dx%d pixels (got %dx%d)", e.max, e.max, e.width, e.height)
+}
+
+// processedImage is an uploaded image ready to be stored, with its dimensions
+// before and after any downscaling
+type processedImage struct {
+	body           io.Reader
+	size           int64
+	originalWidth  int
+	originalHeight int
+	width          int
+	height         int
+}
+
+// processMediaImage enforces the configured maximum image dimension on an upload.
+// Files the standard decoders can't read (eg webp, pdf) are returned as nil and
+// stored unchanged. Oversized images are rejected or downscaled and re-encoded in
+// their original format, depending on the configured policy.
+func (app *application) processMediaImage(file io.ReadSeeker, size int64) (*processedImage, error) {
+	cfg, format, err := image.DecodeConfig(file)
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil {
+		return nil, nil
+	}
+
+	processed := &processedImage{
+		body:           file,
+		size:           size,
+		originalWidth:  cfg.Width,
+		originalHeight: cfg.Height,
+		width:          cfg.Width,
+		height:         cfg.Height,
+	}
+
+	maxDimension := app.config.media.maxImageDimension
+	if maxDimension == 0 || (cfg.Width <= maxDimension && cfg.Height <= maxDimension) {
+		return processed, nil
+	}
+
+	if app.config.media.oversizedImages == oversizedImageReject || cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, &imageTooLargeError{width: cfg.Width, height: cfg.Height, max: maxDimension}
+	}
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := downscaleImage(src, maxDimension)
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	default:
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	processed.body = &buf
+	processed.size = int64(buf.Len())
+	processed.width = dst.Bounds().Dx()
+	processed.height = dst.Bounds().Dy()
+
+	return processed, nil
+}
+
+// downscaleImage shrinks src so its longest side is maxDimension
+func downscaleImage(src image.Image, maxDimension int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	scale := float64(maxDimension) / float64(max(w, h))
+	dw := max(1, int(math.Round(float64(w)*scale)))
+	dh := max(1, int(math.Round(float64(h)*scale)))
+
+	return resizeImage(src, bounds, dw, dh)
+}
+
+// resizeImage scales the bounds area of src to dw x dh, averaging the source pixels
+// that fall under each destination pixel
+func resizeImage(src image.Image, bounds image.Rectangle, dw, dh int) *image.RGBA {
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+// =============================================================================
+// PROFILE PHOTOS
+// =============================================================================
+
+// errNotAnImage is returned for profile photos the avatar pipeline can't read
+var errNotAnImage = errors.New("file must be a JPEG, PNG or WebP image")
+
+// processAvatar turns an uploaded profile photo into the standard avatar: the
+// largest centred square, resized to the configured avatar size and re-encoded in
+// its original format. It returns the encoded avatar and its file extension.
+// WebP photos can't be decoded by the standard library, so they are checked and
+// stored as uploaded.
+func (app *application) processAvatar(file io.Reader) (io.Reader, string, error) {
+	var buf bytes.Buffer
+
+	size := app.config.media.avatarSize
+
+	cfg, format, err := image.DecodeConfig(io.TeeReader(file, &buf))
+	if err != nil {
+		width, height, ok := webpDimensions(buf.Bytes())
+		if !ok {
+			return nil, "", errNotAnImage
+		}
+		if width*height > maxDecodePixels {
+			return nil, "", &imageTooLargeError{width: width, height: height, max: size}
+		}
+		return io.MultiReader(&buf, file), ".webp", nil
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, "", errNotAnImage
+	}
+
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, "", &imageTooLargeError{width: cfg.Width, height: cfg.Height, max: size}
+	}
+
+	src, _, err := image.Decode(io.MultiReader(&buf, file))
+	if err != nil {
+		return nil, "", errNotAnImage
+	}
+
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := resizeImage(src, image.Rect(x0, y0, x0+side, y0+side), size, size)
+
+	var out bytes.Buffer
+	ext := ".png"
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+		ext = ".jpg"
+	} else {
+		err = png.Encode(&out, dst)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &out, ext, nil
+}
+
+// webpDimensions reads the canvas size from the start of a WebP file, reporting false
+// if it isn't a lossy, lossless or extended WebP image
+func webpDimensions(header []byte) (width, height int, ok bool) {
+	if len(header) < 30 || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	switch string(header[12:16]) {
+	case "VP8 ":
+		// Lossy: a key frame start code followed by 14-bit dimensions
+		if header[23] != 0x9d || header[24] != 0x01 || header[25] != 0x2a {
+			return 0, 0, false
+		}
+		width = int(binary.LittleEndian.Uint16(header[26:28]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(header[28:30]) & 0x3fff)
+	case "VP8L":
+		// Lossless: a signature byte followed by 14-bit dimensions less one
+		if header[20] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(header[21:25])
+		width = int(bits&0x3fff) + 1
+		height = int(bits>>14&0x3fff) + 1
+	case "VP8X":
+		// Extended: 24-bit canvas dimensions less one
+		width = int(uint32(header[24])|uint32(header[25])<<8|uint32(header[26])<<16) + 1
+		height = int(uint32(header[27])|uint32(header[28])<<8|uint32(header[29])<<16) + 1
+	default:
+		return 0, 0, false
+	}
+
+	return width, height, width > 0 && height > 0
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessMediaImageOversized(t *testing.T) {
+	oversized := encodeTestPNG(t, 400, 200)
+
+	t.Run("reject policy", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.media.maxImageDimension = 100
+		app.config.media.oversizedImages = oversizedImageReject
+
+		_, err := app.processMediaImage(bytes.NewReader(oversized), int64(len(oversized)))
+
+		var tooLarge *imageTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Fatalf("got error %v; want an imageTooLargeError", err)
+		}
+		if tooLarge.width != 400 || tooLarge.height != 200 || tooLarge.max != 100 {
+			t.Errorf("got %+v; want 400x200 over 100", *tooLarge)
+		}
+	})
+
+	t.Run("downscale policy", func(t *testing.T) {
+		app := newTestApplication(t)
+		app.config.media.maxImageDimension = 100
+		app.config.media.oversizedImages = oversizedImageDownscale
+
+		processed, err := app.processMediaImage(bytes.NewReader(oversized), int64(len(oversized)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if processed.originalWidth != 400 || processed.originalHeight != 200 {
+			t.Errorf("got original %dx%d; want 400x200", processed.originalWidth, processed.originalHeight)
+		}
+		if processed.width != 100 || processed.height != 50 {
+			t.Errorf("got stored %dx%d; want 100x50", processed.width, processed.height)
+		}
+
+		body, err := io.ReadAll(processed.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(body)) != processed.size {
+			t.Errorf("got size %d; body is %d bytes", processed.size, len(body))
+		}
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format != "png" || cfg.Width != 100 || cfg.Height != 50 {
+			t.Errorf("got stored %s %dx%d; want png 100x50", format, cfg.Width, cfg.Height)
+		}
+	})
+}
+
+func TestProcessMediaImageWithinLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.media.maxImageDimension = 100
+	app.config.media.oversizedImages = oversizedImageReject
+
+	small := encodeTestPNG(t, 80, 40)
+
+	processed, err := app.processMediaImage(bytes.NewReader(small), int64(len(small)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.width != 80 || processed.height != 40 || processed.size != int64(len(small)) {
+		t.Errorf("got %dx%d, %d bytes; want the upload unchanged", processed.width, processed.height, processed.size)
+	}
+}
+
+func TestProcessMediaImageSkipsNonImages(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.media.maxImageDimension = 100
+	app.config.media.oversizedImages = oversizedImageReject
+
+	pdf := []byte("%PDF-1.4\n%%EOF\n")
+
+	processed, err := app.processMediaImage(bytes.NewReader(pdf), int64(len(pdf)))
+	if err != nil || processed != nil {
+		t.Errorf("got %v, %v; want the file passed through", processed, err)
+	}
+}
+
+// testWebPHeader returns the first bytes of a WebP file with the given chunk payload
+func testWebPHeader(chunk string, payload ...byte) []byte {
+	header := append([]byte("RIFF\x00\x00\x00\x00WEBP"+chunk+"\x00\x00\x00\x00"), payload...)
+	for len(header) < 30 {
+		header = append(header, 0)
+	}
+	return header
+}
+
+func TestWebPDimensions(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        []byte
+		width, height int
+		ok            bool
+	}{
+		// 640x480: 0x0280 and 0x01e0 after the key frame start code
+		{"lossy", testWebPHeader("VP8 ", 0, 0, 0, 0x9d, 0x01, 0x2a, 0x80, 0x02, 0xe0, 0x01), 640, 480, true},
+		// 640x480 stored less one in 14-bit fields: 639 | 479<<14
+		{"lossless", testWebPHeader("VP8L", 0x2f, 0x7f, 0xc2, 0x77, 0x00), 640, 480, true},
+		// 640x480 stored less one in 24-bit fields
+		{"extended", testWebPHeader("VP8X", 0, 0, 0, 0, 0x7f, 0x02, 0x00, 0xdf, 0x01, 0x00), 640, 480, true},
+		{"unknown chunk", testWebPHeader("ALPH"), 0, 0, false},
+		{"not a webp", []byte("%PDF-1.4\n%%EOF\n.............."), 0, 0, false},
+		{"truncated", []byte("RIFF\x00\x00\x00\x00WEBP"), 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := webpDimensions(tt.header)
+			if width != tt.width || height != tt.height || ok != tt.ok {
+				t.Errorf("got %dx%d, %v; want %dx%d, %v", width, height, ok, tt.width, tt.height, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProcessAvatarAcceptsWebP(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.media.avatarSize = 256
+
+	upload := append(testWebPHeader("VP8X", 0, 0, 0, 0, 0x7f, 0x02, 0x00, 0xdf, 0x01, 0x00), bytes.Repeat([]byte{0xaa}, 8192)...)
+
+	avatar, ext, err := app.processAvatar(bytes.NewReader(upload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext != ".webp" {
+		t.Errorf("got extension %q; want .webp", ext)
+	}
+
+	stored, err := io.ReadAll(avatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored, upload) {
+		t.Errorf("stored %d bytes; want the %d byte upload unchanged", len(stored), len(upload))
+	}
+
+	_, _, err = app.processAvatar(bytes.NewReader([]byte("not an image at all, just some text")))
+	if !errors.Is(err, errNotAnImage) {
+		t.Errorf("got error %v; want errNotAnImage", err)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"crypto/subtle"
 	"errors"
 	"expvar"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,7 +32,9 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-// rateLimit limits request rate using token bucket
+// rateLimit limits request rate using token bucket. It runs after authenticate, so
+// authenticated users get a bucket keyed by their user ID, however many addresses they
+// call from; anonymous requests are limited per client IP.
 func (app *application) rateLimit(next http.Handler) http.Handler {
 	type client struct {
 		limiter  *rate.Limiter
@@ -46,9 +50,9 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 		for {
 			time.Sleep(time.Minute)
 			mu.Lock()
-			for ip, c := range clients {
+			for key, c := range clients {
 				if time.Since(c.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(clients, key)
 				}
 			}
 			mu.Unlock()
@@ -56,17 +60,21 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 	}()
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if app.config.limiter.enabled {
-			ip := realip.FromRequest(r)
+		if app.config.limiter.enabled && !app.rateLimitExempt(r) {
+			key := "ip:" + realip.FromRequest(r)
+			if user := app.contextGetUser(r); !user.IsAnonymous() {
+				key = "user:" + strconv.FormatInt(user.ID, 10)
+			}
+
 			mu.Lock()
-			if _, found := clients[ip]; !found {
-				clients[ip] = &client{
+			if _, found := clients[key]; !found {
+				clients[key] = &client{
 					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps),
 						app.config.limiter.burst),
 				}
 			}
-			clients[ip].lastSeen = time.Now()
-			if !clients[ip].limiter.Allow() {
+			clients[key].lastSeen = time.Now()
+			if !clients[key].limiter.Allow() {
 				mu.Unlock()
 				app.rateLimitExceededResponse(w, r)
 				return
@@ -77,6 +85,33 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 	})
 }
 
+// rateLimitExempt reports whether the request comes from an admin, going by the user
+// authenticate loaded rather than the token, or from an internal caller
+func (app *application) rateLimitExempt(r *http.Request) bool {
+	if app.config.limiter.exemptAdmins && app.contextGetUser(r).Role == "admin" {
+		return true
+	}
+
+	key := r.Header.Get("X-Internal-Key")
+	if key == "" {
+		return false
+	}
+	for _, internalKey := range app.config.limiter.internalKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(internalKey)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptTokenClaims reports whether the claims are current and were issued by and for this
+// API. Tokens without an audience are refused, where the jwt package would accept them.
+func (app *application) acceptTokenClaims(claims *jwt.Claims) bool {
+	return claims.Valid(time.Now()) &&
+		claims.Issuer == app.config.jwt.issuer &&
+		slices.Contains(claims.Audiences, app.config.jwt.audience)
+}
+
 // authenticate verifies JWT tokens and checks if they're revoked
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -104,9 +139,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		}
 
 		// Validate token timing and metadata
-		if !claims.Valid(time.Now()) ||
-			claims.Issuer != "propertyown.api" ||
-			!claims.AcceptAudience("propertyown.api") {
+		if !app.acceptTokenClaims(claims) {
 			app.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
@@ -176,6 +209,16 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requireActivationPolicy guards write actions that reach other users. Accounts must be
+// activated when the require-activation policy is on, otherwise any authenticated user may proceed.
+func (app *application) requireActivationPolicy(next http.HandlerFunc) http.HandlerFunc {
+	if app.config.accounts.requireActivation {
+		return app.requireActivatedUser(next)
+	}
+
+	return app.requireAuthenticatedUser(next)
+}
+
 // requirePermission ensures the user has the specified permission
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -217,6 +260,17 @@ func (app *application) requireAdminRole(next http.HandlerFunc) http.HandlerFunc
 	})
 }
 
+// strictTransportSecurity tells browsers to use HTTPS only, when the server is serving TLS
+func (app *application) strictTransportSecurity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.tlsEnabled() {
+			w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // enableCORS handles CORS
 func (app *application) enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -242,13 +296,16 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// Request metrics are published once per process, so routes can be built more than once
+var (
+	totalRequestsReceived           = expvar.NewInt("total_requests_received")
+	totalResponsesSent              = expvar.NewInt("total_responses_sent")
+	totalProcessingTimeMicroseconds = expvar.NewInt("total_processing_time_μs")
+	totalResponsesSentByStatus      = expvar.NewMap("total_responses_sent_by_status")
+)
+
 // metrics collects request metrics
 func (app *application) metrics(next http.Handler) http.Handler {
-	totalRequestsReceived := expvar.NewInt("total_requests_received")
-	totalResponsesSent := expvar.NewInt("total_responses_sent")
-	totalProcessingTimeMicroseconds := expvar.NewInt("total_processing_time_μs")
-	totalResponsesSentByStatus := expvar.NewMap("total_responses_sent_by_status")
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		totalRequestsReceived.Add(1)
 
@@ -259,3 +316,17 @@ func (app *application) metrics(next http.Handler) http.Handler {
 		totalResponsesSentByStatus.Add(strconv.Itoa(metrics.Code), 1)
 	})
 }
+
+// jsonFormat honours ?pretty=true or ?pretty=false, overriding the configured default
+// for writeJSON through the request context
+func (app *application) jsonFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty"))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetPrettyJSON(r, pretty))
+	})
+}
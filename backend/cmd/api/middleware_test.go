@@ -0,0 +1,247 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/jsonlog"
+	"github.com/pascaldekloe/jwt"
+)
+
+const testJWTSecret = "test-secret-with-at-least-32-bytes!"
+
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	app := &application{logger: jsonlog.New(io.Discard, jsonlog.LevelInfo)}
+	app.config.jwt.secret = testJWTSecret
+	app.config.jwt.issuer = "propertyown.api"
+	app.config.jwt.audience = "propertyown.api"
+	return app
+}
+
+func signTestToken(t *testing.T, issuer string, audiences ...string) string {
+	t.Helper()
+
+	var claims jwt.Claims
+	claims.Subject = "1"
+	claims.Issued = jwt.NewNumericTime(time.Now())
+	claims.NotBefore = jwt.NewNumericTime(time.Now())
+	claims.Expires = jwt.NewNumericTime(time.Now().Add(time.Hour))
+	claims.Issuer = issuer
+	claims.Audiences = audiences
+
+	token, err := claims.HMACSign(jwt.HS256, []byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(token)
+}
+
+func TestAuthenticateRejectsForeignTokens(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong issuer", signTestToken(t, "other.api", "propertyown.api")},
+		{"wrong audience", signTestToken(t, "propertyown.api", "other.api")},
+		{"no audience", signTestToken(t, "propertyown.api")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("next handler called for a rejected token")
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			app.authenticate(next).ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestAcceptTokenClaims(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"matching issuer and audience", signTestToken(t, "propertyown.api", "propertyown.api"), true},
+		{"audience among several", signTestToken(t, "propertyown.api", "other.api", "propertyown.api"), true},
+		{"wrong issuer", signTestToken(t, "other.api", "propertyown.api"), false},
+		{"wrong audience", signTestToken(t, "propertyown.api", "other.api"), false},
+		{"no audience", signTestToken(t, "propertyown.api"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := jwt.HMACCheck([]byte(tt.token), []byte(testJWTSecret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := app.acceptTokenClaims(claims); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRunsBeforeRateLimit(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limiter.enabled = true
+	app.config.limiter.rps = 0.001
+	app.config.limiter.burst = 1
+	routes := app.routes()
+
+	// Signed with another secret, so authenticate answers 401 before any limit is counted
+	var claims jwt.Claims
+	claims.Subject = "1"
+	token, err := claims.HMACSign(jwt.HS256, []byte("some-other-secret-of-32-bytes-or-more"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(authorization string) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	for i := range 2 {
+		if code := send("Bearer " + string(token)); code != http.StatusUnauthorized {
+			t.Errorf("bad token request %d: got status %d; want %d", i+1, code, http.StatusUnauthorized)
+		}
+	}
+
+	// The rejected requests didn't use up the client's bucket
+	if code := send(""); code == http.StatusTooManyRequests {
+		t.Errorf("first anonymous request: got status %d", code)
+	}
+	if code := send(""); code != http.StatusTooManyRequests {
+		t.Errorf("second anonymous request: got status %d; want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limiter.enabled = true
+	app.config.limiter.rps = 0.001
+	app.config.limiter.burst = 1
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limited := app.rateLimit(next)
+
+	send := func(remoteAddr string, user *data.User) int {
+		r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil)
+		r.RemoteAddr = remoteAddr
+		r = app.contextSetUser(r, user)
+		rr := httptest.NewRecorder()
+		limited.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	// Anonymous requests share a bucket per IP
+	if code := send("192.0.2.1:1000", data.AnonymousUser); code != http.StatusOK {
+		t.Fatalf("first anonymous request: got status %d", code)
+	}
+	if code := send("192.0.2.1:1001", data.AnonymousUser); code != http.StatusTooManyRequests {
+		t.Errorf("second anonymous request from the same IP: got status %d; want %d", code, http.StatusTooManyRequests)
+	}
+	if code := send("192.0.2.2:1000", data.AnonymousUser); code != http.StatusOK {
+		t.Errorf("anonymous request from another IP: got status %d; want %d", code, http.StatusOK)
+	}
+
+	// An authenticated user gets their own bucket, even from an IP that is already limited
+	user := &data.User{ID: 1, Role: "user"}
+	if code := send("192.0.2.1:1002", user); code != http.StatusOK {
+		t.Errorf("authenticated request: got status %d; want %d", code, http.StatusOK)
+	}
+	if code := send("192.0.2.3:1000", user); code != http.StatusTooManyRequests {
+		t.Errorf("same user from another IP: got status %d; want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitExemptions(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limiter.enabled = true
+	app.config.limiter.rps = 0.001
+	app.config.limiter.burst = 1
+	app.config.limiter.exemptAdmins = true
+	app.config.limiter.internalKeys = []string{"internal-key"}
+
+	admin := &data.User{ID: 1, Role: "admin"}
+	agent := &data.User{ID: 2, Role: "agent"}
+
+	tests := []struct {
+		name        string
+		user        *data.User
+		internalKey string
+		limited     bool
+	}{
+		{"admin", admin, "", false},
+		{"agent", agent, "", true},
+		{"anonymous", data.AnonymousUser, "", true},
+		{"internal key", data.AnonymousUser, "internal-key", false},
+		{"wrong internal key", data.AnonymousUser, "guess", true},
+	}
+
+	send := func(limited http.Handler, user *data.User, internalKey string) int {
+		r := app.contextSetUser(httptest.NewRequest(http.MethodGet, "/v1/healthcheck", nil), user)
+		if internalKey != "" {
+			r.Header.Set("X-Internal-Key", internalKey)
+		}
+		rr := httptest.NewRecorder()
+		limited.ServeHTTP(rr, r)
+		return rr.Code
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limited := app.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			var codes []int
+			for range 5 {
+				codes = append(codes, send(limited, tt.user, tt.internalKey))
+			}
+
+			if got := slices.Contains(codes, http.StatusTooManyRequests); got != tt.limited {
+				t.Errorf("got statuses %v; want limited=%v", codes, tt.limited)
+			}
+		})
+	}
+
+	t.Run("admins limited when the exemption is off", func(t *testing.T) {
+		app.config.limiter.exemptAdmins = false
+		defer func() { app.config.limiter.exemptAdmins = true }()
+
+		limited := app.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		var last int
+		for range 2 {
+			last = send(limited, admin, "")
+		}
+		if last != http.StatusTooManyRequests {
+			t.Errorf("got status %d; want %d", last, http.StatusTooManyRequests)
+		}
+	})
+}
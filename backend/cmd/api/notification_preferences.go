@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// Digest email limits
+const (
+	maxInquiryDigestItems   = 25  // inquiries listed in a single digest email
+	maxInquiryDigestExcerpt = 200 // characters of each inquiry message shown
+)
+
+// =============================================================================
+// AGENT: NOTIFICATION PREFERENCES
+// =============================================================================
+
+// getNotificationPreferencesHandler returns how the agent is emailed about new inquiries
+// and payment outcomes
+func (app *application) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	prefs, err := app.models.Notifications.Get(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"notification_preferences": prefs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateNotificationPreferencesHandler switches the agent between an email per inquiry
+// and an hourly or daily digest, whether urgent inquiries skip the digest, and whether
+// payment outcomes are emailed
+func (app *application) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	prefs, err := app.models.Notifications.Get(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var input struct {
+		InquiryEmails     *string `json:"inquiry_emails"`
+		UrgentImmediately *bool   `json:"urgent_immediately"`
+		PaymentEmails     *bool   `json:"payment_emails"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.InquiryEmails != nil {
+		prefs.InquiryEmails = *input.InquiryEmails
+	}
+	if input.UrgentImmediately != nil {
+		prefs.UrgentImmediately = *input.UrgentImmediately
+	}
+	if input.PaymentEmails != nil {
+		prefs.PaymentEmails = *input.PaymentEmails
+	}
+
+	v := validator.New()
+	if data.ValidateNotificationPreferences(v, prefs); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Notifications.Set(prefs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"notification_preferences": prefs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// BACKGROUND: INQUIRY DIGESTS
+// =============================================================================
+
+// sendInquiryDigests emails each agent in digest mode a summary of the inquiries
+// received since their last digest
+func (app *application) sendInquiryDigests() {
+	now := time.Now()
+
+	digests, err := app.models.Notifications.GetDueDigests(now)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "send_inquiry_digests",
+		})
+		return
+	}
+
+	sent := 0
+
+	for _, digest := range digests {
+		ok, err := app.sendInquiryDigest(digest, now)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":      "send_inquiry_digests",
+				"agent_id": strconv.FormatInt(digest.AgentID, 10),
+			})
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	if len(digests) > 0 {
+		app.logger.PrintInfo("inquiry digests processed", map[string]string{
+			"job":         "send_inquiry_digests",
+			"agents":      strconv.Itoa(len(digests)),
+			"emails_sent": strconv.Itoa(sent),
+		})
+	}
+}
+
+// sendInquiryDigest emails one agent's digest and records the run. It reports whether
+// an email was sent; nothing is sent when no inquiries arrived.
+func (app *application) sendInquiryDigest(digest *data.InquiryDigest, ranAt time.Time) (bool, error) {
+	inquiries, total, err := app.models.Inquiries.GetForDigest(digest.AgentID, digest.LastDigestAt, ranAt, digest.UrgentImmediately, maxInquiryDigestItems)
+	if err != nil {
+		return false, err
+	}
+
+	if len(inquiries) > 0 {
+		items := make([]map[string]interface{}, len(inquiries))
+		for i, inquiry := range inquiries {
+			items[i] = map[string]interface{}{
+				"id":            inquiry.ID,
+				"inquirerName":  inquiry.Name,
+				"propertyTitle": inquiry.PropertyTitle,
+				"inquiryType":   inquiry.InquiryType,
+				"priority":      inquiry.Priority,
+				"message":       digestExcerpt(inquiry.Message),
+			}
+		}
+
+		emailData := map[string]interface{}{
+			"agentName": digest.AgentName,
+			"period":    digest.InquiryEmails,
+			"total":     total,
+			"inquiries": items,
+			"moreCount": total - len(inquiries),
+		}
+
+		err = app.mailer.Send(digest.AgentEmail, "inquiry_digest.tmpl", emailData)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = app.models.Notifications.MarkDigestSent(digest.AgentID, ranAt)
+	if err != nil {
+		return false, err
+	}
+
+	return len(inquiries) > 0, nil
+}
+
+// digestExcerpt shortens an inquiry message for the digest email
+func digestExcerpt(message string) string {
+	runes := []rune(message)
+	if len(runes) <= maxInquiryDigestExcerpt {
+		return message
+	}
+	return string(runes[:maxInquiryDigestExcerpt]) + "..."
+}
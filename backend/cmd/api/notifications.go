@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+// =============================================================================
+// USER: IN-APP NOTIFICATIONS
+// =============================================================================
+
+// listNotificationsHandler returns the user's most recent in-app notifications, only
+// unread ones with ?unread=true, and how many are unread
+func (app *application) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	unreadOnly := app.readString(r.URL.Query(), "unread", "") == "true"
+
+	notifications, unread, err := app.models.Inbox.GetAllForUser(user.ID, unreadOnly)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"notifications": notifications, "unread": unread}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readNotificationHandler marks one of the user's notifications as read
+func (app *application) readNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Inbox.MarkRead(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNotificationNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "notification marked as read"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// AGENT OPEN HOUSES
+// =============================================================================
+
+// createOpenHouseHandler schedules an open house on one of the agent's properties
+func (app *application) createOpenHouseHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PropertyID int64     `json:"property_id"`
+		StartsAt   time.Time `json:"starts_at"`
+		EndsAt     time.Time `json:"ends_at"`
+		Capacity   int       `json:"capacity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	openHouse := &data.OpenHouse{
+		PropertyID: input.PropertyID,
+		AgentID:    user.ID,
+		StartsAt:   input.StartsAt.UTC(),
+		EndsAt:     input.EndsAt.UTC(),
+		Capacity:   input.Capacity,
+	}
+
+	v := validator.New()
+	v.Check(input.PropertyID > 0, "property_id", "must be provided")
+	if data.ValidateOpenHouse(v, openHouse); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Open houses can only be held on the agent's own listings
+	property, err := app.models.Properties.Get(input.PropertyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			v.AddError("property_id", "property not found")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !property.AgentID.Valid || property.AgentID.Int64 != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.OpenHouses.Insert(openHouse)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"open_house": openHouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listAgentOpenHousesHandler lists the agent's upcoming open houses, or all of them with ?include_past=true
+func (app *application) listAgentOpenHousesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	includePast := app.readString(r.URL.Query(), "include_past", "false") == "true"
+
+	openHouses, err := app.models.OpenHouses.GetAllForAgent(user.ID, includePast)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"open_houses": openHouses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateOpenHouseHandler changes the times or capacity of one of the agent's open houses
+func (app *application) updateOpenHouseHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	openHouse, ok := app.agentOpenHouse(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+		Capacity *int       `json:"capacity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.StartsAt != nil {
+		openHouse.StartsAt = input.StartsAt.UTC()
+	}
+	if input.EndsAt != nil {
+		openHouse.EndsAt = input.EndsAt.UTC()
+	}
+	if input.Capacity != nil {
+		openHouse.Capacity = *input.Capacity
+	}
+
+	v := validator.New()
+	data.ValidateOpenHouse(v, openHouse)
+	v.Check(openHouse.Capacity >= openHouse.Attendees, "capacity", "must not be less than the number of attendees")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.OpenHouses.Update(openHouse)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"open_house": openHouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteOpenHouseHandler cancels one of the agent's open houses
+func (app *application) deleteOpenHouseHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.OpenHouses.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOpenHouseNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "open house successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listOpenHouseAttendeesHandler lists who has RSVP'd to one of the agent's open houses
+func (app *application) listOpenHouseAttendeesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	openHouse, ok := app.agentOpenHouse(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	attendees, err := app.models.OpenHouses.GetAttendees(openHouse.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"open_house": openHouse, "attendees": attendees}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// PUBLIC OPEN HOUSES & RSVPS
+// =============================================================================
+
+// listPropertyOpenHousesHandler lists a property's upcoming open houses
+func (app *application) listPropertyOpenHousesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	openHouses, err := app.models.OpenHouses.GetUpcomingForProperty(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"open_houses": openHouses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// rsvpOpenHouseHandler reserves a place at an open house for the authenticated user
+func (app *application) rsvpOpenHouseHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.OpenHouses.RSVP(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOpenHouseNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrOpenHouseFull),
+			errors.Is(err, data.ErrOpenHouseEnded),
+			errors.Is(err, data.ErrAlreadyRSVPd):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	openHouse, err := app.models.OpenHouses.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"open_house": openHouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// cancelOpenHouseRSVPHandler releases the authenticated user's place at an open house
+func (app *application) cancelOpenHouseRSVPHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.OpenHouses.CancelRSVP(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRSVPNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "rsvp successfully cancelled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// agentOpenHouse loads the open house named in the URL, responding with 404 unless
+// it belongs to the agent. The second return value is false when a response was sent.
+func (app *application) agentOpenHouse(w http.ResponseWriter, r *http.Request, agentID int64) (*data.OpenHouse, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	openHouse, err := app.models.OpenHouses.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOpenHouseNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if openHouse.AgentID != agentID {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return openHouse, true
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/mpesa"
@@ -57,6 +58,17 @@ func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Don't take payment for a featured slot that isn't free
+	available, err := app.models.Properties.FeaturedSlotAvailable(property.ID, app.featurePolicy())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !available {
+		app.featuredSlotsFullResponse(w, r)
+		return
+	}
+
 	// Normalize phone number for M-Pesa (remove spaces, dashes, plus signs)
 	if input.PaymentProvider == "mpesa" {
 		input.PhoneNumber = normalizePhoneNumber(input.PhoneNumber)
@@ -77,7 +89,7 @@ func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Requ
 
 	// Validate payment
 	v := validator.New()
-	if data.ValidatePayment(v, payment); !v.Valid() {
+	if data.ValidatePayment(v, payment, app.config.env != "production"); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -90,6 +102,8 @@ func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Requ
 		err = app.processBankPayment(payment)
 	case "card":
 		err = app.processCardPayment(payment)
+	case "test":
+		err = app.processTestPayment(payment)
 	default:
 		app.badRequestResponse(w, r, errors.New("unsupported payment provider"))
 		return
@@ -100,15 +114,27 @@ func (app *application) createPaymentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Insert payment record
-	err = app.models.Payments.Create(payment)
+	// Test payments settle immediately, so the property is featured as the payment is
+	// recorded; if the last slot went since the check above, neither is written
+	if payment.PaymentProvider == "test" && payment.Status == "completed" {
+		err = app.models.Payments.CreateFeatured(payment, app.featurePolicy())
+	} else {
+		err = app.models.Payments.Create(payment)
+	}
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrFeaturedSlotsFull):
+			app.featuredSlotsFullResponse(w, r)
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	// Return payment response
-	err = app.writeJSON(w, http.StatusCreated, envelope{"payment": payment}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"payment": payment}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -172,6 +198,26 @@ func (app *application) processCardPayment(payment *data.Payment) error {
 	return nil
 }
 
+// processTestPayment settles a payment immediately without calling any external provider
+// Only reachable outside production; the magic amount data.TestPaymentFailAmount fails it
+func (app *application) processTestPayment(payment *data.Payment) error {
+	if app.config.env == "production" {
+		return errors.New("test payment provider is disabled in production")
+	}
+
+	payment.TransactionID = fmt.Sprintf("TEST-%d-%d", payment.PropertyID, time.Now().UnixNano())
+	payment.TransactionDesc = fmt.Sprintf("Test payment for property %d", payment.PropertyID)
+
+	if payment.Amount == data.TestPaymentFailAmount {
+		payment.Status = "failed"
+		return nil
+	}
+
+	payment.Status = "completed"
+
+	return nil
+}
+
 // mpesaCallbackHandler handles M-Pesa payment callbacks
 func (app *application) mpesaCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	var callback struct {
@@ -207,6 +253,19 @@ func (app *application) mpesaCallbackHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// M-Pesa retries callbacks it doesn't see acknowledged; a payment that has already
+	// been settled is acknowledged again without featuring or notifying a second time
+	if paymentSettled(payment.Status) {
+		err = app.writeJSON(w, r, http.StatusOK, envelope{
+			"ResultCode": 0,
+			"ResultDesc": "Success",
+		}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	// Extract transaction ID from callback metadata
 	var transactionID string
 	for _, item := range callback.Body.StkCallback.CallbackMetadata.Item {
@@ -217,22 +276,13 @@ func (app *application) mpesaCallbackHandler(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	// Determine payment status based on result code
-	status := "failed"
-	if callback.Body.StkCallback.ResultCode == 0 {
-		status = "completed"
-	}
-
-	// Update payment status
-	err = app.models.Payments.UpdateStatus(
-		payment.ID,
-		status,
+	err = app.settleMpesaPayment(
+		payment,
+		callback.Body.StkCallback.ResultCode == 0,
 		transactionID,
 		fmt.Sprintf("%d", callback.Body.StkCallback.ResultCode),
 		callback.Body.StkCallback.ResultDesc,
-		payment.Version,
 	)
-
 	if err != nil {
 		app.logger.PrintError(err, map[string]string{
 			"payment_id": fmt.Sprintf("%d", payment.ID),
@@ -241,18 +291,13 @@ func (app *application) mpesaCallbackHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// If payment is successful, feature the property
-	if status == "completed" {
-		err = app.models.Properties.Feature(payment.PropertyID)
-		if err != nil {
-			app.logger.PrintError(err, map[string]string{
-				"property_id": fmt.Sprintf("%d", payment.PropertyID),
-			})
-		}
-	}
+	// Let the agent know the outcome instead of leaving them to poll for it
+	app.background(func() {
+		app.notifyPaymentOutcome(payment)
+	})
 
 	// Respond to M-Pesa
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"ResultCode": 0,
 		"ResultDesc": "Success",
 	}, nil)
@@ -261,6 +306,123 @@ func (app *application) mpesaCallbackHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// settleMpesaPayment records the outcome M-Pesa reported for a pending payment. A
+// successful payment is completed and its property featured together; if the property
+// can't be featured the payment is failed instead and logged for a refund, as the
+// agent has already been charged.
+func (app *application) settleMpesaPayment(payment *data.Payment, succeeded bool, transactionID, resultCode, resultDesc string) error {
+	if !succeeded {
+		err := app.models.Payments.UpdateStatus(payment.ID, "failed", transactionID, resultCode, resultDesc, payment.Version)
+		if err != nil {
+			return err
+		}
+
+		payment.Status = "failed"
+		payment.ResultCode = resultCode
+		payment.ResultDesc = resultDesc
+		if transactionID != "" {
+			payment.TransactionID = transactionID
+		}
+		return nil
+	}
+
+	err := app.models.Payments.CompleteFeatured(payment, transactionID, resultCode, resultDesc, app.featurePolicy())
+	switch {
+	case errors.Is(err, data.ErrFeaturedSlotsFull), errors.Is(err, data.ErrPropertyNotFound):
+		app.logger.PrintError(fmt.Errorf("payment failed after charging, needs refund: %w", err), map[string]string{
+			"payment_id":     fmt.Sprintf("%d", payment.ID),
+			"transaction_id": payment.TransactionID,
+		})
+		return nil
+	default:
+		return err
+	}
+}
+
+// paymentSettled reports whether a payment has reached a final status and can no
+// longer change
+func paymentSettled(status string) bool {
+	return status == "completed" || status == "failed"
+}
+
+// paymentNotification builds the in-app notification telling the agent how a payment ended
+func paymentNotification(payment *data.Payment, propertyTitle string) *data.UserNotification {
+	notification := &data.UserNotification{
+		UserID:     payment.AgentID,
+		Kind:       data.NotificationPaymentFailed,
+		Title:      "Payment failed",
+		Body:       fmt.Sprintf("Your payment of %.2f for %q was not completed.", payment.Amount, propertyTitle),
+		PropertyID: &payment.PropertyID,
+	}
+
+	if payment.Status == "completed" {
+		notification.Kind = data.NotificationPaymentCompleted
+		notification.Title = "Payment received"
+		notification.Body = fmt.Sprintf("We received your payment of %.2f for %q. Your listing is now featured.", payment.Amount, propertyTitle)
+	} else if payment.ResultDesc != "" {
+		notification.Body += " " + payment.ResultDesc
+	}
+
+	return notification
+}
+
+// notifyPaymentOutcome tells the agent the result of a payment with an in-app
+// notification, an email if they haven't switched payment emails off, and the
+// matching payment.completed or payment.failed webhook event
+func (app *application) notifyPaymentOutcome(payment *data.Payment) {
+	app.enqueueWebhookEvent(payment.AgentID, "payment."+payment.Status, payment)
+
+	// The title is only for the message, so a listing deleted since paying doesn't stop it
+	propertyTitle := fmt.Sprintf("property %d", payment.PropertyID)
+	property, err := app.models.Properties.Get(payment.PropertyID)
+	if err == nil {
+		propertyTitle = property.Title
+	}
+
+	err = app.models.Inbox.Insert(paymentNotification(payment, propertyTitle))
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"payment_id": fmt.Sprintf("%d", payment.ID),
+		})
+	}
+
+	prefs, err := app.models.Notifications.Get(payment.AgentID)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"payment_id": fmt.Sprintf("%d", payment.ID),
+		})
+		return
+	}
+	if !prefs.PaymentEmails {
+		return
+	}
+
+	agent, err := app.models.Users.GetByID(payment.AgentID)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"payment_id": fmt.Sprintf("%d", payment.ID),
+		})
+		return
+	}
+
+	emailData := map[string]interface{}{
+		"agentName":     agent.Name,
+		"propertyTitle": propertyTitle,
+		"propertyID":    payment.PropertyID,
+		"paymentID":     payment.ID,
+		"amount":        fmt.Sprintf("%.2f", payment.Amount),
+		"succeeded":     payment.Status == "completed",
+		"resultDesc":    payment.ResultDesc,
+	}
+
+	err = app.mailer.Send(agent.Email, "payment_outcome.tmpl", emailData)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"payment_id": fmt.Sprintf("%d", payment.ID),
+		})
+	}
+}
+
 // queryPaymentStatusHandler allows checking payment status
 func (app *application) queryPaymentStatusHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
@@ -305,25 +467,27 @@ func (app *application) queryPaymentStatusHandler(w http.ResponseWriter, r *http
 
 		status, err := mpesaClient.QuerySTKPushStatus(payment.CheckoutRequestID)
 		if err == nil && status.ResultCode == "0" {
-			// Update payment if successful
-			_ = app.models.Payments.UpdateStatus(
-				payment.ID,
-				"completed",
-				"",
-				status.ResultCode,
-				status.ResultDesc,
-				payment.Version,
-			)
-
-			// Feature the property
-			_ = app.models.Properties.Feature(payment.PropertyID)
-
-			// Refetch updated payment
-			payment, _ = app.models.Payments.Get(id)
+			err = app.settleMpesaPayment(payment, true, "", status.ResultCode, status.ResultDesc)
+			switch {
+			case err == nil:
+				app.background(func() {
+					app.notifyPaymentOutcome(payment)
+				})
+			case errors.Is(err, data.ErrEditConflict):
+				// The callback settled the payment first, so show its outcome
+				payment, err = app.models.Payments.Get(id)
+				if err != nil {
+					app.serverErrorResponse(w, r, err)
+					return
+				}
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
 		}
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"payment": payment}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"payment": payment}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+func TestPaymentSettled(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{"pending", false},
+		{"completed", true},
+		{"failed", true},
+	}
+
+	for _, tt := range tests {
+		if got := paymentSettled(tt.status); got != tt.want {
+			t.Errorf("paymentSettled(%q) = %t; want %t", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentNotification(t *testing.T) {
+	tests := []struct {
+		name     string
+		payment  data.Payment
+		wantKind string
+		wantBody string
+	}{
+		{
+			name:     "completed",
+			payment:  data.Payment{AgentID: 7, PropertyID: 42, Amount: 1500, Status: "completed"},
+			wantKind: data.NotificationPaymentCompleted,
+			wantBody: "now featured",
+		},
+		{
+			name:     "failed",
+			payment:  data.Payment{AgentID: 7, PropertyID: 42, Amount: 1500, Status: "failed", ResultDesc: "Request cancelled by user"},
+			wantKind: data.NotificationPaymentFailed,
+			wantBody: "Request cancelled by user",
+		},
+		{
+			name:     "failed for want of a featured slot",
+			payment:  data.Payment{AgentID: 7, PropertyID: 42, Amount: 1500, Status: "failed", ResultDesc: data.FeaturedSlotsFullDesc},
+			wantKind: data.NotificationPaymentFailed,
+			wantBody: "refunded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := paymentNotification(&tt.payment, "Garden flat")
+
+			if notification.UserID != 7 {
+				t.Errorf("got user %d; want 7", notification.UserID)
+			}
+			if notification.Kind != tt.wantKind {
+				t.Errorf("got kind %q; want %q", notification.Kind, tt.wantKind)
+			}
+			if notification.PropertyID == nil || *notification.PropertyID != 42 {
+				t.Errorf("got property %v; want 42", notification.PropertyID)
+			}
+			for _, want := range []string{"1500.00", "Garden flat", tt.wantBody} {
+				if !strings.Contains(notification.Body, want) {
+					t.Errorf("body %q is missing %q", notification.Body, want)
+				}
+			}
+		})
+	}
+}
@@ -5,28 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/julienschmidt/httprouter"
 )
 
 // createPropertyHandler handles creating a new property
 func (app *application) createPropertyHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Title        string   `json:"title"`
-		YearBuilt    int32    `json:"year_built"`
-		Area         int32    `json:"area"`
-		Bedrooms     int32    `json:"bedrooms"`
-		Bathrooms    int32    `json:"bathrooms"`
-		Floor        int32    `json:"floor"`
-		Price        float64  `json:"price"`
-		Location     string   `json:"location"`
-		PropertyType string   `json:"property_type"`
-		Features     []string `json:"features"`
-		Images       []string `json:"images"`
-		// Note: NO agent_id field - we get it from the authenticated user
-	}
+	var input propertyInput
 
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -38,8 +29,73 @@ func (app *application) createPropertyHandler(w http.ResponseWriter, r *http.Req
 	user := app.contextGetUser(r)
 
 	// Create property with values from input and authenticated user
-	property := &data.Property{
+	property := input.property(user.ID)
+
+	v := validator.New()
+	if input.validate(v, property, app.propertyLimits()); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	app.setListingExpiry(property)
+
+	err = app.applyListingTrust(user, property, app.models.Agents.IsVerified)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Properties.Insert(property)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.invalidateStats(user.ID)
+
+	headers := make(http.Header)
+	headers.Set("Location", propertyLocation(property.ID))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"property": property}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// propertyInput is the request body for a new listing
+type propertyInput struct {
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	YearBuilt    int32    `json:"year_built"`
+	Area         int32    `json:"area"`
+	Bedrooms     int32    `json:"bedrooms"`
+	Bathrooms    int32    `json:"bathrooms"`
+	Floor        int32    `json:"floor"`
+	Price        float64  `json:"price"`
+	Location     string   `json:"location"`
+	PropertyType string   `json:"property_type"`
+	Features     []string `json:"features"`
+	Images       []string `json:"images"`
+	// Note: NO agent_id field - we get it from the authenticated user
+
+	// Optional structured attributes
+	EnergyRating  *string  `json:"energy_rating"`
+	ParkingSpaces *int32   `json:"parking_spaces"`
+	LotSize       *int32   `json:"lot_size"`
+	HeatingType   *string  `json:"heating_type"`
+	Furnished     *string  `json:"furnished"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+
+	// Optional future time at which the listing goes live
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// property builds the listing for the agent from the input
+func (input propertyInput) property(agentID int64) *data.Property {
+	return &data.Property{
 		Title:        input.Title,
+		Description:  input.Description,
 		YearBuilt:    input.YearBuilt,
 		Area:         data.Area(input.Area),
 		Bedrooms:     data.Bedrooms(input.Bedrooms),
@@ -50,28 +106,67 @@ func (app *application) createPropertyHandler(w http.ResponseWriter, r *http.Req
 		PropertyType: input.PropertyType,
 		Features:     input.Features,
 		Images:       input.Images,
-		AgentID:      sql.NullInt64{Int64: user.ID, Valid: true},
+		AgentID:      sql.NullInt64{Int64: agentID, Valid: true},
+
+		EnergyRating:  input.EnergyRating,
+		ParkingSpaces: input.ParkingSpaces,
+		LotSize:       input.LotSize,
+		HeatingType:   input.HeatingType,
+		Furnished:     input.Furnished,
+		Latitude:      input.Latitude,
+		Longitude:     input.Longitude,
+		PublishAt:     input.PublishAt,
 	}
+}
 
-	v := validator.New()
-	if data.ValidateProperty(v, property); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+// validate checks the listing built from the input, including its publish time
+func (input propertyInput) validate(v *validator.Validator, property *data.Property, limits data.PropertyLimits) {
+	if data.ValidateProperty(v, property, limits); input.PublishAt != nil {
+		data.ValidatePublishAt(v, *input.PublishAt)
+	}
+}
+
+// applyListingTrust lets a new listing skip moderation when auto-approval is enabled and
+// the creating agent is verified, as reported by isVerified; otherwise it is left for
+// moderation. A trusted listing is still only approved once it has a cover image (see
+// approveTrustedListing), and a scheduled one no earlier than its publish time.
+func (app *application) applyListingTrust(user *data.User, property *data.Property, isVerified func(agentID int64) (bool, error)) error {
+	if !app.config.listings.autoApproveVerified || user.Role != "agent" {
+		return nil
 	}
 
-	err = app.models.Properties.Insert(property)
+	verified, err := isVerified(user.ID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+		return err
 	}
 
-	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/v1/properties/%d", property.ID))
+	property.AutoApprove = verified
+	return nil
+}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"property": property}, headers)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+// approveTrustedListing approves a listing set to skip moderation once it has a cover
+// image, auditing the approval on behalf of the user who completed it
+func (app *application) approveTrustedListing(user *data.User, property *data.Property) error {
+	approved, err := app.models.Properties.ApproveTrusted(property.ID)
+	if err != nil || !approved {
+		return err
+	}
+
+	app.recordAudit(user.ID, "properties.auto_approve", "property", property.ID, map[string]interface{}{
+		"reason": "verified agent",
+	})
+	app.invalidateStats(property.AgentID.Int64)
+	return nil
+}
+
+// setListingExpiry gives a new listing the configured lifetime, counted from when it goes live
+func (app *application) setListingExpiry(property *data.Property) {
+	liveFrom := time.Now()
+	if property.PublishAt != nil {
+		liveFrom = *property.PublishAt
 	}
+	expiresAt := liveFrom.Add(app.config.listings.lifetime)
+	property.ExpiresAt = &expiresAt
 }
 
 // showPropertyHandler returns a property by ID as a JSON response
@@ -95,8 +190,221 @@ func (app *application) showPropertyHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.writePropertyResponse(w, r, property)
+}
+
+// canViewProperty reports whether the requester may see the listing. Only live approved
+// listings are public (see Property.IsPublic); scheduled, pending, rejected and expired
+// ones are visible to their agent and admins alone, and are reported to everyone else
+// as not found.
+func (app *application) canViewProperty(r *http.Request, property *data.Property) bool {
+	if property.IsPublic() {
+		return true
+	}
+
+	user := app.contextGetUser(r)
+	return ownsProperty(user, property) || user.Role == "admin"
+}
+
+// ownsProperty reports whether the user is the agent the property is listed by
+func ownsProperty(user *data.User, property *data.Property) bool {
+	return property.AgentID.Valid && property.AgentID.Int64 == user.ID
+}
+
+// visibleProperties drops the listings the requester may not see, keeping the order
+func (app *application) visibleProperties(r *http.Request, properties []*data.Property) []*data.Property {
+	visible := make([]*data.Property, 0, len(properties))
+	for _, property := range properties {
+		if app.canViewProperty(r, property) {
+			visible = append(visible, property)
+		}
+	}
+	return visible
+}
+
+// maxSimilarProperties caps the ?limit of the similar listings endpoint
+const maxSimilarProperties = 20
+
+// listSimilarPropertiesHandler returns listings like the given one: same type and
+// location at a similar price. No matches is an empty list, not an error.
+func (app *application) listSimilarPropertiesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	limit := app.readInt(r.URL.Query(), "limit", 6, v)
+	v.Check(limit >= 1 && limit <= maxSimilarProperties, "limit", fmt.Sprintf("must be between 1 and %d", maxSimilarProperties))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	properties, err := app.models.Properties.GetSimilar(id, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"properties": properties}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showPropertyBySlugHandler returns a property by its slug. Slugs the listing had
+// before being renamed redirect permanently to the current one.
+func (app *application) showPropertyBySlugHandler(w http.ResponseWriter, r *http.Request) {
+	slug := httprouter.ParamsFromContext(r.Context()).ByName("slug")
+
+	property, err := app.models.Properties.GetBySlug(slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if location, stale := staleSlugRedirect(r, slug, property); stale {
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+		return
+	}
+
+	app.writePropertyResponse(w, r, property)
+}
+
+// staleSlugRedirect returns where to redirect a request for a listing by slug when the
+// slug isn't the listing's current one, keeping the query string
+func staleSlugRedirect(r *http.Request, slug string, property *data.Property) (string, bool) {
+	if property.Slug == slug {
+		return "", false
+	}
+
+	location := propertySlugPath(property)
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	return location, true
+}
+
+// propertySlugPath returns the canonical slug path of a listing
+func propertySlugPath(property *data.Property) string {
+	slug := property.Slug
+	if slug == "" {
+		slug = data.PropertySlug(property.Title, property.ID)
+	}
+	return "/v1/properties/slug/" + url.PathEscape(slug)
+}
+
+// propertyLimits returns the configured list limits for listings
+func (app *application) propertyLimits() data.PropertyLimits {
+	return data.NewPropertyLimits(app.config.listings.maxFeatures, app.config.listings.maxImages)
+}
+
+// featurePolicy returns the configured rules for featuring a listing
+func (app *application) featurePolicy() data.FeaturePolicy {
+	return data.FeaturePolicy{
+		Duration:       app.config.listings.featuredDuration,
+		MaxListings:    app.config.listings.maxFeatured,
+		MaxPerLocation: app.config.listings.maxFeaturedPerLocation,
+	}
+}
+
+// publicPropertyURL returns the absolute slug URL to share a listing by, eg on signage
+func (app *application) publicPropertyURL(property *data.Property) string {
+	return strings.TrimRight(app.config.baseURL, "/") + propertySlugPath(property)
+}
+
+// writePropertyResponse sends a single listing with its optional embeds and records the view
+func (app *application) writePropertyResponse(w http.ResponseWriter, r *http.Request, property *data.Property) {
+	//Validate optional embeds, e.g. ?include=agent
+	v := validator.New()
+	include := app.readCSV(r.URL.Query(), "include", []string{})
+	for _, value := range include {
+		v.Check(validator.In(value, "agent"), "include", "must only contain: agent")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	//Read the optional sparse fieldset, e.g. ?fields=id,title,price
+	fields, err := app.readFields(r.URL.Query(), data.Property{})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	//Unmoderated listings are only visible to their agent and admins
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	selected, err := selectFields(property, fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"property": selected}
+
+	//Embed the agent card; properties without an agent get a null agent
+	if validator.In("agent", include...) {
+		var card *data.AgentCard
+		if property.AgentID.Valid {
+			card, err = app.models.Agents.GetCard(property.AgentID.Int64)
+			if err != nil && !errors.Is(err, data.ErrUserNotFound) {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+		if card != nil {
+			app.gateAgentPhone(r, card)
+		}
+		env["agent"] = card
+	}
+
+	//Include upcoming open houses on the listing
+	openHouses, err := app.models.OpenHouses.GetUpcomingForProperty(property.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	env["open_houses"] = openHouses
+
+	//Record the view for agent analytics
+	viewerID := app.contextGetUser(r).ID
+	app.background(func() {
+		err := app.models.Analytics.RecordView(property.ID, viewerID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
 	//Send JSON response
-	err = app.writeJSON(w, http.StatusOK, envelope{"property": property}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -126,6 +434,7 @@ func (app *application) updatePropertyHandler(w http.ResponseWriter, r *http.Req
 	//Struct to capture incomming JSON updates
 	var input struct {
 		Title        *string         `json:"title"`
+		Description  *string         `json:"description"`
 		YearBuilt    *int32          `json:"year_built"`
 		Area         *data.Area      `json:"area"`
 		Bedrooms     *data.Bedrooms  `json:"bedrooms"`
@@ -136,6 +445,18 @@ func (app *application) updatePropertyHandler(w http.ResponseWriter, r *http.Req
 		PropertyType *string         `json:"property_type"`
 		Features     []string        `json:"features"`
 		Images       []string        `json:"images"`
+
+		// Optional structured attributes; an explicit null clears them
+		EnergyRating  nullable[string]  `json:"energy_rating"`
+		ParkingSpaces nullable[int32]   `json:"parking_spaces"`
+		LotSize       nullable[int32]   `json:"lot_size"`
+		HeatingType   nullable[string]  `json:"heating_type"`
+		Furnished     nullable[string]  `json:"furnished"`
+		Latitude      nullable[float64] `json:"latitude"`
+		Longitude     nullable[float64] `json:"longitude"`
+
+		// Reschedules a listing that has not been published yet
+		PublishAt *time.Time `json:"publish_at"`
 	}
 
 	//Decode JSON request into the input struct
@@ -149,6 +470,9 @@ func (app *application) updatePropertyHandler(w http.ResponseWriter, r *http.Req
 	if input.Title != nil {
 		property.Title = *input.Title
 	}
+	if input.Description != nil {
+		property.Description = *input.Description
+	}
 	if input.YearBuilt != nil {
 		property.YearBuilt = *input.YearBuilt
 	}
@@ -180,10 +504,37 @@ func (app *application) updatePropertyHandler(w http.ResponseWriter, r *http.Req
 	if input.Images != nil {
 		property.Images = input.Images
 	}
+	if input.EnergyRating.Set {
+		property.EnergyRating = input.EnergyRating.Value
+	}
+	if input.ParkingSpaces.Set {
+		property.ParkingSpaces = input.ParkingSpaces.Value
+	}
+	if input.LotSize.Set {
+		property.LotSize = input.LotSize.Value
+	}
+	if input.HeatingType.Set {
+		property.HeatingType = input.HeatingType.Value
+	}
+	if input.Furnished.Set {
+		property.Furnished = input.Furnished.Value
+	}
+	if input.Latitude.Set {
+		property.Latitude = input.Latitude.Value
+	}
+	if input.Longitude.Set {
+		property.Longitude = input.Longitude.Value
+	}
 
 	//Validate the updated property
 	v := validator.New()
-	if data.ValidateProperty(v, property); !v.Valid() {
+	data.ValidateProperty(v, property, app.propertyLimits())
+	if input.PublishAt != nil {
+		v.Check(property.IsScheduled(), "publish_at", "can only be changed before the listing is published")
+		data.ValidatePublishAt(v, *input.PublishAt)
+		property.PublishAt = input.PublishAt
+	}
+	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -210,7 +561,7 @@ func (app *application) updatePropertyHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	//Return the updated property in the response
-	err = app.writeJSON(w, http.StatusOK, envelope{"property": property}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -227,7 +578,7 @@ func (app *application) deletePropertyHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	//Attempt to delete the property from the database
-	err = app.models.Properties.Delete(id)
+	agentID, err := app.models.Properties.Delete(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrPropertyNotFound):
@@ -238,22 +589,28 @@ func (app *application) deletePropertyHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	app.invalidateListingStats(agentID)
+
 	//Respond with success message
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "property successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "property successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
 }
 
+// maxPropertyTypeFilters caps how many property types one listing query may match
+const maxPropertyTypeFilters = 10
+
 // listPropertyHandler reads query parameters for filtering, pagination, and sorting of properties
 func (app *application) listPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 	//An anonymous struct to hold filter and pagination parameters from the query string
 	var input struct {
-		Title        string
-		Location     string
-		PropertyType string
-		Features     []string
+		Title         string
+		Location      string
+		PropertyTypes []string
+		Features      []string
+		PriceRange    data.PriceRange
 		data.Filters
 	}
 
@@ -265,17 +622,33 @@ func (app *application) listPropertiesHandler(w http.ResponseWriter, r *http.Req
 	//Read filter values from the query string
 	input.Title = app.readString(qs, "title", "")
 	input.Location = app.readString(qs, "location", "")
-	input.PropertyType = app.readString(qs, "property_type", "")
+	input.PropertyTypes = app.readCSV(qs, "property_type", []string{})
 	input.Features = app.readCSV(qs, "features", []string{})
 
+	//Property types are matched exactly, any of them
+	for i, propertyType := range input.PropertyTypes {
+		input.PropertyTypes[i] = strings.TrimSpace(propertyType)
+	}
+	v.Check(len(input.PropertyTypes) <= maxPropertyTypeFilters, "property_type", fmt.Sprintf("must not contain more than %d values", maxPropertyTypeFilters))
+
+	//Optional price bounds
+	input.PriceRange.Min = app.readFloat(qs, "min_price", 0, v)
+	input.PriceRange.Max = app.readFloat(qs, "max_price", 0, v)
+	data.ValidatePriceRange(v, input.PriceRange)
+
 	//Read pagination and sorting values from query string
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Sort = app.readString(qs, "sort", app.config.sort.properties)
 
-	//Define a whitelist of allowed sort values to prevent SQL injection
-	input.Filters.SortSafelist = []string{
-		"id", "title", "year_built", "price", "-id", "-title", "-year_built", "-price",
+	//Define a whitelist of allowed sort values to prevent SQL injection;
+	//a title search can also be sorted by relevance
+	input.Filters.SortSafelist = data.PropertySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+	if input.Title != "" {
+		input.Filters.SortSafelist = append(append([]string{}, data.PropertySortSafelist...), "relevance")
+	} else if validator.In("relevance", strings.Split(input.Filters.Sort, ",")...) {
+		v.AddError("sort", "relevance requires a title search")
 	}
 
 	//Validate the filters(page, page_size, sort)
@@ -284,12 +657,20 @@ func (app *application) listPropertiesHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	//Read the optional sparse fieldset, e.g. ?fields=id,title,price
+	fields, err := app.readFields(qs, data.Property{})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	//Fetch filtered, sorted and paginated properties from the database
-	properties, metadata, err := app.models.Properties.GetAll(
+	properties, metadata, aggregates, err := app.models.Properties.GetAll(
 		input.Title,
 		input.Location,
-		input.PropertyType,
+		input.PropertyTypes,
 		input.Features,
+		input.PriceRange,
 		input.Filters,
 	)
 	if err != nil {
@@ -297,8 +678,20 @@ func (app *application) listPropertiesHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	selected, err := selectFields(properties, fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	//Return the properties as a JSON response
-	err = app.writeJSON(w, http.StatusOK, envelope{"properties": properties, "metadata": metadata}, nil)
+	env := envelope{
+		"properties": selected,
+		"metadata":   app.withPageLinks(r, metadata),
+		"aggregates": aggregates,
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -314,11 +707,13 @@ func (app *application) featurePropertyHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	//Mark property as featured
-	err = app.models.Properties.Feature(id)
+	err = app.models.Properties.Feature(id, app.featurePolicy())
 	if err != nil {
 		switch err {
 		case data.ErrPropertyNotFound:
 			app.notFoundResponse(w, r)
+		case data.ErrFeaturedSlotsFull:
+			app.featuredSlotsFullResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -332,7 +727,9 @@ func (app *application) featurePropertyHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"property": property}, nil)
+	app.invalidateStats(property.AgentID.Int64)
+
+	app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
 }
 
 // UnfeaturePropertyHandler unfeatures properties
@@ -362,5 +759,7 @@ func (app *application) unfeaturePropertyHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"property": property}, nil)
+	app.invalidateStats(property.AgentID.Int64)
+
+	app.writeJSON(w, r, http.StatusOK, envelope{"property": property}, nil)
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+func TestPublicPropertyURL(t *testing.T) {
+	app := &application{}
+	app.config.baseURL = "https://propertyown.example/"
+
+	tests := []struct {
+		property data.Property
+		want     string
+	}{
+		{data.Property{ID: 42, Title: "3 Bed House, Karen", Slug: "3-bed-house-karen-42"}, "https://propertyown.example/v1/properties/slug/3-bed-house-karen-42"},
+		{data.Property{ID: 7, Title: "Loft in Westlands"}, "https://propertyown.example/v1/properties/slug/loft-in-westlands-7"},
+	}
+
+	for _, tt := range tests {
+		if got := app.publicPropertyURL(&tt.property); got != tt.want {
+			t.Errorf("got %q; want %q", got, tt.want)
+		}
+	}
+}
+
+func TestStaleSlugRedirect(t *testing.T) {
+	property := &data.Property{ID: 42, Title: "3 Bed House, Karen", Slug: "3-bed-house-karen-42"}
+
+	tests := []struct {
+		name      string
+		target    string
+		slug      string
+		wantStale bool
+		want      string
+	}{
+		{"current slug", "/v1/properties/slug/3-bed-house-karen-42", "3-bed-house-karen-42", false, ""},
+		{"previous slug", "/v1/properties/slug/house-in-karen-42", "house-in-karen-42", true, "/v1/properties/slug/3-bed-house-karen-42"},
+		{"previous slug with query", "/v1/properties/slug/house-in-karen-42?embed=agent", "house-in-karen-42", true, "/v1/properties/slug/3-bed-house-karen-42?embed=agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			got, stale := staleSlugRedirect(r, tt.slug, property)
+			if stale != tt.wantStale || got != tt.want {
+				t.Errorf("got %q, %v; want %q, %v", got, stale, tt.want, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestShowPropertyBySlugNotFound(t *testing.T) {
+	app := newTestApplication(t)
+
+	// An empty slug is unknown without a database lookup
+	r := httptest.NewRequest(http.MethodGet, "/v1/properties/slug/", nil)
+	rr := httptest.NewRecorder()
+
+	app.showPropertyBySlugHandler(rr, r)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got status %d; want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestApplyListingTrust(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	publishAt := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		autoApprove bool
+		role        string
+		publishAt   *time.Time
+		verified    bool
+		lookupErr   error
+		wantTrusted bool
+		wantErr     error
+	}{
+		{"verified agent", true, "agent", nil, true, nil, true, nil},
+		{"unverified agent", true, "agent", nil, false, nil, false, nil},
+		{"verified agent with auto-approval off", false, "agent", nil, true, nil, false, nil},
+		{"verified agent scheduling a listing", true, "agent", &publishAt, true, nil, true, nil},
+		{"unverified agent scheduling a listing", true, "agent", &publishAt, false, nil, false, nil},
+		{"admin", true, "admin", nil, true, nil, false, nil},
+		{"verification lookup fails", true, "agent", nil, false, errLookup, false, errLookup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.listings.autoApproveVerified = tt.autoApprove
+
+			user := &data.User{ID: 7, Role: tt.role}
+			property := &data.Property{PublishAt: tt.publishAt}
+			isVerified := func(agentID int64) (bool, error) {
+				if agentID != user.ID {
+					t.Errorf("verification looked up for %d; want %d", agentID, user.ID)
+				}
+				return tt.verified, tt.lookupErr
+			}
+
+			err := app.applyListingTrust(user, property, isVerified)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v; want %v", err, tt.wantErr)
+			}
+			if property.AutoApprove != tt.wantTrusted {
+				t.Errorf("got auto-approve %v; want %v", property.AutoApprove, tt.wantTrusted)
+			}
+
+			// Trusted listings still wait for a cover image before going public
+			if property.Status != "" {
+				t.Errorf("got status %q; want it left for the database to default", property.Status)
+			}
+		})
+	}
+}
+
+func TestCanViewProperty(t *testing.T) {
+	app := newTestApplication(t)
+
+	expired := time.Now().Add(-time.Hour)
+	agent := sql.NullInt64{Int64: 7, Valid: true}
+
+	listings := map[string]*data.Property{
+		"approved":  {Status: data.PropertyStatusApproved, AgentID: agent},
+		"expired":   {Status: data.PropertyStatusApproved, AgentID: agent, ExpiresAt: &expired},
+		"pending":   {Status: data.PropertyStatusPending, AgentID: agent},
+		"scheduled": {Status: data.PropertyStatusScheduled, AgentID: agent},
+		"rejected":  {Status: "rejected", AgentID: agent},
+	}
+
+	tests := []struct {
+		name string
+		user *data.User
+		want []string
+	}{
+		{"public", data.AnonymousUser, []string{"approved"}},
+		{"other agent", &data.User{ID: 9, Role: "agent"}, []string{"approved"}},
+		{"owner", &data.User{ID: 7, Role: "agent"}, []string{"approved", "expired", "pending", "rejected", "scheduled"}},
+		{"admin", &data.User{ID: 1, Role: "admin"}, []string{"approved", "expired", "pending", "rejected", "scheduled"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := app.contextSetUser(httptest.NewRequest(http.MethodGet, "/v1/properties/1", nil), tt.user)
+
+			for status, property := range listings {
+				want := slices.Contains(tt.want, status)
+				if got := app.canViewProperty(r, property); got != want {
+					t.Errorf("%s listing: got %v; want %v", status, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnsProperty(t *testing.T) {
+	listed := &data.Property{AgentID: sql.NullInt64{Int64: 7, Valid: true}}
+	unassigned := &data.Property{}
+
+	tests := []struct {
+		name     string
+		user     *data.User
+		property *data.Property
+		want     bool
+	}{
+		// Viewings and inquiries from the listing's own agent are rejected
+		{"listing agent", &data.User{ID: 7, Role: "agent"}, listed, true},
+		{"other agent", &data.User{ID: 9, Role: "agent"}, listed, false},
+		{"user", &data.User{ID: 12, Role: "user"}, listed, false},
+		{"anonymous", data.AnonymousUser, listed, false},
+		{"anonymous on a listing without an agent", data.AnonymousUser, unassigned, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ownsProperty(tt.user, tt.property); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropertyLimitsFollowConfig(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.listings.maxFeatures = 15
+	app.config.listings.maxImages = 25
+
+	limits := app.propertyLimits()
+
+	if limits.Features.Min != data.MinPropertyFeatures || limits.Features.Max != 15 {
+		t.Errorf("got feature limits %+v; want %d to 15", limits.Features, data.MinPropertyFeatures)
+	}
+	if limits.Images.Min != data.MinPropertyImages || limits.Images.Max != 25 {
+		t.Errorf("got image limits %+v; want %d to 25", limits.Images, data.MinPropertyImages)
+	}
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/pdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Brochure limits
+const (
+	maxBrochureCacheLen   = 50
+	maxBrochurePhotos     = 4
+	maxBrochurePhotoPixel = 1200 // longest side of a photo embedded in the brochure
+)
+
+// Brochure page layout in points
+const (
+	brochureMargin  = 40.0
+	brochureWidth   = pdf.A4Width - 2*brochureMargin
+	brochureFooterY = pdf.A4Height - 180.0
+)
+
+// brochureAccent is the colour of the header band and price
+var brochureAccent = [3]float64{0.12, 0.32, 0.52}
+
+// propertyBrochureHandler returns a printable one-page PDF brochure of the listing:
+// photos, details, price, the agent's contact details and a QR code to the listing
+func (app *application) propertyBrochureHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Unmoderated listings are only visible to their agent and admins
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	mediaList, err := app.models.Media.GetAllForProperty(property.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	photos := []*data.PropertyMedia{}
+	for _, media := range mediaList {
+		if media.MediaType == "image" {
+			photos = append(photos, media)
+		}
+	}
+
+	var card *data.AgentCard
+	if property.AgentID.Valid {
+		card, err = app.models.Agents.GetCard(property.AgentID.Int64)
+		if err != nil && !errors.Is(err, data.ErrUserNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if card != nil {
+			app.gateAgentPhone(r, card)
+		}
+	}
+
+	// Serve from cache while the listing, its photos and the agent details shown are unchanged
+	key := brochureCacheKey(property, photos, card)
+	brochure, ok := app.brochures.get(key)
+	if !ok {
+		brochure, err = app.renderBrochure(property, photos, card)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.brochures.set(key, brochure)
+	}
+
+	filename := property.Slug
+	if filename == "" {
+		filename = fmt.Sprintf("property-%d", property.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-brochure.pdf"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(brochure)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(brochure)
+}
+
+// brochureCacheKey identifies a rendered brochure by everything printed on it that
+// can change without the property version changing
+func brochureCacheKey(property *data.Property, photos []*data.PropertyMedia, card *data.AgentCard) string {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%d:%d", property.ID, property.Version)
+
+	for i, photo := range photos {
+		if i == maxBrochurePhotos {
+			break
+		}
+		fmt.Fprintf(&key, ":%d.%d", photo.ID, photo.Version)
+	}
+
+	if card != nil {
+		fmt.Fprintf(&key, ":%s|%s|%s", card.Name, derefString(card.Phone), derefString(card.Email))
+	}
+
+	return key.String()
+}
+
+// derefString returns the string s points to, or "" for nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// renderBrochure lays out the brochure page and returns the PDF
+func (app *application) renderBrochure(property *data.Property, photos []*data.PropertyMedia, card *data.AgentCard) ([]byte, error) {
+	page := pdf.NewPage(pdf.A4Width, pdf.A4Height)
+	page.Title = property.Title
+	listingURL := app.publicPropertyURL(property)
+
+	// Header band
+	page.SetFill(brochureAccent[0], brochureAccent[1], brochureAccent[2])
+	page.Rect(0, 0, pdf.A4Width, 56)
+	page.SetFill(1, 1, 1)
+	page.Text(brochureMargin, 34, pdf.Bold, 16, "PropertyOwn")
+	label := "Property brochure"
+	page.Text(pdf.A4Width-brochureMargin-pdf.TextWidth(pdf.Regular, 10, label), 34, pdf.Regular, 10, label)
+
+	// Title, location and price
+	y := 90.0
+	page.SetFill(0.1, 0.1, 0.1)
+	for i, line := range pdf.WrapText(pdf.Bold, 20, brochureWidth, property.Title) {
+		if i == 2 {
+			break
+		}
+		page.Text(brochureMargin, y, pdf.Bold, 20, line)
+		y += 24
+	}
+
+	page.SetFill(0.4, 0.4, 0.4)
+	page.Text(brochureMargin, y, pdf.Regular, 11, property.Location)
+	y += 26
+
+	page.SetFill(brochureAccent[0], brochureAccent[1], brochureAccent[2])
+	page.Text(brochureMargin, y, pdf.Bold, 18, formatBrochurePrice(property.Price))
+	y += 16
+
+	// Photos: a large main photo with up to three thumbnails below
+	images := app.loadBrochurePhotos(photos)
+	y, err := drawBrochurePhotos(page, images, y)
+	if err != nil {
+		return nil, err
+	}
+
+	// Details in two columns
+	y += 24
+	page.SetFill(0.1, 0.1, 0.1)
+	page.Text(brochureMargin, y, pdf.Bold, 13, "Details")
+	y += 18
+
+	details := brochureDetails(property)
+	columnWidth := brochureWidth / 2
+	for i, detail := range details {
+		x := brochureMargin + float64(i%2)*columnWidth
+		page.SetFill(0.4, 0.4, 0.4)
+		page.Text(x, y, pdf.Regular, 10, detail[0])
+		page.SetFill(0.1, 0.1, 0.1)
+		page.Text(x+90, y, pdf.Bold, 10, detail[1])
+		if i%2 == 1 || i == len(details)-1 {
+			y += 15
+		}
+	}
+
+	// Features and description fill the space left above the footer
+	lineHeight := 13.0
+	remaining := int((brochureFooterY - 16 - y) / lineHeight)
+
+	if len(property.Features) > 0 && remaining > 2 {
+		y += 10
+		page.SetFill(0.1, 0.1, 0.1)
+		page.Text(brochureMargin, y, pdf.Bold, 13, "Features")
+		y += 16
+		remaining = int((brochureFooterY - 16 - y) / lineHeight)
+
+		lines := pdf.WrapText(pdf.Regular, 10, brochureWidth, strings.Join(property.Features, "  •  "))
+		y = drawBrochureLines(page, lines, y, lineHeight, min(3, remaining))
+		remaining = int((brochureFooterY - 16 - y) / lineHeight)
+	}
+
+	if property.Description != "" && remaining > 2 {
+		y += 10
+		page.SetFill(0.1, 0.1, 0.1)
+		page.Text(brochureMargin, y, pdf.Bold, 13, "Description")
+		y += 16
+		remaining = int((brochureFooterY - 16 - y) / lineHeight)
+
+		lines := pdf.WrapText(pdf.Regular, 10, brochureWidth, property.Description)
+		drawBrochureLines(page, lines, y, lineHeight, remaining)
+	}
+
+	// Footer: agent contact on the left, QR code to the listing on the right
+	page.SetFill(0.95, 0.95, 0.95)
+	page.Rect(brochureMargin, brochureFooterY, brochureWidth, 130)
+
+	y = brochureFooterY + 26
+	page.SetFill(0.1, 0.1, 0.1)
+	page.Text(brochureMargin+16, y, pdf.Bold, 13, "Contact the agent")
+	y += 22
+
+	if card != nil {
+		page.Text(brochureMargin+16, y, pdf.Bold, 11, card.Name)
+		y += 17
+		page.SetFill(0.3, 0.3, 0.3)
+		if card.Phone != nil {
+			page.Text(brochureMargin+16, y, pdf.Regular, 10, "Phone: "+*card.Phone)
+			y += 15
+		} else if card.PhoneHidden {
+			page.Text(brochureMargin+16, y, pdf.Regular, 10, "Phone: sign in to view online")
+			y += 15
+		}
+		if card.Email != nil {
+			page.Text(brochureMargin+16, y, pdf.Regular, 10, "Email: "+*card.Email)
+			y += 15
+		}
+	} else {
+		page.SetFill(0.3, 0.3, 0.3)
+		page.Text(brochureMargin+16, y, pdf.Regular, 10, "Enquire online using the link below.")
+	}
+
+	page.SetFill(0.3, 0.3, 0.3)
+	page.Text(brochureMargin+16, brochureFooterY+116, pdf.Regular, 9, listingURL)
+
+	qrSize := 100.0
+	qrX := brochureMargin + brochureWidth - qrSize - 14
+	err = drawBrochureQRCode(page, listingURL, qrX, brochureFooterY+8, qrSize)
+	if err != nil {
+		return nil, err
+	}
+	page.SetFill(0.3, 0.3, 0.3)
+	scan := "Scan to view online"
+	page.Text(qrX+(qrSize-pdf.TextWidth(pdf.Regular, 8, scan))/2, brochureFooterY+120, pdf.Regular, 8, scan)
+
+	var buf bytes.Buffer
+	_, err = page.WriteTo(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// loadBrochurePhotos decodes up to maxBrochurePhotos of the listing's photos,
+// shrinking large ones. Photos that are missing or can't be decoded are skipped.
+func (app *application) loadBrochurePhotos(photos []*data.PropertyMedia) []image.Image {
+	images := []image.Image{}
+
+	for _, photo := range photos {
+		if len(images) == maxBrochurePhotos {
+			break
+		}
+
+		img, err := decodeBrochurePhoto(photo.FilePath)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"media_id": strconv.FormatInt(photo.ID, 10),
+			})
+			continue
+		}
+		if img != nil {
+			images = append(images, img)
+		}
+	}
+
+	return images
+}
+
+// decodeBrochurePhoto reads an image file, returning nil for formats the standard
+// decoders don't support and images too large to decode safely
+func decodeBrochurePhoto(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil || cfg.Width*cfg.Height > maxDecodePixels {
+		return nil, nil
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, nil
+	}
+
+	if cfg.Width > maxBrochurePhotoPixel || cfg.Height > maxBrochurePhotoPixel {
+		return downscaleImage(img, maxBrochurePhotoPixel), nil
+	}
+	return img, nil
+}
+
+// drawBrochurePhotos draws the main photo and thumbnails from y, or a placeholder
+// when the listing has no photos, and returns the y below them
+func drawBrochurePhotos(page *pdf.Page, images []image.Image, y float64) (float64, error) {
+	if len(images) == 0 {
+		page.SetFill(0.93, 0.93, 0.93)
+		page.Rect(brochureMargin, y, brochureWidth, 110)
+		page.SetFill(0.5, 0.5, 0.5)
+		text := "No photos available"
+		page.Text(brochureMargin+(brochureWidth-pdf.TextWidth(pdf.Regular, 12, text))/2, y+60, pdf.Regular, 12, text)
+		return y + 110, nil
+	}
+
+	// A single photo gets more room
+	mainHeight := 240.0
+	if len(images) == 1 {
+		mainHeight = 300
+	}
+
+	err := drawFittedImage(page, images[0], brochureMargin, y, brochureWidth, mainHeight)
+	if err != nil {
+		return 0, err
+	}
+	y += mainHeight
+
+	thumbs := images[1:]
+	if len(thumbs) == 0 {
+		return y, nil
+	}
+
+	gap := 8.0
+	thumbWidth := (brochureWidth - 2*gap) / 3
+	thumbHeight := 95.0
+	y += gap
+
+	for i, img := range thumbs {
+		err := drawFittedImage(page, img, brochureMargin+float64(i)*(thumbWidth+gap), y, thumbWidth, thumbHeight)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return y + thumbHeight, nil
+}
+
+// drawFittedImage draws img as large as fits in the box, centred on a light background
+func drawFittedImage(page *pdf.Page, img image.Image, x, y, w, h float64) error {
+	page.SetFill(0.93, 0.93, 0.93)
+	page.Rect(x, y, w, h)
+
+	bounds := img.Bounds()
+	scale := min(w/float64(bounds.Dx()), h/float64(bounds.Dy()))
+	iw, ih := float64(bounds.Dx())*scale, float64(bounds.Dy())*scale
+
+	return page.Image(img, x+(w-iw)/2, y+(h-ih)/2, iw, ih)
+}
+
+// drawBrochureLines draws up to maxLines of text from y, ending with an ellipsis
+// when lines are left out, and returns the y below them
+func drawBrochureLines(page *pdf.Page, lines []string, y, lineHeight float64, maxLines int) float64 {
+	if maxLines <= 0 {
+		return y
+	}
+
+	page.SetFill(0.2, 0.2, 0.2)
+	for i, line := range lines {
+		if i == maxLines-1 && len(lines) > maxLines {
+			line += " …"
+		}
+		page.Text(brochureMargin, y, pdf.Regular, 10, line)
+		y += lineHeight
+		if i == maxLines-1 {
+			break
+		}
+	}
+
+	return y
+}
+
+// drawBrochureQRCode draws a QR code linking to url as a size x size square
+func drawBrochureQRCode(page *pdf.Page, url string, x, y, size float64) error {
+	qr, err := qrcode.New(url, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+
+	bitmap := qr.Bitmap()
+	module := size / float64(len(bitmap))
+
+	page.SetFill(1, 1, 1)
+	page.Rect(x, y, size, size)
+	page.SetFill(0, 0, 0)
+
+	// Draw each run of dark modules in a row as one rectangle
+	for row, cells := range bitmap {
+		for col := 0; col < len(cells); {
+			if !cells[col] {
+				col++
+				continue
+			}
+			start := col
+			for col < len(cells) && cells[col] {
+				col++
+			}
+			page.Rect(x+float64(start)*module, y+float64(row)*module, float64(col-start)*module, module)
+		}
+	}
+
+	return nil
+}
+
+// brochureDetails lists the label and value of each detail the listing has
+func brochureDetails(property *data.Property) [][2]string {
+	details := [][2]string{
+		{"Type", brochureLabel(property.PropertyType)},
+		{"Bedrooms", strconv.Itoa(int(property.Bedrooms))},
+		{"Bathrooms", strconv.Itoa(int(property.Bathrooms))},
+		{"Area", fmt.Sprintf("%d m²", property.Area)},
+	}
+
+	if property.Floor != 0 {
+		details = append(details, [2]string{"Floor", strconv.Itoa(int(property.Floor))})
+	}
+	if property.YearBuilt != 0 {
+		details = append(details, [2]string{"Year built", strconv.Itoa(int(property.YearBuilt))})
+	}
+	if property.ParkingSpaces != nil {
+		details = append(details, [2]string{"Parking", strconv.Itoa(int(*property.ParkingSpaces))})
+	}
+	if property.LotSize != nil {
+		details = append(details, [2]string{"Lot size", fmt.Sprintf("%d m²", *property.LotSize)})
+	}
+	if property.Furnished != nil {
+		details = append(details, [2]string{"Furnished", brochureLabel(*property.Furnished)})
+	}
+	if property.HeatingType != nil {
+		details = append(details, [2]string{"Heating", brochureLabel(*property.HeatingType)})
+	}
+	if property.EnergyRating != nil {
+		details = append(details, [2]string{"Energy rating", *property.EnergyRating})
+	}
+
+	return details
+}
+
+// brochureLabel turns a stored value such as "semi_furnished" into "Semi furnished"
+func brochureLabel(value string) string {
+	value = strings.ReplaceAll(value, "_", " ")
+	if value == "" {
+		return value
+	}
+	return strings.ToUpper(value[:1]) + value[1:]
+}
+
+// formatBrochurePrice formats a price with thousands separators, eg KSh 12,500,000
+func formatBrochurePrice(price data.Price) string {
+	whole := strconv.FormatInt(int64(price), 10)
+
+	var sb strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(digit)
+	}
+
+	return "KSh " + sb.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+// =============================================================================
+// AGENT CONTACT REVEAL
+// =============================================================================
+
+// gateAgentPhone hides the agent's phone number from anonymous visitors when gating
+// is enabled; signed-in users reveal it through the contact endpoint
+func (app *application) gateAgentPhone(r *http.Request, card *data.AgentCard) {
+	if !app.config.contact.gatePhone || !app.contextGetUser(r).IsAnonymous() {
+		return
+	}
+
+	if card.Phone != nil {
+		card.Phone = nil
+		card.PhoneHidden = true
+	}
+}
+
+// showPropertyContactHandler returns the listing agent's contact details to a signed-in
+// user and records the reveal for the agent's analytics
+func (app *application) showPropertyContactHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !property.AgentID.Valid {
+		app.errorResponse(w, r, http.StatusNotFound, "this property does not have an assigned agent")
+		return
+	}
+
+	// Phone and email are still only included if the agent opted in to showing them
+	card, err := app.models.Agents.GetCard(property.AgentID.Int64)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUserNotFound):
+			app.errorResponse(w, r, http.StatusNotFound, "this property does not have an assigned agent")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Limit reveals per user so signed-in accounts cannot be used to scrape numbers. The
+	// reveal is recorded before responding so parallel requests can't slip past the limit.
+	err = app.models.Analytics.RecordContactReveal(property.ID, card.ID, user.ID, app.config.contact.revealLimit, time.Now().Add(-time.Hour))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrContactRevealLimit):
+			app.rateLimitExceededResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	contact := envelope{
+		"agent_id": card.ID,
+		"name":     card.Name,
+		"phone":    card.Phone,
+		"email":    card.Email,
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"contact": contact}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
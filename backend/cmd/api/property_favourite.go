@@ -2,7 +2,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/validator"
@@ -49,7 +52,7 @@ func (app *application) addFavouriteHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Return success response with property details
-	err = app.writeJSON(w, http.StatusCreated, envelope{
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{
 		"message":  "property added to favourites",
 		"property": property,
 	}, nil)
@@ -83,7 +86,7 @@ func (app *application) removeFavouriteHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "property removed from favourites",
 	}, nil)
 	if err != nil {
@@ -104,30 +107,47 @@ func (app *application) listUserFavouritesHandler(w http.ResponseWriter, r *http
 	v := validator.New()
 	qs := r.URL.Query()
 
+	// Optional collection filter; omitted means the implicit "All" collection
+	collectionID := int64(app.readInt(qs, "collection_id", 0, v))
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{
-		"id", "title", "price", "created_at",
-		"-id", "-title", "-price", "-created_at",
-	}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.FavouriteSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
+	v.Check(collectionID >= 0, "collection_id", "must be a positive integer")
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	// The collection must belong to the user
+	if collectionID > 0 {
+		_, err := app.models.Collections.Get(collectionID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrCollectionNotFound):
+				v.AddError("collection_id", "collection not found")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
 	// Fetch favourites
-	favourites, metadata, err := app.models.Favourites.GetAllForUser(user.ID, input.Filters)
+	favourites, metadata, err := app.models.Favourites.GetAllForUser(user.ID, collectionID, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"favourites": favourites,
-		"metadata":   metadata,
+		"metadata":   app.withPageLinks(r, metadata),
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -147,7 +167,7 @@ func (app *application) getUserFavouriteStatsHandler(w http.ResponseWriter, r *h
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -173,7 +193,7 @@ func (app *application) checkFavouriteStatusHandler(w http.ResponseWriter, r *ht
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"property_id":   propertyID,
 		"is_favourited": isFavourited,
 	}, nil)
@@ -182,6 +202,257 @@ func (app *application) checkFavouriteStatusHandler(w http.ResponseWriter, r *ht
 	}
 }
 
+// maxFavouriteStatusIDs caps how many properties one batch favourite status check may cover
+const maxFavouriteStatusIDs = 100
+
+// checkFavouriteStatusesHandler reports whether the user has favourited each of the
+// properties in ?ids=1,2,3, so a listing grid needs one call rather than one per card
+func (app *application) checkFavouriteStatusesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+	ids := app.readIDs(r.URL.Query(), "ids", v)
+	if v.Valid() {
+		v.Check(len(ids) > 0, "ids", "must contain at least 1 id")
+		v.Check(len(ids) <= maxFavouriteStatusIDs, "ids", fmt.Sprintf("must not contain more than %d ids", maxFavouriteStatusIDs))
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	favourited, err := app.models.Favourites.GetFavouritedAmong(user.ID, ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"favourites": favouriteStatuses(ids, favourited)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// favouriteStatuses maps every requested id to whether it is among the favourited ones,
+// so ids the user hasn't favourited are reported as false rather than left out
+func favouriteStatuses(ids, favourited []int64) map[int64]bool {
+	statuses := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		statuses[id] = false
+	}
+	for _, id := range favourited {
+		statuses[id] = true
+	}
+	return statuses
+}
+
+// =============================================================================
+// FAVOURITE COLLECTIONS
+// =============================================================================
+
+// listFavouriteCollectionsHandler lists the user's favourite collections
+func (app *application) listFavouriteCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	collections, err := app.models.Collections.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"collections": collections}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createFavouriteCollectionHandler creates a named collection for the user's favourites
+func (app *application) createFavouriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	collection := &data.FavouriteCollection{
+		UserID: user.ID,
+		Name:   strings.TrimSpace(input.Name),
+	}
+
+	v := validator.New()
+	if data.ValidateFavouriteCollection(v, collection); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collections.Insert(collection)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCollection):
+			v.AddError("name", "a collection with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrCollectionLimitExceeded):
+			app.errorResponse(w, r, http.StatusConflict,
+				fmt.Sprintf("you can have at most %d favourite collections", data.MaxFavouriteCollections))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"collection": collection}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateFavouriteCollectionHandler renames one of the user's collections
+func (app *application) updateFavouriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	collection, err := app.models.Collections.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCollectionNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	collection.Name = strings.TrimSpace(input.Name)
+
+	v := validator.New()
+	if data.ValidateFavouriteCollection(v, collection); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Collections.Update(collection)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateCollection):
+			v.AddError("name", "a collection with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"collection": collection}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteFavouriteCollectionHandler removes a collection; its favourites stay in "All"
+func (app *application) deleteFavouriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Collections.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrCollectionNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "collection successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// setFavouriteCollectionHandler moves a favourite into a collection, or back to "All" with a null collection_id
+func (app *application) setFavouriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	propertyID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		CollectionID *int64 `json:"collection_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if input.CollectionID != nil {
+		_, err := app.models.Collections.Get(*input.CollectionID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrCollectionNotFound):
+				v.AddError("collection_id", "collection not found")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	err = app.models.Favourites.SetCollection(user.ID, propertyID, input.CollectionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrFavouriteNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"property_id":   propertyID,
+		"collection_id": input.CollectionID,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 // =============================================================================
 // PUBLIC ENDPOINTS (Optional - for showing popular properties)
 // =============================================================================
@@ -190,6 +461,7 @@ func (app *application) checkFavouriteStatusHandler(w http.ResponseWriter, r *ht
 func (app *application) listMostFavouritedPropertiesHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	var input struct {
+		Window string
 		data.Filters
 	}
 
@@ -198,23 +470,39 @@ func (app *application) listMostFavouritedPropertiesHandler(w http.ResponseWrite
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortFavourites)
+	input.Filters.SortSafelist = data.FavouritedPropertySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	// Optional window limiting which favourites are counted, eg ?window=30d
+	input.Window = app.readString(qs, "window", "")
+	if input.Window != "" {
+		_, ok := data.FavouriteWindows[input.Window]
+		v.Check(ok, "window", "must be one of 7d, 30d, 90d or 1y")
+	}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	var since *time.Time
+	if days, ok := data.FavouriteWindows[input.Window]; ok {
+		t := time.Now().AddDate(0, 0, -days)
+		since = &t
+	}
+
 	// Fetch most favourited properties
-	properties, metadata, err := app.models.Favourites.GetMostFavouritedProperties(input.Filters)
+	properties, metadata, err := app.models.Favourites.GetMostFavouritedProperties(since, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"properties": properties,
-		"metadata":   metadata,
+		"metadata":   app.withPageLinks(r, metadata),
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -250,7 +538,7 @@ func (app *application) getPropertyFavouriteCountHandler(w http.ResponseWriter,
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"property_id":     propertyID,
 		"favourite_count": count,
 	}, nil)
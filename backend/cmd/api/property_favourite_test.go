@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+func TestFavouriteStatuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        []int64
+		favourited []int64
+		want       map[int64]bool
+	}{
+		{"mix of favourited and not", []int64{4, 8, 15, 16}, []int64{8, 16}, map[int64]bool{4: false, 8: true, 15: false, 16: true}},
+		{"none favourited", []int64{4, 8}, nil, map[int64]bool{4: false, 8: false}},
+		{"all favourited", []int64{4, 8}, []int64{8, 4}, map[int64]bool{4: true, 8: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := favouriteStatuses(tt.ids, tt.favourited); !maps.Equal(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFavouriteStatusesRejectsBadIDs(t *testing.T) {
+	app := newTestApplication(t)
+
+	tooMany := make([]string, maxFavouriteStatusIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i + 1)
+	}
+
+	tests := []struct {
+		name string
+		ids  string
+	}{
+		{"missing", ""},
+		{"not a number", "1,two,3"},
+		{"zero", "0,1"},
+		{"negative", "-4"},
+		{"too many", strings.Join(tooMany, ",")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/users/me/favourites/status?ids="+tt.ids, nil)
+			r = app.contextSetUser(r, &data.User{ID: 7, Role: "user"})
+			rr := httptest.NewRecorder()
+
+			// Bad id lists are rejected before the favourites are looked up
+			app.checkFavouriteStatusesHandler(rr, r)
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusUnprocessableEntity)
+			}
+		})
+	}
+}
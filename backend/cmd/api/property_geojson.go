@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// maxGeoJSONFeatures caps the number of features in a single FeatureCollection
+const maxGeoJSONFeatures = 500
+
+// geoJSONFeatureCollection is a GeoJSON FeatureCollection (RFC 7946)
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	BBox     []float64        `json:"bbox,omitempty"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONFeature is a single property as a GeoJSON Point feature
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         int64                  `json:"id"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONPoint holds coordinates in GeoJSON order: longitude, latitude
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// listPropertiesGeoJSONHandler returns properties matching the search filters as a FeatureCollection
+func (app *application) listPropertiesGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	limit := app.readInt(qs, "limit", maxGeoJSONFeatures, v)
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= maxGeoJSONFeatures, "limit", fmt.Sprintf("must be a maximum of %d", maxGeoJSONFeatures))
+
+	// Validate the sort against the standard rules, then widen the page to the feature limit
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     1,
+		Sort:         app.readString(qs, "sort", app.config.sort.properties),
+		SortSafelist: data.PropertySortSafelist,
+		MaxPage:      app.config.pagination.maxPage,
+	}
+	data.ValidateFilters(v, filters)
+
+	criteria := app.readSearchCriteria(qs, v)
+	criteria.MappableOnly = true
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	filters.PageSize = limit
+
+	properties, _, err := app.models.Properties.AdvancedSearch(criteria, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]geoJSONFeature, 0, len(properties)),
+	}
+	if criteria.BBox != nil {
+		collection.BBox = []float64{criteria.BBox.MinLng, criteria.BBox.MinLat, criteria.BBox.MaxLng, criteria.BBox.MaxLat}
+	}
+
+	for _, p := range properties {
+		if p.Latitude == nil || p.Longitude == nil {
+			continue
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			ID:   p.ID,
+			Geometry: geoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{*p.Longitude, *p.Latitude},
+			},
+			Properties: map[string]interface{}{
+				"title":         p.Title,
+				"price":         p.Price,
+				"bedrooms":      p.Bedrooms,
+				"bathrooms":     p.Bathrooms,
+				"area":          p.Area,
+				"location":      p.Location,
+				"property_type": p.PropertyType,
+				"featured":      p.FeaturedAt != nil,
+				"url":           fmt.Sprintf("/v1/property/%d", p.ID),
+			},
+		})
+	}
+
+	js, err := json.Marshal(collection)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(js, '\n'))
+}
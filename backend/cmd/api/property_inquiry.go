@@ -2,10 +2,13 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/mailer"
 	"github.com/codercollo/property/backend/internal/validator"
 )
 
@@ -13,6 +16,12 @@ import (
 // USER: CREATE INQUIRY
 // =============================================================================
 
+// inquiryLimitReached reports whether a user who has sent count inquiries about a property
+// within the limit window has used up their allowance
+func (app *application) inquiryLimitReached(count int) bool {
+	return app.config.inquiries.limit > 0 && count >= app.config.inquiries.limit
+}
+
 // createInquiryHandler allows users to submit an inquiry about a property
 func (app *application) createInquiryHandler(w http.ResponseWriter, r *http.Request) {
 	// Get property ID from URL
@@ -44,6 +53,28 @@ func (app *application) createInquiryHandler(w http.ResponseWriter, r *http.Requ
 	// Get authenticated user
 	user := app.contextGetUser(r)
 
+	// Agents can't send inquiries about their own listings
+	if ownsProperty(user, property) {
+		app.badRequestResponse(w, r, errors.New("you cannot send an inquiry about your own property"))
+		return
+	}
+
+	// Limit how often one user can open inquiries about the same property; admins are exempt
+	if app.config.inquiries.limit > 0 && user.Role != "admin" {
+		since := time.Now().Add(-app.config.inquiries.limitWindow)
+		count, err := app.models.Inquiries.CountRecentForUserProperty(user.ID, propertyID, since)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if app.inquiryLimitReached(count) {
+			app.errorResponse(w, r, http.StatusTooManyRequests,
+				fmt.Sprintf("you can send at most %d inquiries about this property every %s; the agent will reply to your earlier inquiry",
+					app.config.inquiries.limit, app.config.inquiries.limitWindow))
+			return
+		}
+	}
+
 	// Parse input
 	var input struct {
 		Name                   string     `json:"name"`
@@ -103,8 +134,17 @@ func (app *application) createInquiryHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Send notification email to agent (async)
+	// Send notification email to agent (async), unless it waits for their digest
 	app.background(func() {
+		prefs, err := app.models.Notifications.Get(property.AgentID.Int64)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+		if !prefs.EmailInquiryImmediately(inquiry) {
+			return
+		}
+
 		agent, err := app.models.Users.GetByID(property.AgentID.Int64)
 		if err != nil {
 			app.logger.PrintError(err, nil)
@@ -120,14 +160,23 @@ func (app *application) createInquiryHandler(w http.ResponseWriter, r *http.Requ
 			"inquiryID":     inquiry.ID,
 		}
 
-		err = app.mailer.Send(agent.Email, "inquiry_notification.tmpl", data)
+		// Replies from the agent go to the inquirer, through the relay when enabled
+		err = app.mailer.Send(agent.Email, "inquiry_notification.tmpl", data, mailer.WithReplyTo(app.inquiryReplyTo(inquiry.ID, inquiry.Email)))
 		if err != nil {
 			app.logger.PrintError(err, nil)
 		}
 	})
 
+	// Queue webhook deliveries for the agent
+	app.background(func() {
+		app.enqueueWebhookEvent(inquiry.AgentID, "inquiry.created", inquiry)
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", userInquiryLocation(inquiry.ID))
+
 	// Return created inquiry
-	err = app.writeJSON(w, http.StatusCreated, envelope{"inquiry": inquiry}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"inquiry": inquiry}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -155,24 +204,35 @@ func (app *application) listAgentInquiriesHandler(w http.ResponseWriter, r *http
 	v := validator.New()
 	qs := r.URL.Query()
 
+	agentID := app.readActingAgentID(qs, user, v)
 	input.Status = app.readString(qs, "status", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{
-		"id", "created_at", "priority", "status",
-		"-id", "-created_at", "-priority", "-status",
-	}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.AgentInquirySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	// A delegate may list the inquiries of the agent they are covering for
+	allowed, err := app.canActForAgent(user, agentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
 	// Fetch inquiries
 	inquiries, metadata, err := app.models.Inquiries.GetAllForAgent(
-		user.ID,
+		agentID,
 		input.Status,
+		app.inquirySLA(),
 		input.Filters,
 	)
 	if err != nil {
@@ -181,15 +241,75 @@ func (app *application) listAgentInquiriesHandler(w http.ResponseWriter, r *http
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"inquiries": inquiries,
-		"metadata":  metadata,
+		"metadata":  app.withPageLinks(r, metadata),
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// listOverdueInquiriesHandler lists the agent's new inquiries that have breached the
+// response SLA, oldest first
+func (app *application) listOverdueInquiriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	agentID := app.readActingAgentID(qs, user, v)
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortOldest)
+	input.Filters.SortSafelist = data.AgentInquirySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	allowed, err := app.canActForAgent(user, agentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	inquiries, metadata, err := app.models.Inquiries.GetOverdueForAgent(agentID, app.inquirySLA(), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"inquiries": inquiries,
+		"sla_hours": app.config.inquiries.slaHours,
+		"metadata":  app.withPageLinks(r, metadata),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// inquirySLA returns how long a new inquiry may wait for a response before it is overdue
+func (app *application) inquirySLA() time.Duration {
+	return time.Duration(app.config.inquiries.slaHours) * time.Hour
+}
+
 // getAgentInquiryHandler retrieves a specific inquiry for the agent
 func (app *application) getAgentInquiryHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
@@ -218,14 +338,25 @@ func (app *application) getAgentInquiryHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Verify inquiry belongs to this agent
-	if inquiry.AgentID != user.ID {
+	// Verify inquiry belongs to this agent or one they are covering for
+	allowed, err := app.canActForAgent(user, inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
 		app.notPermittedResponse(w, r)
 		return
 	}
 
+	inquiry.ContactAttempts, err = app.models.Inquiries.GetContactAttempts(inquiry.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
 	// Return inquiry
-	err = app.writeJSON(w, http.StatusOK, envelope{"inquiry": inquiry}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiry": inquiry}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -259,8 +390,13 @@ func (app *application) updateInquiryHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Verify inquiry belongs to this agent
-	if inquiry.AgentID != user.ID {
+	// Verify inquiry belongs to this agent or one they are covering for
+	allowed, err := app.canActForAgent(user, inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
 		app.notPermittedResponse(w, r)
 		return
 	}
@@ -314,6 +450,11 @@ func (app *application) updateInquiryHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	app.recordDelegateAction(user, inquiry.AgentID, "inquiries.update", "inquiry", inquiry.ID, map[string]interface{}{
+		"status":   inquiry.Status,
+		"priority": inquiry.Priority,
+	})
+
 	// Fetch updated inquiry
 	inquiry, err = app.models.Inquiries.Get(id)
 	if err != nil {
@@ -322,12 +463,274 @@ func (app *application) updateInquiryHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Return updated inquiry
-	err = app.writeJSON(w, http.StatusOK, envelope{"inquiry": inquiry}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiry": inquiry}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reassignInquiryHandler transfers one of the agent's inquiries to a colleague, who
+// is emailed about it. The inquirer's later emails go to the new agent.
+func (app *application) reassignInquiryHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	inquiry, err := app.models.Inquiries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Only the agent who owns the inquiry may hand it on
+	if inquiry.AgentID != user.ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		AgentID int64  `json:"agent_id"`
+		Note    string `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Note = strings.TrimSpace(input.Note)
+
+	v := validator.New()
+	v.Check(input.AgentID > 0, "agent_id", "must be provided")
+	v.Check(input.AgentID != user.ID, "agent_id", "must be another agent")
+	v.Check(len(input.Note) <= 1000, "note", "must not exceed 1000 characters")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	active, err := app.models.Agents.IsActive(input.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !active {
+		v.AddError("agent_id", "must be an active agent")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Inquiries.Reassign(inquiry.ID, input.AgentID, inquiry.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.recordAudit(user.ID, "inquiries.reassign", "inquiry", inquiry.ID, map[string]interface{}{
+		"from_agent_id": user.ID,
+		"to_agent_id":   input.AgentID,
+	})
+
+	inquiry, err = app.models.Inquiries.Get(inquiry.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		agent, err := app.models.Users.GetByID(inquiry.AgentID)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"inquiry_id": fmt.Sprintf("%d", inquiry.ID),
+			})
+			return
+		}
+
+		emailData := map[string]interface{}{
+			"agentName":     agent.Name,
+			"fromAgentName": user.Name,
+			"inquirerName":  inquiry.Name,
+			"propertyTitle": inquiry.PropertyTitle,
+			"message":       inquiry.Message,
+			"note":          input.Note,
+			"inquiryID":     inquiry.ID,
+		}
+
+		err = app.mailer.Send(agent.Email, "inquiry_reassigned.tmpl", emailData, mailer.WithReplyTo(app.inquiryReplyTo(inquiry.ID, inquiry.Email)))
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"inquiry_id": fmt.Sprintf("%d", inquiry.ID),
+			})
+		}
+	})
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiry": inquiry}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// logContactAttemptHandler records an attempt to reach the inquirer outside the
+// message thread, eg a phone call. Any attempt counts towards the response rate.
+func (app *application) logContactAttemptHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	inquiry, err := app.models.Inquiries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Verify inquiry belongs to this agent or one they are covering for
+	allowed, err := app.canActForAgent(user, inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Method  string `json:"method"`
+		Outcome string `json:"outcome"`
+		Note    string `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	attempt := &data.ContactAttempt{
+		InquiryID: inquiry.ID,
+		AgentID:   user.ID,
+		Method:    input.Method,
+		Outcome:   input.Outcome,
+		Note:      strings.TrimSpace(input.Note),
+	}
+
+	v := validator.New()
+	if data.ValidateContactAttempt(v, attempt); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Inquiries.LogContactAttempt(attempt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordDelegateAction(user, inquiry.AgentID, "inquiries.contact_attempt", "inquiry", inquiry.ID, map[string]interface{}{
+		"method":  attempt.Method,
+		"outcome": attempt.Outcome,
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", agentInquiryLocation(inquiry.ID))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"contact_attempt": attempt}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// maxBulkInquiryUpdate caps the number of inquiries updated in one request
+const maxBulkInquiryUpdate = 100
+
+// bulkUpdateInquiriesHandler sets the status and/or priority of many of the agent's inquiries at once
+func (app *application) bulkUpdateInquiriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		IDs      []int64 `json:"ids"`
+		Status   *string `json:"status"`
+		Priority *string `json:"priority"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.IDs) > 0, "ids", "must contain at least 1 id")
+	v.Check(len(input.IDs) <= maxBulkInquiryUpdate, "ids", fmt.Sprintf("must not contain more than %d ids", maxBulkInquiryUpdate))
+
+	seen := make(map[int64]bool, len(input.IDs))
+	for _, id := range input.IDs {
+		v.Check(id > 0, "ids", "must contain only positive ids")
+		v.Check(!seen[id], "ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+
+	v.Check(input.Status != nil || input.Priority != nil, "status", "status or priority must be provided")
+	if input.Status != nil {
+		v.Check(validator.In(*input.Status, data.InquiryStatuses...), "status", "must be one of: new, contacted, scheduled, closed, spam")
+	}
+	if input.Priority != nil {
+		v.Check(validator.In(*input.Priority, data.InquiryPriorities...), "priority", "must be one of: low, normal, high, urgent")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, err := app.models.Inquiries.UpdateBulk(user.ID, input.IDs, input.Status, input.Priority)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeBulkResult(w, r, results)
+}
+
 // getAgentInquiryStatsHandler returns inquiry statistics for the agent
 func (app *application) getAgentInquiryStatsHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
@@ -343,7 +746,7 @@ func (app *application) getAgentInquiryStatsHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -367,10 +770,9 @@ func (app *application) listUserInquiriesHandler(w http.ResponseWriter, r *http.
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{
-		"id", "created_at", "-id", "-created_at",
-	}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.UserInquirySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -385,9 +787,9 @@ func (app *application) listUserInquiriesHandler(w http.ResponseWriter, r *http.
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"inquiries": inquiries,
-		"metadata":  metadata,
+		"metadata":  app.withPageLinks(r, metadata),
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -423,8 +825,13 @@ func (app *application) getUserInquiryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Agent notes are private; the inquirer only sees the agent's response
+	if user.Role != "admin" {
+		inquiry.AgentNotes = ""
+	}
+
 	// Return inquiry
-	err = app.writeJSON(w, http.StatusOK, envelope{"inquiry": inquiry}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"inquiry": inquiry}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -472,7 +879,7 @@ func (app *application) deleteInquiryHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Return success
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "inquiry successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "inquiry successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInquiryLimitReached(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		count int
+		want  bool
+	}{
+		{"no earlier inquiries", 1, 0, false},
+		{"at the limit", 1, 1, true},
+		{"over the limit", 1, 2, true},
+		{"one below a higher limit", 3, 2, false},
+		{"at a higher limit", 3, 3, true},
+		{"limit disabled", 0, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.inquiries.limit = tt.limit
+			app.config.inquiries.limitWindow = 24 * time.Hour
+
+			if got := app.inquiryLimitReached(tt.count); got != tt.want {
+				t.Errorf("inquiryLimitReached(%d) with limit %d = %v; want %v", tt.count, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -46,8 +46,20 @@ func (app *application) uploadPropertyMediaHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Parse multipart form (max 50MB)
-	err = r.ParseMultipartForm(50 << 20)
+	// Reject the upload before reading the body once the property is full. Insert
+	// checks again under a lock, as concurrent uploads may both get past this.
+	mediaCount, err := app.models.Media.CountForProperty(propertyID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if mediaCount >= app.config.media.maxPerProperty {
+		app.mediaLimitReachedResponse(w, r)
+		return
+	}
+
+	// Parse multipart form, capped at the configured upload size
+	err = app.parseMultipartForm(w, r, 10<<20)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
 		return
@@ -95,8 +107,29 @@ func (app *application) uploadPropertyMediaHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// Enforce the maximum image dimension; non-images are stored unchanged
+	var body io.Reader = file
+	fileSize := header.Size
+
+	processed, err := app.processMediaImage(file, header.Size)
+	if err != nil {
+		var tooLarge *imageTooLargeError
+		switch {
+		case errors.As(err, &tooLarge):
+			v.AddError("file", tooLarge.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if processed != nil {
+		body = processed.body
+		fileSize = processed.size
+	}
+
 	// Save file to disk
-	filePath, err := app.saveMediaFile(file, header, propertyID, mediaType)
+	filePath, err := app.saveMediaFile(body, header, propertyID, mediaType)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -108,13 +141,20 @@ func (app *application) uploadPropertyMediaHandler(w http.ResponseWriter, r *htt
 		MediaType:    mediaType,
 		FilePath:     filePath,
 		FileName:     header.Filename,
-		FileSize:     header.Size,
+		FileSize:     fileSize,
 		MimeType:     header.Header.Get("Content-Type"),
 		DisplayOrder: displayOrder,
 		Caption:      caption,
 		IsPrimary:    false,
 	}
 
+	if processed != nil {
+		media.OriginalWidth = &processed.originalWidth
+		media.OriginalHeight = &processed.originalHeight
+		media.Width = &processed.width
+		media.Height = &processed.height
+	}
+
 	// Validate media record
 	if data.ValidateMedia(v, media); !v.Valid() {
 		// Clean up saved file if validation fails
@@ -124,21 +164,59 @@ func (app *application) uploadPropertyMediaHandler(w http.ResponseWriter, r *htt
 	}
 
 	// Insert into database
-	err = app.models.Media.Insert(media)
+	err = app.models.Media.Insert(media, app.config.media.maxPerProperty)
 	if err != nil {
 		// Clean up saved file if database insert fails
 		os.Remove(filePath)
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrMediaLimitReached):
+			app.mediaLimitReachedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	// The first image uploaded becomes the cover image, which is all a listing trusted
+	// to skip moderation is waiting for
+	if media.MediaType == "image" {
+		err = app.models.Media.EnsurePrimary(media.PropertyID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = app.approveTrustedListing(user, property)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		media, err = app.models.Media.Get(media.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", propertyMediaLocation(propertyID))
+
 	// Return success response
-	err = app.writeJSON(w, http.StatusCreated, envelope{"media": media}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"media": media}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// mediaLimitReachedResponse rejects an upload to a property that already has the
+// configured maximum number of media items
+func (app *application) mediaLimitReachedResponse(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	v.AddError("file", fmt.Sprintf("property already has the maximum of %d media items", app.config.media.maxPerProperty))
+	app.failedValidationResponse(w, r, v.Errors)
+}
+
 // listPropertyMediaHandler retrieves all media for a property
 func (app *application) listPropertyMediaHandler(w http.ResponseWriter, r *http.Request) {
 	// Get property ID from URL
@@ -148,8 +226,8 @@ func (app *application) listPropertyMediaHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Verify property exists
-	_, err = app.models.Properties.Get(propertyID)
+	// Verify property exists and is visible to the requester
+	property, err := app.models.Properties.Get(propertyID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrPropertyNotFound):
@@ -160,6 +238,11 @@ func (app *application) listPropertyMediaHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	// Get all media for the property
 	mediaList, err := app.models.Media.GetAllForProperty(propertyID)
 	if err != nil {
@@ -168,7 +251,7 @@ func (app *application) listPropertyMediaHandler(w http.ResponseWriter, r *http.
 	}
 
 	// Return media list
-	err = app.writeJSON(w, http.StatusOK, envelope{"media": mediaList}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"media": mediaList}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -244,8 +327,17 @@ func (app *application) deletePropertyMediaHandler(w http.ResponseWriter, r *htt
 	// Delete file from disk (ignore errors)
 	os.Remove(media.FilePath)
 
+	// Promote another image if the cover image was removed
+	if media.MediaType == "image" {
+		err = app.models.Media.EnsurePrimary(media.PropertyID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "media successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "media successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -355,8 +447,23 @@ func (app *application) updatePropertyMediaHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// An image can't stop being the cover without another taking its place
+	if media.MediaType == "image" {
+		err = app.models.Media.EnsurePrimary(media.PropertyID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		media, err = app.models.Media.Get(mediaID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
 	// Return updated media
-	err = app.writeJSON(w, http.StatusOK, envelope{"media": media}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"media": media}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -388,7 +495,7 @@ func (app *application) validateMediaFile(header *multipart.FileHeader, mediaTyp
 }
 
 // Helper function to save media file to disk
-func (app *application) saveMediaFile(file multipart.File, header *multipart.FileHeader, propertyID int64, mediaType string) (string, error) {
+func (app *application) saveMediaFile(file io.Reader, header *multipart.FileHeader, propertyID int64, mediaType string) (string, error) {
 	// Create upload directory structure: uploads/properties/{propertyID}/{mediaType}/
 	uploadDir := filepath.Join("uploads", "properties", fmt.Sprintf("%d", propertyID), mediaType)
 	err := os.MkdirAll(uploadDir, 0755)
@@ -441,3 +548,78 @@ func sanitizeFilename(filename string) string {
 
 	return name
 }
+
+// reorderPropertyMediaHandler sets the gallery order of a property's media in one
+// step from an ordered list of media IDs, and returns the reordered media
+func (app *application) reorderPropertyMediaHandler(w http.ResponseWriter, r *http.Request) {
+	propertyID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(propertyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if property.AgentID.Valid && property.AgentID.Int64 != user.ID && user.Role != "admin" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		MediaIDs []int64 `json:"media_ids"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.MediaIDs) > 0, "media_ids", "must contain at least one media id")
+	v.Check(len(input.MediaIDs) <= data.MaxMediaReorder, "media_ids", fmt.Sprintf("must not contain more than %d media ids", data.MaxMediaReorder))
+
+	seen := make(map[int64]bool, len(input.MediaIDs))
+	for _, id := range input.MediaIDs {
+		v.Check(!seen[id], "media_ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Media.Reorder(propertyID, input.MediaIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrMediaNotInProperty):
+			v.AddError("media_ids", "must only contain media of this property")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	mediaList, err := app.models.Media.GetAllForProperty(propertyID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"media": mediaList}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// PROPERTY NOTES
+// =============================================================================
+
+// listPropertyNotesHandler returns the private notes thread on a listing
+func (app *application) listPropertyNotesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	property, ok := app.notesProperty(w, r, user)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafelist = data.PropertyNoteSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	notes, metadata, err := app.models.PropertyNotes.GetAllForProperty(property.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"notes": notes, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createPropertyNoteHandler adds a note to a listing's private thread
+func (app *application) createPropertyNoteHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	property, ok := app.notesProperty(w, r, user)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	note := &data.PropertyNote{
+		PropertyID: property.ID,
+		AuthorID:   user.ID,
+		AuthorName: user.Name,
+		AuthorRole: user.Role,
+		Kind:       data.PropertyNoteComment,
+		Note:       input.Note,
+	}
+
+	v := validator.New()
+	if data.ValidatePropertyNote(v, note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.PropertyNotes.Insert(note)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"note": note}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// notesProperty loads the property named in the URL if the user may see its notes:
+// admins and the owning agent only. The second return value is false when a response was sent.
+func (app *application) notesProperty(w http.ResponseWriter, r *http.Request, user *data.User) (*data.Property, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	isOwner := user.Role == "agent" && property.AgentID.Valid && property.AgentID.Int64 == user.ID
+	if user.Role != "admin" && !isOwner {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return property, true
+}
+
+// addModerationNote records a moderation outcome in the listing's notes thread.
+// Failures are logged rather than returned since the moderation itself has succeeded.
+func (app *application) addModerationNote(propertyID int64, admin *data.User, kind, text string) {
+	note := &data.PropertyNote{
+		PropertyID: propertyID,
+		AuthorID:   admin.ID,
+		AuthorRole: "admin",
+		Kind:       kind,
+		Note:       text,
+	}
+
+	err := app.models.PropertyNotes.Insert(note)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"context": "recording moderation note",
+		})
+	}
+}
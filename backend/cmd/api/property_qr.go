@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QR code size bounds in pixels
+const (
+	minQRSize     = 128
+	maxQRSize     = 1024
+	defaultQRSize = 256
+	maxQRCacheLen = 500
+)
+
+// renderCache holds generated files, such as QR code PNGs, keyed by whatever
+// determines their content, eg property id, version and size
+type renderCache struct {
+	mu     sync.Mutex
+	items  map[string][]byte
+	maxLen int
+}
+
+// newRenderCache creates an empty cache holding at most maxLen files
+func newRenderCache(maxLen int) *renderCache {
+	return &renderCache{items: make(map[string][]byte), maxLen: maxLen}
+}
+
+// get returns a cached file if present
+func (c *renderCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, ok := c.items[key]
+	return file, ok
+}
+
+// set stores a file, clearing the cache once it grows past its bound
+func (c *renderCache) set(key string, file []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.items) >= c.maxLen {
+		c.items = make(map[string][]byte)
+	}
+	c.items[key] = file
+}
+
+// propertyQRCodeHandler returns a PNG QR code linking to the public property URL
+func (app *application) propertyQRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	size := app.readInt(r.URL.Query(), "size", defaultQRSize, v)
+	v.Check(size >= minQRSize, "size", fmt.Sprintf("must be at least %d", minQRSize))
+	v.Check(size <= maxQRSize, "size", fmt.Sprintf("must not be more than %d", maxQRSize))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Serve from cache when this version has already been rendered
+	key := fmt.Sprintf("%d:%d:%d", property.ID, property.Version, size)
+	png, ok := app.qrCodes.get(key)
+	if !ok {
+		png, err = qrcode.Encode(app.publicPropertyURL(property), qrcode.Medium, size)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.qrCodes.set(key, png)
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(png)))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
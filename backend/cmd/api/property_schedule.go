@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/mailer"
 	"github.com/codercollo/property/backend/internal/validator"
 )
 
@@ -45,11 +46,18 @@ func (app *application) createScheduleHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Agents can't book viewings of their own listings
+	if ownsProperty(user, property) {
+		app.badRequestResponse(w, r, errors.New("you cannot book a viewing of your own property"))
+		return
+	}
+
 	// Parse request body
 	var input struct {
 		ScheduledAt     time.Time `json:"scheduled_at"`
 		DurationMinutes int       `json:"duration_minutes"`
 		Notes           string    `json:"notes"`
+		Timezone        string    `json:"timezone"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -58,20 +66,48 @@ func (app *application) createScheduleHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Set default duration if not provided
-	if input.DurationMinutes == 0 {
-		input.DurationMinutes = 60 // Default 1 hour
+	input.DurationMinutes = app.scheduleDuration(input.DurationMinutes)
+
+	// Default the display timezone to the booking user's own
+	if input.Timezone == "" {
+		input.Timezone = user.Timezone
+	}
+
+	// Look up the agent's booking slot size
+	slotMinutes, err := app.models.Agents.GetSlotMinutes(property.AgentID.Int64)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Working hours are checked in the agent's local timezone
+	agentLocation, err := app.agentLocation(property.AgentID.Int64)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Agents with configured availability are bound by it instead of the standard hours
+	workingHours, err := app.models.Availability.WorkingHours(property.AgentID.Int64, app.config.schedules.workingHours)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	// Create schedule
 	schedule := &data.Schedule{
-		PropertyID:      propertyID,
-		UserID:          user.ID,
-		AgentID:         property.AgentID.Int64,
-		ScheduledAt:     input.ScheduledAt,
-		DurationMinutes: input.DurationMinutes,
-		Status:          "pending",
-		Notes:           input.Notes,
+		PropertyID:         propertyID,
+		UserID:             user.ID,
+		AgentID:            property.AgentID.Int64,
+		ScheduledAt:        input.ScheduledAt.UTC(),
+		DurationMinutes:    input.DurationMinutes,
+		Status:             "pending",
+		Notes:              input.Notes,
+		Timezone:           input.Timezone,
+		SlotMinutes:        slotMinutes,
+		AgentLocation:      agentLocation,
+		WorkingHours:       workingHours,
+		MaxDurationMinutes: app.config.schedules.maxDuration,
 	}
 
 	// Validate
@@ -88,6 +124,12 @@ func (app *application) createScheduleHandler(w http.ResponseWriter, r *http.Req
 		case errors.Is(err, data.ErrScheduleConflict):
 			v.AddError("scheduled_at", "this time slot is already booked")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUserScheduleConflict):
+			v.AddError("scheduled_at", "you already have another viewing booked at this time")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrOutsideAvailability):
+			v.AddError("scheduled_at", "is outside the agent's available hours")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -102,10 +144,120 @@ func (app *application) createScheduleHandler(w http.ResponseWriter, r *http.Req
 			"property_id": strconv.FormatInt(propertyID, 10),
 			"agent_id":    strconv.FormatInt(property.AgentID.Int64, 10),
 		})
+
+		app.enqueueWebhookEvent(schedule.AgentID, "schedule.created", schedule)
 	})
 
+	headers := make(http.Header)
+	headers.Set("Location", userScheduleLocation(schedule.ID))
+
 	// Return created schedule
-	err = app.writeJSON(w, http.StatusCreated, envelope{"schedule": schedule}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"schedule": schedule}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// scheduleDuration returns the requested viewing length, or the configured default
+// when the booking doesn't give one
+func (app *application) scheduleDuration(requested int) int {
+	if requested == 0 {
+		return app.config.schedules.defaultDuration
+	}
+	return requested
+}
+
+// Available slot lookups
+const (
+	defaultSlotDurationMinutes = 60
+	maxSlotLookaheadDays       = 90
+)
+
+// listAvailableSlotsHandler lists the times on a date at which a viewing of the
+// property could be booked with its agent, so users don't have to guess free times
+func (app *application) listAvailableSlotsHandler(w http.ResponseWriter, r *http.Request) {
+	propertyID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	property, err := app.models.Properties.Get(propertyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Unmoderated listings are only visible to their agent and admins
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !property.AgentID.Valid {
+		app.badRequestResponse(w, r, errors.New("property does not have an assigned agent"))
+		return
+	}
+	agentID := property.AgentID.Int64
+
+	slotMinutes, err := app.models.Agents.GetSlotMinutes(agentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The date is a day in the agent's timezone, today by default
+	loc, err := app.agentLocation(agentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	date := today
+	if s := qs.Get("date"); s != "" {
+		date, err = time.ParseInLocation("2006-01-02", s, loc)
+		if err != nil {
+			v.AddError("date", "must be a YYYY-MM-DD date")
+		}
+	}
+	duration := app.readInt(qs, "duration", defaultSlotDurationMinutes, v)
+
+	if v.Valid() {
+		v.Check(!date.Before(today), "date", "must not be in the past")
+		v.Check(date.Before(today.AddDate(0, 0, maxSlotLookaheadDays+1)), "date", fmt.Sprintf("must be within %d days", maxSlotLookaheadDays))
+		v.Check(duration > 0, "duration", "must be positive")
+		v.Check(duration <= app.config.schedules.maxDuration, "duration", fmt.Sprintf("must not exceed %d minutes", app.config.schedules.maxDuration))
+		v.Check(duration%slotMinutes == 0, "duration", fmt.Sprintf("must be a multiple of %d minutes", slotMinutes))
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	slots, err := app.models.Schedules.GetAvailableSlots(agentID, date, duration, slotMinutes, app.config.schedules.workingHours)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"date":             date.Format("2006-01-02"),
+		"timezone":         loc.String(),
+		"duration_minutes": duration,
+		"slots":            slots,
+	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -124,8 +276,9 @@ func (app *application) listUserSchedulesHandler(w http.ResponseWriter, r *http.
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-scheduled_at")
-	input.Filters.SortSafelist = []string{"id", "scheduled_at", "-id", "-scheduled_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortLatestSchedule)
+	input.Filters.SortSafelist = data.ScheduleSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -138,7 +291,7 @@ func (app *application) listUserSchedulesHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"schedules": schedules, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedules": schedules, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -171,7 +324,7 @@ func (app *application) getUserScheduleHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"schedule": schedule}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedule": schedule}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -222,7 +375,7 @@ func (app *application) cancelUserScheduleHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "schedule successfully cancelled"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "schedule successfully cancelled"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -250,24 +403,105 @@ func (app *application) listAgentSchedulesHandler(w http.ResponseWriter, r *http
 	v := validator.New()
 	qs := r.URL.Query()
 
+	agentID := app.readActingAgentID(qs, user, v)
 	input.Status = app.readString(qs, "status", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "scheduled_at")
-	input.Filters.SortSafelist = []string{"id", "scheduled_at", "-id", "-scheduled_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortUpcoming)
+	input.Filters.SortSafelist = data.ScheduleSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	schedules, metadata, err := app.models.Schedules.GetAllForAgent(user.ID, input.Status, input.Filters)
+	// A delegate may list the schedules of the agent they are covering for
+	allowed, err := app.canActForAgent(user, agentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	schedules, metadata, err := app.models.Schedules.GetAllForAgent(agentID, input.Status, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"schedules": schedules, "metadata": metadata}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedules": schedules, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listAgentPropertySchedulesHandler lists the viewings booked for one of the agent's
+// properties; properties belonging to someone else are reported as not found
+func (app *application) listAgentPropertySchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortUpcoming)
+	input.Filters.SortSafelist = data.ScheduleSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, "pending", "confirmed", "cancelled", "completed"), "status", "must be pending, confirmed, cancelled, or completed")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	property, err := app.models.Properties.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !property.AgentID.Valid || property.AgentID.Int64 != user.ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	schedules, metadata, err := app.models.Schedules.GetAllForProperty(property.ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedules": schedules, "metadata": app.withPageLinks(r, metadata)}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -300,13 +534,18 @@ func (app *application) getAgentScheduleHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Verify this is the agent's schedule
-	if schedule.AgentID != user.ID {
+	// Verify this is the agent's schedule or one they are covering for
+	allowed, err := app.canActForAgent(user, schedule.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
 		app.notPermittedResponse(w, r)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"schedule": schedule}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedule": schedule}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -339,8 +578,13 @@ func (app *application) updateAgentScheduleStatusHandler(w http.ResponseWriter,
 		return
 	}
 
-	// Verify this is the agent's schedule
-	if schedule.AgentID != user.ID {
+	// Verify this is the agent's schedule or one they are covering for
+	allowed, err := app.canActForAgent(user, schedule.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
 		app.notPermittedResponse(w, r)
 		return
 	}
@@ -377,6 +621,10 @@ func (app *application) updateAgentScheduleStatusHandler(w http.ResponseWriter,
 		return
 	}
 
+	app.recordDelegateAction(user, schedule.AgentID, "schedules.update_status", "schedule", schedule.ID, map[string]interface{}{
+		"status": input.Status,
+	})
+
 	// Fetch updated schedule
 	updatedSchedule, err := app.models.Schedules.Get(id)
 	if err != nil {
@@ -384,7 +632,7 @@ func (app *application) updateAgentScheduleStatusHandler(w http.ResponseWriter,
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"schedule": updatedSchedule}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"schedule": updatedSchedule}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -406,7 +654,43 @@ func (app *application) getAgentScheduleStatsHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"stats": stats}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAgentScheduleTrendHandler returns the agent's daily schedule activity over a period,
+// e.g. ?period=30d
+func (app *application) getAgentScheduleTrendHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	period := app.readString(r.URL.Query(), "period", "30d")
+
+	days, ok := data.ScheduleTrendPeriods[period]
+	v.Check(ok, "period", "must be one of: 7d, 30d, 90d, 1y")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The series ends with today (UTC)
+	to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	from := to.AddDate(0, 0, -days)
+
+	trend, err := app.models.Schedules.GetTrendForAgent(user.ID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"period": period, "trend": trend}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -460,21 +744,55 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 		newDuration = *input.DurationMinutes
 	}
 
+	// Look up the agent's booking slot size
+	schedule.SlotMinutes, err = app.models.Agents.GetSlotMinutes(schedule.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Working hours are checked in the agent's local timezone
+	schedule.AgentLocation, err = app.agentLocation(schedule.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Agents with configured availability are bound by it instead of the standard hours
+	schedule.WorkingHours, err = app.models.Availability.WorkingHours(schedule.AgentID, app.config.schedules.workingHours)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	schedule.MaxDurationMinutes = app.config.schedules.maxDuration
+
+	// Store times in UTC regardless of the offset supplied
+	input.ScheduledAt = input.ScheduledAt.UTC()
+
 	// Validate reschedule request
 	v := validator.New()
 	data.ValidateReschedule(v, schedule, input.ScheduledAt, newDuration)
+	if input.Notes != nil {
+		data.ValidateScheduleNotes(v, *input.Notes)
+	}
 	if !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	// Perform the reschedule
-	err = app.models.Schedules.Reschedule(id, input.ScheduledAt, newDuration, schedule.Version)
+	err = app.models.Schedules.Reschedule(id, input.ScheduledAt, newDuration, schedule.Version, schedule.AgentLocation)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrScheduleConflict):
 			v.AddError("scheduled_at", "this time slot is already booked")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUserScheduleConflict):
+			v.AddError("scheduled_at", "you already have another viewing booked at this time")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrOutsideAvailability):
+			v.AddError("scheduled_at", "is outside the agent's available hours")
+			app.failedValidationResponse(w, r, v.Errors)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -483,15 +801,18 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	// Update notes if provided
+	// Update notes if provided; the reschedule bumped the version, so this mustn't check it
 	if input.Notes != nil {
-		updatedSchedule, err := app.models.Schedules.Get(id)
+		err = app.models.Schedules.UpdateNotes(id, *input.Notes)
 		if err != nil {
-			app.serverErrorResponse(w, r, err)
+			switch {
+			case errors.Is(err, data.ErrScheduleNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
 			return
 		}
-		updatedSchedule.Notes = *input.Notes
-		// Note: You might want to add an UpdateNotes method to avoid version conflict issues
 	}
 
 	// Fetch the updated schedule
@@ -528,7 +849,7 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 				"scheduleID":      id,
 			}
 
-			err = app.mailer.Send(agent.Email, "schedule_rescheduled.tmpl", emailData)
+			err = app.mailer.Send(agent.Email, "schedule_rescheduled.tmpl", emailData, mailer.WithReplyTo(user.Email))
 			if err != nil {
 				app.logger.PrintError(err, map[string]string{
 					"context": "sending reschedule notification email",
@@ -537,6 +858,11 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 		})
 	}
 
+	// Queue webhook deliveries for the agent
+	app.background(func() {
+		app.enqueueWebhookEvent(updatedSchedule.AgentID, "schedule.rescheduled", updatedSchedule)
+	})
+
 	// Log the reschedule action
 	app.logger.PrintInfo("schedule rescheduled", map[string]string{
 		"schedule_id":      fmt.Sprintf("%d", id),
@@ -548,7 +874,7 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 	})
 
 	// Return success response with updated schedule
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message":  "schedule successfully rescheduled",
 		"schedule": updatedSchedule,
 	}, nil)
@@ -556,3 +882,118 @@ func (app *application) rescheduleUserScheduleHandler(w http.ResponseWriter, r *
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// completeAgentPastSchedulesHandler marks the agent's finished confirmed viewings as completed
+func (app *application) completeAgentPastSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	count, err := app.completePastSchedules(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"completed": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminCompletePastSchedulesHandler marks finished confirmed viewings as completed for every agent
+func (app *application) adminCompletePastSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := app.completePastSchedules(0)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"completed": count}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// completePastSchedules completes finished confirmed viewings (for one agent, or all when
+// agentID is 0) and notifies the agents so they can follow up
+func (app *application) completePastSchedules(agentID int64) (int64, error) {
+	count, schedules, err := app.models.Schedules.CompletePastConfirmed(agentID, app.config.schedules.completionGrace)
+	if err != nil {
+		return 0, err
+	}
+
+	app.notifyCompletedSchedules(schedules)
+
+	return count, nil
+}
+
+// notifyCompletedSchedules tells each agent which of their viewings were completed so they
+// can follow up
+func (app *application) notifyCompletedSchedules(schedules []*data.Schedule) {
+	if len(schedules) == 0 {
+		return
+	}
+
+	byAgent := make(map[int64][]*data.Schedule)
+	for _, schedule := range schedules {
+		byAgent[schedule.AgentID] = append(byAgent[schedule.AgentID], schedule)
+	}
+
+	app.background(func() {
+		// Look up every property named in the emails at once
+		propertyIDs := make([]int64, 0, len(schedules))
+		for _, schedule := range schedules {
+			propertyIDs = append(propertyIDs, schedule.PropertyID)
+		}
+
+		propertyTitles := make(map[int64]string)
+		properties, err := app.models.Properties.GetBatch(propertyIDs)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		for _, property := range properties {
+			propertyTitles[property.ID] = property.Title
+		}
+
+		for agentID, completed := range byAgent {
+			for _, schedule := range completed {
+				app.enqueueWebhookEvent(agentID, "schedule.completed", schedule)
+			}
+
+			agent, err := app.models.Users.Get(agentID)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"agent_id": strconv.FormatInt(agentID, 10),
+				})
+				continue
+			}
+
+			emailData := map[string]interface{}{
+				"agentName":      agent.Name,
+				"count":          len(completed),
+				"schedules":      completed,
+				"propertyTitles": propertyTitles,
+			}
+
+			err = app.mailer.Send(agent.Email, "schedules_auto_completed.tmpl", emailData)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"agent_id": strconv.FormatInt(agentID, 10),
+				})
+			}
+		}
+	})
+}
+
+// agentLocation loads the agent's configured timezone
+func (app *application) agentLocation(agentID int64) (*time.Location, error) {
+	agent, err := app.models.Users.Get(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return agent.Location(), nil
+}
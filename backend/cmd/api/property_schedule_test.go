@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestScheduleDurationDefault(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.schedules.defaultDuration = 45
+	app.config.schedules.maxDuration = 90
+
+	if got := app.scheduleDuration(0); got != 45 {
+		t.Errorf("omitted duration: got %d; want the configured default 45", got)
+	}
+	if got := app.scheduleDuration(30); got != 30 {
+		t.Errorf("given duration: got %d; want 30", got)
+	}
+}
+
+func TestScheduleDurationMaxEnforced(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.schedules.defaultDuration = 45
+	app.config.schedules.maxDuration = 90
+
+	tests := []struct {
+		requested int
+		wantValid bool
+	}{
+		{0, true},
+		{90, true},
+		{91, false},
+	}
+
+	for _, tt := range tests {
+		schedule := &data.Schedule{
+			PropertyID:         1,
+			UserID:             2,
+			AgentID:            3,
+			ScheduledAt:        time.Now().Add(24 * time.Hour),
+			DurationMinutes:    app.scheduleDuration(tt.requested),
+			Status:             "pending",
+			MaxDurationMinutes: app.config.schedules.maxDuration,
+		}
+
+		v := validator.New()
+		data.ValidateSchedule(v, schedule)
+		if valid := v.Errors["duration_minutes"] == ""; valid != tt.wantValid {
+			t.Errorf("requested %d minutes: got duration valid %t; want %t (errors %v)", tt.requested, valid, tt.wantValid, v.Errors)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/mailer"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// =============================================================================
+// AGENT REPLY TEMPLATES
+// =============================================================================
+
+// listReplyTemplatesHandler lists the agent's reply templates, seeding the defaults on first use
+func (app *application) listReplyTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err := app.models.ReplyTemplates.SeedDefaults(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	replyTemplates, err := app.models.ReplyTemplates.GetAllForAgent(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"reply_templates": replyTemplates,
+		"placeholders":    data.ReplyTemplatePlaceholders,
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createReplyTemplateHandler adds a reply template for the agent
+func (app *application) createReplyTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Make sure the defaults are not seeded over the agent's own first template
+	err = app.models.ReplyTemplates.SeedDefaults(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	replyTemplate := &data.ReplyTemplate{
+		AgentID: user.ID,
+		Title:   strings.TrimSpace(input.Title),
+		Body:    input.Body,
+	}
+
+	v := validator.New()
+	if data.ValidateReplyTemplate(v, replyTemplate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ReplyTemplates.Insert(replyTemplate)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReplyTemplate):
+			v.AddError("title", "a template with this title already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"reply_template": replyTemplate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateReplyTemplateHandler changes the title or body of one of the agent's reply templates
+func (app *application) updateReplyTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	replyTemplate, err := app.models.ReplyTemplates.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrReplyTemplateNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title *string `json:"title"`
+		Body  *string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Title != nil {
+		replyTemplate.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Body != nil {
+		replyTemplate.Body = *input.Body
+	}
+
+	v := validator.New()
+	if data.ValidateReplyTemplate(v, replyTemplate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.ReplyTemplates.Update(replyTemplate)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateReplyTemplate):
+			v.AddError("title", "a template with this title already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"reply_template": replyTemplate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteReplyTemplateHandler removes one of the agent's reply templates
+func (app *application) deleteReplyTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.ReplyTemplates.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrReplyTemplateNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "reply template successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// AGENT: REPLY TO INQUIRY
+// =============================================================================
+
+// replyToInquiryHandler sends the agent's reply to the inquirer: it is stored as the
+// inquiry's response, added to the conversation and emailed to them, and the first
+// reply marks the inquiry as contacted. The reply is either a free-text message or a
+// template_id rendered with the inquiry's details.
+func (app *application) replyToInquiryHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	if user.Role != "agent" {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	inquiry, err := app.models.Inquiries.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canActForAgent(user, inquiry.AgentID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Message    *string `json:"message"`
+		TemplateID *int64  `json:"template_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Message != nil || input.TemplateID != nil, "message", "message or template_id must be provided")
+	v.Check(input.Message == nil || input.TemplateID == nil, "message", "must not be provided together with template_id")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var message string
+
+	if input.TemplateID != nil {
+		replyTemplate, err := app.models.ReplyTemplates.Get(*input.TemplateID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrReplyTemplateNotFound):
+				v.AddError("template_id", "reply template not found")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		message, err = data.RenderReplyTemplate(replyTemplate.Body, data.ReplyTemplateData{
+			Name:        inquiry.Name,
+			Property:    inquiry.PropertyTitle,
+			Agent:       user.Name,
+			InquiryType: inquiry.InquiryType,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		message = *input.Message
+	}
+
+	// The latest reply is stored as the response shown to the inquirer; agent notes stay private
+	inquiry.ResponseMessage = strings.TrimSpace(message)
+
+	if data.ValidateInquiryResponse(v, inquiry.ResponseMessage); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The first reply also marks the inquiry as contacted
+	err = app.models.Inquiries.Respond(inquiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Keep the reply in the inquiry's conversation
+	err = app.models.Inquiries.InsertMessage(&data.InquiryMessage{
+		InquiryID: inquiry.ID,
+		Sender:    data.InquirySenderAgent,
+		Body:      inquiry.ResponseMessage,
+		Via:       data.InquiryViaPlatform,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.recordDelegateAction(user, inquiry.AgentID, "inquiries.reply", "inquiry", inquiry.ID, nil)
+
+	app.background(func() {
+		data := map[string]interface{}{
+			"inquirerName":    inquiry.Name,
+			"agentName":       user.Name,
+			"propertyTitle":   inquiry.PropertyTitle,
+			"inquiryMessage":  inquiry.Message,
+			"responseMessage": inquiry.ResponseMessage,
+		}
+
+		// Answers from the inquirer go back to the agent, through the relay when enabled
+		err := app.mailer.Send(inquiry.Email, "inquiry_response.tmpl", data, mailer.WithReplyTo(app.inquiryReplyTo(inquiry.ID, user.Email)))
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"inquiry_id": strconv.FormatInt(inquiry.ID, 10),
+			})
+		}
+	})
+
+	env := envelope{
+		"inquiry": inquiry,
+		"reply":   envelope{"message": inquiry.ResponseMessage, "template_id": input.TemplateID},
+	}
+
+	err = app.writeJSON(w, r, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -17,8 +17,8 @@ func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	//Verify property exists
-	_, err = app.models.Properties.Get(propertyID)
+	//Verify property exists and is visible to the reviewer
+	property, err := app.models.Properties.Get(propertyID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrPropertyNotFound):
@@ -29,6 +29,11 @@ func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
 	//Parse input
 	var input struct {
 		Rating  int32  `json:"rating"`
@@ -68,6 +73,8 @@ func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.invalidateStats(0)
+
 	//Fetch the complete review with user name
 	review, err = app.models.Reviews.Get(review.ID)
 	if err != nil {
@@ -75,8 +82,11 @@ func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	headers := make(http.Header)
+	headers.Set("Location", propertyReviewsLocation(propertyID))
+
 	//Return response
-	err = app.writeJSON(w, http.StatusCreated, envelope{"review": review}, nil)
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"review": review}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -91,18 +101,40 @@ func (app *application) listReviewsForPropertyHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	// Parse query parameters
+	// Verify property exists and is visible to the requester
+	property, err := app.models.Properties.Get(propertyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrPropertyNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canViewProperty(r, property) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Parse query parameters, e.g. ?min_rating=4&sort=-rating
 	var input struct {
+		MinRating int
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
 
+	input.MinRating = app.readInt(qs, "min_rating", 0, v)
+	v.Check(input.MinRating >= 0 && input.MinRating <= 5, "min_rating", "must be between 1 and 5")
+
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{"created_at", "-created_at", "rating", "-rating"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.PropertyReviewSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -110,7 +142,7 @@ func (app *application) listReviewsForPropertyHandler(w http.ResponseWriter, r *
 	}
 
 	// Fetch reviews
-	reviews, metadata, err := app.models.Reviews.GetAllForProperty(propertyID, input.Filters)
+	reviews, metadata, err := app.models.Reviews.GetAllForProperty(propertyID, input.MinRating, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -124,9 +156,9 @@ func (app *application) listReviewsForPropertyHandler(w http.ResponseWriter, r *
 	}
 
 	// Return response with reviews, metadata, and rating stats
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"reviews":        reviews,
-		"metadata":       metadata,
+		"metadata":       app.withPageLinks(r, metadata),
 		"average_rating": avgRating,
 		"total_reviews":  totalReviews,
 	}, nil)
@@ -147,8 +179,9 @@ func (app *application) listPendingReviewsHandler(w http.ResponseWriter, r *http
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
-	input.Filters.SortSafelist = []string{"created_at", "-created_at"}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.PendingReviewSortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -163,9 +196,9 @@ func (app *application) listPendingReviewsHandler(w http.ResponseWriter, r *http
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"reviews":  reviews,
-		"metadata": metadata,
+		"metadata": app.withPageLinks(r, metadata),
 	}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -196,6 +229,8 @@ func (app *application) approveReviewHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	app.invalidateStats(0)
+
 	// Fetch updated review
 	review, err := app.models.Reviews.Get(id)
 	if err != nil {
@@ -204,7 +239,7 @@ func (app *application) approveReviewHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{"review": review}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"review": review}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -234,6 +269,8 @@ func (app *application) rejectReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.invalidateStats(0)
+
 	// Fetch updated review
 	review, err := app.models.Reviews.Get(id)
 	if err != nil {
@@ -242,7 +279,7 @@ func (app *application) rejectReviewHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Return response
-	err = app.writeJSON(w, http.StatusOK, envelope{"review": review}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"review": review}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -290,8 +327,10 @@ func (app *application) deleteReviewHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	app.invalidateStats(0)
+
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "review successfully deleted"}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "review successfully deleted"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
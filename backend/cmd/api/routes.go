@@ -7,12 +7,27 @@ import (
 	"github.com/julienschmidt/httprouter"
 )
 
-// Defines and returns the application's route mappings
+// Defines and returns the application's route mappings, wrapped in the middleware chain
 func (app *application) routes() http.Handler {
+	router := app.staticRouter(app.router())
+
+	// authenticate runs before rateLimit so limits are keyed on the user it verified
+	return app.recoverPanic(app.metrics(app.strictTransportSecurity(app.enableCORS(app.versionResponses(app.authenticate(app.rateLimit(app.jsonFormat(router))))))))
+}
+
+// router returns the application's route mappings on their own
+func (app *application) router() *httprouter.Router {
 	router := httprouter.New()
 
+	// Public agent routes. httprouter can't hold /v1/agents/:id next to /v1/agents/me in
+	// one tree, so they get their own router, tried when the main one finds no match.
+	agentRouter := httprouter.New()
+	agentRouter.NotFound = http.HandlerFunc(app.notFoundResponse)
+	agentRouter.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
+	agentRouter.HandlerFunc(http.MethodGet, "/v1/agents/:id/reviews", app.listPublicAgentReviewsHandler)
+
 	// Custom 404 & 405 handlers
-	router.NotFound = http.HandlerFunc(app.notFoundResponse)
+	router.NotFound = agentRouter
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
 	// =============================================================================
@@ -24,6 +39,8 @@ func (app *application) routes() http.Handler {
 	// PROPERTIES ENDPOINTS
 	// =============================================================================
 	router.HandlerFunc(http.MethodGet, "/v1/properties", app.listPropertiesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/properties.geojson", app.listPropertiesGeoJSONHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/properties/slug/:slug", app.showPropertyBySlugHandler)
 	router.HandlerFunc(http.MethodPost, "/v1/properties", app.requirePermission("properties:write", app.createPropertyHandler))
 
 	// Static routes BEFORE wildcards
@@ -33,23 +50,39 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/property-search", app.requirePermission("properties:read", app.advancedPropertySearchHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/property-filters", app.requirePermission("properties:read", app.getPropertyFiltersHandler))
 
+	// Market stats by location and property type
+	router.HandlerFunc(http.MethodGet, "/v1/market-stats", app.showMarketStatsHandler)
+
+	// Saved comparisons, shared by token
+	router.HandlerFunc(http.MethodPost, "/v1/comparisons", app.createComparisonHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/comparisons/:token", app.showComparisonHandler)
+
 	// =============================================================================
 	// PROPERTY OPERATIONS (using /v1/property/:id to avoid conflicts)
 	// =============================================================================
 	// Longer paths first
-	router.HandlerFunc(http.MethodPost, "/v1/property/:id/feature-payment", app.requireAuthenticatedUser(app.createFeaturePaymentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/property/:id/feature-payment", app.requireActivationPolicy(app.createFeaturePaymentHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/property/:id/feature", app.requirePermission("properties:feature", app.featurePropertyHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/property/:id/feature", app.requirePermission("properties:feature", app.unfeaturePropertyHandler))
 
 	router.HandlerFunc(http.MethodGet, "/v1/property/:id/favourite-count", app.getPropertyFavouriteCountHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/qr", app.propertyQRCodeHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/brochure.pdf", app.propertyBrochureHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/similar", app.listSimilarPropertiesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/available-slots", app.listAvailableSlotsHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/contact", app.requireAuthenticatedUser(app.showPropertyContactHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/property/:id/media", app.requirePermission("properties:write", app.uploadPropertyMediaHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/property/:id/media", app.requirePermission("properties:read", app.listPropertyMediaHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/property/:id/media", app.requirePermission("properties:write", app.updatePropertyMediaHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/property/:id/media/reorder", app.requirePermission("properties:write", app.reorderPropertyMediaHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/property/:id/media", app.requirePermission("properties:write", app.deletePropertyMediaHandler))
 
-	router.HandlerFunc(http.MethodPost, "/v1/property/:id/inquiries", app.requireAuthenticatedUser(app.createInquiryHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/property/:id/schedule", app.requireAuthenticatedUser(app.createScheduleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/property/:id/inquiries", app.requireActivationPolicy(app.createInquiryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/property/:id/schedule", app.requireActivationPolicy(app.createScheduleHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/open-houses", app.listPropertyOpenHousesHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/property/:id/notes", app.requireAuthenticatedUser(app.listPropertyNotesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/property/:id/notes", app.requireAuthenticatedUser(app.createPropertyNoteHandler))
 
 	router.HandlerFunc(http.MethodGet, "/v1/property/:id/reviews", app.requirePermission("reviews:read", app.listReviewsForPropertyHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/property/:id/reviews", app.requirePermission("reviews:write", app.createReviewHandler))
@@ -72,10 +105,25 @@ func (app *application) routes() http.Handler {
 	// =============================================================================
 	// User favourites - static routes first
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/favourites/stats", app.requireAuthenticatedUser(app.getUserFavouriteStatsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/favourites/status", app.requireAuthenticatedUser(app.checkFavouriteStatusesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/favourites", app.requireAuthenticatedUser(app.listUserFavouritesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/favourite/:id/status", app.requireAuthenticatedUser(app.checkFavouriteStatusHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/users/me/favourite/:id", app.requireAuthenticatedUser(app.addFavouriteHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/users/me/favourite/:id", app.requireAuthenticatedUser(app.removeFavouriteHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/favourite/:id/collection", app.requireAuthenticatedUser(app.setFavouriteCollectionHandler))
+
+	// User favourite collections
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/favourite-collections", app.requireAuthenticatedUser(app.listFavouriteCollectionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/favourite-collections", app.requireAuthenticatedUser(app.createFavouriteCollectionHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/favourite-collections/:id", app.requireAuthenticatedUser(app.updateFavouriteCollectionHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/favourite-collections/:id", app.requireAuthenticatedUser(app.deleteFavouriteCollectionHandler))
+
+	// User saved searches, emailed when new listings match
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/saved-searches", app.requireAuthenticatedUser(app.listSavedSearchesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/saved-searches", app.requireAuthenticatedUser(app.createSavedSearchHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/saved-searches/:id", app.requireAuthenticatedUser(app.showSavedSearchHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/saved-searches/:id", app.requireAuthenticatedUser(app.updateSavedSearchHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/saved-searches/:id", app.requireAuthenticatedUser(app.deleteSavedSearchHandler))
 
 	// User profile photo
 	router.HandlerFunc(http.MethodPost, "/v1/users/me/photo", app.requireAuthenticatedUser(app.uploadProfilePhotoHandler))
@@ -85,14 +133,29 @@ func (app *application) routes() http.Handler {
 	// User schedules (viewings and schedules are the same thing)
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/schedules", app.requireAuthenticatedUser(app.listUserSchedulesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/schedules/:id", app.requireAuthenticatedUser(app.getUserScheduleHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/users/me/schedules/:id", app.requireAuthenticatedUser(app.rescheduleUserScheduleHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/users/me/schedules/:id", app.requireActivationPolicy(app.rescheduleUserScheduleHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/users/me/schedules/:id", app.requireAuthenticatedUser(app.cancelUserScheduleHandler))
 
+	// Open house RSVPs
+	router.HandlerFunc(http.MethodPost, "/v1/open-houses/:id/rsvp", app.requireActivationPolicy(app.rsvpOpenHouseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/open-houses/:id/rsvp", app.requireAuthenticatedUser(app.cancelOpenHouseRSVPHandler))
+
 	// User inquiries
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/inquiries", app.requireAuthenticatedUser(app.listUserInquiriesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/users/me/inquiries/:id", app.requireAuthenticatedUser(app.getUserInquiryHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/inquiries/:id", app.requireAuthenticatedUser(app.deleteInquiryHandler))
 
+	// In-app notifications
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/notifications", app.requireAuthenticatedUser(app.listNotificationsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/notifications/:id/read", app.requireAuthenticatedUser(app.readNotificationHandler))
+
+	// User webhooks
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/webhooks", app.requireAuthenticatedUser(app.listWebhooksHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/webhooks", app.requireAuthenticatedUser(app.createWebhookHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/webhooks/:id/deliveries", app.requireAuthenticatedUser(app.listWebhookDeliveriesHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me/webhooks/:id", app.requireAuthenticatedUser(app.deleteWebhookHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/users/me/webhook-deliveries/:id/redeliver", app.requireAuthenticatedUser(app.redeliverWebhookHandler))
+
 	// User authentication & registration
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
@@ -113,8 +176,11 @@ func (app *application) routes() http.Handler {
 	// Static payment routes BEFORE wildcard routes
 	router.HandlerFunc(http.MethodPost, "/v1/payments/mpesa/callback", app.mpesaCallbackHandler)
 
+	// Inbound mail for the masked inquiry relay
+	router.HandlerFunc(http.MethodPost, "/v1/mail/inbound", app.inboundMailHandler)
+
 	// General payment routes
-	router.HandlerFunc(http.MethodPost, "/v1/payments", app.requireAuthenticatedUser(app.createPaymentHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/payments", app.requireActivationPolicy(app.createPaymentHandler))
 
 	// Payment status with wildcard (AFTER static routes)
 	router.HandlerFunc(http.MethodGet, "/v1/payments/:id/status", app.requireAuthenticatedUser(app.queryPaymentStatusHandler))
@@ -127,19 +193,63 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/inquiries", app.requireAuthenticatedUser(app.listAgentInquiriesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/inquiries/:id", app.requireAuthenticatedUser(app.getAgentInquiryHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/inquiries/:id", app.requireAuthenticatedUser(app.updateInquiryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/inquiries/:id/reply", app.requireAuthenticatedUser(app.replyToInquiryHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/inquiries/:id/reassign", app.requireAuthenticatedUser(app.reassignInquiryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/inquiries/:id/contact-attempts", app.requireAuthenticatedUser(app.logContactAttemptHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/inquiries/:id/messages", app.requireAuthenticatedUser(app.listAgentInquiryMessagesHandler))
+
+	// Agent reply templates
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/reply-templates", app.requireAuthenticatedUser(app.listReplyTemplatesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/reply-templates", app.requireAuthenticatedUser(app.createReplyTemplateHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/reply-templates/:id", app.requireAuthenticatedUser(app.updateReplyTemplateHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/agents/me/reply-templates/:id", app.requireAuthenticatedUser(app.deleteReplyTemplateHandler))
 
 	// Agent schedules - static routes first
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/schedule-stats", app.requireAuthenticatedUser(app.getAgentScheduleStatsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/schedule-stats/trend", app.requireAuthenticatedUser(app.getAgentScheduleTrendHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/schedules", app.requireAuthenticatedUser(app.listAgentSchedulesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/schedule-completions", app.requireAuthenticatedUser(app.completeAgentPastSchedulesHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/schedules/:id", app.requireAuthenticatedUser(app.getAgentScheduleHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/schedules/:id", app.requireAuthenticatedUser(app.updateAgentScheduleStatusHandler))
 
+	// Agent calendar
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/calendar.ics", app.requireAuthenticatedUser(app.exportAgentCalendarHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/calendar", app.requireAuthenticatedUser(app.getAgentCalendarHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/blackouts", app.requireAuthenticatedUser(app.createBlackoutHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/agents/me/blackouts/:id", app.requireAuthenticatedUser(app.deleteBlackoutHandler))
+
+	// Agent weekly availability
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/availability", app.requireAuthenticatedUser(app.listAgentAvailabilityHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/availability", app.requireAuthenticatedUser(app.createAgentAvailabilityHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/availability/:id", app.requireAuthenticatedUser(app.updateAgentAvailabilityHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/agents/me/availability/:id", app.requireAuthenticatedUser(app.deleteAgentAvailabilityHandler))
+
+	// Agent open houses
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/open-houses", app.requireAuthenticatedUser(app.createOpenHouseHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/open-houses", app.requireAuthenticatedUser(app.listAgentOpenHousesHandler))
+	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/open-houses/:id", app.requireAuthenticatedUser(app.updateOpenHouseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/agents/me/open-houses/:id", app.requireAuthenticatedUser(app.deleteOpenHouseHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/open-houses/:id/attendees", app.requireAuthenticatedUser(app.listOpenHouseAttendeesHandler))
+
+	// Agent out-of-office delegation
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/delegation", app.requireAuthenticatedUser(app.getAgentDelegationHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/agents/me/delegation", app.requireAuthenticatedUser(app.setAgentDelegationHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/agents/me/delegation", app.requireAuthenticatedUser(app.clearAgentDelegationHandler))
+
+	// Agent notification preferences
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/notification-preferences", app.requireAuthenticatedUser(app.getNotificationPreferencesHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/agents/me/notification-preferences", app.requireAuthenticatedUser(app.updateNotificationPreferencesHandler))
+
 	// Agent profile
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me", app.requireAuthenticatedUser(app.getAgentProfileHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/agents/me", app.requireAuthenticatedUser(app.updateAgentProfileHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/agents/me", app.requireAuthenticatedUser(app.deleteAgentAccountHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/password", app.requireAuthenticatedUser(app.changeAgentPasswordHandler))
 
+	// Agent specialties and service areas
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/specialties", app.requireAuthenticatedUser(app.getAgentSpecialtiesHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/agents/me/specialties", app.requireAuthenticatedUser(app.updateAgentSpecialtiesHandler))
+
 	// Agent profile photo
 	router.HandlerFunc(http.MethodPost, "/v1/agents/me/photo", app.requireAuthenticatedUser(app.uploadAgentProfilePhotoHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/photo", app.requireAuthenticatedUser(app.getAgentProfilePhotoHandler))
@@ -148,12 +258,20 @@ func (app *application) routes() http.Handler {
 	// Agent properties - static routes first
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/property-stats", app.requireAuthenticatedUser(app.getAgentPropertyStatsHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/properties", app.requireAuthenticatedUser(app.listAgentPropertiesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/properties/:id/report.csv", app.requireAuthenticatedUser(app.exportPropertyReportHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/properties/:id/benchmark", app.requireAuthenticatedUser(app.getPropertyBenchmarkHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/properties/:id/schedules", app.requireAuthenticatedUser(app.listAgentPropertySchedulesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/properties/:id/renew", app.requireAuthenticatedUser(app.renewAgentPropertyHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/properties/:id/bump", app.requireAuthenticatedUser(app.bumpAgentPropertyHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/properties/:id", app.requireAuthenticatedUser(app.getAgentPropertyHandler))
 
 	// Agent reviews - static routes first
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/reviews/pending", app.requireAuthenticatedUser(app.listAgentPendingReviewsHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/reviews", app.requireAuthenticatedUser(app.listAgentReviewsHandler))
 
+	// Public agent search by service area and specialty
+	router.HandlerFunc(http.MethodGet, "/v1/agents/search", app.searchAgentsHandler)
+
 	// Agent payments - static routes first
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/payments", app.requireAuthenticatedUser(app.listPaymentHistoryHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/agents/me/payments/:id", app.requireAuthenticatedUser(app.getPaymentStatusHandler))
@@ -185,7 +303,14 @@ func (app *application) routes() http.Handler {
 
 	// Admin property management
 	router.HandlerFunc(http.MethodGet, "/v1/admin/properties", app.requireAdminRole(app.listAllPropertiesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/schedule-completions", app.requireAdminRole(app.adminCompletePastSchedulesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/properties/:id/approve", app.requireAdminRole(app.approvePropertyHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/properties/:id/reject", app.requireAdminRole(app.rejectPropertyHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/admin/properties/:id", app.requireAdminRole(app.adminDeletePropertyHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/properties/:id/restore", app.requireAdminRole(app.adminRestorePropertyHandler))
+
+	// Admin inquiry management
+	router.HandlerFunc(http.MethodGet, "/v1/admin/inquiries", app.requireAdminRole(app.listAllInquiriesHandler))
 
 	// Admin statistics - longer path first
 	router.HandlerFunc(http.MethodGet, "/v1/admin/stats/growth", app.requireAdminRole(app.getGrowthMetricsHandler))
@@ -199,5 +324,31 @@ func (app *application) routes() http.Handler {
 	// Serve static files (profile photos)
 	router.ServeFiles("/uploads/*filepath", http.Dir("./uploads"))
 
-	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
+	return router
+}
+
+// staticRouter returns the static routes that sit where a route of the same method has a
+// wildcard, such as /v1/agents/me/inquiries/bulk beside /v1/agents/me/inquiries/:id.
+// httprouter can't hold both in one tree, and the wildcard would match the static path
+// first, so they get their own router, tried before next; anything else falls through to it.
+func (app *application) staticRouter(next http.Handler) *httprouter.Router {
+	router := httprouter.New()
+	router.NotFound = next
+	router.HandleMethodNotAllowed = false
+
+	// Agent inquiries
+	router.HandlerFunc(http.MethodPatch, "/v1/agents/me/inquiries/bulk", app.requireAuthenticatedUser(app.bulkUpdateInquiriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/agents/me/inquiries/overdue", app.requireAuthenticatedUser(app.listOverdueInquiriesHandler))
+
+	// Agent properties
+	router.HandlerFunc(http.MethodPost, "/v1/agents/me/properties/bulk", app.requirePermission("properties:write", app.importAgentPropertiesHandler))
+
+	// Admin agent management
+	router.HandlerFunc(http.MethodPost, "/v1/admin/agents/verify-bulk", app.requireAdminRole(app.bulkApproveAgentVerificationHandler))
+
+	// Admin property management
+	router.HandlerFunc(http.MethodPost, "/v1/admin/properties/feature-bulk", app.requireAdminRole(app.bulkFeaturePropertiesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/properties/unfeature-bulk", app.requireAdminRole(app.bulkUnfeaturePropertiesHandler))
+
+	return router
 }
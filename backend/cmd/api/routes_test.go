@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+func TestPublicAgentRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	routes := app.routes()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		// An invalid page fails validation before the database is queried
+		{"agent reviews", http.MethodGet, "/v1/agents/5/reviews?page=0", http.StatusUnprocessableEntity},
+		{"agent reviews with a bad method", http.MethodPost, "/v1/agents/5/reviews", http.StatusMethodNotAllowed},
+		{"old singular path", http.MethodGet, "/v1/agent/5/reviews?page=0", http.StatusNotFound},
+		{"unknown agent route", http.MethodGet, "/v1/agents/5/unknown", http.StatusNotFound},
+		{"agent self route still wins", http.MethodGet, "/v1/agents/me/reviews", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			routes.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticRoutesBesideWildcards(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.limits.maxJSONBytes = 1024
+	router := app.staticRouter(app.router())
+
+	agent := &data.User{ID: 7, Name: "Agent", Email: "agent@example.com", Role: "agent", Activated: true}
+	admin := &data.User{ID: 1, Name: "Admin", Email: "admin@example.com", Role: "admin", Activated: true}
+	unactivated := &data.User{ID: 8, Name: "Unverified", Email: "unverified@example.com", Role: "agent", Activated: false}
+
+	// Each request is turned away by the static route's handler or its middleware, where
+	// the wildcard route beside it would report the listing or inquiry as not found
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   *data.User
+		want   int
+	}{
+		{"bulk inquiry update", http.MethodPatch, "/v1/agents/me/inquiries/bulk", agent, http.StatusUnprocessableEntity},
+		{"other methods fall through to the wildcard", http.MethodGet, "/v1/agents/me/inquiries/bulk", agent, http.StatusNotFound},
+		{"overdue inquiries", http.MethodGet, "/v1/agents/me/inquiries/overdue?page=0", agent, http.StatusUnprocessableEntity},
+		{"bulk agent verification", http.MethodPost, "/v1/admin/agents/verify-bulk", admin, http.StatusUnprocessableEntity},
+		{"bulk feature", http.MethodPost, "/v1/admin/properties/feature-bulk", admin, http.StatusUnprocessableEntity},
+		{"bulk unfeature", http.MethodPost, "/v1/admin/properties/unfeature-bulk", admin, http.StatusUnprocessableEntity},
+		{"bulk property import", http.MethodPost, "/v1/agents/me/properties/bulk", unactivated, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			r.Header.Set("Content-Type", "application/json")
+			r = app.contextSetUser(r, tt.user)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, r)
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %d; want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnactivatedUsersBlockedFromWriteActions(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.accounts.requireActivation = true
+	router := app.router()
+
+	user := &data.User{ID: 7, Name: "Unverified", Email: "unverified@example.com", Role: "user", Activated: false}
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/v1/property/1/inquiries"},
+		{http.MethodPost, "/v1/property/1/schedule"},
+		{http.MethodPost, "/v1/property/1/reviews"},
+		{http.MethodPatch, "/v1/users/me/schedules/1"},
+		{http.MethodPost, "/v1/open-houses/1/rsvp"},
+		{http.MethodPost, "/v1/payments"},
+		{http.MethodPost, "/v1/property/1/feature-payment"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			r := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+			r = app.contextSetUser(r, user)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestRequireActivationPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    bool
+		user      *data.User
+		wantCode  int
+		wantReach bool
+	}{
+		{"unactivated with the policy on", true, &data.User{ID: 1}, http.StatusForbidden, false},
+		{"activated with the policy on", true, &data.User{ID: 1, Activated: true}, http.StatusOK, true},
+		{"unactivated with the policy off", false, &data.User{ID: 1}, http.StatusOK, true},
+		{"anonymous with the policy off", false, data.AnonymousUser, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.accounts.requireActivation = tt.policy
+
+			reached := false
+			handler := app.requireActivationPolicy(func(w http.ResponseWriter, r *http.Request) {
+				reached = true
+			})
+
+			r := app.contextSetUser(httptest.NewRequest(http.MethodPost, "/", nil), tt.user)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, r)
+
+			if rr.Code != tt.wantCode || reached != tt.wantReach {
+				t.Errorf("got status %d, reached=%v; want %d, reached=%v", rr.Code, reached, tt.wantCode, tt.wantReach)
+			}
+		})
+	}
+}
+
+func TestInquiryReplyRoute(t *testing.T) {
+	app := newTestApplication(t)
+	router := app.router()
+
+	if handle, _, _ := router.Lookup(http.MethodPost, "/v1/agents/me/inquiries/1/reply"); handle == nil {
+		t.Error("no route to reply to an inquiry")
+	}
+	if handle, _, _ := router.Lookup(http.MethodPost, "/v1/agents/me/inquiries/1/respond"); handle != nil {
+		t.Error("inquiries can still be answered through /respond as well as /reply")
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// maxSavedSearchAlertListings caps how many new listings a single alert email lists
+const maxSavedSearchAlertListings = 20
+
+// =============================================================================
+// SAVED SEARCHES
+// =============================================================================
+
+// readSavedSearchQuery parses a saved search query, written like the query string of
+// GET /v1/property-search, into the search criteria
+func (app *application) readSavedSearchQuery(search *data.SavedSearch, v *validator.Validator) {
+	qs, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(search.Query), "?"))
+	if err != nil {
+		v.AddError("query", "must be a valid query string")
+		return
+	}
+
+	search.Criteria = app.readSearchCriteria(qs, v)
+	search.Query = qs.Encode()
+}
+
+// listSavedSearchesHandler lists the user's saved searches
+func (app *application) listSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	searches, err := app.models.SavedSearches.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"saved_searches": searches}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createSavedSearchHandler saves a search for the user. New listings matching it are
+// emailed at the chosen frequency, starting from now.
+func (app *application) createSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		Name      string `json:"name"`
+		Query     string `json:"query"`
+		Frequency string `json:"frequency"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	search := &data.SavedSearch{
+		UserID:    user.ID,
+		Name:      strings.TrimSpace(input.Name),
+		Query:     input.Query,
+		Frequency: input.Frequency,
+	}
+
+	if search.Frequency == "" {
+		search.Frequency = "daily"
+	}
+
+	v := validator.New()
+	if data.ValidateSavedSearch(v, search); v.Valid() {
+		app.readSavedSearchQuery(search, v)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.SavedSearches.Insert(search)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSavedSearch):
+			v.AddError("name", "a saved search with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrSavedSearchLimitExceeded):
+			app.errorResponse(w, r, http.StatusConflict,
+				fmt.Sprintf("you can have at most %d saved searches", data.MaxSavedSearches))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", savedSearchLocation(search.ID))
+
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"saved_search": search}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSavedSearchHandler returns one of the user's saved searches
+func (app *application) showSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.models.SavedSearches.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateSavedSearchHandler changes the name, query or frequency of one of the user's saved searches
+func (app *application) updateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.models.SavedSearches.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name      *string `json:"name"`
+		Query     *string `json:"query"`
+		Frequency *string `json:"frequency"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		search.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Query != nil {
+		search.Query = *input.Query
+	}
+	if input.Frequency != nil {
+		search.Frequency = *input.Frequency
+	}
+
+	v := validator.New()
+	if data.ValidateSavedSearch(v, search); v.Valid() && input.Query != nil {
+		app.readSavedSearchQuery(search, v)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.SavedSearches.Update(search)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateSavedSearch):
+			v.AddError("name", "a saved search with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"saved_search": search}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSavedSearchHandler removes one of the user's saved searches and stops its alerts
+func (app *application) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.SavedSearches.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSavedSearchNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "saved search successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// SAVED SEARCH ALERTS
+// =============================================================================
+
+// sendSavedSearchAlerts runs every saved search that is due and emails the user the
+// listings published since its last run. Listings already sent for a search are
+// skipped, so overlapping runs never repeat a listing.
+func (app *application) sendSavedSearchAlerts() {
+	now := time.Now()
+
+	alerts, err := app.models.SavedSearches.GetDue(now)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "send_saved_search_alerts",
+		})
+		return
+	}
+
+	sent := 0
+
+	for _, alert := range alerts {
+		ok, err := app.sendSavedSearchAlert(alert, now)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":             "send_saved_search_alerts",
+				"saved_search_id": strconv.FormatInt(alert.ID, 10),
+			})
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	if len(alerts) > 0 {
+		app.logger.PrintInfo("saved search alerts processed", map[string]string{
+			"job":         "send_saved_search_alerts",
+			"searches":    strconv.Itoa(len(alerts)),
+			"emails_sent": strconv.Itoa(sent),
+		})
+	}
+}
+
+// sendSavedSearchAlert emails the new matches for one saved search and records the
+// run. Matches and the run are only recorded once the email has been sent, so a failed
+// send is retried on the next run. It reports whether an email was sent.
+func (app *application) sendSavedSearchAlert(alert *data.SavedSearchAlert, ranAt time.Time) (bool, error) {
+	criteria := alert.Criteria
+	criteria.ListedAfter = &alert.LastRunAt
+
+	filters := data.Filters{
+		Page:         1,
+		PageSize:     maxSavedSearchAlertListings,
+		Sort:         defaultSortNewest,
+		SortSafelist: []string{defaultSortNewest},
+	}
+
+	properties, metadata, err := app.models.Properties.AdvancedSearch(criteria, filters)
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]int64, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+	}
+
+	newIDs, err := app.models.SavedSearches.UnsentMatches(alert.ID, ids)
+	if err != nil {
+		return false, err
+	}
+
+	if len(newIDs) == 0 {
+		return false, app.models.SavedSearches.MarkRun(alert.ID, ranAt)
+	}
+
+	isNew := make(map[int64]bool, len(newIDs))
+	for _, id := range newIDs {
+		isNew[id] = true
+	}
+
+	listings := []map[string]interface{}{}
+	for _, property := range properties {
+		if !isNew[property.ID] {
+			continue
+		}
+		listings = append(listings, map[string]interface{}{
+			"id":       property.ID,
+			"slug":     property.Slug,
+			"title":    property.Title,
+			"location": property.Location,
+			"price":    fmt.Sprintf("%.2f", float64(property.Price)),
+		})
+	}
+
+	emailData := map[string]interface{}{
+		"userName":   alert.UserName,
+		"searchName": alert.Name,
+		"listings":   listings,
+		"moreCount":  max(0, metadata.TotalListings-len(properties)),
+	}
+
+	err = app.mailer.Send(alert.UserEmail, "saved_search_alert.tmpl", emailData)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = app.models.SavedSearches.RecordMatches(alert.ID, newIDs)
+	if err != nil {
+		return true, err
+	}
+
+	err = app.models.SavedSearches.MarkRun(alert.ID, ranAt)
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/codercollo/property/backend/internal/data"
 	"github.com/codercollo/property/backend/internal/validator"
@@ -9,21 +12,7 @@ import (
 
 // advancedPropertySearchHandler handles advanced property search with multiple filters
 func (app *application) advancedPropertySearchHandler(w http.ResponseWriter, r *http.Request) {
-	// Define input struct for all search parameters
 	var input struct {
-		Location     string
-		PropertyType string
-		Status       string // featured, standard, all
-		MinPrice     float64
-		MaxPrice     float64
-		MinBedrooms  int32
-		MaxBedrooms  int32
-		MinBathrooms int32
-		MaxBathrooms int32
-		MinArea      int32
-		MaxArea      int32
-		Features     []string
-		SortBy       string // price, -price, bedrooms, -bedrooms, area, -area, created_at, -created_at
 		data.Filters
 	}
 
@@ -31,39 +20,23 @@ func (app *application) advancedPropertySearchHandler(w http.ResponseWriter, r *
 	v := validator.New()
 	qs := r.URL.Query()
 
-	// Read filter values from query string
-	input.Location = app.readString(qs, "location", "")
-	input.PropertyType = app.readString(qs, "property_type", "")
-	input.Status = app.readString(qs, "status", "all")
-
-	// Price range
-	input.MinPrice = float64(app.readInt(qs, "min_price", 0, v))
-	input.MaxPrice = float64(app.readInt(qs, "max_price", 0, v))
-
-	// Bedrooms range
-	input.MinBedrooms = int32(app.readInt(qs, "min_bedrooms", 0, v))
-	input.MaxBedrooms = int32(app.readInt(qs, "max_bedrooms", 0, v))
-
-	// Bathrooms range
-	input.MinBathrooms = int32(app.readInt(qs, "min_bathrooms", 0, v))
-	input.MaxBathrooms = int32(app.readInt(qs, "max_bathrooms", 0, v))
-
-	// Area range
-	input.MinArea = int32(app.readInt(qs, "min_area", 0, v))
-	input.MaxArea = int32(app.readInt(qs, "max_area", 0, v))
-
-	// Features/amenities
-	input.Features = app.readCSV(qs, "features", []string{})
+	// Read and validate the search filters
+	searchCriteria := app.readSearchCriteria(qs, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
 	// Pagination and sorting
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.Sort = app.readString(qs, "sort", app.config.sort.properties)
 
-	// Define allowed sort values
-	input.Filters.SortSafelist = []string{
-		"id", "price", "bedrooms", "bathrooms", "area", "created_at",
-		"-id", "-price", "-bedrooms", "-bathrooms", "-area", "-created_at",
+	// Define allowed sort values; results can be sorted by distance in a radius search
+	input.Filters.SortSafelist = data.PropertySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+	if searchCriteria.HasRadius() {
+		input.Filters.SortSafelist = append(append([]string{}, data.PropertySortSafelist...), "distance", "-distance")
 	}
 
 	// Validate filters
@@ -72,69 +45,209 @@ func (app *application) advancedPropertySearchHandler(w http.ResponseWriter, r *
 		return
 	}
 
+	// Read the optional sparse fieldset
+	fields, err := app.readFields(qs, data.Property{})
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Perform advanced search
+	properties, metadata, err := app.models.Properties.AdvancedSearch(searchCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	selected, err := selectFields(properties, fields)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Return results
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
+		"properties": selected,
+		"metadata":   app.withPageLinks(r, metadata),
+		"filters":    newSearchFilters(searchCriteria),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// searchFilters echoes the criteria of a search in the response. It keeps the original
+// field names, with Location and PropertyType holding the values as they were given, and
+// adds Locations and PropertyTypes alongside.
+type searchFilters struct {
+	Location      string
+	PropertyType  string
+	Locations     []string
+	PropertyTypes []string
+	Status        string
+	MinPrice      float64
+	MaxPrice      float64
+	MinBedrooms   int32
+	MaxBedrooms   int32
+	MinBathrooms  int32
+	MaxBathrooms  int32
+	MinArea       int32
+	MaxArea       int32
+	Features      []string
+	MinParking    int32
+	Furnished     string
+	BBox          *data.BoundingBox `json:",omitempty"`
+	Latitude      float64           `json:",omitempty"`
+	Longitude     float64           `json:",omitempty"`
+	RadiusKm      float64           `json:",omitempty"`
+}
+
+// newSearchFilters returns the response form of the search criteria
+func newSearchFilters(criteria data.PropertySearchCriteria) searchFilters {
+	return searchFilters{
+		Location:      strings.Join(criteria.Locations, ","),
+		PropertyType:  strings.Join(criteria.PropertyTypes, ","),
+		Locations:     criteria.Locations,
+		PropertyTypes: criteria.PropertyTypes,
+		Status:        criteria.Status,
+		MinPrice:      criteria.MinPrice,
+		MaxPrice:      criteria.MaxPrice,
+		MinBedrooms:   criteria.MinBedrooms,
+		MaxBedrooms:   criteria.MaxBedrooms,
+		MinBathrooms:  criteria.MinBathrooms,
+		MaxBathrooms:  criteria.MaxBathrooms,
+		MinArea:       criteria.MinArea,
+		MaxArea:       criteria.MaxArea,
+		Features:      criteria.Features,
+		MinParking:    criteria.MinParking,
+		Furnished:     criteria.Furnished,
+		BBox:          criteria.BBox,
+		Latitude:      criteria.Latitude,
+		Longitude:     criteria.Longitude,
+		RadiusKm:      criteria.RadiusKm,
+	}
+}
+
+// readSearchCriteria reads the property search filters from the query string,
+// recording any validation errors in v
+func (app *application) readSearchCriteria(qs url.Values, v *validator.Validator) data.PropertySearchCriteria {
+	var criteria data.PropertySearchCriteria
+
+	// Read filter values from query string
+	// Comma-separated values match any of them, eg ?location=Kilimani,Lavington
+	criteria.Locations = app.readMultiValue(qs, "location")
+	criteria.PropertyTypes = app.readMultiValue(qs, "property_type")
+	criteria.Status = app.readString(qs, "status", "all")
+
+	// Price range
+	criteria.MinPrice = float64(app.readInt(qs, "min_price", 0, v))
+	criteria.MaxPrice = float64(app.readInt(qs, "max_price", 0, v))
+
+	// Bedrooms range
+	criteria.MinBedrooms = int32(app.readInt(qs, "min_bedrooms", 0, v))
+	criteria.MaxBedrooms = int32(app.readInt(qs, "max_bedrooms", 0, v))
+
+	// Bathrooms range
+	criteria.MinBathrooms = int32(app.readInt(qs, "min_bathrooms", 0, v))
+	criteria.MaxBathrooms = int32(app.readInt(qs, "max_bathrooms", 0, v))
+
+	// Area range
+	criteria.MinArea = int32(app.readInt(qs, "min_area", 0, v))
+	criteria.MaxArea = int32(app.readInt(qs, "max_area", 0, v))
+
+	// Features/amenities
+	criteria.Features = app.readCSV(qs, "features", []string{})
+
+	// Parking and furnished status
+	criteria.MinParking = int32(app.readInt(qs, "min_parking", 0, v))
+	criteria.Furnished = app.readString(qs, "furnished", "")
+
 	// Validate price range
-	if input.MaxPrice > 0 && input.MinPrice > input.MaxPrice {
+	if criteria.MaxPrice > 0 && criteria.MinPrice > criteria.MaxPrice {
 		v.AddError("max_price", "must be greater than min_price")
 	}
 
 	// Validate bedrooms range
-	if input.MaxBedrooms > 0 && input.MinBedrooms > input.MaxBedrooms {
+	if criteria.MaxBedrooms > 0 && criteria.MinBedrooms > criteria.MaxBedrooms {
 		v.AddError("max_bedrooms", "must be greater than min_bedrooms")
 	}
 
 	// Validate bathrooms range
-	if input.MaxBathrooms > 0 && input.MinBathrooms > input.MaxBathrooms {
+	if criteria.MaxBathrooms > 0 && criteria.MinBathrooms > criteria.MaxBathrooms {
 		v.AddError("max_bathrooms", "must be greater than min_bathrooms")
 	}
 
 	// Validate area range
-	if input.MaxArea > 0 && input.MinArea > input.MaxArea {
+	if criteria.MaxArea > 0 && criteria.MinArea > criteria.MaxArea {
 		v.AddError("max_area", "must be greater than min_area")
 	}
 
+	// Validate multi-value filters
+	v.Check(len(criteria.Locations) <= 20, "location", "must not contain more than 20 values")
+	v.Check(len(criteria.PropertyTypes) <= 20, "property_type", "must not contain more than 20 values")
+
 	// Validate status
 	validStatuses := []string{"all", "featured", "standard"}
-	if !validator.In(input.Status, validStatuses...) {
+	if !validator.In(criteria.Status, validStatuses...) {
 		v.AddError("status", "must be one of: all, featured, standard")
 	}
 
-	if !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
-		return
+	// Validate parking and furnished status
+	v.Check(criteria.MinParking >= 0, "min_parking", "must be zero or more")
+	if criteria.Furnished != "" && !validator.In(criteria.Furnished, data.FurnishedStates...) {
+		v.AddError("furnished", "must be one of: furnished, semi_furnished, unfurnished")
 	}
 
-	// Create search criteria struct
-	searchCriteria := data.PropertySearchCriteria{
-		Location:     input.Location,
-		PropertyType: input.PropertyType,
-		Status:       input.Status,
-		MinPrice:     input.MinPrice,
-		MaxPrice:     input.MaxPrice,
-		MinBedrooms:  input.MinBedrooms,
-		MaxBedrooms:  input.MaxBedrooms,
-		MinBathrooms: input.MinBathrooms,
-		MaxBathrooms: input.MaxBathrooms,
-		MinArea:      input.MinArea,
-		MaxArea:      input.MaxArea,
-		Features:     input.Features,
+	// Optional bounding box: bbox=west,south,east,north
+	if qs.Get("bbox") != "" {
+		criteria.BBox = app.readBoundingBox(qs, "bbox", v)
 	}
 
-	// Perform advanced search
-	properties, metadata, err := app.models.Properties.AdvancedSearch(searchCriteria, input.Filters)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
+	// Optional radius search: lat, lng and radius_km must be given together
+	given := 0
+	for _, key := range []string{"lat", "lng", "radius_km"} {
+		if qs.Get(key) != "" {
+			given++
+		}
+	}
+	switch given {
+	case 0:
+	case 3:
+		criteria.Latitude = app.readFloat(qs, "lat", 0, v)
+		criteria.Longitude = app.readFloat(qs, "lng", 0, v)
+		criteria.RadiusKm = app.readFloat(qs, "radius_km", 0, v)
+		if v.Valid() {
+			data.ValidateRadius(v, criteria)
+		}
+	default:
+		v.AddError("radius_km", "lat, lng and radius_km must be provided together")
 	}
 
-	// Return results
-	err = app.writeJSON(w, http.StatusOK, envelope{
-		"properties": properties,
-		"metadata":   metadata,
-		"filters":    searchCriteria,
-	}, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+	return criteria
+}
+
+// readBoundingBox parses a west,south,east,north bounding box from the query string
+func (app *application) readBoundingBox(qs url.Values, key string, v *validator.Validator) *data.BoundingBox {
+	parts := app.readCSV(qs, key, nil)
+	if len(parts) != 4 {
+		v.AddError(key, "must be four comma-separated numbers: west,south,east,north")
+		return nil
+	}
+
+	coords := make([]float64, 4)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			v.AddError(key, "must be four comma-separated numbers: west,south,east,north")
+			return nil
+		}
+		coords[i] = f
 	}
+
+	bbox := &data.BoundingBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	data.ValidateBoundingBox(v, bbox)
+
+	return bbox
 }
 
 // getPropertyFiltersHandler returns available filter options
@@ -145,7 +258,12 @@ func (app *application) getPropertyFiltersHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"filters": filters}, nil)
+	env := envelope{
+		"filters": filters,
+		"limits":  app.propertyLimits(),
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestReadSearchCriteriaMultiValue(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		query         string
+		wantLocations []string
+		wantTypes     []string
+	}{
+		{"location=Kilimani&property_type=apartment", []string{"Kilimani"}, []string{"apartment"}},
+		{"location=Kilimani,Lavington&property_type=apartment,townhouse", []string{"Kilimani", "Lavington"}, []string{"apartment", "townhouse"}},
+		{"location=Kilimani,+Lavington,,kilimani", []string{"Kilimani", "Lavington"}, nil},
+		{"", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			qs, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := validator.New()
+			criteria := app.readSearchCriteria(qs, v)
+			if !v.Valid() {
+				t.Fatalf("got errors %v", v.Errors)
+			}
+
+			if !slices.Equal(criteria.Locations, tt.wantLocations) {
+				t.Errorf("locations: got %q; want %q", criteria.Locations, tt.wantLocations)
+			}
+			if !slices.Equal(criteria.PropertyTypes, tt.wantTypes) {
+				t.Errorf("property types: got %q; want %q", criteria.PropertyTypes, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestSearchFiltersKeepOriginalKeys(t *testing.T) {
+	app := newTestApplication(t)
+
+	qs, err := url.ParseQuery("location=Kilimani,Lavington&property_type=apartment&min_price=1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := validator.New()
+	body, err := json.Marshal(newSearchFilters(app.readSearchCriteria(qs, v)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var filters map[string]interface{}
+	if err := json.Unmarshal(body, &filters); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"Location":      "Kilimani,Lavington",
+		"PropertyType":  "apartment",
+		"Locations":     []interface{}{"Kilimani", "Lavington"},
+		"PropertyTypes": []interface{}{"apartment"},
+		"MinPrice":      float64(1000),
+		"Status":        "all",
+	}
+	for key, value := range want {
+		got, ok := filters[key]
+		if !ok {
+			t.Errorf("missing key %q in %s", key, body)
+			continue
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(value)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s: got %s; want %s", key, gotJSON, wantJSON)
+		}
+	}
+}
@@ -2,22 +2,82 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
-func (app *application) serve() error {
-	srv := &http.Server{
+// tlsEnabled reports whether the server is configured to serve HTTPS
+func (app *application) tlsEnabled() bool {
+	return app.config.tls.certFile != "" && app.config.tls.keyFile != ""
+}
+
+// validateTLSFiles checks that the certificate and key are either both unset or both readable
+func validateTLSFiles(certFile, keyFile string) error {
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+
+	for _, file := range []string{certFile, keyFile} {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		f.Close()
+	}
+
+	_, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	return nil
+}
+
+// newServer returns the API server for handler with the configured timeouts and limits
+func (app *application) newServer(handler http.Handler) *http.Server {
+	return &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
-		Handler:      app.routes(),
+		Handler:      handler,
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
+		// Oversized headers are rejected by net/http with 431 Request Header Fields Too Large
+		MaxHeaderBytes: app.config.limits.maxHeaderBytes,
+	}
+}
+
+func (app *application) serve() error {
+	srv := app.newServer(app.routes())
+
+	// HTTP/2 is negotiated automatically over TLS
+	var redirect *http.Server
+	if app.tlsEnabled() {
+		srv.TLSConfig = &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		}
+
+		if app.config.tls.redirectPort > 0 {
+			redirect = &http.Server{
+				Addr:         fmt.Sprintf(":%d", app.config.tls.redirectPort),
+				Handler:      app.redirectToHTTPS(),
+				IdleTimeout:  time.Minute,
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+			}
+		}
 	}
 
 	// Channel to receive shutdown errors.
@@ -39,6 +99,9 @@ func (app *application) serve() error {
 		defer cancel()
 
 		//Shutdown server; report error if any
+		if redirect != nil {
+			redirect.Shutdown(ctx)
+		}
 		err := srv.Shutdown(ctx)
 		if err != nil {
 			shutdownError <- err
@@ -57,14 +120,30 @@ func (app *application) serve() error {
 	app.logger.PrintInfo("starting server", map[string]string{
 		"addr": srv.Addr,
 		"env":  app.config.env,
+		"tls":  strconv.FormatBool(app.tlsEnabled()),
 	})
 
-	// //TLS cert and keys files
-	// certFile := "tls/cert.pem"
-	// keyFile := "tls/key.pem"
+	if redirect != nil {
+		go func() {
+			app.logger.PrintInfo("starting https redirect listener", map[string]string{
+				"addr": redirect.Addr,
+			})
+			err := redirect.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				app.logger.PrintError(err, map[string]string{
+					"addr": redirect.Addr,
+				})
+			}
+		}()
+	}
 
 	// Ignore expected server-closed error during shutdown.
-	err := srv.ListenAndServe()
+	var err error
+	if app.tlsEnabled() {
+		err = srv.ListenAndServeTLS(app.config.tls.certFile, app.config.tls.keyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -81,3 +160,23 @@ func (app *application) serve() error {
 
 	return nil
 }
+
+// redirectToHTTPS sends plain HTTP requests to the same path on the HTTPS port
+func (app *application) redirectToHTTPS() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			// No port, but an IPv6 literal still comes bracketed
+			host = strings.TrimSuffix(strings.TrimPrefix(r.Host, "["), "]")
+		}
+
+		switch {
+		case app.config.port != 443:
+			host = net.JoinHostPort(host, strconv.Itoa(app.config.port))
+		case strings.Contains(host, ":"):
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name string
+		port int
+		host string
+		want string
+	}{
+		{"hostname with port", 4000, "example.com:8080", "https://example.com:4000/v1/healthcheck?x=1"},
+		{"hostname without port", 4000, "example.com", "https://example.com:4000/v1/healthcheck?x=1"},
+		{"hostname on 443", 443, "example.com:80", "https://example.com/v1/healthcheck?x=1"},
+		{"IPv4 with port", 4000, "192.0.2.1:80", "https://192.0.2.1:4000/v1/healthcheck?x=1"},
+		{"IPv6 with port", 4000, "[2001:db8::1]:80", "https://[2001:db8::1]:4000/v1/healthcheck?x=1"},
+		{"IPv6 without port", 4000, "[2001:db8::1]", "https://[2001:db8::1]:4000/v1/healthcheck?x=1"},
+		{"IPv6 on 443", 443, "[::1]:80", "https://[::1]/v1/healthcheck?x=1"},
+		{"IPv6 without port on 443", 443, "[::1]", "https://[::1]/v1/healthcheck?x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.config.port = tt.port
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/healthcheck?x=1", nil)
+			r.Host = tt.host
+			rr := httptest.NewRecorder()
+
+			app.redirectToHTTPS().ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusMovedPermanently {
+				t.Errorf("got status %d; want %d", rr.Code, http.StatusMovedPermanently)
+			}
+			if got := rr.Header().Get("Location"); got != tt.want {
+				t.Errorf("got Location %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
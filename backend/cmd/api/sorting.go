@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// Default sort orders shared by the list endpoints
+const (
+	defaultSortID             = "id"
+	defaultSortIDDesc         = "-id"
+	defaultSortNewest         = "-created_at"
+	defaultSortOldest         = "created_at"
+	defaultSortUpcoming       = "scheduled_at"
+	defaultSortLatestSchedule = "-scheduled_at"
+	defaultSortFavourites     = "-favourites"
+
+	// defaultSortProperties puts featured listings first, then the newest or most recently
+	// bumped within each group
+	defaultSortProperties = "featured,-freshness"
+)
+
+// validateDefaultSort checks that every key of a configured default sort is in the safelist
+func validateDefaultSort(sort string, safelist []string) error {
+	for _, key := range strings.Split(sort, ",") {
+		if !validator.In(key, safelist...) {
+			return fmt.Errorf("invalid default sort key %q", key)
+		}
+	}
+	return nil
+}
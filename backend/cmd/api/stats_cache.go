@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+// maxDashboardStatsCacheLen bounds the agent dashboard stats cache
+const maxDashboardStatsCacheLen = 1000
+
+// platformStatsCache holds the latest platform statistics, kept fresh by a background
+// refresher so the admin dashboard doesn't run the full aggregate on every request
+type platformStatsCache struct {
+	mu          sync.RWMutex
+	stats       *data.PlatformStats
+	refreshedAt time.Time
+}
+
+// get returns the cached stats if they were computed less than maxAge ago
+func (c *platformStatsCache) get(maxAge time.Duration) (*data.PlatformStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.stats == nil || time.Since(c.refreshedAt) >= maxAge {
+		return nil, false
+	}
+	return c.stats, true
+}
+
+// set stores freshly computed stats
+func (c *platformStatsCache) set(stats *data.PlatformStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = stats
+	c.refreshedAt = time.Now()
+}
+
+// invalidate drops the cached stats so the next request recomputes them
+func (c *platformStatsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = nil
+}
+
+// dashboardStatsEntry is an agent's cached dashboard stats and when they stop being served
+type dashboardStatsEntry struct {
+	stats     *data.DashboardStats
+	expiresAt time.Time
+}
+
+// dashboardStatsCache holds agent dashboard stats keyed by agent ID
+type dashboardStatsCache struct {
+	mu    sync.Mutex
+	items map[int64]dashboardStatsEntry
+}
+
+// newDashboardStatsCache creates an empty dashboard stats cache
+func newDashboardStatsCache() *dashboardStatsCache {
+	return &dashboardStatsCache{items: make(map[int64]dashboardStatsEntry)}
+}
+
+// get returns the agent's cached stats if present and not yet expired
+func (c *dashboardStatsCache) get(agentID int64) (*data.DashboardStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.items[agentID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.stats, true
+}
+
+// set stores the agent's stats for ttl, clearing the cache once it grows past its bound
+func (c *dashboardStatsCache) set(agentID int64, stats *data.DashboardStats, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.items) >= maxDashboardStatsCacheLen {
+		c.items = make(map[int64]dashboardStatsEntry)
+	}
+	c.items[agentID] = dashboardStatsEntry{stats: stats, expiresAt: time.Now().Add(ttl)}
+}
+
+// invalidate drops the agent's cached stats, or every agent's when agentID is zero
+func (c *dashboardStatsCache) invalidate(agentID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if agentID == 0 {
+		c.items = make(map[int64]dashboardStatsEntry)
+		return
+	}
+	delete(c.items, agentID)
+}
+
+// invalidateStats drops cached platform stats and the agent's dashboard stats after a
+// change they count; an agentID of zero drops every agent's dashboard stats
+func (app *application) invalidateStats(agentID int64) {
+	app.platformStats.invalidate()
+	app.dashboardStats.invalidate(agentID)
+}
+
+// invalidateListingStats drops cached platform stats and the dashboard stats of the agent
+// owning a changed listing, if it has one, leaving other agents' dashboards cached
+func (app *application) invalidateListingStats(agentID sql.NullInt64) {
+	if !agentID.Valid {
+		app.platformStats.invalidate()
+		return
+	}
+	app.invalidateStats(agentID.Int64)
+}
+
+// refreshPlatformStats recomputes the cached platform statistics
+func (app *application) refreshPlatformStats() {
+	stats, err := app.models.Admin.GetPlatformStats()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "refresh_platform_stats",
+		})
+		return
+	}
+
+	app.platformStats.set(stats)
+}
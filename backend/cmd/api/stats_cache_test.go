@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+)
+
+// The test application has no database, so these handlers only succeed on a cache hit
+
+func TestPlatformStatsServedFromCache(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.stats.cacheTTL = time.Minute
+	app.platformStats = &platformStatsCache{}
+	app.platformStats.set(&data.PlatformStats{TotalUsers: 42})
+
+	rr := httptest.NewRecorder()
+	app.getPlatformStatsHandler(rr, httptest.NewRequest(http.MethodGet, "/v1/admin/stats", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	var body struct {
+		Stats data.PlatformStats `json:"stats"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Stats.TotalUsers != 42 {
+		t.Errorf("got total_users %d; want the cached 42", body.Stats.TotalUsers)
+	}
+}
+
+func TestAgentDashboardStatsServedFromCache(t *testing.T) {
+	app := newTestApplication(t)
+	app.config.stats.cacheTTL = time.Minute
+	app.dashboardStats = newDashboardStatsCache()
+	app.dashboardStats.set(7, &data.DashboardStats{PropertiesCount: 3}, time.Minute)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/agents/me/dashboard", nil)
+	r = app.contextSetUser(r, &data.User{ID: 7, Role: "agent"})
+	rr := httptest.NewRecorder()
+	app.getAgentDashboardStatsHandler(rr, r)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	var body struct {
+		Stats data.DashboardStats `json:"stats"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Stats.PropertiesCount != 3 {
+		t.Errorf("got properties_count %d; want the cached 3", body.Stats.PropertiesCount)
+	}
+}
+
+func TestPlatformStatsCacheMisses(t *testing.T) {
+	cache := &platformStatsCache{}
+
+	if _, ok := cache.get(time.Minute); ok {
+		t.Error("empty cache: got a hit")
+	}
+
+	cache.set(&data.PlatformStats{TotalUsers: 1})
+	if _, ok := cache.get(time.Minute); !ok {
+		t.Error("fresh stats: got a miss")
+	}
+
+	cache.refreshedAt = time.Now().Add(-2 * time.Minute)
+	if _, ok := cache.get(time.Minute); ok {
+		t.Error("stale stats: got a hit")
+	}
+
+	cache.set(&data.PlatformStats{TotalUsers: 1})
+	cache.invalidate()
+	if _, ok := cache.get(time.Minute); ok {
+		t.Error("invalidated stats: got a hit")
+	}
+}
+
+func TestDashboardStatsCacheMisses(t *testing.T) {
+	cache := newDashboardStatsCache()
+
+	cache.set(1, &data.DashboardStats{}, time.Minute)
+	cache.set(2, &data.DashboardStats{}, time.Minute)
+	cache.set(3, &data.DashboardStats{}, -time.Second)
+
+	if _, ok := cache.get(3); ok {
+		t.Error("expired stats: got a hit")
+	}
+
+	cache.invalidate(1)
+	if _, ok := cache.get(1); ok {
+		t.Error("invalidated agent: got a hit")
+	}
+	if _, ok := cache.get(2); !ok {
+		t.Error("other agent: got a miss after invalidating agent 1")
+	}
+
+	cache.invalidate(0)
+	if _, ok := cache.get(2); ok {
+		t.Error("after invalidating every agent: got a hit")
+	}
+}
+
+func TestInvalidateListingStats(t *testing.T) {
+	tests := []struct {
+		name            string
+		agentID         sql.NullInt64
+		wantOwnerCached bool
+	}{
+		{"listing with an agent", sql.NullInt64{Int64: 1, Valid: true}, false},
+		{"listing without an agent", sql.NullInt64{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t)
+			app.platformStats = &platformStatsCache{}
+			app.platformStats.set(&data.PlatformStats{})
+			app.dashboardStats = newDashboardStatsCache()
+			app.dashboardStats.set(1, &data.DashboardStats{}, time.Minute)
+			app.dashboardStats.set(2, &data.DashboardStats{}, time.Minute)
+
+			app.invalidateListingStats(tt.agentID)
+
+			if _, ok := app.platformStats.get(time.Minute); ok {
+				t.Error("platform stats: got a hit")
+			}
+			if _, ok := app.dashboardStats.get(1); ok != tt.wantOwnerCached {
+				t.Errorf("owning agent: got hit %v; want %v", ok, tt.wantOwnerCached)
+			}
+			if _, ok := app.dashboardStats.get(2); !ok {
+				t.Error("other agent: got a miss")
+			}
+		})
+	}
+}
+
+func TestStatsCachesConcurrentUse(t *testing.T) {
+	platform := &platformStatsCache{}
+	dashboard := newDashboardStatsCache()
+
+	var wg sync.WaitGroup
+	for i := int64(0); i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				platform.set(&data.PlatformStats{TotalUsers: j})
+				platform.get(time.Minute)
+				dashboard.set(i, &data.DashboardStats{PropertiesCount: j}, time.Minute)
+				dashboard.get(i)
+				if j%10 == 0 {
+					platform.invalidate()
+					dashboard.invalidate(i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -59,8 +59,10 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	claims.Issued = jwt.NewNumericTime(time.Now())
 	claims.NotBefore = jwt.NewNumericTime(time.Now())
 	claims.Expires = jwt.NewNumericTime(time.Now().Add(24 * time.Hour))
-	claims.Issuer = "propertyown.api"
-	claims.Audiences = []string{"propertyown.api"}
+	claims.Issuer = app.config.jwt.issuer
+	claims.Audiences = []string{app.config.jwt.audience}
+	// The role lets rateLimit exempt admins without a database lookup
+	claims.Set = map[string]interface{}{"role": user.Role}
 
 	jwtBytes, err := claims.HMACSign(jwt.HS256, []byte(app.config.jwt.secret))
 	if err != nil {
@@ -70,6 +72,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 
 	err = app.writeJSON(
 		w,
+		r,
 		http.StatusCreated,
 		envelope{
 			"authentication_token": string(jwtBytes),
@@ -138,7 +141,7 @@ func (app *application) revokeAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "token successfully revoked",
 	}, nil)
 	if err != nil {
@@ -191,7 +194,7 @@ func (app *application) revokeAllTokensHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	// Respond with success
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "all tokens successfully revoked",
 	}, nil)
 	if err != nil {
@@ -262,7 +265,7 @@ func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r
 		"message": "an email will be sent to you containing password reset instructions",
 	}
 
-	if err := app.writeJSON(w, http.StatusAccepted, env, nil); err != nil {
+	if err := app.writeJSON(w, r, http.StatusAccepted, env, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
@@ -326,7 +329,7 @@ func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *h
 		"message": "an email will be sent to you containing activation instructions",
 	}
 
-	if err := app.writeJSON(w, http.StatusAccepted, env, nil); err != nil {
+	if err := app.writeJSON(w, r, http.StatusAccepted, env, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
@@ -13,8 +13,8 @@ import (
 func (app *application) uploadProfilePhotoHandler(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 
-	// Parse multipart form (max 10MB in memory)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form (max 10MB in memory), capped at the configured upload size
+	if err := app.parseMultipartForm(w, r, 10<<20); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
@@ -35,9 +35,10 @@ func (app *application) uploadProfilePhotoHandler(w http.ResponseWriter, r *http
 
 	// Create upload struct
 	upload := &data.ProfilePhotoUpload{
-		File:   file,
-		Header: header,
-		UserID: user.ID,
+		File:    file,
+		Header:  header,
+		UserID:  user.ID,
+		MaxSize: app.config.media.avatarMaxBytes,
 	}
 
 	// Validate upload
@@ -48,6 +49,23 @@ func (app *application) uploadProfilePhotoHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	// Crop and resize to the standard avatar before replacing the old photo
+	avatar, ext, err := app.processAvatar(file)
+	if err != nil {
+		var tooLarge *imageTooLargeError
+		switch {
+		case errors.Is(err, errNotAnImage):
+			v.AddError("photo", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.As(err, &tooLarge):
+			v.AddError("photo", "image dimensions are too large")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	// Delete old profile photo if exists
 	if user.ProfilePhoto != "" {
 		if err := data.DeleteProfilePhoto(user.ProfilePhoto); err != nil {
@@ -60,7 +78,7 @@ func (app *application) uploadProfilePhotoHandler(w http.ResponseWriter, r *http
 	}
 
 	// Save new photo to disk
-	photoURL, err := data.SaveProfilePhoto(file, header, user.ID)
+	photoURL, err := data.SaveProfilePhoto(avatar, ext, user.ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -84,7 +102,7 @@ func (app *application) uploadProfilePhotoHandler(w http.ResponseWriter, r *http
 	user.ProfilePhoto = photoURL
 
 	// Return success response
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message":       "profile photo uploaded successfully",
 		"profile_photo": photoURL,
 		"user":          user,
@@ -109,7 +127,7 @@ func (app *application) getProfilePhotoHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"profile_photo": photoURL,
 	}, nil)
 	if err != nil {
@@ -155,7 +173,7 @@ func (app *application) deleteProfilePhotoHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{
+	err = app.writeJSON(w, r, http.StatusOK, envelope{
 		"message": "profile photo deleted successfully",
 	}, nil)
 	if err != nil {
@@ -17,6 +17,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Email    string `json:"email"`
 		Password string `json:"password"`
 		Role     string `json:"role,omitempty"` // Optional role field, defaults to "user"
+		Timezone string `json:"timezone,omitempty"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -35,6 +36,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Email:     input.Email,
 		Activated: false,
 		Role:      input.Role,
+		Timezone:  input.Timezone,
 	}
 
 	// Hash and set the user's password
@@ -89,7 +91,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	})
 
 	// Respond with 202 Accepted containing the new user
-	err = app.writeJSON(w, http.StatusAccepted, envelope{"user": user}, nil)
+	err = app.writeJSON(w, r, http.StatusAccepted, envelope{"user": user}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -146,7 +148,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	//Return updated user data
-	if err := app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil); err != nil {
+	if err := app.writeJSON(w, r, http.StatusOK, envelope{"user": user}, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 
@@ -226,7 +228,7 @@ func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	//Return the updated user
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": updatedUser}, nil)
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"user": updatedUser}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -305,7 +307,7 @@ func (app *application) updateUserPasswordHandler(
 		"message": "your password was successfully reset",
 	}
 
-	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+	if err := app.writeJSON(w, r, http.StatusOK, env, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
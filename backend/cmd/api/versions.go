@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Clients select a response version with a vendor media type in the Accept header,
+// eg "Accept: application/vnd.property.v1+json". Plain JSON gets the default version.
+const (
+	vendorMediaTypePrefix  = "application/vnd.property.v"
+	vendorMediaTypeSuffix  = "+json"
+	defaultResponseVersion = 1
+)
+
+// apiVersionHeader carries the negotiated version on the response
+const apiVersionHeader = "API-Version"
+
+// responseSerializer turns a handler's envelope into the body for one API version
+type responseSerializer func(data envelope) (interface{}, error)
+
+// responseVersions lists every supported response version. A new version is added
+// by registering a serializer that reshapes the v1 envelope; handlers stay unchanged.
+var responseVersions = map[int]responseSerializer{
+	1: serializeV1,
+}
+
+// serializeV1 is the original response shape, returned as is
+func serializeV1(data envelope) (interface{}, error) {
+	return data, nil
+}
+
+// vendorMediaType returns the media type for an API version
+func vendorMediaType(version int) string {
+	return vendorMediaTypePrefix + strconv.Itoa(version) + vendorMediaTypeSuffix
+}
+
+// supportedVersions returns the registered versions in ascending order
+func supportedVersions() []int {
+	versions := make([]int, 0, len(responseVersions))
+	for version := range responseVersions {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// negotiateVersion picks the response version from the Accept header. The first
+// acceptable media range wins; vendor ranges naming an unsupported version are skipped.
+// It returns false if only unsupported versions were requested.
+func negotiateVersion(accept string) (version int, vendor bool, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return defaultResponseVersion, false, true
+	}
+
+	requestedVendor := false
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(mediaType, vendorMediaTypePrefix) && strings.HasSuffix(mediaType, vendorMediaTypeSuffix):
+			requestedVendor = true
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaTypePrefix), vendorMediaTypeSuffix))
+			if err != nil {
+				continue
+			}
+			if _, exists := responseVersions[n]; exists {
+				return n, true, true
+			}
+		case mediaType == "application/json", mediaType == "application/*", mediaType == "*/*":
+			return defaultResponseVersion, false, true
+		}
+	}
+
+	// Accept headers naming only other media types (eg text/csv) are left to the handler
+	return defaultResponseVersion, false, !requestedVendor
+}
+
+// apiVersion is the response version negotiated for a request, and whether the client
+// asked for it by vendor media type
+type apiVersion struct {
+	version int
+	vendor  bool
+}
+
+// apiVersionContextKey is the key used to store the negotiated apiVersion in the context
+const apiVersionContextKey = contextKey("apiVersion")
+
+// contextGetAPIVersion reads the version negotiated for the request, falling back to the
+// default for requests that didn't pass through versionResponses
+func contextGetAPIVersion(r *http.Request) apiVersion {
+	negotiated, ok := r.Context().Value(apiVersionContextKey).(apiVersion)
+	if !ok {
+		return apiVersion{version: defaultResponseVersion}
+	}
+	return negotiated
+}
+
+// versionResponses negotiates the API version and records it in the request context so
+// writeJSON can serialize for it, rejecting requests for versions this server does not
+// have. Only the version header is set here; Content-Type is left to whatever writes the
+// body, as not every route returns JSON.
+func (app *application) versionResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		version, vendor, ok := negotiateVersion(r.Header.Get("Accept"))
+		if !ok {
+			versions := make([]string, 0, len(responseVersions))
+			for _, v := range supportedVersions() {
+				versions = append(versions, vendorMediaType(v))
+			}
+			message := fmt.Sprintf("unsupported API version; supported media types are application/json, %s", strings.Join(versions, ", "))
+			app.errorResponse(w, r, http.StatusNotAcceptable, message)
+			return
+		}
+
+		w.Header().Set(apiVersionHeader, strconv.Itoa(version))
+
+		ctx := context.WithValue(r.Context(), apiVersionContextKey, apiVersion{version: version, vendor: vendor})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVersionResponsesLeavesContentTypeToHandler(t *testing.T) {
+	app := newTestApplication(t)
+
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "photo.png"), []byte("\x89PNG\r\n\x1a\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploads := app.versionResponses(http.StripPrefix("/uploads", http.FileServer(http.Dir(dir))))
+	jsonHandler := app.versionResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.writeJSON(w, r, http.StatusOK, envelope{"ok": true}, nil)
+	}))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		path    string
+		accept  string
+		want    string
+	}{
+		{"upload with vendor accept", uploads, "/uploads/photo.png", vendorMediaType(1), "image/png"},
+		{"upload with plain accept", uploads, "/uploads/photo.png", "", "image/png"},
+		{"json with vendor accept", jsonHandler, "/v1/healthcheck", vendorMediaType(1), vendorMediaType(1)},
+		{"json with plain accept", jsonHandler, "/v1/healthcheck", "application/json", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d; want %d", rr.Code, http.StatusOK)
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("got Content-Type %q; want %q", got, tt.want)
+			}
+			if got := rr.Header().Get(apiVersionHeader); got != "1" {
+				t.Errorf("got %s %q; want \"1\"", apiVersionHeader, got)
+			}
+		})
+	}
+}
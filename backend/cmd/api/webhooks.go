@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/data"
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// webhookClient is used for all outbound webhook deliveries. It only connects to public
+// addresses, checked after DNS resolution and on every redirect.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// publicAddressOnly refuses connections to addresses webhooks may not be delivered to
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !data.PublicWebhookIP(ip) {
+		return fmt.Errorf("webhook address %s is not public", host)
+	}
+
+	return nil
+}
+
+// =============================================================================
+// WEBHOOK MANAGEMENT
+// =============================================================================
+
+// createWebhookHandler registers a new webhook for the authenticated user
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	var input struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := &data.Webhook{
+		UserID: user.ID,
+		URL:    input.URL,
+		Events: input.Events,
+	}
+
+	v := validator.New()
+	if data.ValidateWebhook(v, webhook); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Generate the signing secret
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	webhook.Secret = hex.EncodeToString(secret)
+
+	err = app.models.Webhooks.Insert(webhook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The secret is only ever returned once, at creation
+	err = app.writeJSON(w, r, http.StatusCreated, envelope{"webhook": webhook, "secret": webhook.Secret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhooksHandler lists the authenticated user's webhooks
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	webhooks, err := app.models.Webhooks.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"webhooks": webhooks}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteWebhookHandler removes one of the authenticated user's webhooks
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Webhooks.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrWebhookNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"message": "webhook successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listWebhookDeliveriesHandler lists deliveries for one of the user's webhooks
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	webhook, err := app.models.Webhooks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrWebhookNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if webhook.UserID != user.ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", defaultSortNewest)
+	input.Filters.SortSafelist = data.WebhookDeliverySortSafelist
+	input.Filters.MaxPage = app.config.pagination.maxPage
+
+	if input.Status != "" {
+		v.Check(validator.In(input.Status, "pending", "delivered", "dead"), "status", "must be pending, delivered or dead")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	deliveries, metadata, err := app.models.Webhooks.GetDeliveriesForWebhook(webhook.ID, input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusOK, envelope{"deliveries": deliveries, "metadata": app.withPageLinks(r, metadata)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// redeliverWebhookHandler requeues a delivered or dead delivery for another attempt
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	delivery, err := app.models.Webhooks.GetDelivery(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrWebhookDeliveryNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Verify the delivery belongs to one of the user's webhooks
+	webhook, err := app.models.Webhooks.Get(delivery.WebhookID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if webhook.UserID != user.ID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if delivery.Status == "pending" {
+		app.errorResponse(w, r, http.StatusConflict, "delivery is already pending")
+		return
+	}
+
+	err = app.models.Webhooks.Redeliver(delivery.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrWebhookDeliveryNotFound):
+			app.errorResponse(w, r, http.StatusConflict, "delivery is already pending")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, r, http.StatusAccepted, envelope{"message": "delivery queued for redelivery"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// =============================================================================
+// EVENT DISPATCH
+// =============================================================================
+
+// enqueueWebhookEvent persists a delivery for each of the user's webhooks subscribed to the event
+func (app *application) enqueueWebhookEvent(userID int64, event string, payload interface{}) {
+	webhooks, err := app.models.Webhooks.GetActiveForEvent(userID, event)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"event": event})
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":      event,
+		"created_at": time.Now().UTC(),
+		"data":       payload,
+	})
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"event": event})
+		return
+	}
+
+	for _, webhook := range webhooks {
+		_, err := app.models.Webhooks.InsertDelivery(webhook, event, body)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"event":      event,
+				"webhook_id": strconv.FormatInt(webhook.ID, 10),
+			})
+		}
+	}
+}
+
+// processWebhookDeliveries attempts every delivery that is currently due
+func (app *application) processWebhookDeliveries() {
+	deliveries, err := app.models.Webhooks.GetDueDeliveries(50)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job": "process_webhook_deliveries",
+		})
+		return
+	}
+
+	for _, delivery := range deliveries {
+		app.attemptWebhookDelivery(delivery)
+	}
+}
+
+// attemptWebhookDelivery posts a stored delivery and records the outcome
+func (app *application) attemptWebhookDelivery(delivery *data.WebhookDelivery) {
+	status, err := postWebhook(delivery)
+	if err == nil {
+		err = app.models.Webhooks.MarkDelivered(delivery.ID, status)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"job":         "process_webhook_deliveries",
+				"delivery_id": strconv.FormatInt(delivery.ID, 10),
+			})
+		}
+		return
+	}
+
+	attempts := delivery.AttemptCount + 1
+	dead := attempts >= data.MaxWebhookAttempts
+	next := time.Now().Add(webhookBackoff(attempts))
+
+	err = app.models.Webhooks.MarkFailed(delivery.ID, status, err.Error(), next, dead)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"job":         "process_webhook_deliveries",
+			"delivery_id": strconv.FormatInt(delivery.ID, 10),
+		})
+		return
+	}
+
+	if dead {
+		app.logger.PrintInfo("webhook delivery marked dead", map[string]string{
+			"job":         "process_webhook_deliveries",
+			"delivery_id": strconv.FormatInt(delivery.ID, 10),
+			"webhook_id":  strconv.FormatInt(delivery.WebhookID, 10),
+		})
+	}
+}
+
+// postWebhook sends the stored payload, signed over the exact stored bytes with the
+// webhook's current secret, returning the response status
+func postWebhook(delivery *data.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Signature", data.SignWebhookPayload(delivery.Secret, delivery.Payload))
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// webhookBackoff returns the delay before the next attempt, doubling from 30s up to 6h
+func webhookBackoff(attempts int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= 6*time.Hour {
+			return 6 * time.Hour
+		}
+	}
+	return delay
+}
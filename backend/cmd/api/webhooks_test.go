@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestPublicAddressOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"203.0.113.10:443", true},
+		{"[2001:db8::1]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.8:443", false},
+		{"169.254.169.254:80", false},
+	}
+
+	for _, tt := range tests {
+		err := publicAddressOnly("tcp", tt.address, nil)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s: got error %v; want allowed=%v", tt.address, err, tt.allowed)
+		}
+	}
+}
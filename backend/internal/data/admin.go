@@ -3,10 +3,9 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
-
-	"github.com/lib/pq"
 )
 
 // =============================================================================
@@ -56,11 +55,11 @@ func (m AdminModel) GetPlatformStats() (*PlatformStats, error) {
 		SELECT 
 			(SELECT COUNT(*) FROM users) as total_users,
 			(SELECT COUNT(*) FROM users WHERE role = 'agent') as total_agents,
-			(SELECT COUNT(*) FROM properties) as total_properties,
+			(SELECT COUNT(*) FROM properties WHERE deleted_at IS NULL) as total_properties,
 			(SELECT COUNT(*) FROM reviews WHERE status = 'approved') as total_reviews,
 			(SELECT COALESCE(SUM(amount), 0) FROM payments WHERE status = 'completed') as total_revenue,
 			(SELECT COUNT(*) FROM users WHERE role = 'agent' AND activated = true) as active_agents,
-			(SELECT COUNT(*) FROM properties WHERE featured_at IS NOT NULL) as featured_listings,
+			(SELECT COUNT(*) FROM properties WHERE featured_at IS NOT NULL AND deleted_at IS NULL) as featured_listings,
 			(SELECT COUNT(*) FROM reviews WHERE status = 'pending') as pending_reviews,
 			(SELECT COUNT(*) FROM agent_profiles WHERE verified = true) as verified_agents,
 			(SELECT COUNT(*) FROM agent_profiles WHERE status = 'suspended') as suspended_agents
@@ -224,7 +223,7 @@ func (m AdminModel) getRevenuePoints(ctx context.Context, query string) ([]Reven
 // GetAll retrieves all users with filtering and pagination
 func (m UserModel) GetAll(role, search string, filters Filters) ([]*User, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, name, email, activated, role, version
+		SELECT count(*) OVER(), id, created_at, name, email, activated, role, timezone, version
 		FROM users
 		WHERE (role = $1 OR $1 = '')
 		AND (name ILIKE '%%' || $2 || '%%' OR email ILIKE '%%' || $2 || '%%' OR $2 = '')
@@ -256,6 +255,7 @@ func (m UserModel) GetAll(role, search string, filters Filters) ([]*User, Metada
 			&user.Email,
 			&user.Activated,
 			&user.Role,
+			&user.Timezone,
 			&user.Version,
 		)
 		if err != nil {
@@ -280,7 +280,7 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 	}
 
 	query := `
-		SELECT id, created_at, name, email, activated, role, version
+		SELECT id, created_at, name, email, activated, role, timezone, version
 		FROM users
 		WHERE id = $1`
 
@@ -296,6 +296,7 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 		&user.Email,
 		&user.Activated,
 		&user.Role,
+		&user.Timezone,
 		&user.Version,
 	)
 
@@ -311,7 +312,9 @@ func (m UserModel) GetByID(id int64) (*User, error) {
 	return &user, nil
 }
 
-// Delete removes a user from the database
+// Delete removes a user from the database. Their reviews are kept, anonymized, so
+// agents' ratings don't change; their inquiries, schedules and other personal records
+// are removed with them by the database's ON DELETE rules.
 func (m UserModel) Delete(id int64) error {
 	if id < 1 {
 		return ErrUserNotFound
@@ -430,22 +433,66 @@ func (m AgentModel) GetAll(status, search string, filters Filters) ([]*AgentProf
 	return agents, metadata, nil
 }
 
-// ApproveVerification marks an agent as verified
-func (m AgentModel) ApproveVerification(userID int64) error {
-	query := `
+// approveVerificationQuery marks an agent's profile as verified, creating it if needed
+const approveVerificationQuery = `
 		INSERT INTO agent_profiles (user_id, verified, status)
 		VALUES ($1, true, 'active')
 		ON CONFLICT (user_id) 
 		DO UPDATE SET verified = true, status = 'active'
 	`
 
+// ApproveVerification marks an agent as verified
+func (m AgentModel) ApproveVerification(userID int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userID)
+	_, err := m.DB.ExecContext(ctx, approveVerificationQuery, userID)
 	return err
 }
 
+// ApproveVerificationBulk verifies every id that belongs to an agent in a single transaction
+func (m AgentModel) ApproveVerificationBulk(userIDs []int64) (*BulkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := NewBulkResult(len(userIDs))
+
+	for _, id := range userIDs {
+		var role string
+		err := tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1`, id).Scan(&role)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				results.Fail(id, BulkErrorNotFound, "user not found")
+				continue
+			}
+			return nil, err
+		}
+
+		if role != "agent" {
+			results.Fail(id, BulkErrorNotAgent, "user is not an agent")
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, approveVerificationQuery, id)
+		if err != nil {
+			return nil, err
+		}
+		results.Succeed(id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // Suspend marks an agent as suspended
 func (m AgentModel) Suspend(userID int64) error {
 	query := `
@@ -544,9 +591,7 @@ func (m AgentModel) RejectVerification(userID int64, reason string) error {
 // GetAllAdmin retrieves all properties with admin filters
 func (p PropertyModel) GetAllAdmin(agentID int64, status, propertyType string, filters Filters) ([]*Property, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year_built, area, bedrooms, 
-		       bathrooms, floor, price, location, property_type, features, images, 
-		       featured_at, COALESCE(agent_id, 0) as agent_id, version
+		SELECT count(*) OVER(), %s
 		FROM properties
 		WHERE (agent_id = $1 OR $1 = 0)
 		AND (property_type ILIKE '%%' || $2 || '%%' OR $2 = '')
@@ -557,7 +602,7 @@ func (p PropertyModel) GetAllAdmin(agentID int64, status, propertyType string, f
 		)
 		ORDER BY %s %s, id ASC
 		LIMIT $4 OFFSET $5
-	`, filters.sortColumn(), filters.sortDirection())
+	`, propertyColumns(""), filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -575,29 +620,93 @@ func (p PropertyModel) GetAllAdmin(agentID int64, status, propertyType string, f
 
 	for rows.Next() {
 		var property Property
+		err := rows.Scan(append([]interface{}{&totalRecords}, property.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		properties = append(properties, &property)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return properties, metadata, nil
+}
+
+// =============================================================================
+// EXTENDED INQUIRY MODEL FOR ADMIN
+// =============================================================================
+
+// GetAllForAdmin retrieves inquiries across all agents with admin filters; a zero
+// agent ID or empty status or type matches everything
+func (m InquiryModel) GetAllForAdmin(agentID int64, status, inquiryType string, filters Filters) ([]*Inquiry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(),
+		       i.id, i.property_id, i.user_id, i.agent_id, i.name, i.email, i.phone,
+		       i.message, i.inquiry_type, i.preferred_contact_method,
+		       i.preferred_viewing_date, i.status, i.priority,
+		       COALESCE(i.agent_notes, '') as agent_notes,
+		       i.response_message,
+		       i.responded_at, i.created_at, i.updated_at, i.version,
+		       p.title as property_title, u.name as user_name, a.name as agent_name
+		FROM inquiries i
+		INNER JOIN properties p ON i.property_id = p.id
+		INNER JOIN users u ON i.user_id = u.id
+		INNER JOIN users a ON i.agent_id = a.id
+		WHERE (i.agent_id = $1 OR $1 = 0)
+		AND (i.status = $2 OR $2 = '')
+		AND (i.inquiry_type = $3 OR $3 = '')
+		ORDER BY i.%s %s, i.id DESC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{agentID, status, inquiryType, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	inquiries := []*Inquiry{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var inquiry Inquiry
 		err := rows.Scan(
 			&totalRecords,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
+			&inquiry.ID,
+			&inquiry.PropertyID,
+			&inquiry.UserID,
+			&inquiry.AgentID,
+			&inquiry.Name,
+			&inquiry.Email,
+			&inquiry.Phone,
+			&inquiry.Message,
+			&inquiry.InquiryType,
+			&inquiry.PreferredContactMethod,
+			&inquiry.PreferredViewingDate,
+			&inquiry.Status,
+			&inquiry.Priority,
+			&inquiry.AgentNotes,
+			&inquiry.ResponseMessage,
+			&inquiry.RespondedAt,
+			&inquiry.CreatedAt,
+			&inquiry.UpdatedAt,
+			&inquiry.Version,
+			&inquiry.PropertyTitle,
+			&inquiry.UserName,
+			&inquiry.AgentName,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
-		properties = append(properties, &property)
+		inquiries = append(inquiries, &inquiry)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -606,5 +715,5 @@ func (p PropertyModel) GetAllAdmin(agentID int64, status, propertyType string, f
 
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 
-	return properties, metadata, nil
+	return inquiries, metadata, nil
 }
@@ -0,0 +1,341 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// AgentAvailability is a weekly window, in the agent's timezone, in which viewings
+// may be booked. Times are "HH:MM"; an end time of "24:00" runs to midnight.
+type AgentAvailability struct {
+	ID        int64     `json:"id"`
+	AgentID   int64     `json:"agent_id"`
+	DayOfWeek int       `json:"day_of_week"`
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	ErrAvailabilityNotFound = errors.New("availability not found")
+	ErrAvailabilityOverlap  = errors.New("availability overlaps another window on the same day")
+	ErrOutsideAvailability  = errors.New("schedule falls outside the agent's availability")
+)
+
+// ValidateAgentAvailability validates availability fields
+func ValidateAgentAvailability(v *validator.Validator, availability *AgentAvailability) {
+	v.Check(availability.DayOfWeek >= 0 && availability.DayOfWeek <= 6, "day_of_week", "must be between 0 (Sunday) and 6 (Saturday)")
+
+	start, ok := clockMinutes(availability.StartTime)
+	v.Check(ok && start < minutesPerDay, "start_time", "must be a time between 00:00 and 23:59")
+
+	end, ok := clockMinutes(availability.EndTime)
+	v.Check(ok, "end_time", "must be a time between 00:01 and 24:00")
+
+	if v.Valid() {
+		v.Check(end > start, "end_time", "must be after start_time")
+	}
+}
+
+const minutesPerDay = 24 * 60
+
+// clockMinutes parses an "HH:MM" time of day, including "24:00", into minutes after midnight
+func clockMinutes(clock string) (int, bool) {
+	if clock == "24:00" {
+		return minutesPerDay, true
+	}
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// AgentAvailabilityModel wraps database operations for agent availability windows
+type AgentAvailabilityModel struct {
+	DB *sql.DB
+}
+
+// availabilityColumns selects availability with times formatted as "HH:MM"
+const availabilityColumns = `id, agent_id, day_of_week, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'), created_at`
+
+// scanAvailability scans a row selected with availabilityColumns
+func scanAvailability(row interface{ Scan(...interface{}) error }, availability *AgentAvailability) error {
+	return row.Scan(
+		&availability.ID,
+		&availability.AgentID,
+		&availability.DayOfWeek,
+		&availability.StartTime,
+		&availability.EndTime,
+		&availability.CreatedAt,
+	)
+}
+
+// overlappingAvailability matches the agent's other windows on the same day that overlap
+// $3 to $4; $5 is a window to leave out, or 0
+const overlappingAvailability = `
+	SELECT 1 FROM agent_availability
+	WHERE agent_id = $1 AND day_of_week = $2
+	AND start_time < $4::time AND end_time > $3::time
+	AND id <> $5`
+
+// Insert adds an availability window unless it overlaps another on the same day
+func (m AgentAvailabilityModel) Insert(availability *AgentAvailability) error {
+	query := `
+		INSERT INTO agent_availability (agent_id, day_of_week, start_time, end_time)
+		SELECT $1, $2, $3::time, $4::time
+		WHERE NOT EXISTS (` + overlappingAvailability + `)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{availability.AgentID, availability.DayOfWeek, availability.StartTime, availability.EndTime, 0}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&availability.ID, &availability.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAvailabilityOverlap
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get retrieves one of the agent's availability windows
+func (m AgentAvailabilityModel) Get(id, agentID int64) (*AgentAvailability, error) {
+	if id < 1 {
+		return nil, ErrAvailabilityNotFound
+	}
+
+	query := `
+		SELECT ` + availabilityColumns + `
+		FROM agent_availability
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var availability AgentAvailability
+
+	err := scanAvailability(m.DB.QueryRowContext(ctx, query, id, agentID), &availability)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAvailabilityNotFound
+		}
+		return nil, err
+	}
+
+	return &availability, nil
+}
+
+// GetAllForAgent retrieves the agent's availability windows by day and start time
+func (m AgentAvailabilityModel) GetAllForAgent(agentID int64) ([]*AgentAvailability, error) {
+	query := `
+		SELECT ` + availabilityColumns + `
+		FROM agent_availability
+		WHERE agent_id = $1
+		ORDER BY day_of_week ASC, start_time ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []*AgentAvailability{}
+
+	for rows.Next() {
+		var availability AgentAvailability
+		if err := scanAvailability(rows, &availability); err != nil {
+			return nil, err
+		}
+		windows = append(windows, &availability)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// Update changes an availability window unless it would overlap another on the same day
+func (m AgentAvailabilityModel) Update(availability *AgentAvailability) error {
+	query := `
+		UPDATE agent_availability
+		SET day_of_week = $2, start_time = $3::time, end_time = $4::time
+		WHERE id = $5 AND agent_id = $1
+		AND NOT EXISTS (` + overlappingAvailability + `)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{availability.AgentID, availability.DayOfWeek, availability.StartTime, availability.EndTime, availability.ID}
+
+	result, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	// The window was found by the caller, so nothing updated means an overlap
+	if rowsAffected == 0 {
+		return ErrAvailabilityOverlap
+	}
+
+	return nil
+}
+
+// Delete removes one of the agent's availability windows
+func (m AgentAvailabilityModel) Delete(id, agentID int64) error {
+	if id < 1 {
+		return ErrAvailabilityNotFound
+	}
+
+	query := `
+		DELETE FROM agent_availability
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrAvailabilityNotFound
+	}
+
+	return nil
+}
+
+// WorkingHours returns the fixed hours the agent's viewings must fall within: hours for
+// agents with no availability configured, and none for the rest, whose availability
+// windows replace them
+func (m AgentAvailabilityModel) WorkingHours(agentID int64, hours WorkingHours) (WorkingHours, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM agent_availability WHERE agent_id = $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var configured bool
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(&configured)
+	if err != nil {
+		return WorkingHours{}, err
+	}
+
+	if configured {
+		return WorkingHours{}, nil
+	}
+	return hours, nil
+}
+
+// availabilityWindow is an availability window in minutes after local midnight
+type availabilityWindow struct {
+	day        time.Weekday
+	start, end int
+}
+
+// loadAvailabilityWindows reads all of the agent's availability windows
+func loadAvailabilityWindows(ctx context.Context, db *sql.DB, agentID int64) ([]availabilityWindow, error) {
+	query := `
+		SELECT day_of_week, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI')
+		FROM agent_availability
+		WHERE agent_id = $1`
+
+	rows, err := db.QueryContext(ctx, query, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []availabilityWindow{}
+
+	for rows.Next() {
+		var day int
+		var from, to string
+		if err := rows.Scan(&day, &from, &to); err != nil {
+			return nil, err
+		}
+
+		start, ok := clockMinutes(from)
+		if !ok {
+			return nil, fmt.Errorf("invalid availability start time %q", from)
+		}
+		end, ok := clockMinutes(to)
+		if !ok {
+			return nil, fmt.Errorf("invalid availability end time %q", to)
+		}
+
+		windows = append(windows, availabilityWindow{day: time.Weekday(day), start: start, end: end})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// checkAvailability returns ErrOutsideAvailability unless the viewing from start to end
+// falls inside one of the agent's windows, read in loc (UTC when nil). Agents with no
+// windows are available at any time.
+func checkAvailability(ctx context.Context, db *sql.DB, agentID int64, start, end time.Time, loc *time.Location) error {
+	windows, err := loadAvailabilityWindows(ctx, db, agentID)
+	if err != nil {
+		return err
+	}
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	start, end = start.In(loc), end.In(loc)
+
+	// Minutes after the start day's midnight by the local clock, so a viewing running
+	// past midnight ends after 24:00 and only fits a window on a later day
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+	if days := dayNumber(end) - dayNumber(start); days > 0 {
+		endMinutes += days * minutesPerDay
+	}
+
+	for _, window := range windows {
+		if window.day == start.Weekday() && startMinutes >= window.start && endMinutes <= window.end {
+			return nil
+		}
+	}
+
+	return ErrOutsideAvailability
+}
+
+// dayNumber counts days since the epoch for t's local date
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
@@ -0,0 +1,124 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// Blackout represents a period during which an agent is unavailable for viewings
+type Blackout struct {
+	ID        int64     `json:"id"`
+	AgentID   int64     `json:"agent_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var ErrBlackoutNotFound = errors.New("blackout not found")
+
+// ValidateBlackout validates blackout fields
+func ValidateBlackout(v *validator.Validator, blackout *Blackout) {
+	v.Check(!blackout.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(!blackout.EndsAt.IsZero(), "ends_at", "must be provided")
+	v.Check(blackout.EndsAt.After(blackout.StartsAt), "ends_at", "must be after starts_at")
+	v.Check(len(blackout.Reason) <= 500, "reason", "must not exceed 500 characters")
+}
+
+// BlackoutModel wraps database operations for agent blackouts
+type BlackoutModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new blackout period
+func (m BlackoutModel) Insert(blackout *Blackout) error {
+	query := `
+		INSERT INTO agent_blackouts (agent_id, starts_at, ends_at, reason)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{blackout.AgentID, blackout.StartsAt, blackout.EndsAt, blackout.Reason}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&blackout.ID, &blackout.CreatedAt)
+}
+
+// GetAllForAgentInRange retrieves blackouts for an agent that overlap the given range
+func (m BlackoutModel) GetAllForAgentInRange(agentID int64, from, to time.Time) ([]*Blackout, error) {
+	query := `
+		SELECT id, agent_id, starts_at, ends_at, reason, created_at
+		FROM agent_blackouts
+		WHERE agent_id = $1
+		AND starts_at < $3
+		AND ends_at > $2
+		ORDER BY starts_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blackouts := []*Blackout{}
+
+	for rows.Next() {
+		var blackout Blackout
+		err := rows.Scan(
+			&blackout.ID,
+			&blackout.AgentID,
+			&blackout.StartsAt,
+			&blackout.EndsAt,
+			&blackout.Reason,
+			&blackout.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		blackouts = append(blackouts, &blackout)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return blackouts, nil
+}
+
+// Delete removes a blackout belonging to the given agent
+func (m BlackoutModel) Delete(id, agentID int64) error {
+	if id < 1 {
+		return ErrBlackoutNotFound
+	}
+
+	query := `
+		DELETE FROM agent_blackouts
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrBlackoutNotFound
+	}
+
+	return nil
+}
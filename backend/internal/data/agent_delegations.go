@@ -0,0 +1,173 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// Delegation lets another agent handle an agent's inquiries and schedules while
+// the agent is out of office
+type Delegation struct {
+	AgentID       int64     `json:"agent_id"`
+	AgentName     string    `json:"agent_name"`
+	DelegateID    int64     `json:"delegate_id"`
+	DelegateName  string    `json:"delegate_name"`
+	DelegateEmail string    `json:"delegate_email"`
+	Until         time.Time `json:"until"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// MaxDelegationDuration caps how far ahead a delegation may run
+const MaxDelegationDuration = 90 * 24 * time.Hour
+
+var ErrDelegationNotFound = errors.New("delegation not found")
+
+// ValidateDelegation validates delegation fields
+func ValidateDelegation(v *validator.Validator, delegation *Delegation, now time.Time) {
+	v.Check(delegation.DelegateID > 0, "delegate_id", "must be provided")
+	v.Check(delegation.DelegateID != delegation.AgentID, "delegate_id", "must not be yourself")
+	v.Check(!delegation.Until.IsZero(), "until", "must be provided")
+	v.Check(delegation.Until.After(now), "until", "must be in the future")
+	v.Check(!delegation.Until.After(now.Add(MaxDelegationDuration)), "until", "must be within 90 days")
+}
+
+// DelegationModel wraps database operations for agent delegations
+type DelegationModel struct {
+	DB *sql.DB
+}
+
+// Set stores the agent's delegation, replacing any existing one
+func (m DelegationModel) Set(delegation *Delegation) error {
+	query := `
+		INSERT INTO agent_delegations (agent_id, delegate_id, until)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (agent_id)
+		DO UPDATE SET delegate_id = EXCLUDED.delegate_id, until = EXCLUDED.until, created_at = NOW()
+		RETURNING created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{delegation.AgentID, delegation.DelegateID, delegation.Until}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&delegation.CreatedAt)
+}
+
+// delegationQuery selects delegations with both agents' names
+const delegationQuery = `
+	SELECT d.agent_id, a.name, d.delegate_id, dl.name, dl.email, d.until, d.created_at
+	FROM agent_delegations d
+	INNER JOIN users a ON a.id = d.agent_id
+	INNER JOIN users dl ON dl.id = d.delegate_id`
+
+// scanDelegation scans a row selected by delegationQuery
+func scanDelegation(row interface{ Scan(...interface{}) error }, delegation *Delegation) error {
+	return row.Scan(
+		&delegation.AgentID,
+		&delegation.AgentName,
+		&delegation.DelegateID,
+		&delegation.DelegateName,
+		&delegation.DelegateEmail,
+		&delegation.Until,
+		&delegation.CreatedAt,
+	)
+}
+
+// GetActive retrieves the agent's delegation if it has not yet ended
+func (m DelegationModel) GetActive(agentID int64) (*Delegation, error) {
+	query := delegationQuery + `
+		WHERE d.agent_id = $1 AND d.until > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var delegation Delegation
+
+	err := scanDelegation(m.DB.QueryRowContext(ctx, query, agentID), &delegation)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDelegationNotFound
+		}
+		return nil, err
+	}
+
+	return &delegation, nil
+}
+
+// GetActiveForDelegate lists the current delegations the user is acting on
+func (m DelegationModel) GetActiveForDelegate(delegateID int64) ([]*Delegation, error) {
+	query := delegationQuery + `
+		WHERE d.delegate_id = $1 AND d.until > NOW()
+		ORDER BY d.until ASC, d.agent_id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, delegateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := []*Delegation{}
+
+	for rows.Next() {
+		var delegation Delegation
+		if err := scanDelegation(rows, &delegation); err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, &delegation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+// IsActiveDelegate reports whether the user currently acts for the agent
+func (m DelegationModel) IsActiveDelegate(agentID, delegateID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM agent_delegations
+			WHERE agent_id = $1 AND delegate_id = $2 AND until > NOW()
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var active bool
+	err := m.DB.QueryRowContext(ctx, query, agentID, delegateID).Scan(&active)
+	return active, err
+}
+
+// Delete clears the agent's delegation
+func (m DelegationModel) Delete(agentID int64) error {
+	query := `
+		DELETE FROM agent_delegations
+		WHERE agent_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDelegationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// AgentSpecialties lists the property types an agent specialises in and the areas they serve
+type AgentSpecialties struct {
+	PropertyTypes []string `json:"property_types"`
+	ServiceAreas  []string `json:"service_areas"`
+}
+
+// Caps on how many specialties and service areas one agent may list
+const (
+	MaxAgentSpecialties  = 10
+	MaxAgentServiceAreas = 20
+)
+
+// AgentSearchResult is a verified agent matching an agent search, with the number of
+// live listings they have matching it
+type AgentSearchResult struct {
+	AgentCard
+	ListingCount int `json:"listing_count"`
+}
+
+// canonicalValue returns the known value equal to value ignoring case
+func canonicalValue(value string, known []string) (string, bool) {
+	for _, k := range known {
+		if strings.EqualFold(k, strings.TrimSpace(value)) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// ValidateAgentSpecialties checks the specialties against the property types and
+// locations known from listings, replacing each with its canonical spelling
+func ValidateAgentSpecialties(v *validator.Validator, s *AgentSpecialties, knownTypes, knownAreas []string) {
+	v.Check(len(s.PropertyTypes) <= MaxAgentSpecialties, "property_types", fmt.Sprintf("must not contain more than %d values", MaxAgentSpecialties))
+	v.Check(len(s.ServiceAreas) <= MaxAgentServiceAreas, "service_areas", fmt.Sprintf("must not contain more than %d values", MaxAgentServiceAreas))
+
+	for i, propertyType := range s.PropertyTypes {
+		canonical, ok := canonicalValue(propertyType, knownTypes)
+		if !ok {
+			v.AddError("property_types", fmt.Sprintf("unknown property type %q", propertyType))
+			continue
+		}
+		s.PropertyTypes[i] = canonical
+	}
+	v.Check(validator.Unique(s.PropertyTypes), "property_types", "must not contain duplicate values")
+
+	for i, area := range s.ServiceAreas {
+		canonical, ok := canonicalValue(area, knownAreas)
+		if !ok {
+			v.AddError("service_areas", fmt.Sprintf("unknown area %q", area))
+			continue
+		}
+		s.ServiceAreas[i] = canonical
+	}
+	v.Check(validator.Unique(s.ServiceAreas), "service_areas", "must not contain duplicate values")
+}
+
+// GetSpecialties returns the agent's specialties and service areas
+func (m AgentModel) GetSpecialties(agentID int64) (*AgentSpecialties, error) {
+	query := `
+		SELECT ARRAY(SELECT property_type FROM agent_specialties WHERE agent_id = $1 ORDER BY property_type),
+		       ARRAY(SELECT area FROM agent_service_areas WHERE agent_id = $1 ORDER BY area)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var s AgentSpecialties
+
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(pq.Array(&s.PropertyTypes), pq.Array(&s.ServiceAreas))
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// ReplaceSpecialties sets the agent's specialties and service areas, replacing any existing ones
+func (m AgentModel) ReplaceSpecialties(agentID int64, s *AgentSpecialties) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []struct {
+		query  string
+		values []string
+	}{
+		{`DELETE FROM agent_specialties WHERE agent_id = $1`, nil},
+		{`DELETE FROM agent_service_areas WHERE agent_id = $1`, nil},
+		{`INSERT INTO agent_specialties (agent_id, property_type) SELECT $1, unnest($2::text[])`, s.PropertyTypes},
+		{`INSERT INTO agent_service_areas (agent_id, area) SELECT $1, unnest($2::text[])`, s.ServiceAreas},
+	}
+
+	for _, q := range queries {
+		args := []interface{}{agentID}
+		if q.values != nil {
+			args = append(args, pq.Array(q.values))
+		}
+
+		_, err = tx.ExecContext(ctx, q.query, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Search returns verified agents serving area and specialising in propertyType (either
+// may be empty), ranked by their live listings matching both, then by their rating
+func (m AgentModel) Search(area, propertyType string, filters Filters) ([]*AgentSearchResult, Metadata, error) {
+	query := `
+		SELECT count(*) OVER(), u.id, u.name, COALESCE(u.profile_photo, ''),
+		       COALESCE(l.listing_count, 0), COALESCE(rs.average_rating, 0), COALESCE(rs.review_count, 0)
+		FROM users u
+		INNER JOIN agent_profiles ap ON ap.user_id = u.id AND ap.verified
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS listing_count
+			FROM properties p
+			WHERE p.agent_id = u.id
+			AND ` + publicPropertyCondition("p") + `
+			AND (p.location ILIKE '%' || $1 || '%' OR $1 = '')
+			AND (lower(p.property_type) = lower($2) OR $2 = '')
+		) l ON true
+		LEFT JOIN LATERAL (
+			SELECT AVG(r.rating) AS average_rating, COUNT(r.id) AS review_count
+			FROM reviews r
+			INNER JOIN properties p ON p.id = r.property_id
+			WHERE r.status = 'approved' AND p.agent_id = u.id AND p.deleted_at IS NULL
+		) rs ON true
+		WHERE u.role = 'agent'
+		AND ($1 = '' OR EXISTS (SELECT 1 FROM agent_service_areas a WHERE a.agent_id = u.id AND lower(a.area) = lower($1)))
+		AND ($2 = '' OR EXISTS (SELECT 1 FROM agent_specialties s WHERE s.agent_id = u.id AND lower(s.property_type) = lower($2)))
+		ORDER BY 5 DESC, 6 DESC, u.id ASC
+		LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, area, propertyType, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	agents := []*AgentSearchResult{}
+	totalRecords := 0
+
+	for rows.Next() {
+		agent := AgentSearchResult{AgentCard: AgentCard{Verified: true}}
+		err := rows.Scan(
+			&totalRecords,
+			&agent.ID,
+			&agent.Name,
+			&agent.ProfilePhoto,
+			&agent.ListingCount,
+			&agent.AverageRating,
+			&agent.ReviewCount,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		agents = append(agents, &agent)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return agents, metadata, nil
+}
@@ -3,9 +3,20 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"regexp"
 	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
 )
 
+// DefaultSlotMinutes is the booking granularity used when an agent has not set one
+const DefaultSlotMinutes = 30
+
+// AllowedSlotMinutes lists the slot sizes an agent may choose from
+var AllowedSlotMinutes = []int{5, 10, 15, 20, 30, 60}
+
 type AgentModel struct {
 	DB *sql.DB
 }
@@ -33,7 +44,7 @@ func (m AgentModel) GetDashboardStats(agentID int64) (*DashboardStats, error) {
 		FROM properties p
 		LEFT JOIN reviews r ON p.id = r.property_id AND r.status IN ('approved', 'pending')
 		LEFT JOIN payments pay ON p.agent_id = pay.agent_id
-		WHERE p.agent_id = $1`
+		WHERE p.agent_id = $1 AND p.deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -54,3 +65,179 @@ func (m AgentModel) GetDashboardStats(agentID int64) (*DashboardStats, error) {
 
 	return &stats, nil
 }
+
+// ValidateSlotMinutes checks that a slot size is one of the allowed values
+func ValidateSlotMinutes(v *validator.Validator, slotMinutes int) {
+	allowed := false
+	for _, m := range AllowedSlotMinutes {
+		if slotMinutes == m {
+			allowed = true
+			break
+		}
+	}
+	v.Check(allowed, "slot_minutes", "must be one of 5, 10, 15, 20, 30 or 60")
+}
+
+// GetSlotMinutes returns the agent's booking slot size, falling back to the default
+func (m AgentModel) GetSlotMinutes(agentID int64) (int, error) {
+	query := `
+		SELECT slot_minutes
+		FROM agent_profiles
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var slotMinutes int
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(&slotMinutes)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DefaultSlotMinutes, nil
+		default:
+			return 0, err
+		}
+	}
+
+	return slotMinutes, nil
+}
+
+// UpdateSlotMinutes sets the agent's booking slot size, creating the profile row if needed
+func (m AgentModel) UpdateSlotMinutes(agentID int64, slotMinutes int) error {
+	query := `
+		INSERT INTO agent_profiles (user_id, slot_minutes)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id)
+		DO UPDATE SET slot_minutes = EXCLUDED.slot_minutes`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, agentID, slotMinutes)
+	return err
+}
+
+// PhoneRX is a loose check for international phone numbers
+var PhoneRX = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{6,19}$`)
+
+// ValidatePhone checks that a phone number looks like one
+func ValidatePhone(v *validator.Validator, phone string) {
+	v.Check(validator.Matches(phone, PhoneRX), "phone", "must be a valid phone number")
+}
+
+// AgentCard is the public summary of an agent shown alongside their listings
+type AgentCard struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	ProfilePhoto  string  `json:"profile_photo,omitempty"`
+	Verified      bool    `json:"verified"`
+	AverageRating float64 `json:"average_rating"`
+	ReviewCount   int     `json:"review_count"`
+	Phone         *string `json:"phone,omitempty"`
+	Email         *string `json:"email,omitempty"`
+	PhoneHidden   bool    `json:"phone_hidden,omitempty"` // set when the phone requires signing in to reveal
+
+	Specialties  []string `json:"specialties,omitempty"`
+	ServiceAreas []string `json:"service_areas,omitempty"`
+}
+
+// GetCard returns the public agent card; phone and email are only included if the agent opted in
+func (m AgentModel) GetCard(agentID int64) (*AgentCard, error) {
+	query := `
+		SELECT u.id, u.name, COALESCE(u.profile_photo, ''),
+		       COALESCE(ap.verified, false),
+		       CASE WHEN COALESCE(ap.show_phone, false) THEN ap.phone END,
+		       CASE WHEN COALESCE(ap.show_email, false) THEN u.email END,
+		       COALESCE(rs.average_rating, 0), COALESCE(rs.review_count, 0),
+		       ARRAY(SELECT property_type FROM agent_specialties WHERE agent_id = u.id ORDER BY property_type),
+		       ARRAY(SELECT area FROM agent_service_areas WHERE agent_id = u.id ORDER BY area)
+		FROM users u
+		LEFT JOIN agent_profiles ap ON ap.user_id = u.id
+		LEFT JOIN (
+			SELECT p.agent_id, AVG(r.rating) AS average_rating, COUNT(r.id) AS review_count
+			FROM reviews r
+			INNER JOIN properties p ON p.id = r.property_id
+			WHERE r.status = 'approved' AND p.agent_id = $1 AND p.deleted_at IS NULL
+			GROUP BY p.agent_id
+		) rs ON rs.agent_id = u.id
+		WHERE u.id = $1 AND u.role = 'agent'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var card AgentCard
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(
+		&card.ID,
+		&card.Name,
+		&card.ProfilePhoto,
+		&card.Verified,
+		&card.Phone,
+		&card.Email,
+		&card.AverageRating,
+		&card.ReviewCount,
+		pq.Array(&card.Specialties),
+		pq.Array(&card.ServiceAreas),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrUserNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &card, nil
+}
+
+// IsVerified reports whether an admin has verified the agent
+func (m AgentModel) IsVerified(agentID int64) (bool, error) {
+	query := `
+		SELECT COALESCE(bool_or(verified), false)
+		FROM agent_profiles
+		WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var verified bool
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(&verified)
+	return verified, err
+}
+
+// UpdateContactSettings sets the agent's phone and contact visibility; nil values are left unchanged
+func (m AgentModel) UpdateContactSettings(agentID int64, phone *string, showPhone, showEmail *bool) error {
+	query := `
+		INSERT INTO agent_profiles (user_id, phone, show_phone, show_email)
+		VALUES ($1, $2, COALESCE($3, false), COALESCE($4, false))
+		ON CONFLICT (user_id)
+		DO UPDATE SET phone = COALESCE($2, agent_profiles.phone),
+		              show_phone = COALESCE($3, agent_profiles.show_phone),
+		              show_email = COALESCE($4, agent_profiles.show_email)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, agentID, phone, showPhone, showEmail)
+	return err
+}
+
+// IsActive reports whether the user is an activated agent who has not been suspended
+func (m AgentModel) IsActive(userID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM users u
+			WHERE u.id = $1 AND u.role = 'agent' AND u.activated = true
+			AND NOT EXISTS (
+				SELECT 1 FROM agent_profiles ap
+				WHERE ap.user_id = u.id AND ap.status = 'suspended'
+			)
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var active bool
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&active)
+	return active, err
+}
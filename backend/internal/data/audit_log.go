@@ -0,0 +1,49 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records an administrative action taken against an entity
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	ActorID    int64                  `json:"actor_id"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entity_type"`
+	EntityID   int64                  `json:"entity_id,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditModel wraps database operations for the audit log
+type AuditModel struct {
+	DB *sql.DB
+}
+
+// Insert appends an entry to the audit log
+func (m AuditModel) Insert(entry *AuditEntry) error {
+	details := entry.Details
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, details)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{entry.ActorID, entry.Action, entry.EntityType, entry.EntityID, detailsJSON}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&entry.ID, &entry.CreatedAt)
+}
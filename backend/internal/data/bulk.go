@@ -0,0 +1,68 @@
+package data
+
+// Error codes reported for ids that failed in a bulk operation
+const (
+	BulkErrorNotFound = "not_found"
+	BulkErrorNotAgent = "not_agent"
+	BulkErrorInvalid  = "invalid"
+
+	BulkErrorSlotsFull = "featured_slots_full"
+)
+
+// BulkFailure describes one id a bulk operation could not apply
+type BulkFailure struct {
+	ID        int64             `json:"id"`
+	ErrorCode string            `json:"error_code"`
+	Message   string            `json:"message"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+// BulkCounts summarises a bulk operation
+type BulkCounts struct {
+	Requested int `json:"requested"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
+// BulkResult is the shared outcome of every bulk endpoint. Each requested id ends up
+// in exactly one of Succeeded, Failed or Skipped (already in the requested state).
+type BulkResult struct {
+	Succeeded []int64       `json:"succeeded"`
+	Failed    []BulkFailure `json:"failed"`
+	Skipped   []int64       `json:"skipped"`
+	Counts    BulkCounts    `json:"counts"`
+}
+
+// NewBulkResult returns an empty result for a bulk operation over requested ids
+func NewBulkResult(requested int) *BulkResult {
+	return &BulkResult{
+		Succeeded: []int64{},
+		Failed:    []BulkFailure{},
+		Skipped:   []int64{},
+		Counts:    BulkCounts{Requested: requested},
+	}
+}
+
+// Succeed records an id the operation was applied to
+func (b *BulkResult) Succeed(id int64) {
+	b.Succeeded = append(b.Succeeded, id)
+	b.Counts.Succeeded++
+}
+
+// Fail records an id the operation could not be applied to
+func (b *BulkResult) Fail(id int64, code, message string) {
+	b.FailWithErrors(id, code, message, nil)
+}
+
+// FailWithErrors records a failed id along with its field validation errors
+func (b *BulkResult) FailWithErrors(id int64, code, message string, errors map[string]string) {
+	b.Failed = append(b.Failed, BulkFailure{ID: id, ErrorCode: code, Message: message, Errors: errors})
+	b.Counts.Failed++
+}
+
+// Skip records an id that needed no change
+func (b *BulkResult) Skip(id int64) {
+	b.Skipped = append(b.Skipped, id)
+	b.Counts.Skipped++
+}
@@ -0,0 +1,16 @@
+package data
+
+import "strings"
+
+// qualifyColumns joins column names into a select list, prefixing each with alias when one is given
+func qualifyColumns(alias string, names []string) string {
+	if alias == "" {
+		return strings.Join(names, ", ")
+	}
+
+	qualified := make([]string, len(names))
+	for i, name := range names {
+		qualified[i] = alias + "." + name
+	}
+	return strings.Join(qualified, ", ")
+}
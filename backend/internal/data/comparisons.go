@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// Comparison is a saved set of properties to compare side by side, shared by its token
+type Comparison struct {
+	ID          int64      `json:"id"`
+	UserID      *int64     `json:"-"`
+	PropertyIDs []int64    `json:"property_ids"`
+	ShareToken  string     `json:"share_token"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// A comparison needs at least two properties and is capped at MaxComparisonProperties
+const (
+	MinComparisonProperties = 2
+	MaxComparisonProperties = 4
+)
+
+var ErrComparisonNotFound = errors.New("comparison not found")
+
+// ValidateComparison checks the comparison's property set
+func ValidateComparison(v *validator.Validator, comparison *Comparison) {
+	v.Check(len(comparison.PropertyIDs) >= MinComparisonProperties, "property_ids", fmt.Sprintf("must contain at least %d properties", MinComparisonProperties))
+	v.Check(len(comparison.PropertyIDs) <= MaxComparisonProperties, "property_ids", fmt.Sprintf("must not contain more than %d properties", MaxComparisonProperties))
+
+	seen := make(map[int64]bool, len(comparison.PropertyIDs))
+	for _, id := range comparison.PropertyIDs {
+		v.Check(id > 0, "property_ids", "must contain valid property ids")
+		v.Check(!seen[id], "property_ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+}
+
+// ComparisonModel wraps database operations for saved comparisons
+type ComparisonModel struct {
+	DB *sql.DB
+}
+
+// Insert saves a comparison under a new random share token
+func (m ComparisonModel) Insert(comparison *Comparison) error {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return err
+	}
+	comparison.ShareToken = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	query := `
+		INSERT INTO comparisons (user_id, property_ids, share_token, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{comparison.UserID, pq.Array(comparison.PropertyIDs), comparison.ShareToken, comparison.ExpiresAt}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&comparison.ID, &comparison.CreatedAt)
+}
+
+// GetByToken retrieves a comparison by its share token, unless it has expired
+func (m ComparisonModel) GetByToken(token string) (*Comparison, error) {
+	query := `
+		SELECT id, user_id, property_ids, share_token, created_at, expires_at
+		FROM comparisons
+		WHERE share_token = $1 AND (expires_at IS NULL OR expires_at > NOW())`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var comparison Comparison
+
+	err := m.DB.QueryRowContext(ctx, query, token).Scan(
+		&comparison.ID,
+		&comparison.UserID,
+		pq.Array(&comparison.PropertyIDs),
+		&comparison.ShareToken,
+		&comparison.CreatedAt,
+		&comparison.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrComparisonNotFound
+		}
+		return nil, err
+	}
+
+	return &comparison, nil
+}
+
+// DeleteExpired removes expired anonymous comparisons and returns how many were removed
+func (m ComparisonModel) DeleteExpired() (int64, error) {
+	query := `
+		DELETE FROM comparisons
+		WHERE expires_at IS NOT NULL AND expires_at <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,212 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// FavouriteCollection is a named folder of a user's favourites. Favourites outside
+// any collection are still listed under the implicit "All" collection.
+type FavouriteCollection struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	Name       string    `json:"name"`
+	Favourites int       `json:"favourites"`
+	CreatedAt  time.Time `json:"created_at"`
+	Version    int       `json:"version"`
+}
+
+// AllFavouritesCollection names the implicit collection holding every favourite
+const AllFavouritesCollection = "All"
+
+// MaxFavouriteCollections caps how many collections a single user may create
+const MaxFavouriteCollections = 20
+
+var (
+	ErrCollectionNotFound      = errors.New("favourite collection not found")
+	ErrDuplicateCollection     = errors.New("duplicate favourite collection name")
+	ErrCollectionLimitExceeded = errors.New("favourite collection limit reached")
+)
+
+// ValidateFavouriteCollection validates favourite collection fields
+func ValidateFavouriteCollection(v *validator.Validator, collection *FavouriteCollection) {
+	v.Check(collection.Name != "", "name", "must be provided")
+	v.Check(len(collection.Name) <= 50, "name", "must not exceed 50 characters")
+	v.Check(!strings.EqualFold(collection.Name, AllFavouritesCollection), "name", "is reserved")
+}
+
+// FavouriteCollectionModel wraps database operations for favourite collections
+type FavouriteCollectionModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a collection, failing once the user has MaxFavouriteCollections
+func (m FavouriteCollectionModel) Insert(collection *FavouriteCollection) error {
+	query := `
+		INSERT INTO favourite_collections (user_id, name)
+		SELECT $1, $2
+		WHERE (SELECT COUNT(*) FROM favourite_collections WHERE user_id = $1) < $3
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{collection.UserID, collection.Name, MaxFavouriteCollections}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.ID, &collection.CreatedAt, &collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrCollectionLimitExceeded
+		case err.Error() == `pq: duplicate key value violates unique constraint "favourite_collections_user_name_key"`:
+			return ErrDuplicateCollection
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves one of the user's collections with its favourite count
+func (m FavouriteCollectionModel) Get(id, userID int64) (*FavouriteCollection, error) {
+	if id < 1 {
+		return nil, ErrCollectionNotFound
+	}
+
+	query := `
+		SELECT c.id, c.user_id, c.name,
+		       (SELECT COUNT(*) FROM user_favourites uf WHERE uf.collection_id = c.id),
+		       c.created_at, c.version
+		FROM favourite_collections c
+		WHERE c.id = $1 AND c.user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var collection FavouriteCollection
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&collection.ID,
+		&collection.UserID,
+		&collection.Name,
+		&collection.Favourites,
+		&collection.CreatedAt,
+		&collection.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+// GetAllForUser lists the user's collections by name with their favourite counts
+func (m FavouriteCollectionModel) GetAllForUser(userID int64) ([]*FavouriteCollection, error) {
+	query := `
+		SELECT c.id, c.user_id, c.name,
+		       (SELECT COUNT(*) FROM user_favourites uf WHERE uf.collection_id = c.id),
+		       c.created_at, c.version
+		FROM favourite_collections c
+		WHERE c.user_id = $1
+		ORDER BY lower(c.name) ASC, c.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []*FavouriteCollection{}
+
+	for rows.Next() {
+		var collection FavouriteCollection
+		err := rows.Scan(
+			&collection.ID,
+			&collection.UserID,
+			&collection.Name,
+			&collection.Favourites,
+			&collection.CreatedAt,
+			&collection.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, &collection)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// Update renames a collection using optimistic locking
+func (m FavouriteCollectionModel) Update(collection *FavouriteCollection) error {
+	query := `
+		UPDATE favourite_collections
+		SET name = $1, version = version + 1
+		WHERE id = $2 AND user_id = $3 AND version = $4
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{collection.Name, collection.ID, collection.UserID, collection.Version}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "favourite_collections_user_name_key"`:
+			return ErrDuplicateCollection
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes one of the user's collections. Its favourites are kept and fall back to "All".
+func (m FavouriteCollectionModel) Delete(id, userID int64) error {
+	if id < 1 {
+		return ErrCollectionNotFound
+	}
+
+	query := `
+		DELETE FROM favourite_collections
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrCollectionNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrFeaturedSlotsFull is returned when featuring a property would exceed a featured slots cap
+var ErrFeaturedSlotsFull = errors.New("featured slots full")
+
+// FeaturePolicy holds the configured rules for featuring a listing
+type FeaturePolicy struct {
+	// Duration is how long a listing stays featured; zero features it until unfeatured
+	Duration time.Duration
+	// MaxListings caps how many properties may be featured at once across the platform,
+	// and MaxPerLocation how many in any one location; zero leaves a cap off
+	MaxListings    int
+	MaxPerLocation int
+}
+
+// capped reports whether either featured slots cap is on
+func (policy FeaturePolicy) capped() bool {
+	return policy.MaxListings > 0 || policy.MaxPerLocation > 0
+}
+
+// slotsFull reports whether another listing can't be featured while total listings
+// are featured across the platform, inLocation of them in its location
+func (policy FeaturePolicy) slotsFull(total, inLocation int) bool {
+	if policy.MaxListings > 0 && total >= policy.MaxListings {
+		return true
+	}
+	return policy.MaxPerLocation > 0 && inLocation >= policy.MaxPerLocation
+}
+
+// featuredSlotsLockKey serialises featuring so concurrent requests can't both take the last slot
+const featuredSlotsLockKey = 7465237
+
+// CountFeatured returns how many live properties are featured, in the given location
+// when one is given (case-insensitively) or across the platform otherwise
+func (p PropertyModel) CountFeatured(location string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM properties
+		WHERE featured_at IS NOT NULL
+		AND deleted_at IS NULL
+		AND ($1 = '' OR lower(location) = lower($1))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := p.DB.QueryRowContext(ctx, query, location).Scan(&count)
+	return count, err
+}
+
+// FeaturedSlotAvailable reports whether the property could be featured now without
+// exceeding a cap. Properties that are already featured always can be, as featuring
+// them again only renews their slot. Payment flows check this before charging.
+func (p PropertyModel) FeaturedSlotAvailable(id int64, policy FeaturePolicy) (bool, error) {
+	if id < 1 {
+		return false, ErrPropertyNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = checkFeaturedSlots(ctx, tx, id, policy)
+	switch {
+	case errors.Is(err, ErrFeaturedSlotsFull):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checkFeaturedSlots returns ErrFeaturedSlotsFull if featuring the property would exceed
+// a cap, and ErrPropertyNotFound if it doesn't exist
+func checkFeaturedSlots(ctx context.Context, tx *sql.Tx, id int64, policy FeaturePolicy) error {
+	var location string
+	var featured bool
+
+	query := `
+		SELECT location, featured_at IS NOT NULL
+		FROM properties
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	err := tx.QueryRowContext(ctx, query, id).Scan(&location, &featured)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPropertyNotFound
+		}
+		return err
+	}
+
+	if featured || !policy.capped() {
+		return nil
+	}
+
+	query = `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE lower(location) = lower($1))
+		FROM properties
+		WHERE featured_at IS NOT NULL AND deleted_at IS NULL`
+
+	var total, inLocation int
+	err = tx.QueryRowContext(ctx, query, location).Scan(&total, &inLocation)
+	if err != nil {
+		return err
+	}
+
+	if policy.slotsFull(total, inLocation) {
+		return ErrFeaturedSlotsFull
+	}
+
+	return nil
+}
+
+// featureInTx features the property within tx once the featured slots lock is held,
+// returning ErrFeaturedSlotsFull when no slot is free
+func featureInTx(ctx context.Context, tx *sql.Tx, id int64, policy FeaturePolicy) error {
+	err := checkFeaturedSlots(ctx, tx, id, policy)
+	if err != nil {
+		return err
+	}
+
+	var newVersion int32
+
+	err = tx.QueryRowContext(ctx, featureQuery, id, policy.Duration.Seconds()).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPropertyNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// lockFeaturedSlots takes the transaction-scoped lock that featuring holds while it
+// counts and claims a slot
+func lockFeaturedSlots(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, featuredSlotsLockKey)
+	return err
+}
+
+// ExpireFeatured returns every listing whose featured period has ended to standard,
+// returning their ids
+func (p PropertyModel) ExpireFeatured() ([]int64, error) {
+	query := `
+		UPDATE properties
+		SET featured_at = NULL, featured_until = NULL, version = version + 1
+		WHERE featured_until <= NOW()
+		RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFeaturePolicySlotsFull(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     FeaturePolicy
+		total      int
+		inLocation int
+		want       bool
+	}{
+		{"no caps", FeaturePolicy{}, 500, 500, false},
+		{"under platform cap", FeaturePolicy{MaxListings: 10}, 9, 9, false},
+		{"at platform cap", FeaturePolicy{MaxListings: 10}, 10, 0, true},
+		{"beyond platform cap", FeaturePolicy{MaxListings: 10}, 12, 0, true},
+		{"under location cap", FeaturePolicy{MaxPerLocation: 3}, 50, 2, false},
+		{"at location cap", FeaturePolicy{MaxPerLocation: 3}, 50, 3, true},
+		{"location full under platform cap", FeaturePolicy{MaxListings: 10, MaxPerLocation: 3}, 5, 3, true},
+		{"platform full under location cap", FeaturePolicy{MaxListings: 10, MaxPerLocation: 3}, 10, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.slotsFull(tt.total, tt.inLocation); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureRejectsBeyondCap(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	first := seedTestProperty(t, models, agent.ID)
+	second := seedTestProperty(t, models, agent.ID)
+
+	featured, err := models.Properties.CountFeatured("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave room for exactly one more featured listing
+	policy := FeaturePolicy{MaxListings: featured + 1}
+
+	if err := models.Properties.Feature(first.ID, policy); err != nil {
+		t.Fatalf("featuring into the last slot: %v", err)
+	}
+
+	err = models.Properties.Feature(second.ID, policy)
+	if !errors.Is(err, ErrFeaturedSlotsFull) {
+		t.Fatalf("got error %v featuring beyond the cap; want %v", err, ErrFeaturedSlotsFull)
+	}
+
+	// Featuring an already featured listing again only renews its slot
+	if err := models.Properties.Feature(first.ID, policy); err != nil {
+		t.Errorf("renewing a featured listing: %v", err)
+	}
+
+	payment := &Payment{
+		AgentID:         agent.ID,
+		PropertyID:      second.ID,
+		Amount:          100,
+		PaymentMethod:   "test",
+		PaymentProvider: "test",
+		Status:          "completed",
+	}
+
+	err = models.Payments.CreateFeatured(payment, policy)
+	if !errors.Is(err, ErrFeaturedSlotsFull) {
+		t.Fatalf("got error %v paying beyond the cap; want %v", err, ErrFeaturedSlotsFull)
+	}
+
+	var payments int
+	err = db.QueryRow(`SELECT COUNT(*) FROM payments WHERE property_id = $1`, second.ID).Scan(&payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payments != 0 {
+		t.Errorf("got %d payments recorded beyond the cap; want 0", payments)
+	}
+}
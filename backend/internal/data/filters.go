@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"math"
 	"strings"
 
@@ -14,6 +15,18 @@ type Metadata struct {
 	FirstPage     int `json:"first_page,omitempty"`
 	LastPage      int `json:"last_page,omitempty"`
 	TotalListings int `json:"total_listings,omitempty"`
+
+	// Navigation URLs for the list, set by the handler from the request
+	Links *PageLinks `json:"links,omitempty"`
+}
+
+// PageLinks holds ready-to-use URLs for the pages around the current one. Prev and
+// Next are left out on the first and last pages.
+type PageLinks struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last"`
 }
 
 // Filters holds pagination and sorting info for property queries
@@ -22,13 +35,39 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+
+	// MaxPage is the deepest page offset pagination will serve. Deep pages force the
+	// database to scan and discard every earlier row, so clients are asked to narrow
+	// their query instead. Handlers set it from configuration.
+	MaxPage int
+}
+
+// MaxSortKeys is the most keys a compound sort such as "featured,-created_at" may contain
+const MaxSortKeys = 3
+
+// sortExpressions maps sort keys that are not plain columns to their SQL expression
+var sortExpressions = map[string]string{
+	"featured": "(featured_at IS NULL)",
+	// Bumped listings sort as if they had just been created
+	"freshness": "COALESCE(bumped_at, created_at)",
+	// Only available where the query computes favourite_count
+	"favourites": "favourite_count",
+	// Only available for text searches; negated so "relevance" puts the best match first
+	"relevance": "-relevance",
+}
+
+// sortKeys splits a compound sort value into its individual keys
+func (f Filters) sortKeys() []string {
+	return strings.Split(f.Sort, ",")
 }
 
 // Returns the column name to sort by if its's in the safelist
+// For compound sorts only the first key is used
 func (f Filters) sortColumn() string {
+	key := f.sortKeys()[0]
 	for _, safeValue := range f.SortSafelist {
-		if f.Sort == safeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+		if key == safeValue {
+			return strings.TrimPrefix(key, "-")
 		}
 
 	}
@@ -36,13 +75,39 @@ func (f Filters) sortColumn() string {
 }
 
 // Returns the sort direction ("ASC" or "DESC") based on the '-' prefix
+// For compound sorts only the first key is used
 func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
+	if strings.HasPrefix(f.sortKeys()[0], "-") {
 		return "DESC"
 	}
 	return "ASC"
 }
 
+// orderBy returns the full ORDER BY list for a sort value, supporting compound sorts
+func (f Filters) orderBy() string {
+	var clauses []string
+
+	for _, key := range f.sortKeys() {
+		if !validator.In(key, f.SortSafelist...) {
+			panic("unsafe sort parameter: " + f.Sort)
+		}
+
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+		}
+
+		column := strings.TrimPrefix(key, "-")
+		if expr, ok := sortExpressions[column]; ok {
+			column = expr
+		}
+
+		clauses = append(clauses, column+" "+direction)
+	}
+
+	return strings.Join(clauses, ", ")
+}
+
 // limit returns the number of records to fetch (PageSize)
 func (f Filters) limit() int {
 	return f.PageSize
@@ -71,14 +136,25 @@ func calculateMetadata(totalListings, page, pageSize int) Metadata {
 
 // ValidateFilters checks that pagination and sorting parameters are valid
 func ValidateFilters(v *validator.Validator, f Filters) {
-	//Page must be . 0 and <= 10 million
+	//Page must be > 0 and no deeper than MaxPage
 	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(f.Page <= f.MaxPage, "page", fmt.Sprintf("must be a maximum of %d; narrow the results with filters or a different sort to reach later records", f.MaxPage))
 
 	//PageSize must be > 0 and <= 100
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 
-	// Sort must be one of the allowed values
-	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+	// Every sort key must be one of the allowed values
+	keys := f.sortKeys()
+	v.Check(len(keys) <= MaxSortKeys, "sort", "must not contain more than 3 sort keys")
+	for _, key := range keys {
+		v.Check(validator.In(key, f.SortSafelist...), "sort", "invalid sort value")
+	}
+
+	// The same column may only appear once
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = strings.TrimPrefix(key, "-")
+	}
+	v.Check(validator.Unique(columns), "sort", "must not contain duplicate sort keys")
 }
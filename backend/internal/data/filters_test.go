@@ -0,0 +1,30 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestValidateFiltersMaxPage(t *testing.T) {
+	tests := []struct {
+		page    int
+		maxPage int
+		valid   bool
+	}{
+		{1, 1000, true},
+		{1000, 1000, true},
+		{1001, 1000, false},
+		{50, 20, false},
+		{20, 20, true},
+	}
+
+	for _, tt := range tests {
+		f := Filters{Page: tt.page, PageSize: 20, Sort: "id", SortSafelist: []string{"id"}, MaxPage: tt.maxPage}
+
+		v := validator.New()
+		if ValidateFilters(v, f); v.Valid() != tt.valid {
+			t.Errorf("page %d of at most %d: got errors %v; want valid=%v", tt.page, tt.maxPage, v.Errors, tt.valid)
+		}
+	}
+}
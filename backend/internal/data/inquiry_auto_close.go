@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StaleInquiry is an open inquiry with no activity, with what is needed to email the inquirer
+type StaleInquiry struct {
+	ID            int64
+	Name          string
+	Email         string
+	PropertyTitle string
+}
+
+// MarkStaleForPrompt flags open inquiries with no activity for longer than after and returns
+// them so the inquirer can be asked whether they are still interested. Inquiries that saw
+// activity since their last prompt can be prompted again.
+func (m InquiryModel) MarkStaleForPrompt(after time.Duration) ([]*StaleInquiry, error) {
+	query := `
+		UPDATE inquiries
+		SET stale_prompted_at = NOW()
+		WHERE status IN ('new', 'contacted')
+		AND updated_at < NOW() - make_interval(secs => $1)
+		AND (stale_prompted_at IS NULL OR stale_prompted_at < updated_at)
+		RETURNING id, name, email, (SELECT title FROM properties WHERE id = inquiries.property_id)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, after.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stale := []*StaleInquiry{}
+
+	for rows.Next() {
+		var inquiry StaleInquiry
+		if err := rows.Scan(&inquiry.ID, &inquiry.Name, &inquiry.Email, &inquiry.PropertyTitle); err != nil {
+			return nil, err
+		}
+		stale = append(stale, &inquiry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+// AutoCloseStale closes open inquiries with no activity for longer than after, adding a
+// system note. When prompted is true only inquiries whose inquirer was prompted at least
+// grace ago, with no activity since, are closed. It returns the number closed.
+func (m InquiryModel) AutoCloseStale(after time.Duration, prompted bool, grace time.Duration) (int64, error) {
+	query := `
+		UPDATE inquiries
+		SET status = 'closed',
+		    auto_closed_at = NOW(),
+		    agent_notes = concat_ws(E'\n', NULLIF(agent_notes, ''), $4::text),
+		    version = version + 1
+		WHERE status IN ('new', 'contacted')
+		AND (
+			(NOT $2 AND updated_at < NOW() - make_interval(secs => $1))
+			OR ($2 AND stale_prompted_at IS NOT NULL
+			    AND updated_at <= stale_prompted_at
+			    AND stale_prompted_at < NOW() - make_interval(secs => $3))
+		)`
+
+	note := fmt.Sprintf("[system] Closed automatically after %d days without activity.", int(after.Hours()/24))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, after.Seconds(), prompted, grace.Seconds(), note)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// ContactAttempt records an agent trying to reach an inquirer outside the message
+// thread, eg a phone call
+type ContactAttempt struct {
+	ID        int64     `json:"id"`
+	InquiryID int64     `json:"inquiry_id"`
+	AgentID   int64     `json:"agent_id"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allowed contact attempt methods and outcomes
+var (
+	ContactAttemptMethods  = []string{"phone", "sms", "whatsapp", "email", "in_person"}
+	ContactAttemptOutcomes = []string{"reached", "no_answer", "voicemail", "busy", "wrong_number"}
+)
+
+// ValidateContactAttempt checks a contact attempt's method, outcome and note
+func ValidateContactAttempt(v *validator.Validator, attempt *ContactAttempt) {
+	v.Check(validator.In(attempt.Method, ContactAttemptMethods...), "method",
+		"must be one of: phone, sms, whatsapp, email, in_person")
+	v.Check(validator.In(attempt.Outcome, ContactAttemptOutcomes...), "outcome",
+		"must be one of: reached, no_answer, voicemail, busy, wrong_number")
+	v.Check(len(attempt.Note) <= 1000, "note", "must not exceed 1000 characters")
+}
+
+// LogContactAttempt records a contact attempt on an inquiry and marks the inquiry active
+func (m InquiryModel) LogContactAttempt(attempt *ContactAttempt) error {
+	query := `
+		WITH touched AS (
+			UPDATE inquiries SET updated_at = NOW() WHERE id = $1
+		)
+		INSERT INTO inquiry_contact_attempts (inquiry_id, agent_id, method, outcome, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{attempt.InquiryID, attempt.AgentID, attempt.Method, attempt.Outcome, attempt.Note}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&attempt.ID, &attempt.CreatedAt)
+}
+
+// GetContactAttempts lists an inquiry's contact attempts, oldest first
+func (m InquiryModel) GetContactAttempts(inquiryID int64) ([]*ContactAttempt, error) {
+	query := `
+		SELECT id, inquiry_id, agent_id, method, outcome, note, created_at
+		FROM inquiry_contact_attempts
+		WHERE inquiry_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, inquiryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*ContactAttempt{}
+
+	for rows.Next() {
+		var attempt ContactAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.InquiryID,
+			&attempt.AgentID,
+			&attempt.Method,
+			&attempt.Outcome,
+			&attempt.Note,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InquiryMessage is one message in an inquiry's conversation
+type InquiryMessage struct {
+	ID        int64     `json:"id"`
+	InquiryID int64     `json:"inquiry_id"`
+	Sender    string    `json:"sender"`
+	Body      string    `json:"body"`
+	Via       string    `json:"via"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Who sent an inquiry message, and how it arrived
+const (
+	InquirySenderAgent    = "agent"
+	InquirySenderInquirer = "inquirer"
+
+	InquiryViaPlatform = "platform"
+	InquiryViaEmail    = "email"
+)
+
+// MaxInquiryMessageLength caps the stored body of a single message
+const MaxInquiryMessageLength = 10000
+
+var ErrInvalidRelayAddress = errors.New("invalid relay address")
+
+// relayAddressTag signs an inquiry ID so relay addresses can't be guessed
+func relayAddressTag(secret string, inquiryID int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("inquiry-" + strconv.FormatInt(inquiryID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// InquiryRelayAddress returns the masked address for an inquiry's conversation,
+// eg inquiry-42-1a2b3c4d5e6f@relay.example.com
+func InquiryRelayAddress(secret, domain string, inquiryID int64) string {
+	return fmt.Sprintf("inquiry-%d-%s@%s", inquiryID, relayAddressTag(secret, inquiryID), domain)
+}
+
+// ParseInquiryRelayAddress returns the inquiry ID from a relay address, rejecting
+// addresses for another domain or with a tag that doesn't match
+func ParseInquiryRelayAddress(secret, domain, address string) (int64, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return 0, ErrInvalidRelayAddress
+	}
+
+	local, host, found := strings.Cut(parsed.Address, "@")
+	if !found || !strings.EqualFold(host, domain) {
+		return 0, ErrInvalidRelayAddress
+	}
+
+	parts := strings.Split(strings.TrimPrefix(strings.ToLower(local), "inquiry-"), "-")
+	if len(parts) != 2 {
+		return 0, ErrInvalidRelayAddress
+	}
+
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id < 1 {
+		return 0, ErrInvalidRelayAddress
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(relayAddressTag(secret, id))) {
+		return 0, ErrInvalidRelayAddress
+	}
+
+	return id, nil
+}
+
+// InsertMessage adds a message to an inquiry's conversation and marks the inquiry active
+func (m InquiryModel) InsertMessage(message *InquiryMessage) error {
+	query := `
+		WITH touched AS (
+			UPDATE inquiries SET updated_at = NOW() WHERE id = $1
+		)
+		INSERT INTO inquiry_messages (inquiry_id, sender, body, via)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{message.InquiryID, message.Sender, message.Body, message.Via}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&message.ID, &message.CreatedAt)
+}
+
+// GetMessages lists an inquiry's conversation, oldest first
+func (m InquiryModel) GetMessages(inquiryID int64) ([]*InquiryMessage, error) {
+	query := `
+		SELECT id, inquiry_id, sender, body, via, created_at
+		FROM inquiry_messages
+		WHERE inquiry_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, inquiryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*InquiryMessage{}
+
+	for rows.Next() {
+		var message InquiryMessage
+		err := rows.Scan(
+			&message.ID,
+			&message.InquiryID,
+			&message.Sender,
+			&message.Body,
+			&message.Via,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, &message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
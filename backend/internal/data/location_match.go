@@ -0,0 +1,117 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// LocationSearch configures how location filters match
+type LocationSearch struct {
+	// Fuzzy lets location filters also match near-miss spellings, such as "Kilmani" for
+	// "Kilimani", using pg_trgm word similarity. Only turn it on when the extension is
+	// installed; otherwise filters fall back to ILIKE alone.
+	Fuzzy bool
+
+	// SimilarityThreshold is the word similarity, from 0 to 1, a location must reach to
+	// match a misspelt filter
+	SimilarityThreshold float64
+}
+
+// TrigramAvailable reports whether the pg_trgm extension is installed in the database
+func TrigramAvailable(db *sql.DB) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var available bool
+	err := db.QueryRowContext(ctx, query).Scan(&available)
+	return available, err
+}
+
+// match returns the condition matching location against the filter in the given
+// placeholder: a case-insensitive substring match, or a close enough spelling when
+// fuzzy matching is on. The <% operator compares against pg_trgm.word_similarity_threshold,
+// which queryLocations sets, and lets the location trigram index be used.
+func (l LocationSearch) match(placeholder string) string {
+	substring := fmt.Sprintf("location ILIKE '%%' || %s || '%%'", placeholder)
+	if !l.Fuzzy {
+		return substring
+	}
+	return fmt.Sprintf("(%s OR %s <%% location)", substring, placeholder)
+}
+
+// matchAny returns the condition matching location against any of the filters in the
+// locations text array placeholder, by substring through the matching ILIKE patterns
+// placeholder or by spelling. It is only used with fuzzy matching on.
+func (l LocationSearch) matchAny(locations, patterns string) string {
+	return fmt.Sprintf("(location ILIKE ANY(%s) OR EXISTS (SELECT 1 FROM unnest(%s::text[]) l WHERE l <%% location))",
+		patterns, locations)
+}
+
+// order returns the ORDER BY prefix that puts the closest location matches to the
+// filter in the given placeholder first, or nothing when fuzzy matching is off.
+// Locations containing the filter as a whole word score 1, ahead of misspelt ones.
+func (l LocationSearch) order(placeholder string) string {
+	if !l.Fuzzy {
+		return ""
+	}
+	return fmt.Sprintf("word_similarity(%s, location) DESC, ", placeholder)
+}
+
+// orderAny is order for a locations text array placeholder, ranking by the closest of
+// them. It is only used with fuzzy matching on.
+func (l LocationSearch) orderAny(locations string) string {
+	return fmt.Sprintf("(SELECT max(word_similarity(l, location)) FROM unnest(%s::text[]) l) DESC, ", locations)
+}
+
+// queryLocations runs query and hands its rows to scan. When the query filters on location
+// with fuzzy matching on, it runs in a read-only transaction with
+// pg_trgm.word_similarity_threshold set for that transaction only, like SET LOCAL, so <%
+// matches use the configured threshold.
+func (p PropertyModel) queryLocations(ctx context.Context, filtered bool, query string, args []interface{}, scan func(*sql.Rows) error) error {
+	if !p.Locations.Fuzzy || !filtered {
+		rows, err := p.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if err := scan(rows); err != nil {
+			return err
+		}
+		return rows.Err()
+	}
+
+	tx, err := p.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// set_config with is_local true is SET LOCAL that accepts a bind parameter
+	threshold := strconv.FormatFloat(p.Locations.SimilarityThreshold, 'f', -1, 64)
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, threshold)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := scan(rows); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return tx.Commit()
+}
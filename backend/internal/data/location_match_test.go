@@ -0,0 +1,112 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLocationMatch(t *testing.T) {
+	exact := LocationSearch{SimilarityThreshold: 0.3}
+
+	if got, want := exact.match("$3"), "location ILIKE '%' || $3 || '%'"; got != want {
+		t.Errorf("exact: got %q; want %q", got, want)
+	}
+	if got := exact.order("$3"); got != "" {
+		t.Errorf("exact: got order %q; want none", got)
+	}
+
+	fuzzy := LocationSearch{Fuzzy: true, SimilarityThreshold: 0.4}
+
+	match := fuzzy.match("$3")
+	for _, want := range []string{"location ILIKE '%' || $3 || '%'", "$3 <% location"} {
+		if !strings.Contains(match, want) {
+			t.Errorf("fuzzy: %q is missing %q", match, want)
+		}
+	}
+	if strings.Contains(match, "0.4") {
+		t.Errorf("fuzzy: %q interpolates the threshold", match)
+	}
+	if got, want := fuzzy.order("$3"), "word_similarity($3, location) DESC, "; got != want {
+		t.Errorf("fuzzy: got order %q; want %q", got, want)
+	}
+}
+
+func TestAdvancedSearchQueryFuzzyLocations(t *testing.T) {
+	locations := []string{"Kilmani", "Lavingtn"}
+	query, args := advancedSearchQuery(PropertySearchCriteria{Locations: locations}, testSearchFilters(), LocationSearch{Fuzzy: true, SimilarityThreshold: 0.3})
+
+	for _, want := range []string{
+		"location ILIKE ANY($1)",
+		"unnest($2::text[]) l WHERE l <% location",
+		"ORDER BY (SELECT max(word_similarity(l, location)) FROM unnest($2::text[]) l) DESC, ",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query is missing %q:\n%s", want, query)
+		}
+	}
+
+	if got, want := stringArrayArg(t, args[0]), []string{"%Kilmani%", "%Lavingtn%"}; !slices.Equal(got, want) {
+		t.Errorf("location patterns: got %v; want %v", got, want)
+	}
+	if got := stringArrayArg(t, args[1]); !slices.Equal(got, locations) {
+		t.Errorf("locations: got %v; want %v", got, locations)
+	}
+}
+
+func TestFuzzyLocationMatchesMisspellings(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	available, err := TrigramAvailable(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !available {
+		t.Skip("pg_trgm is not installed")
+	}
+	models.Properties.Locations = LocationSearch{Fuzzy: true, SimilarityThreshold: 0.3}
+
+	agent := seedTestUser(t, models, "agent")
+	property := seedTestProperty(t, models, agent.ID)
+
+	_, err = db.Exec(`UPDATE properties SET location = 'Kileleshwa' WHERE id = $1`, property.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters := Filters{Page: 1, PageSize: 100, Sort: "id", SortSafelist: PropertySortSafelist}
+
+	for _, misspelling := range []string{"Kileleshwa", "kileleshwa", "Kilelshwa", "Kileleswa", "Kilileshwa"} {
+		t.Run(misspelling, func(t *testing.T) {
+			properties, _, _, err := models.Properties.GetAll("", misspelling, nil, nil, PriceRange{}, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !containsProperty(properties, property.ID) {
+				t.Errorf("listing search for %q did not match Kileleshwa", misspelling)
+			}
+
+			properties, _, err = models.Properties.AdvancedSearch(PropertySearchCriteria{Locations: []string{misspelling}}, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !containsProperty(properties, property.ID) {
+				t.Errorf("advanced search for %q did not match Kileleshwa", misspelling)
+			}
+		})
+	}
+
+	properties, _, _, err := models.Properties.GetAll("", "Westlands", nil, nil, PriceRange{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if containsProperty(properties, property.ID) {
+		t.Error("search for an unrelated location matched Kileleshwa")
+	}
+}
+
+// containsProperty reports whether the property with the given id is among properties
+func containsProperty(properties []*Property, id int64) bool {
+	return slices.ContainsFunc(properties, func(p *Property) bool { return p.ID == id })
+}
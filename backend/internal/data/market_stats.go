@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// MarketStats summarises approved, live listings for one location and property type.
+// The price figures are null when there are fewer than MinMarketStatsListings listings.
+type MarketStats struct {
+	Location           string   `json:"location"`
+	PropertyType       string   `json:"property_type"`
+	ListingCount       int      `json:"listing_count"`
+	AveragePrice       *float64 `json:"average_price"`
+	MedianPrice        *float64 `json:"median_price"`
+	AveragePricePerSqm *float64 `json:"average_price_per_sqm"`
+	MedianPricePerSqm  *float64 `json:"median_price_per_sqm"`
+}
+
+// MinMarketStatsListings is the fewest listings a group needs before its prices are
+// reported, so a handful of listings aren't presented as a market figure
+const MinMarketStatsListings = 3
+
+// MaxMarketStatsGroups caps how many location and property type groups are returned
+const MaxMarketStatsGroups = 100
+
+// GetMarketStats returns price statistics grouped by location and property type, the
+// largest groups first. Empty location or propertyType match every value; both are
+// compared case-insensitively.
+func (p PropertyModel) GetMarketStats(location, propertyType string) ([]*MarketStats, error) {
+	query := `
+	SELECT location, property_type, count(*),
+	       CASE WHEN count(*) >= $3 THEN round(avg(price), 2) END,
+	       CASE WHEN count(*) >= $3 THEN round(percentile_cont(0.5) WITHIN GROUP (ORDER BY price)::numeric, 2) END,
+	       CASE WHEN count(*) >= $3 THEN round(avg(price / NULLIF(area, 0)), 2) END,
+	       CASE WHEN count(*) >= $3 THEN round(percentile_cont(0.5) WITHIN GROUP (ORDER BY price / NULLIF(area, 0))::numeric, 2) END
+	FROM properties
+	WHERE ` + publicPropertyCondition("") + `
+	AND (lower(location) = lower($1) OR $1 = '')
+	AND (lower(property_type) = lower($2) OR $2 = '')
+	GROUP BY location, property_type
+	ORDER BY count(*) DESC, location ASC, property_type ASC
+	LIMIT $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, location, propertyType, MinMarketStatsListings, MaxMarketStatsGroups)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []*MarketStats{}
+
+	for rows.Next() {
+		var s MarketStats
+		err := rows.Scan(
+			&s.Location,
+			&s.PropertyType,
+			&s.ListingCount,
+			&s.AveragePrice,
+			&s.MedianPrice,
+			&s.AveragePricePerSqm,
+			&s.MedianPricePerSqm,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -13,40 +13,69 @@ var (
 	ErrReviewNotFound   = errors.New("review not found")
 	ErrDuplicateEmail   = errors.New("duplicate email")
 	ErrPaymentNotFound  = errors.New("payment not found")
+	ErrNoPrimaryImage   = errors.New("property has no primary image")
 )
 
 // Models wraps all model types
 type Models struct {
-	Properties    PropertyModel
-	Users         UserModel
-	Tokens        TokenModel
-	RevokedTokens RevokedTokenModel
-	Permissions   PermissionModel
-	Reviews       ReviewModel
-	Payments      PaymentModel
-	Agents        AgentModel
-	Admin         AdminModel
-	Media         MediaModel
-	Inquiries     InquiryModel
-	Favourites    FavouriteModel
-	Schedules     ScheduleModel
+	Properties     PropertyModel
+	Users          UserModel
+	Tokens         TokenModel
+	RevokedTokens  RevokedTokenModel
+	Permissions    PermissionModel
+	Reviews        ReviewModel
+	Payments       PaymentModel
+	Agents         AgentModel
+	Admin          AdminModel
+	Media          MediaModel
+	Inquiries      InquiryModel
+	ReplyTemplates ReplyTemplateModel
+	Favourites     FavouriteModel
+	Collections    FavouriteCollectionModel
+	Schedules      ScheduleModel
+	Blackouts      BlackoutModel
+	OpenHouses     OpenHouseModel
+	PropertyNotes  PropertyNoteModel
+	Webhooks       WebhookModel
+	Analytics      AnalyticsModel
+	AuditLog       AuditModel
+	SavedSearches  SavedSearchModel
+	Comparisons    ComparisonModel
+	Delegations    DelegationModel
+	Notifications  NotificationPreferenceModel
+	Availability   AgentAvailabilityModel
+	Inbox          UserNotificationModel
 }
 
 // NewModels initializes and returns a Models struct with the given DB connection
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Properties:    PropertyModel{DB: db},
-		Users:         UserModel{DB: db},
-		Tokens:        TokenModel{DB: db},
-		RevokedTokens: RevokedTokenModel{DB: db},
-		Permissions:   PermissionModel{DB: db},
-		Reviews:       ReviewModel{DB: db},
-		Payments:      PaymentModel{DB: db},
-		Agents:        AgentModel{DB: db},
-		Admin:         AdminModel{DB: db},
-		Media:         MediaModel{DB: db},
-		Inquiries:     InquiryModel{DB: db},
-		Favourites:    FavouriteModel{DB: db},
-		Schedules:     ScheduleModel{DB: db},
+		Properties:     PropertyModel{DB: db},
+		Users:          UserModel{DB: db},
+		Tokens:         TokenModel{DB: db},
+		RevokedTokens:  RevokedTokenModel{DB: db},
+		Permissions:    PermissionModel{DB: db},
+		Reviews:        ReviewModel{DB: db},
+		Payments:       PaymentModel{DB: db},
+		Agents:         AgentModel{DB: db},
+		Admin:          AdminModel{DB: db},
+		Media:          MediaModel{DB: db},
+		Inquiries:      InquiryModel{DB: db},
+		ReplyTemplates: ReplyTemplateModel{DB: db},
+		Favourites:     FavouriteModel{DB: db},
+		Collections:    FavouriteCollectionModel{DB: db},
+		Schedules:      ScheduleModel{DB: db},
+		Blackouts:      BlackoutModel{DB: db},
+		OpenHouses:     OpenHouseModel{DB: db},
+		PropertyNotes:  PropertyNoteModel{DB: db},
+		Webhooks:       WebhookModel{DB: db},
+		Analytics:      AnalyticsModel{DB: db},
+		AuditLog:       AuditModel{DB: db},
+		SavedSearches:  SavedSearchModel{DB: db},
+		Comparisons:    ComparisonModel{DB: db},
+		Delegations:    DelegationModel{DB: db},
+		Notifications:  NotificationPreferenceModel{DB: db},
+		Availability:   AgentAvailabilityModel{DB: db},
+		Inbox:          UserNotificationModel{DB: db},
 	}
 }
@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// NotificationPreferences controls how an agent is emailed about new inquiries and
+// payment outcomes
+type NotificationPreferences struct {
+	AgentID           int64     `json:"-"`
+	InquiryEmails     string    `json:"inquiry_emails"`
+	UrgentImmediately bool      `json:"urgent_immediately"`
+	PaymentEmails     bool      `json:"payment_emails"`
+	LastDigestAt      time.Time `json:"last_digest_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// InquiryEmailsImmediate sends one email per inquiry; the other modes are digests
+const InquiryEmailsImmediate = "immediate"
+
+// InquiryDigestFrequencies maps each digest mode to the time between digests
+var InquiryDigestFrequencies = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+}
+
+// DefaultNotificationPreferences are used for agents who never changed their preferences
+func DefaultNotificationPreferences(agentID int64) *NotificationPreferences {
+	return &NotificationPreferences{
+		AgentID:           agentID,
+		InquiryEmails:     InquiryEmailsImmediate,
+		UrgentImmediately: true,
+		PaymentEmails:     true,
+	}
+}
+
+// ValidateNotificationPreferences validates notification preference fields
+func ValidateNotificationPreferences(v *validator.Validator, prefs *NotificationPreferences) {
+	_, digest := InquiryDigestFrequencies[prefs.InquiryEmails]
+	v.Check(prefs.InquiryEmails == InquiryEmailsImmediate || digest, "inquiry_emails", "must be immediate, hourly or daily")
+}
+
+// EmailInquiryImmediately reports whether an inquiry should be emailed to the agent
+// as it arrives rather than in their next digest
+func (prefs *NotificationPreferences) EmailInquiryImmediately(inquiry *Inquiry) bool {
+	if prefs.InquiryEmails == InquiryEmailsImmediate {
+		return true
+	}
+	return prefs.UrgentImmediately && isUrgentPriority(inquiry.Priority)
+}
+
+// isUrgentPriority reports whether an inquiry priority skips the digest when the
+// agent asked for urgent inquiries immediately
+func isUrgentPriority(priority string) bool {
+	return priority == "high" || priority == "urgent"
+}
+
+// InquiryDigest is an agent due a digest of the inquiries received since LastDigestAt
+type InquiryDigest struct {
+	NotificationPreferences
+	AgentName  string
+	AgentEmail string
+}
+
+// NotificationPreferenceModel wraps database operations for agent notification preferences
+type NotificationPreferenceModel struct {
+	DB *sql.DB
+}
+
+// Get retrieves the agent's preferences, or the defaults if they have none stored
+func (m NotificationPreferenceModel) Get(agentID int64) (*NotificationPreferences, error) {
+	query := `
+		SELECT agent_id, inquiry_emails, urgent_immediately, payment_emails, last_digest_at, updated_at
+		FROM agent_notification_preferences
+		WHERE agent_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var prefs NotificationPreferences
+
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(
+		&prefs.AgentID,
+		&prefs.InquiryEmails,
+		&prefs.UrgentImmediately,
+		&prefs.PaymentEmails,
+		&prefs.LastDigestAt,
+		&prefs.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DefaultNotificationPreferences(agentID), nil
+		}
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// Set stores the agent's preferences. Switching from immediate emails to a digest
+// starts the digest from now, so inquiries already emailed are not sent again.
+func (m NotificationPreferenceModel) Set(prefs *NotificationPreferences) error {
+	query := `
+		INSERT INTO agent_notification_preferences (agent_id, inquiry_emails, urgent_immediately, payment_emails)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (agent_id)
+		DO UPDATE SET inquiry_emails = EXCLUDED.inquiry_emails,
+		              urgent_immediately = EXCLUDED.urgent_immediately,
+		              payment_emails = EXCLUDED.payment_emails,
+		              last_digest_at = CASE
+		                  WHEN agent_notification_preferences.inquiry_emails = 'immediate' THEN NOW()
+		                  ELSE agent_notification_preferences.last_digest_at
+		              END,
+		              updated_at = NOW()
+		RETURNING last_digest_at, updated_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{prefs.AgentID, prefs.InquiryEmails, prefs.UrgentImmediately, prefs.PaymentEmails}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&prefs.LastDigestAt, &prefs.UpdatedAt)
+}
+
+// GetDueDigests returns the agents whose digest interval has passed, oldest digest
+// first, along with their names and emails
+func (m NotificationPreferenceModel) GetDueDigests(now time.Time) ([]*InquiryDigest, error) {
+	frequencies := make([]string, 0, len(InquiryDigestFrequencies))
+	cutoffs := make([]time.Time, 0, len(InquiryDigestFrequencies))
+	for frequency, interval := range InquiryDigestFrequencies {
+		frequencies = append(frequencies, frequency)
+		cutoffs = append(cutoffs, now.Add(-interval))
+	}
+
+	query := `
+		SELECT n.agent_id, n.inquiry_emails, n.urgent_immediately, n.payment_emails, n.last_digest_at, n.updated_at,
+		       u.name, u.email
+		FROM agent_notification_preferences n
+		INNER JOIN users u ON u.id = n.agent_id
+		INNER JOIN unnest($1::text[], $2::timestamptz[]) AS due(frequency, cutoff) ON due.frequency = n.inquiry_emails
+		WHERE n.last_digest_at <= due.cutoff AND u.activated = true AND u.role = 'agent'
+		ORDER BY n.last_digest_at ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(frequencies), pq.Array(formatTimes(cutoffs)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	digests := []*InquiryDigest{}
+
+	for rows.Next() {
+		var digest InquiryDigest
+		err := rows.Scan(
+			&digest.AgentID,
+			&digest.InquiryEmails,
+			&digest.UrgentImmediately,
+			&digest.PaymentEmails,
+			&digest.LastDigestAt,
+			&digest.UpdatedAt,
+			&digest.AgentName,
+			&digest.AgentEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, &digest)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return digests, nil
+}
+
+// MarkDigestSent records that the agent's digest covers inquiries up to sentAt
+func (m NotificationPreferenceModel) MarkDigestSent(agentID int64, sentAt time.Time) error {
+	query := `
+		UPDATE agent_notification_preferences
+		SET last_digest_at = $1
+		WHERE agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, sentAt, agentID)
+	return err
+}
+
+// GetForDigest retrieves the agent's inquiries created after since and up to until,
+// newest first, leaving out urgent ones already emailed when skipUrgent is true. It
+// returns at most limit inquiries and the total count.
+func (m InquiryModel) GetForDigest(agentID int64, since, until time.Time, skipUrgent bool, limit int) ([]*Inquiry, int, error) {
+	query := `
+		SELECT count(*) OVER(), i.id, i.name, i.inquiry_type, i.priority, i.message, i.created_at,
+		       p.title AS property_title
+		FROM inquiries i
+		INNER JOIN properties p ON i.property_id = p.id
+		WHERE i.agent_id = $1
+		AND i.created_at > $2 AND i.created_at <= $3
+		AND NOT ($4 AND i.priority IN ('high', 'urgent'))
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT $5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID, since, until, skipUrgent, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	inquiries := []*Inquiry{}
+	total := 0
+
+	for rows.Next() {
+		var inquiry Inquiry
+		err := rows.Scan(
+			&total,
+			&inquiry.ID,
+			&inquiry.Name,
+			&inquiry.InquiryType,
+			&inquiry.Priority,
+			&inquiry.Message,
+			&inquiry.CreatedAt,
+			&inquiry.PropertyTitle,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		inquiries = append(inquiries, &inquiry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return inquiries, total, nil
+}
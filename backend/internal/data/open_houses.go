@@ -0,0 +1,355 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// OpenHouse is a public viewing slot on a property that many users can attend
+type OpenHouse struct {
+	ID         int64     `json:"id"`
+	PropertyID int64     `json:"property_id"`
+	AgentID    int64     `json:"agent_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Capacity   int       `json:"capacity"`
+	Attendees  int       `json:"attendees"`
+	CreatedAt  time.Time `json:"created_at"`
+	Version    int       `json:"version"`
+}
+
+// OpenHouseAttendee is a user who has RSVP'd to an open house
+type OpenHouseAttendee struct {
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"rsvped_at"`
+}
+
+// MaxOpenHouseCapacity caps how many attendees a single open house may take
+const MaxOpenHouseCapacity = 500
+
+var (
+	ErrOpenHouseNotFound = errors.New("open house not found")
+	ErrOpenHouseFull     = errors.New("open house is full")
+	ErrOpenHouseEnded    = errors.New("open house has ended")
+	ErrAlreadyRSVPd      = errors.New("already rsvp'd to this open house")
+	ErrRSVPNotFound      = errors.New("rsvp not found")
+)
+
+// ValidateOpenHouse validates open house fields
+func ValidateOpenHouse(v *validator.Validator, openHouse *OpenHouse) {
+	v.Check(!openHouse.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(!openHouse.EndsAt.IsZero(), "ends_at", "must be provided")
+	v.Check(openHouse.StartsAt.After(time.Now()), "starts_at", "must be in the future")
+	v.Check(openHouse.EndsAt.After(openHouse.StartsAt), "ends_at", "must be after starts_at")
+	v.Check(openHouse.EndsAt.Sub(openHouse.StartsAt) <= 12*time.Hour, "ends_at", "open house must not last more than 12 hours")
+	v.Check(openHouse.Capacity > 0, "capacity", "must be greater than zero")
+	v.Check(openHouse.Capacity <= MaxOpenHouseCapacity, "capacity", "must not be more than 500")
+}
+
+// OpenHouseModel wraps database operations for open houses and their RSVPs
+type OpenHouseModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new open house
+func (m OpenHouseModel) Insert(openHouse *OpenHouse) error {
+	query := `
+		INSERT INTO open_houses (property_id, agent_id, starts_at, ends_at, capacity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{openHouse.PropertyID, openHouse.AgentID, openHouse.StartsAt, openHouse.EndsAt, openHouse.Capacity}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&openHouse.ID, &openHouse.CreatedAt, &openHouse.Version)
+}
+
+// Get retrieves an open house with its current attendee count
+func (m OpenHouseModel) Get(id int64) (*OpenHouse, error) {
+	if id < 1 {
+		return nil, ErrOpenHouseNotFound
+	}
+
+	query := `
+		SELECT oh.id, oh.property_id, oh.agent_id, oh.starts_at, oh.ends_at, oh.capacity,
+		       (SELECT COUNT(*) FROM open_house_attendees a WHERE a.open_house_id = oh.id),
+		       oh.created_at, oh.version
+		FROM open_houses oh
+		WHERE oh.id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var openHouse OpenHouse
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&openHouse.ID,
+		&openHouse.PropertyID,
+		&openHouse.AgentID,
+		&openHouse.StartsAt,
+		&openHouse.EndsAt,
+		&openHouse.Capacity,
+		&openHouse.Attendees,
+		&openHouse.CreatedAt,
+		&openHouse.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOpenHouseNotFound
+		}
+		return nil, err
+	}
+
+	return &openHouse, nil
+}
+
+// GetUpcomingForProperty retrieves the property's open houses that have not yet ended
+func (m OpenHouseModel) GetUpcomingForProperty(propertyID int64) ([]*OpenHouse, error) {
+	query := `
+		SELECT oh.id, oh.property_id, oh.agent_id, oh.starts_at, oh.ends_at, oh.capacity,
+		       (SELECT COUNT(*) FROM open_house_attendees a WHERE a.open_house_id = oh.id),
+		       oh.created_at, oh.version
+		FROM open_houses oh
+		WHERE oh.property_id = $1
+		AND oh.ends_at > NOW()
+		ORDER BY oh.starts_at ASC, oh.id ASC`
+
+	return m.list(query, propertyID)
+}
+
+// GetAllForAgent retrieves the agent's open houses, including past ones when requested
+func (m OpenHouseModel) GetAllForAgent(agentID int64, includePast bool) ([]*OpenHouse, error) {
+	query := `
+		SELECT oh.id, oh.property_id, oh.agent_id, oh.starts_at, oh.ends_at, oh.capacity,
+		       (SELECT COUNT(*) FROM open_house_attendees a WHERE a.open_house_id = oh.id),
+		       oh.created_at, oh.version
+		FROM open_houses oh
+		WHERE oh.agent_id = $1
+		AND ($2 OR oh.ends_at > NOW())
+		ORDER BY oh.starts_at ASC, oh.id ASC`
+
+	return m.list(query, agentID, includePast)
+}
+
+// list runs an open house query and scans every row
+func (m OpenHouseModel) list(query string, args ...interface{}) ([]*OpenHouse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	openHouses := []*OpenHouse{}
+
+	for rows.Next() {
+		var openHouse OpenHouse
+		err := rows.Scan(
+			&openHouse.ID,
+			&openHouse.PropertyID,
+			&openHouse.AgentID,
+			&openHouse.StartsAt,
+			&openHouse.EndsAt,
+			&openHouse.Capacity,
+			&openHouse.Attendees,
+			&openHouse.CreatedAt,
+			&openHouse.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		openHouses = append(openHouses, &openHouse)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return openHouses, nil
+}
+
+// Update saves new times and capacity using optimistic locking
+func (m OpenHouseModel) Update(openHouse *OpenHouse) error {
+	query := `
+		UPDATE open_houses
+		SET starts_at = $1, ends_at = $2, capacity = $3, version = version + 1
+		WHERE id = $4 AND agent_id = $5 AND version = $6
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{
+		openHouse.StartsAt,
+		openHouse.EndsAt,
+		openHouse.Capacity,
+		openHouse.ID,
+		openHouse.AgentID,
+		openHouse.Version,
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&openHouse.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes an open house belonging to the given agent, along with its RSVPs
+func (m OpenHouseModel) Delete(id, agentID int64) error {
+	if id < 1 {
+		return ErrOpenHouseNotFound
+	}
+
+	query := `
+		DELETE FROM open_houses
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrOpenHouseNotFound
+	}
+
+	return nil
+}
+
+// RSVP adds the user to the open house's attendees. The open house row is locked
+// while the attendee count is checked so concurrent RSVPs cannot exceed capacity.
+func (m OpenHouseModel) RSVP(openHouseID, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var capacity int
+	var endsAt time.Time
+
+	err = tx.QueryRowContext(ctx, `SELECT capacity, ends_at FROM open_houses WHERE id = $1 FOR UPDATE`, openHouseID).Scan(&capacity, &endsAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOpenHouseNotFound
+		}
+		return err
+	}
+
+	if !endsAt.After(time.Now()) {
+		return ErrOpenHouseEnded
+	}
+
+	var attendees int
+	var attending bool
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(BOOL_OR(user_id = $2), false)
+		FROM open_house_attendees
+		WHERE open_house_id = $1`, openHouseID, userID).Scan(&attendees, &attending)
+	if err != nil {
+		return err
+	}
+
+	if attending {
+		return ErrAlreadyRSVPd
+	}
+	if attendees >= capacity {
+		return ErrOpenHouseFull
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO open_house_attendees (open_house_id, user_id) VALUES ($1, $2)`, openHouseID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelRSVP removes the user from the open house's attendees
+func (m OpenHouseModel) CancelRSVP(openHouseID, userID int64) error {
+	query := `
+		DELETE FROM open_house_attendees
+		WHERE open_house_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, openHouseID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRSVPNotFound
+	}
+
+	return nil
+}
+
+// GetAttendees lists the users who have RSVP'd to an open house, earliest first
+func (m OpenHouseModel) GetAttendees(openHouseID int64) ([]*OpenHouseAttendee, error) {
+	query := `
+		SELECT u.id, u.name, u.email, a.created_at
+		FROM open_house_attendees a
+		INNER JOIN users u ON u.id = a.user_id
+		WHERE a.open_house_id = $1
+		ORDER BY a.created_at ASC, u.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, openHouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attendees := []*OpenHouseAttendee{}
+
+	for rows.Next() {
+		var attendee OpenHouseAttendee
+		err := rows.Scan(&attendee.UserID, &attendee.Name, &attendee.Email, &attendee.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, &attendee)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attendees, nil
+}
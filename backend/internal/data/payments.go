@@ -36,8 +36,12 @@ type PaymentModel struct {
 	DB *sql.DB
 }
 
+// TestPaymentFailAmount is the amount that makes a test-provider payment fail
+const TestPaymentFailAmount = 13
+
 // ValidatePayment validates payment fields
-func ValidatePayment(v *validator.Validator, payment *Payment) {
+// The test provider is only accepted when allowTestProvider is set (non-production)
+func ValidatePayment(v *validator.Validator, payment *Payment, allowTestProvider bool) {
 	v.Check(payment.AgentID > 0, "agent_id", "must be provided")
 	v.Check(payment.PropertyID > 0, "property_id", "must be provided")
 	v.Check(payment.Amount > 0, "amount", "must be greater than zero")
@@ -45,7 +49,12 @@ func ValidatePayment(v *validator.Validator, payment *Payment) {
 	v.Check(payment.PaymentProvider != "", "payment_provider", "must be provided")
 
 	validProviders := []string{"mpesa", "bank", "card"}
-	v.Check(validator.In(payment.PaymentProvider, validProviders...), "payment_provider", "must be mpesa, bank, or card")
+	if allowTestProvider {
+		validProviders = append(validProviders, "test")
+		v.Check(validator.In(payment.PaymentProvider, validProviders...), "payment_provider", "must be mpesa, bank, card, or test")
+	} else {
+		v.Check(validator.In(payment.PaymentProvider, validProviders...), "payment_provider", "must be mpesa, bank, or card")
+	}
 
 	// Provider-specific validations
 	if payment.PaymentProvider == "mpesa" {
@@ -60,19 +69,64 @@ func ValidatePayment(v *validator.Validator, payment *Payment) {
 
 // Create inserts a new payment record
 func (m PaymentModel) Create(payment *Payment) error {
-	query := `
-		INSERT INTO payments (
-			agent_id, property_id, amount, payment_method, payment_provider,
-			transaction_id, phone_number, account_reference, transaction_desc,
-			merchant_request_id, checkout_request_id, status
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, created_at, updated_at, version`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, insertPaymentQuery, insertPaymentArgs(payment)...).Scan(
+		&payment.ID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+		&payment.Version,
+	)
+}
 
+// CreateFeatured inserts a payment that settled as soon as it was taken and features its
+// property in the same transaction. When no featured slot is free nothing is written and
+// ErrFeaturedSlotsFull is returned, so the payment is never recorded without its slot.
+func (m PaymentModel) CreateFeatured(payment *Payment, policy FeaturePolicy) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFeaturedSlots(ctx, tx); err != nil {
+		return err
+	}
+
+	if err = featureInTx(ctx, tx, payment.PropertyID, policy); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, insertPaymentQuery, insertPaymentArgs(payment)...).Scan(
+		&payment.ID,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+		&payment.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SQL to insert a payment, shared by Create and CreateFeatured
+const insertPaymentQuery = `
+	INSERT INTO payments (
+		agent_id, property_id, amount, payment_method, payment_provider,
+		transaction_id, phone_number, account_reference, transaction_desc,
+		merchant_request_id, checkout_request_id, status
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	RETURNING id, created_at, updated_at, version`
+
+// insertPaymentArgs returns the payment's values for insertPaymentQuery
+func insertPaymentArgs(payment *Payment) []interface{} {
+	return []interface{}{
 		payment.AgentID,
 		payment.PropertyID,
 		payment.Amount,
@@ -86,19 +140,6 @@ func (m PaymentModel) Create(payment *Payment) error {
 		nullString(payment.CheckoutRequestID),
 		payment.Status,
 	}
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
-		&payment.ID,
-		&payment.CreatedAt,
-		&payment.UpdatedAt,
-		&payment.Version,
-	)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
 }
 
 // Get retrieves a payment by ID
@@ -215,22 +256,11 @@ func (m PaymentModel) GetByCheckoutRequestID(checkoutRequestID string) (*Payment
 
 // UpdateStatus updates payment status and related fields
 func (m PaymentModel) UpdateStatus(id int64, status, transactionID, resultCode, resultDesc string, version int32) error {
-	query := `
-		UPDATE payments
-		SET status = $1,
-		    transaction_id = COALESCE(NULLIF($2, ''), transaction_id),
-		    result_code = $3,
-		    result_desc = $4,
-		    updated_at = NOW(),
-		    version = version + 1
-		WHERE id = $5 AND version = $6
-		RETURNING version`
-
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var newVersion int32
-	err := m.DB.QueryRowContext(ctx, query, status, transactionID, resultCode, resultDesc, id, version).Scan(&newVersion)
+	err := m.DB.QueryRowContext(ctx, updatePaymentStatusQuery, status, transactionID, resultCode, resultDesc, id, version).Scan(&newVersion)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -242,6 +272,82 @@ func (m PaymentModel) UpdateStatus(id int64, status, transactionID, resultCode,
 	return nil
 }
 
+// Results recorded on a payment that was failed because its property couldn't be
+// featured once the payment went through
+const (
+	FeaturedSlotsFullDesc = "No featured slot was free when the payment went through, so it will be refunded."
+	PropertyRemovedDesc   = "The listing was removed before the payment went through, so it will be refunded."
+)
+
+// CompleteFeatured settles a pending payment as completed and features its property in
+// the same transaction, updating payment to match. When no featured slot is free, or the
+// property is gone, the payment is failed instead and ErrFeaturedSlotsFull or
+// ErrPropertyNotFound returned, so a payment is never completed without its slot. It
+// returns ErrEditConflict if the payment changed since it was read.
+func (m PaymentModel) CompleteFeatured(payment *Payment, transactionID, resultCode, resultDesc string, policy FeaturePolicy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockFeaturedSlots(ctx, tx); err != nil {
+		return err
+	}
+
+	status := "completed"
+
+	featureErr := featureInTx(ctx, tx, payment.PropertyID, policy)
+	switch {
+	case errors.Is(featureErr, ErrFeaturedSlotsFull):
+		status = "failed"
+		resultDesc = FeaturedSlotsFullDesc
+	case errors.Is(featureErr, ErrPropertyNotFound):
+		status = "failed"
+		resultDesc = PropertyRemovedDesc
+	case featureErr != nil:
+		return featureErr
+	}
+
+	var newVersion int32
+	err = tx.QueryRowContext(ctx, updatePaymentStatusQuery, status, transactionID, resultCode, resultDesc, payment.ID, payment.Version).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	payment.Status = status
+	payment.ResultCode = resultCode
+	payment.ResultDesc = resultDesc
+	payment.Version = newVersion
+	if transactionID != "" {
+		payment.TransactionID = transactionID
+	}
+
+	return featureErr
+}
+
+// SQL to settle a payment, guarded by its version, shared by UpdateStatus and CompleteFeatured
+const updatePaymentStatusQuery = `
+	UPDATE payments
+	SET status = $1,
+	    transaction_id = COALESCE(NULLIF($2, ''), transaction_id),
+	    result_code = $3,
+	    result_desc = $4,
+	    updated_at = NOW(),
+	    version = version + 1
+	WHERE id = $5 AND version = $6
+	RETURNING version`
+
 // GetAllForAgent retrieves all payments for a specific agent with pagination
 func (m PaymentModel) GetAllForAgent(agentID int64, filters Filters) ([]*Payment, Metadata, error) {
 	query := fmt.Sprintf(`
@@ -6,10 +6,34 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/codercollo/property/backend/internal/validator"
 )
 
 var ErrInvalidPriceFormat = errors.New("invalid price format")
 
+// PriceRange bounds listing prices; a zero Min or Max leaves that side open
+type PriceRange struct {
+	Min float64
+	Max float64
+}
+
+// PriceAggregates summarises prices across every listing matching a query, not just one page
+type PriceAggregates struct {
+	Min     float64 `json:"min_price"`
+	Max     float64 `json:"max_price"`
+	Average float64 `json:"average_price"`
+}
+
+// ValidatePriceRange checks the bounds are not negative and not reversed
+func ValidatePriceRange(v *validator.Validator, r PriceRange) {
+	v.Check(r.Min >= 0, "min_price", "must not be negative")
+	v.Check(r.Max >= 0, "max_price", "must not be negative")
+	if r.Max > 0 {
+		v.Check(r.Min <= r.Max, "min_price", "must not be greater than max_price")
+	}
+}
+
 // Price represents a property's price and formats it as a JSON string(KSH)
 type Price float64
 
@@ -5,7 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/codercollo/property/backend/internal/validator"
 	"github.com/lib/pq"
@@ -16,7 +20,9 @@ import (
 type Property struct {
 	ID           int64         `json:"id"`
 	CreatedAt    time.Time     `json:"-"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 	Title        string        `json:"title,"`
+	Description  string        `json:"description,omitempty"`
 	YearBuilt    int32         `json:"year_built,omitempty"`
 	Area         Area          `json:"area,omitempty"`
 	Bedrooms     Bedrooms      `json:"bedrooms,omitempty"`
@@ -30,8 +36,165 @@ type Property struct {
 	FeaturedAt   *time.Time    `json:"featured_at,omitempty"`
 	AgentID      sql.NullInt64 `json:"agent_id,omitempty"`
 	Version      int32         `json:"version"`
+
+	// When a featured listing drops back to standard; nil keeps it featured until unfeatured
+	FeaturedUntil *time.Time `json:"featured_until,omitempty"`
+
+	// Optional structured attributes
+	EnergyRating  *string `json:"energy_rating,omitempty"`
+	ParkingSpaces *int32  `json:"parking_spaces,omitempty"`
+	LotSize       *int32  `json:"lot_size,omitempty"`
+	HeatingType   *string `json:"heating_type,omitempty"`
+	Furnished     *string `json:"furnished,omitempty"`
+
+	// Optional map coordinates, always set together
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+
+	// Listing lifetime; DaysUntilExpiry is only filled in for the owning agent
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	DaysUntilExpiry *int       `json:"days_until_expiry,omitempty"`
+
+	// Moderation status; listings with a future PublishAt stay "scheduled" until released
+	Status    string     `json:"status,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+
+	// Set on create when the listing skips moderation and is approved once it has a cover image
+	AutoApprove bool `json:"-"`
+
+	// Set when the agent last bumped the listing to the top of the newest sort
+	BumpedAt *time.Time `json:"bumped_at,omitempty"`
+
+	// URL-friendly title plus id, changed whenever the title changes
+	Slug string `json:"slug,omitempty"`
+
+	// Set when the listing was deleted; deleted listings are only visible to admins
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Text search rank, only filled in for listings matched by a title search
+	Relevance *float64 `json:"relevance,omitempty"`
+
+	// Completeness score, only filled in for the owning agent
+	Quality *ListingQuality `json:"quality,omitempty"`
+}
+
+// Listing statuses set on create: scheduled listings wait for their publish_at time,
+// the rest wait for moderation unless the agent is trusted to skip it
+const (
+	PropertyStatusScheduled = "scheduled"
+	PropertyStatusPending   = "pending"
+	PropertyStatusApproved  = "approved"
+)
+
+// MaxDescriptionLength caps the long-form listing description
+const MaxDescriptionLength = 5000
+
+// MaxPublishDelay is how far ahead a listing may be scheduled
+const MaxPublishDelay = 365 * 24 * time.Hour
+
+// ValidatePublishAt checks a requested publish time is in the future and not too far ahead
+func ValidatePublishAt(v *validator.Validator, publishAt time.Time) {
+	v.Check(publishAt.After(time.Now()), "publish_at", "must be in the future")
+	v.Check(publishAt.Before(time.Now().Add(MaxPublishDelay)), "publish_at", "must be within one year")
+}
+
+// IsScheduled reports whether the listing is waiting to be published
+func (p *Property) IsScheduled() bool {
+	return p.Status == PropertyStatusScheduled
+}
+
+// IsPublic reports whether the listing may be shown to anyone: it has passed moderation
+// and is neither expired nor deleted. It is the check publicPropertyCondition makes in SQL.
+func (p *Property) IsPublic() bool {
+	return p.Status == PropertyStatusApproved && !p.IsExpired() && p.DeletedAt == nil
 }
 
+// publicPropertyCondition returns the condition matching the listings every public query
+// may return, the same ones Property.IsPublic accepts, with the properties columns
+// qualified by alias when one is given
+func publicPropertyCondition(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return fmt.Sprintf("(%[1]sstatus = '%[2]s' AND (%[1]sexpires_at IS NULL OR %[1]sexpires_at > NOW()) AND %[1]sdeleted_at IS NULL)",
+		alias, PropertyStatusApproved)
+}
+
+// propertyColumnNames lists the properties columns in the order scanned by Property.scanDest
+var propertyColumnNames = []string{
+	"id", "created_at", "updated_at", "title", "year_built", "area", "bedrooms", "bathrooms",
+	"floor", "price", "location", "property_type", "features", "images", "featured_at", "agent_id", "version",
+	"energy_rating", "parking_spaces", "lot_size", "heating_type", "furnished", "latitude", "longitude", "expires_at",
+	"status", "publish_at", "bumped_at", "slug", "deleted_at", "description", "featured_until",
+}
+
+// propertyColumns returns the select list for a full Property, qualified by alias when one is given
+func propertyColumns(alias string) string {
+	return qualifyColumns(alias, propertyColumnNames)
+}
+
+// scanDest returns the scan destinations matching propertyColumns
+func (p *Property) scanDest() []interface{} {
+	return []interface{}{
+		&p.ID,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.Title,
+		&p.YearBuilt,
+		&p.Area,
+		&p.Bedrooms,
+		&p.Bathrooms,
+		&p.Floor,
+		&p.Price,
+		&p.Location,
+		&p.PropertyType,
+		pq.Array(&p.Features),
+		pq.Array(&p.Images),
+		&p.FeaturedAt,
+		&p.AgentID,
+		&p.Version,
+		&p.EnergyRating,
+		&p.ParkingSpaces,
+		&p.LotSize,
+		&p.HeatingType,
+		&p.Furnished,
+		&p.Latitude,
+		&p.Longitude,
+		&p.ExpiresAt,
+		&p.Status,
+		&p.PublishAt,
+		&p.BumpedAt,
+		&p.Slug,
+		&p.DeletedAt,
+		&p.Description,
+		&p.FeaturedUntil,
+	}
+}
+
+// SetDaysUntilExpiry fills in DaysUntilExpiry relative to now, never below zero
+func (p *Property) SetDaysUntilExpiry(now time.Time) {
+	if p.ExpiresAt == nil {
+		return
+	}
+	days := int(p.ExpiresAt.Sub(now).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	p.DaysUntilExpiry = &days
+}
+
+// IsExpired reports whether the listing's lifetime has passed
+func (p *Property) IsExpired() bool {
+	return p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now())
+}
+
+// Allowed values for the optional structured property attributes
+var (
+	EnergyRatings   = []string{"A", "B", "C", "D", "E", "F", "G"}
+	HeatingTypes    = []string{"none", "gas", "electric", "oil", "heat_pump", "solar", "district", "other"}
+	FurnishedStates = []string{"furnished", "semi_furnished", "unfurnished"}
+)
+
 // PropertyStats holds statistics about an agent's properties
 type PropertyStats struct {
 	TotalProperties    int `json:"total_properties"`
@@ -39,12 +202,49 @@ type PropertyStats struct {
 	PendingProperties  int `json:"pending_properties"`
 }
 
-// ValidateProperty checks that all fields of a Property are valid
-func ValidateProperty(v *validator.Validator, property *Property) {
+// Every listing needs at least one feature and one image
+const (
+	MinPropertyFeatures = 1
+	MinPropertyImages   = 1
+)
+
+// ListLimit is the allowed length of a list field
+type ListLimit struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// PropertyLimits are the list limits ValidateProperty enforces, published so clients
+// can apply the same limits
+type PropertyLimits struct {
+	Features ListLimit `json:"features"`
+	Images   ListLimit `json:"images"`
+}
+
+// NewPropertyLimits returns the list limits for the configured maximum features and images
+func NewPropertyLimits(maxFeatures, maxImages int) PropertyLimits {
+	return PropertyLimits{
+		Features: ListLimit{Min: MinPropertyFeatures, Max: maxFeatures},
+		Images:   ListLimit{Min: MinPropertyImages, Max: maxImages},
+	}
+}
+
+// Longest single feature and image reference a listing may have
+const (
+	MaxFeatureLength = 100
+	MaxImageLength   = 2000
+)
+
+// ValidateProperty checks that all fields of a Property are valid. Features and
+// images are trimmed of surrounding whitespace before they are checked.
+func ValidateProperty(v *validator.Validator, property *Property, limits PropertyLimits) {
 	// Validate title
 	v.Check(property.Title != "", "title", "must be provided")
 	v.Check(len(property.Title) <= 500, "title", "must not be more than 500 bytes long")
 
+	// Validate optional description
+	v.Check(utf8.RuneCountInString(property.Description) <= MaxDescriptionLength, "description", fmt.Sprintf("must not be more than %d characters long", MaxDescriptionLength))
+
 	// Validate year built
 	v.Check(property.YearBuilt != 0, "year_built", "must be provided")
 	v.Check(property.YearBuilt >= 1800, "year_built", "must be greater than 1800")
@@ -63,37 +263,134 @@ func ValidateProperty(v *validator.Validator, property *Property) {
 
 	// Validate features list
 	v.Check(property.Features != nil, "features", "must be provided")
-	v.Check(len(property.Features) >= 1, "features", "must contain at least 1 feature")
-	v.Check(len(property.Features) <= 10, "features", "must not contain more than 10 features")
+	v.Check(len(property.Features) >= limits.Features.Min, "features", fmt.Sprintf("must contain at least %d feature", limits.Features.Min))
+	v.Check(len(property.Features) <= limits.Features.Max, "features", fmt.Sprintf("must not contain more than %d features", limits.Features.Max))
+	for i, feature := range property.Features {
+		property.Features[i] = strings.TrimSpace(feature)
+		v.Check(property.Features[i] != "", "features", "must not contain blank values")
+		v.Check(utf8.RuneCountInString(property.Features[i]) <= MaxFeatureLength, "features", fmt.Sprintf("must not contain values longer than %d characters", MaxFeatureLength))
+	}
 	v.Check(validator.Unique(property.Features), "features", "must not contain duplicate values")
 
 	// Validate images list
 	v.Check(property.Images != nil, "images", "must be provided")
-	v.Check(len(property.Images) >= 1, "images", "must contain at least 1 image")
-	v.Check(len(property.Images) <= 10, "images", "must not contain more than 10 images")
+	v.Check(len(property.Images) >= limits.Images.Min, "images", fmt.Sprintf("must contain at least %d image", limits.Images.Min))
+	v.Check(len(property.Images) <= limits.Images.Max, "images", fmt.Sprintf("must not contain more than %d images", limits.Images.Max))
+	for i, image := range property.Images {
+		property.Images[i] = strings.TrimSpace(image)
+		v.Check(property.Images[i] != "", "images", "must not contain blank values")
+		v.Check(len(property.Images[i]) <= MaxImageLength, "images", fmt.Sprintf("must not contain values longer than %d bytes", MaxImageLength))
+		v.Check(validImageReference(property.Images[i]), "images", "must contain only http or https URLs or file paths")
+	}
 	v.Check(validator.Unique(property.Images), "images", "must not contain duplicate values")
+
+	// Validate optional structured attributes when present
+	if property.EnergyRating != nil {
+		v.Check(validator.In(*property.EnergyRating, EnergyRatings...), "energy_rating", "must be a rating from A to G")
+	}
+	if property.ParkingSpaces != nil {
+		v.Check(*property.ParkingSpaces >= 0, "parking_spaces", "must be zero or more")
+		v.Check(*property.ParkingSpaces <= 100, "parking_spaces", "must not be more than 100")
+	}
+	if property.LotSize != nil {
+		v.Check(*property.LotSize > 0, "lot_size", "must be a positive value")
+	}
+	if property.HeatingType != nil {
+		v.Check(validator.In(*property.HeatingType, HeatingTypes...), "heating_type", "must be one of none, gas, electric, oil, heat_pump, solar, district, other")
+	}
+	if property.Furnished != nil {
+		v.Check(validator.In(*property.Furnished, FurnishedStates...), "furnished", "must be furnished, semi_furnished or unfurnished")
+	}
+
+	// Validate coordinates when present
+	v.Check((property.Latitude == nil) == (property.Longitude == nil), "coordinates", "latitude and longitude must be provided together")
+	if property.Latitude != nil {
+		v.Check(*property.Latitude >= -90 && *property.Latitude <= 90, "latitude", "must be between -90 and 90")
+	}
+	if property.Longitude != nil {
+		v.Check(*property.Longitude >= -180 && *property.Longitude <= 180, "longitude", "must be between -180 and 180")
+	}
+}
+
+// validImageReference reports whether an image is an http or https URL, or a path
+// without a scheme, whitespace or control characters
+func validImageReference(image string) bool {
+	if strings.IndexFunc(image, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return false
+	}
+
+	u, err := url.Parse(image)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return u.Host == "" && u.Path != ""
+	}
+	return (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
 }
 
 // PropertyModel wraps a sql.DB connection pool for properties table operations
 type PropertyModel struct {
-	DB *sql.DB
+	DB        *sql.DB
+	Locations LocationSearch
 }
 
 // Insert adds a new property listing
 func (p PropertyModel) Insert(property *Property) error {
-	//SQL query for inserting a new property and returning system-generated fields.
-	query := `
-		INSERT INTO properties 
-		(title, year_built, area, bedrooms, bathrooms, floor, price, location, property_type, features, images, agent_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, created_at, version
-                `
 	//Create a context with a 3 second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	//Arguments for the SQL placeholder
-	args := []interface{}{
+	//Execute the query and scan the returned values into the property struct
+	return p.DB.QueryRowContext(ctx, insertPropertyQuery, property.insertArgs()...).Scan(property.insertDest()...)
+}
+
+// InsertBatch adds several properties in one transaction; if any insert fails none are kept
+func (p PropertyModel) InsertBatch(properties []*Property) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertPropertyQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, property := range properties {
+		err := stmt.QueryRowContext(ctx, property.insertArgs()...).Scan(property.insertDest()...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertPropertyQuery inserts a property and returns its system-generated fields.
+// The id is drawn up front so the slug can include it.
+const insertPropertyQuery = `
+	WITH next AS (SELECT nextval(pg_get_serial_sequence('properties', 'id')) AS id)
+	INSERT INTO properties
+	(id, title, year_built, area, bedrooms, bathrooms, floor, price, location, property_type, features, images, agent_id,
+	 energy_rating, parking_spaces, lot_size, heating_type, furnished, latitude, longitude, expires_at,
+	 publish_at, status, slug, auto_approve, description)
+	SELECT next.id, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+	       $21, CASE WHEN $21::timestamptz > NOW() THEN 'scheduled' ELSE 'pending' END,
+	       CASE WHEN $22 = '' THEN next.id::text ELSE $22 || '-' || next.id END,
+	       $23, $24
+	FROM next
+	RETURNING id, created_at, updated_at, version, status, slug`
+
+// insertArgs returns the arguments for insertPropertyQuery. New listings are "pending",
+// or "scheduled" when PublishAt is in the future.
+func (property *Property) insertArgs() []interface{} {
+	return []interface{}{
 		property.Title,
 		property.YearBuilt,
 		property.Area,
@@ -106,14 +403,31 @@ func (p PropertyModel) Insert(property *Property) error {
 		pq.Array(property.Features),
 		pq.Array(property.Images),
 		property.AgentID,
+		property.EnergyRating,
+		property.ParkingSpaces,
+		property.LotSize,
+		property.HeatingType,
+		property.Furnished,
+		property.Latitude,
+		property.Longitude,
+		property.ExpiresAt,
+		property.PublishAt,
+		slugBase(property.Title),
+		property.AutoApprove,
+		property.Description,
 	}
+}
 
-	//Execute the query and scan the returned values into the property struct
-	return p.DB.QueryRowContext(ctx, query, args...).Scan(
+// insertDest returns the scan targets for the columns insertPropertyQuery returns
+func (property *Property) insertDest() []interface{} {
+	return []interface{}{
 		&property.ID,
 		&property.CreatedAt,
+		&property.UpdatedAt,
 		&property.Version,
-	)
+		&property.Status,
+		&property.Slug,
+	}
 }
 
 // Get retrieves a property by ID
@@ -125,10 +439,9 @@ func (p PropertyModel) Get(id int64) (*Property, error) {
 
 	//SQL query to fetch a property by ID
 	query := `
-	SELECT id, created_at, title, year_built, area, bedrooms, bathrooms, floor, price, 
-	location, property_type, features, images, featured_at, agent_id, version
+	SELECT ` + propertyColumns("") + `
 	FROM properties
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	//Declare a Property struct to hold query results
 	var property Property
@@ -138,24 +451,7 @@ func (p PropertyModel) Get(id int64) (*Property, error) {
 	defer cancel()
 
 	//Execute the query together with passing the context and scan results
-	err := p.DB.QueryRowContext(ctx, query, id).Scan(
-		&property.ID,
-		&property.CreatedAt,
-		&property.Title,
-		&property.YearBuilt,
-		&property.Area,
-		&property.Bedrooms,
-		&property.Bathrooms,
-		&property.Floor,
-		&property.Price,
-		&property.Location,
-		&property.PropertyType,
-		pq.Array(&property.Features),
-		pq.Array(&property.Images),
-		&property.FeaturedAt,
-		&property.AgentID,
-		&property.Version,
-	)
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(property.scanDest()...)
 
 	//Handle errors
 	if err != nil {
@@ -172,78 +468,170 @@ func (p PropertyModel) Get(id int64) (*Property, error) {
 
 }
 
-// GetAll retrieves property listings with optional filtering, sorting, and pagination.
-// Returns a slice of Property pointers and pagination Metadata.
-func (p PropertyModel) GetAll(title, location, propertyType string, features []string, filters Filters) ([]*Property, Metadata, error) {
-	// SQL query with filtering, sorting, pagination, and total count using a window function
-	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, created_at, title, year_built, area, bedrooms, bathrooms,
-	       floor, price, location, property_type, features, images, featured_at, agent_id, version
+// searchMatch is the indexed text a title search matches against: the title and description
+const searchMatch = `(to_tsvector('simple', title) || to_tsvector('simple', description))`
+
+// searchDocument is the weighted text a title search is ranked against, so matches in
+// the title rank above matches in the location or description
+const searchDocument = `(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', COALESCE(location, '')), 'B') || setweight(to_tsvector('simple', description), 'C'))`
+
+// GetBatch retrieves several properties in one query, in the order of ids. Ids that
+// don't exist or were deleted are left out, so callers should map the results by ID.
+func (p PropertyModel) GetBatch(ids []int64) ([]*Property, error) {
+	properties := []*Property{}
+	if len(ids) == 0 {
+		return properties, nil
+	}
+
+	query := `
+	SELECT ` + propertyColumns("") + `
 	FROM properties
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND (features @> $2 OR $2 = '{}')
-	AND (location ILIKE '%%' || $3 || '%%' OR $3 = '')
-	AND (property_type ILIKE '%%' || $4 || '%%' OR $4 = '')
-	ORDER BY %s %s, id ASC
-	LIMIT $5 OFFSET $6`, filters.sortColumn(), filters.sortDirection())
+	WHERE id = ANY($1) AND deleted_at IS NULL
+	ORDER BY array_position($1::bigint[], id)`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	if features == nil {
-		features = []string{}
+	rows, err := p.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Arguments for placeholders
-	args := []interface{}{title, pq.Array(features), location, propertyType, filters.limit(), filters.offset()}
+	for rows.Next() {
+		var property Property
+		err := rows.Scan(property.scanDest()...)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, &property)
+	}
 
-	// Execute query
-	rows, err := p.DB.QueryContext(ctx, query, args...)
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+// SimilarPriceBand is how far, as a fraction of its price, a similar listing's price
+// may be from the source listing's
+const SimilarPriceBand = 0.2
+
+// GetSimilar finds up to limit public listings with the same property type and location
+// as the given property and a price within SimilarPriceBand of its price, closest price
+// first. A missing source property simply has no similar listings.
+func (p PropertyModel) GetSimilar(id int64, limit int) ([]*Property, error) {
+	query := `
+	WITH source AS (
+		SELECT property_type, location, price
+		FROM properties
+		WHERE id = $1 AND deleted_at IS NULL
+	)
+	SELECT ` + propertyColumns("p") + `
+	FROM properties p, source s
+	WHERE p.id <> $1
+	AND p.property_type = s.property_type
+	AND lower(p.location) = lower(s.location)
+	AND p.price BETWEEN s.price * (1 - $3::numeric) AND s.price * (1 + $3::numeric)
+	AND ` + publicPropertyCondition("p") + `
+	ORDER BY abs(p.price - s.price) ASC, p.id ASC
+	LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, id, limit, SimilarPriceBand)
 	if err != nil {
-		return nil, Metadata{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
 	properties := []*Property{}
-	totalListings := 0
 
-	// Scan rows into Property structs and capture total count
 	for rows.Next() {
 		var property Property
-		err := rows.Scan(
-			&totalListings,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
-		)
+		err := rows.Scan(property.scanDest()...)
 		if err != nil {
-			return nil, Metadata{}, err
+			return nil, err
 		}
 		properties = append(properties, &property)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, Metadata{}, err
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+// GetAll retrieves property listings with optional filtering, sorting, and pagination.
+// Returns a slice of Property pointers, pagination Metadata and price aggregates
+// over the whole filtered set. An empty propertyTypes slice matches every property type.
+func (p PropertyModel) GetAll(title, location string, propertyTypes, features []string, priceRange PriceRange, filters Filters) ([]*Property, Metadata, PriceAggregates, error) {
+	// Closer location matches come first when the location filter is fuzzy
+	orderBy := filters.orderBy()
+	if location != "" {
+		orderBy = p.Locations.order("$3") + orderBy
+	}
+
+	// SQL query with filtering, sorting, pagination, and total count and price aggregates using window functions
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(),
+	       COALESCE(min(price) OVER(), 0), COALESCE(max(price) OVER(), 0), COALESCE(round(avg(price) OVER()::numeric, 2), 0),
+	       %s, relevance
+	FROM properties,
+	LATERAL (SELECT CASE WHEN $1 = '' THEN NULL ELSE ts_rank(%s, plainto_tsquery('simple', $1)) END AS relevance) rank
+	WHERE (%s @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND (features @> $2 OR $2 = '{}')
+	AND (%s OR $3 = '')
+	AND (property_type = ANY($4) OR cardinality($4::text[]) = 0)
+	AND (price >= $5 OR $5 = 0)
+	AND (price <= $6 OR $6 = 0)
+	AND %s
+	ORDER BY %s, id ASC
+	LIMIT $7 OFFSET $8`, propertyColumns(""), searchDocument, searchMatch, p.Locations.match("$3"), publicPropertyCondition(""), orderBy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if features == nil {
+		features = []string{}
+	}
+	if propertyTypes == nil {
+		propertyTypes = []string{}
+	}
+
+	// Arguments for placeholders
+	args := []interface{}{title, pq.Array(features), location, pq.Array(propertyTypes), priceRange.Min, priceRange.Max, filters.limit(), filters.offset()}
+
+	properties := []*Property{}
+	totalListings := 0
+	var aggregates PriceAggregates
+
+	// Execute query, scanning rows into Property structs and capturing total count and aggregates
+	err := p.queryLocations(ctx, location != "", query, args, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var property Property
+			dest := []interface{}{&totalListings, &aggregates.Min, &aggregates.Max, &aggregates.Average}
+			dest = append(dest, property.scanDest()...)
+			err := rows.Scan(append(dest, &property.Relevance)...)
+			if err != nil {
+				return err
+			}
+			properties = append(properties, &property)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, PriceAggregates{}, err
 	}
 
 	// Generate pagination metadata
 	metadata := calculateMetadata(totalListings, filters.Page, filters.PageSize)
 
 	// Include the metadata struct when returning.
-	return properties, metadata, nil
+	return properties, metadata, aggregates, nil
 }
 
 // Update modifies an existing movie record
@@ -264,14 +652,49 @@ SET
     features = $10,
     images = $11,
     agent_id = $12,
+    energy_rating = $13,
+    parking_spaces = $14,
+    lot_size = $15,
+    heating_type = $16,
+    furnished = $17,
+    latitude = $18,
+    longitude = $19,
+    publish_at = $22,
+    slug = $23,
+    description = $24,
+    status = CASE
+        WHEN status = 'scheduled' AND $22::timestamptz > NOW() THEN 'scheduled'
+        WHEN status = 'scheduled' THEN 'pending'
+        ELSE status
+    END,
     version = version + 1
-WHERE id = $13 AND version = $14
-RETURNING version
+WHERE id = $20 AND version = $21 AND deleted_at IS NULL
+RETURNING version, updated_at, status
 `
-	//Create a context with a 3-second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	//Create a context with a 10-second timeout for the transaction
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	//Renaming a listing changes its slug, so the old slug is kept in the same transaction
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousSlug string
+	err = tx.QueryRowContext(ctx, `SELECT slug FROM properties WHERE id = $1 FOR UPDATE`, property.ID).Scan(&previousSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	slug := PropertySlug(property.Title, property.ID)
+
 	// Parameters for the query including version for optimistic locking
 	args := []interface{}{
 		property.Title,
@@ -286,12 +709,22 @@ RETURNING version
 		pq.Array(property.Features),
 		pq.Array(property.Images),
 		property.AgentID,
+		property.EnergyRating,
+		property.ParkingSpaces,
+		property.LotSize,
+		property.HeatingType,
+		property.Furnished,
+		property.Latitude,
+		property.Longitude,
 		property.ID,
 		property.Version,
+		property.PublishAt,
+		slug,
+		property.Description,
 	}
 
 	//Execute the update and scan the new version
-	err := p.DB.QueryRowContext(ctx, query, args...).Scan(&property.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&property.Version, &property.UpdatedAt, &property.Status)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -301,75 +734,129 @@ RETURNING version
 		}
 	}
 
+	err = recordSlugChange(ctx, tx, property.ID, previousSlug, slug)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
 	//Update succeeded
+	property.Slug = slug
 	return nil
 
 }
 
-// Delete removes a property by ID
-func (p PropertyModel) Delete(id int64) error {
+// Delete removes a property by ID, returning the id of the agent who owned it
+func (p PropertyModel) Delete(id int64) (sql.NullInt64, error) {
+	var agentID sql.NullInt64
+
 	//Return ErrPropertyNotFound if the ID is invalid
 	if id < 1 {
-		return ErrPropertyNotFound
+		return agentID, ErrPropertyNotFound
 
 	}
 
-	//SQL query to delete a property by ID
+	//Soft delete: the row is kept so inquiries, schedules and reviews still resolve
 	query := `
-        DELETE FROM properties
-        WHERE id = $1
+        UPDATE properties
+        SET deleted_at = NOW(), version = version + 1
+        WHERE id = $1 AND deleted_at IS NULL
+        RETURNING agent_id
     `
-	//Execute the query
-	results, err := p.DB.Exec(query, id)
-	if err != nil {
-		return err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	//check how many rows were affected
-	rowsAffected, err := results.RowsAffected()
+	//Execute the query, returning ErrPropertyNotFound if no row was deleted
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(&agentID)
 	if err != nil {
-		return nil
-	}
-
-	//Return ErrPropertyNotFound if no row was deleted
-	if rowsAffected == 0 {
-		return ErrPropertyNotFound
+		if errors.Is(err, sql.ErrNoRows) {
+			return agentID, ErrPropertyNotFound
+		}
+		return agentID, err
 	}
 
-	return nil
-
+	return agentID, nil
 }
 
-// Feature marks a property as featured by setting FeaturedAt to now
-func (p PropertyModel) Feature(id int64) error {
-	//invalid ID
+// RestoreProperty undoes a soft delete, returning ErrPropertyNotFound if the
+// property does not exist or is not deleted
+func (p PropertyModel) RestoreProperty(id int64) (*Property, error) {
 	if id < 1 {
-		return ErrPropertyNotFound
+		return nil, ErrPropertyNotFound
 	}
 
-	//SQL to update FeaturedAt and increment version
 	query := `
 		UPDATE properties
-		SET featured_at = NOW(), version = version + 1
-		WHERE id = $1
-		RETURNING version
-	`
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + propertyColumns("")
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var newVersion int32
+	var property Property
 
-	err := p.DB.QueryRowContext(ctx, query, id).Scan(&newVersion)
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(property.scanDest()...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return ErrPropertyNotFound
+			return nil, ErrPropertyNotFound
 		}
+		return nil, err
+	}
+
+	return &property, nil
+}
+
+// Feature marks a property as featured by setting FeaturedAt to now, until the policy's
+// Duration has passed. It returns ErrFeaturedSlotsFull when the featured slots caps leave
+// no room for it.
+func (p PropertyModel) Feature(id int64, policy FeaturePolicy) error {
+	//invalid ID
+	if id < 1 {
+		return ErrPropertyNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	if err = lockFeaturedSlots(ctx, tx); err != nil {
+		return err
+	}
+
+	if err = featureInTx(ctx, tx, id, policy); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// SQL to set or clear FeaturedAt and increment version, shared by the single and bulk paths.
+// A zero duration leaves featured_until unset.
+const (
+	featureQuery = `
+		UPDATE properties
+		SET featured_at = NOW(),
+		    featured_until = CASE WHEN $2::float8 > 0 THEN NOW() + make_interval(secs => $2::float8) END,
+		    version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING version`
+
+	unfeatureQuery = `
+		UPDATE properties
+		SET featured_at = NULL, featured_until = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING version`
+)
+
 // Unfeature clears FeaturedAt to mark a property as not featured
 func (p PropertyModel) Unfeature(id int64) error {
 	//invalid property
@@ -377,19 +864,12 @@ func (p PropertyModel) Unfeature(id int64) error {
 		return ErrPropertyNotFound
 	}
 
-	//SQL to clear FeaturedAt and increment version
-	query := `
-		UPDATE properties
-		SET featured_at = NULL, version = version + 1
-		WHERE id = $1
-		RETURNING version
-	`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var newVersion int32
 
-	err := p.DB.QueryRowContext(ctx, query, id).Scan(&newVersion)
+	err := p.DB.QueryRowContext(ctx, unfeatureQuery, id).Scan(&newVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrPropertyNotFound
@@ -400,23 +880,110 @@ func (p PropertyModel) Unfeature(id int64) error {
 	return nil
 }
 
+// PublishDue releases scheduled listings whose publish_at has passed, returning the ids
+// that were published. Listings set to skip moderation when they were created are
+// approved straight away if they have a cover image; the rest join the moderation queue,
+// where trusted ones are approved once their cover image is uploaded.
+func (p PropertyModel) PublishDue() ([]int64, error) {
+	query := `
+		UPDATE properties p
+		SET status = CASE WHEN p.auto_approve AND ` + hasPrimaryImage + ` THEN 'approved' ELSE 'pending' END,
+		    published_at = CASE WHEN p.auto_approve AND ` + hasPrimaryImage + ` THEN COALESCE(p.published_at, NOW()) ELSE p.published_at END,
+		    version = p.version + 1
+		WHERE p.status = 'scheduled' AND p.publish_at <= NOW() AND p.deleted_at IS NULL
+		RETURNING p.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// FeatureBulk features (or, when feature is false, unfeatures) every existing id in a
+// single transaction; ids that don't fit in the featured slots caps are reported as failed
+func (p PropertyModel) FeatureBulk(ids []int64, feature bool, policy FeaturePolicy) (*BulkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if feature {
+		if err = lockFeaturedSlots(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	results := NewBulkResult(len(ids))
+
+	for _, id := range ids {
+		if feature {
+			err = featureInTx(ctx, tx, id, policy)
+		} else {
+			var newVersion int32
+			err = tx.QueryRowContext(ctx, unfeatureQuery, id).Scan(&newVersion)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrPropertyNotFound
+			}
+		}
+
+		switch {
+		case err == nil:
+			results.Succeed(id)
+		case errors.Is(err, ErrPropertyNotFound):
+			results.Fail(id, BulkErrorNotFound, "property not found")
+		case errors.Is(err, ErrFeaturedSlotsFull):
+			results.Fail(id, BulkErrorSlotsFull, "featured slots are full")
+		default:
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // Replace the GetAllForAgent method in your property.go model file
 
 // GetAllForAgent retrieves all properties belonging to a specific agent
 func (p PropertyModel) GetAllForAgent(agentID int64, filters Filters) ([]*Property, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year_built, area, bedrooms, 
-		       bathrooms, floor, price, location, property_type, features, images, 
-		       featured_at, agent_id, version
+		SELECT count(*) OVER(), %s
 		FROM properties
-		WHERE agent_id = $1
+		WHERE agent_id = $1 AND deleted_at IS NULL
 		ORDER BY %s %s, id ASC
-		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $2 OFFSET $3`, propertyColumns(""), filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	args := []interface{}{agentID, filters.limit(), filters.offset()}
+	now := time.Now()
 
 	rows, err := p.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -429,28 +996,11 @@ func (p PropertyModel) GetAllForAgent(agentID int64, filters Filters) ([]*Proper
 
 	for rows.Next() {
 		var property Property
-		err := rows.Scan(
-			&totalRecords,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
-		)
+		err := rows.Scan(append([]interface{}{&totalRecords}, property.scanDest()...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		property.SetDaysUntilExpiry(now)
 		properties = append(properties, &property)
 	}
 
@@ -471,7 +1021,7 @@ func (p PropertyModel) GetStatsForAgent(agentID int64) (*PropertyStats, error) {
 			COUNT(CASE WHEN featured_at IS NOT NULL THEN 1 END) as featured,
 			0 as pending
 		FROM properties
-		WHERE agent_id = $1`
+		WHERE agent_id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -494,13 +1044,11 @@ func (p PropertyModel) GetStatsForAgent(agentID int64) (*PropertyStats, error) {
 // GetAllForAdmin retrieves all properties with filtering for admin view
 func (p PropertyModel) GetAllForAdmin(status string, filters Filters) ([]*Property, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year_built, area, bedrooms, 
-		       bathrooms, floor, price, location, property_type, features, images, 
-		       featured_at, agent_id, version
+		SELECT count(*) OVER(), %s
 		FROM properties
 		WHERE (status = $1 OR $1 = '')
 		ORDER BY %s %s, id DESC
-		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+		LIMIT $2 OFFSET $3`, propertyColumns(""), filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -518,25 +1066,7 @@ func (p PropertyModel) GetAllForAdmin(status string, filters Filters) ([]*Proper
 
 	for rows.Next() {
 		var property Property
-		err := rows.Scan(
-			&totalRecords,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
-		)
+		err := rows.Scan(append([]interface{}{&totalRecords}, property.scanDest()...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -554,22 +1084,39 @@ func (p PropertyModel) GetAllForAdmin(status string, filters Filters) ([]*Proper
 
 // ApproveProperty approves a property listing
 func (p PropertyModel) ApproveProperty(id, adminID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// Listings need a cover image before they can be approved
+	var hasPrimary bool
+	err := p.DB.QueryRowContext(ctx, `
+		SELECT `+hasPrimaryImage+`
+		FROM properties p
+		WHERE p.id = $1 AND p.deleted_at IS NULL`, id).Scan(&hasPrimary)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPropertyNotFound
+		}
+		return err
+	}
+	if !hasPrimary {
+		return ErrNoPrimaryImage
+	}
+
 	query := `
 		UPDATE properties
 		SET status = 'approved', 
 		    moderated_by = $1, 
 		    moderated_at = NOW(),
+		    published_at = COALESCE(published_at, NOW()),
 		    rejection_reason = NULL,
 		    version = version + 1
-		WHERE id = $2
+		WHERE id = $2 AND deleted_at IS NULL
 		RETURNING version`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
 	var newVersion int32
 
-	err := p.DB.QueryRowContext(ctx, query, adminID, id).Scan(&newVersion)
+	err = p.DB.QueryRowContext(ctx, query, adminID, id).Scan(&newVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrPropertyNotFound
@@ -580,6 +1127,41 @@ func (p PropertyModel) ApproveProperty(id, adminID int64) error {
 	return nil
 }
 
+// hasPrimaryImage is the condition that the listing aliased p has a cover image, which
+// it needs before it can be approved
+const hasPrimaryImage = `EXISTS (
+	SELECT 1 FROM property_media m
+	WHERE m.property_id = p.id AND m.media_type = 'image' AND m.is_primary = true
+)`
+
+// ApproveTrusted approves a pending listing that was set to skip moderation when it was
+// created, provided it now has a cover image. It reports whether the listing was approved.
+func (p PropertyModel) ApproveTrusted(id int64) (bool, error) {
+	query := `
+		UPDATE properties p
+		SET status = 'approved',
+		    published_at = COALESCE(published_at, NOW()),
+		    version = version + 1
+		WHERE p.id = $1 AND p.status = 'pending' AND p.auto_approve AND p.deleted_at IS NULL
+		AND ` + hasPrimaryImage + `
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newVersion int32
+
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
 // RejectProperty rejects a property listing with a reason
 func (p PropertyModel) RejectProperty(id, adminID int64, reason string) error {
 	query := `
@@ -589,7 +1171,7 @@ func (p PropertyModel) RejectProperty(id, adminID int64, reason string) error {
 		    moderated_at = NOW(),
 		    rejection_reason = $2,
 		    version = version + 1
-		WHERE id = $3
+		WHERE id = $3 AND deleted_at IS NULL
 		RETURNING version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
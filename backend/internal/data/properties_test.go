@@ -0,0 +1,182 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestValidatePropertyUsesGivenLimits(t *testing.T) {
+	property := func() *Property {
+		return &Property{
+			Title:        "Garden flat",
+			YearBuilt:    2010,
+			Area:         80,
+			Bedrooms:     2,
+			Price:        100000,
+			Location:     "Kilimani",
+			PropertyType: "apartment",
+			Features:     []string{"garden", "parking", "pool"},
+			Images:       []string{"https://example.com/1.jpg", "https://example.com/2.jpg"},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		limits     PropertyLimits
+		wantErrors []string
+	}{
+		{"within limits", NewPropertyLimits(3, 2), nil},
+		{"too many features", NewPropertyLimits(2, 2), []string{"features"}},
+		{"too many images", NewPropertyLimits(3, 1), []string{"images"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateProperty(v, property(), tt.limits)
+
+			if len(v.Errors) != len(tt.wantErrors) {
+				t.Fatalf("got errors %v; want errors for %v", v.Errors, tt.wantErrors)
+			}
+			for _, key := range tt.wantErrors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("missing error for %q; got %v", key, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePropertyLimitBoundaries(t *testing.T) {
+	limits := NewPropertyLimits(4, 3)
+
+	items := func(prefix string, n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("%s-%d", prefix, i)
+		}
+		return list
+	}
+
+	tests := []struct {
+		name      string
+		features  int
+		images    int
+		wantError string
+	}{
+		{"minimum features and images", MinPropertyFeatures, MinPropertyImages, ""},
+		{"maximum features and images", 4, 3, ""},
+		{"below minimum features", MinPropertyFeatures - 1, 1, "features"},
+		{"one feature over maximum", 5, 1, "features"},
+		{"below minimum images", 1, MinPropertyImages - 1, "images"},
+		{"one image over maximum", 1, 4, "images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property := &Property{
+				Title:        "Garden flat",
+				YearBuilt:    2010,
+				Area:         80,
+				Bedrooms:     2,
+				Price:        100000,
+				Location:     "Kilimani",
+				PropertyType: "apartment",
+				Features:     items("feature", tt.features),
+				Images:       items("https://example.com/photo", tt.images),
+			}
+
+			v := validator.New()
+			ValidateProperty(v, property, limits)
+
+			if tt.wantError == "" {
+				if !v.Valid() {
+					t.Errorf("got errors %v; want none", v.Errors)
+				}
+				return
+			}
+			if len(v.Errors) != 1 || v.Errors[tt.wantError] == "" {
+				t.Errorf("got errors %v; want only a %q error", v.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidatePropertyListElements(t *testing.T) {
+	tests := []struct {
+		name      string
+		features  []string
+		images    []string
+		wantError string
+	}{
+		{"valid values", []string{"garden"}, []string{"https://example.com/1.jpg", "/uploads/properties/1/a.jpg"}, ""},
+		{"empty feature", []string{"garden", ""}, []string{"https://example.com/1.jpg"}, "features"},
+		{"blank feature", []string{"   "}, []string{"https://example.com/1.jpg"}, "features"},
+		{"overlong feature", []string{strings.Repeat("a", MaxFeatureLength+1)}, []string{"https://example.com/1.jpg"}, "features"},
+		{"feature at the length limit", []string{strings.Repeat("é", MaxFeatureLength)}, []string{"https://example.com/1.jpg"}, ""},
+		{"blank image", []string{"garden"}, []string{"\t"}, "images"},
+		{"overlong image", []string{"garden"}, []string{"https://example.com/" + strings.Repeat("a", MaxImageLength)}, "images"},
+		{"image with another scheme", []string{"garden"}, []string{"javascript:alert(1)"}, "images"},
+		{"image url without a host", []string{"garden"}, []string{"https:///1.jpg"}, "images"},
+		{"image path with a space", []string{"garden"}, []string{"/uploads/my photo.jpg"}, "images"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property := &Property{
+				Title:        "Garden flat",
+				YearBuilt:    2010,
+				Area:         80,
+				Bedrooms:     2,
+				Price:        100000,
+				Location:     "Kilimani",
+				PropertyType: "apartment",
+				Features:     tt.features,
+				Images:       tt.images,
+			}
+
+			v := validator.New()
+			ValidateProperty(v, property, NewPropertyLimits(10, 10))
+
+			if tt.wantError == "" {
+				if !v.Valid() {
+					t.Errorf("got errors %v; want none", v.Errors)
+				}
+				return
+			}
+			if len(v.Errors) != 1 || v.Errors[tt.wantError] == "" {
+				t.Errorf("got errors %v; want only a %q error", v.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidatePropertyTrimsListElements(t *testing.T) {
+	property := &Property{
+		Title:        "Garden flat",
+		YearBuilt:    2010,
+		Area:         80,
+		Bedrooms:     2,
+		Price:        100000,
+		Location:     "Kilimani",
+		PropertyType: "apartment",
+		Features:     []string{"  garden ", "pool\n"},
+		Images:       []string{" https://example.com/1.jpg "},
+	}
+
+	v := validator.New()
+	ValidateProperty(v, property, NewPropertyLimits(10, 10))
+	if !v.Valid() {
+		t.Fatalf("got errors %v; want none", v.Errors)
+	}
+
+	if property.Features[0] != "garden" || property.Features[1] != "pool" {
+		t.Errorf("got features %q; want them trimmed", property.Features)
+	}
+	if property.Images[0] != "https://example.com/1.jpg" {
+		t.Errorf("got image %q; want it trimmed", property.Images[0])
+	}
+}
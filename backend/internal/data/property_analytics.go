@@ -0,0 +1,174 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PropertyDailyActivity holds one day of activity for a property
+type PropertyDailyActivity struct {
+	Date       time.Time `json:"date"`
+	Views      int       `json:"views"`
+	Inquiries  int       `json:"inquiries"`
+	Schedules  int       `json:"schedules"`
+	Favourites int       `json:"favourites"`
+}
+
+// PropertyReportSummary holds totals for a property over a date range
+type PropertyReportSummary struct {
+	Views          int     `json:"views"`
+	Inquiries      int     `json:"inquiries"`
+	Schedules      int     `json:"schedules"`
+	Favourites     int     `json:"favourites"`
+	ContactReveals int     `json:"contact_reveals"`
+	AverageRating  float64 `json:"average_rating"`
+	ReviewCount    int     `json:"review_count"`
+}
+
+// AnalyticsModel wraps database operations for property analytics
+type AnalyticsModel struct {
+	DB *sql.DB
+}
+
+// RecordView stores a single view of a property; userID is zero for anonymous visitors
+func (m AnalyticsModel) RecordView(propertyID, userID int64) error {
+	query := `
+		INSERT INTO property_views (property_id, user_id)
+		VALUES ($1, NULLIF($2, 0))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, propertyID, userID)
+	return err
+}
+
+// ErrContactRevealLimit is returned when a user has used up their contact reveals for the window
+var ErrContactRevealLimit = errors.New("contact reveal limit reached")
+
+// contactRevealLockKey namespaces the per-user advisory lock taken while a reveal is counted and recorded
+const contactRevealLockKey = 7465238
+
+// RecordContactReveal stores a signed-in user's reveal of an agent's contact details on a
+// listing, unless they have already made limit reveals since the given time. The count and
+// insert run under a per-user lock so parallel requests can't both take the last reveal.
+func (m AnalyticsModel) RecordContactReveal(propertyID, agentID, userID int64, limit int, since time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, ($2 % 2147483647)::int)`, contactRevealLockKey, userID)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM contact_reveals
+		WHERE user_id = $1 AND revealed_at >= $2`, userID, since).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count >= limit {
+		return ErrContactRevealLimit
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO contact_reveals (property_id, agent_id, user_id)
+		VALUES ($1, $2, $3)`, propertyID, agentID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPropertySummary returns activity totals for a property between from and to
+func (m AnalyticsModel) GetPropertySummary(propertyID int64, from, to time.Time) (*PropertyReportSummary, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM property_views WHERE property_id = $1 AND viewed_at >= $2 AND viewed_at < $3),
+			(SELECT COUNT(*) FROM inquiries WHERE property_id = $1 AND created_at >= $2 AND created_at < $3),
+			(SELECT COUNT(*) FROM schedules WHERE property_id = $1 AND created_at >= $2 AND created_at < $3),
+			(SELECT COUNT(*) FROM user_favourites WHERE property_id = $1 AND created_at >= $2 AND created_at < $3),
+			(SELECT COUNT(*) FROM contact_reveals WHERE property_id = $1 AND revealed_at >= $2 AND revealed_at < $3),
+			(SELECT COALESCE(AVG(rating), 0) FROM reviews WHERE property_id = $1 AND status = 'approved'),
+			(SELECT COUNT(*) FROM reviews WHERE property_id = $1 AND status = 'approved')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var summary PropertyReportSummary
+
+	err := m.DB.QueryRowContext(ctx, query, propertyID, from, to).Scan(
+		&summary.Views,
+		&summary.Inquiries,
+		&summary.Schedules,
+		&summary.Favourites,
+		&summary.ContactReveals,
+		&summary.AverageRating,
+		&summary.ReviewCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// GetPropertyDailyActivity returns one row per day between from and to with activity counts
+func (m AnalyticsModel) GetPropertyDailyActivity(propertyID int64, from, to time.Time) ([]*PropertyDailyActivity, error) {
+	query := `
+		SELECT d.day,
+			(SELECT COUNT(*) FROM property_views
+			 WHERE property_id = $1 AND viewed_at >= d.day AND viewed_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM inquiries
+			 WHERE property_id = $1 AND created_at >= d.day AND created_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM schedules
+			 WHERE property_id = $1 AND created_at >= d.day AND created_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM user_favourites
+			 WHERE property_id = $1 AND created_at >= d.day AND created_at < d.day + interval '1 day')
+		FROM generate_series(date_trunc('day', $2::timestamptz), $3::timestamptz - interval '1 second', interval '1 day') AS d(day)
+		ORDER BY d.day ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, propertyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := []*PropertyDailyActivity{}
+
+	for rows.Next() {
+		var day PropertyDailyActivity
+		err := rows.Scan(
+			&day.Date,
+			&day.Views,
+			&day.Inquiries,
+			&day.Schedules,
+			&day.Favourites,
+		)
+		if err != nil {
+			return nil, err
+		}
+		activity = append(activity, &day)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
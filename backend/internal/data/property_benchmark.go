@@ -0,0 +1,178 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// Comparable listing sets, narrowest first. A benchmark starts with listings similar
+// to the property, as GetSimilar finds them, and widens while there are too few.
+const (
+	BenchmarkScopeSimilar = "similar" // same location and type, price within SimilarPriceBand
+	BenchmarkScopeArea    = "area"    // same location and type
+	BenchmarkScopeType    = "type"    // same type anywhere
+)
+
+// BenchmarkScopes lists the comparable sets in the order they are tried
+var BenchmarkScopes = []string{BenchmarkScopeSimilar, BenchmarkScopeArea, BenchmarkScopeType}
+
+// MinBenchmarkComparables is the fewest comparable listings a benchmark needs before it
+// stops widening; benchmarks against fewer are flagged as low confidence
+const MinBenchmarkComparables = 5
+
+// BenchmarkMetric compares one daily engagement rate of a property with its comparables.
+// Percentile is the share of comparables the property beats, from 0 to 100; it and the
+// quartiles are null when there are no comparables.
+type BenchmarkMetric struct {
+	PerDay     float64  `json:"per_day"`
+	Percentile *float64 `json:"percentile"`
+	P25        *float64 `json:"p25"`
+	Median     *float64 `json:"median"`
+	P75        *float64 `json:"p75"`
+}
+
+// PropertyBenchmark compares a property's views and inquiries per day listed with those
+// of comparable live listings over the same period
+type PropertyBenchmark struct {
+	PropertyID    int64           `json:"property_id"`
+	From          time.Time       `json:"from"`
+	To            time.Time       `json:"to"`
+	Scope         string          `json:"scope"`
+	Comparables   int             `json:"comparables"`
+	LowConfidence bool            `json:"low_confidence"`
+	Views         BenchmarkMetric `json:"views"`
+	Inquiries     BenchmarkMetric `json:"inquiries"`
+}
+
+// benchmarkDays is how many days of the period from to to a listing created at created
+// was live, counting at least one
+func benchmarkDays(created, from, to time.Time) float64 {
+	if created.After(from) {
+		from = created
+	}
+	return max(to.Sub(from).Hours()/24, 1)
+}
+
+// GetBenchmark compares the property's views and inquiries per day between from and to
+// with approved, live listings of the same type, starting with the similar listings
+// and widening the comparable set until it has MinBenchmarkComparables listings
+func (m AnalyticsModel) GetBenchmark(property *Property, from, to time.Time) (*PropertyBenchmark, error) {
+	// Rates run to now at the latest, so a period ending in the future isn't diluted
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+
+	summary, err := m.GetPropertySummary(property.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	days := benchmarkDays(property.CreatedAt, from, to)
+
+	benchmark := &PropertyBenchmark{
+		PropertyID: property.ID,
+		From:       from,
+		To:         to,
+		Views:      BenchmarkMetric{PerDay: float64(summary.Views) / days},
+		Inquiries:  BenchmarkMetric{PerDay: float64(summary.Inquiries) / days},
+	}
+
+	for _, scope := range BenchmarkScopes {
+		benchmark.Scope = scope
+		err = m.compareBenchmark(benchmark, scope)
+		if err != nil {
+			return nil, err
+		}
+		if benchmark.Comparables >= MinBenchmarkComparables {
+			break
+		}
+	}
+
+	benchmark.LowConfidence = benchmark.Comparables < MinBenchmarkComparables
+
+	return benchmark, nil
+}
+
+// compareBenchmark fills in the benchmark's comparables count, quartiles and percentiles
+// against the listings in scope
+func (m AnalyticsModel) compareBenchmark(benchmark *PropertyBenchmark, scope string) error {
+	query := `
+		WITH source AS (
+			SELECT property_type, location, price
+			FROM properties
+			WHERE id = $1
+		),
+		comparables AS (
+			SELECT p.id, GREATEST(EXTRACT(EPOCH FROM ($3::timestamptz - GREATEST(p.created_at, $2::timestamptz)))::float8 / 86400, 1) AS days
+			FROM properties p, source s
+			WHERE p.id <> $1
+			AND ` + publicPropertyCondition("p") + `
+			AND p.created_at < $3
+			AND p.property_type = s.property_type
+			AND ($4 = 'type' OR lower(p.location) = lower(s.location))
+			AND ($4 <> 'similar' OR p.price BETWEEN s.price * (1 - $7::numeric) AND s.price * (1 + $7::numeric))
+		),
+		rates AS (
+			SELECT
+				(SELECT COUNT(*) FROM property_views v WHERE v.property_id = c.id AND v.viewed_at >= $2 AND v.viewed_at < $3)::float8 / c.days AS views,
+				(SELECT COUNT(*) FROM inquiries i WHERE i.property_id = c.id AND i.created_at >= $2 AND i.created_at < $3)::float8 / c.days AS inquiries
+			FROM comparables c
+		)
+		SELECT count(*),
+		       percentile_cont(0.25) WITHIN GROUP (ORDER BY views),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY views),
+		       percentile_cont(0.75) WITHIN GROUP (ORDER BY views),
+		       count(*) FILTER (WHERE views < $5), count(*) FILTER (WHERE views = $5),
+		       percentile_cont(0.25) WITHIN GROUP (ORDER BY inquiries),
+		       percentile_cont(0.5) WITHIN GROUP (ORDER BY inquiries),
+		       percentile_cont(0.75) WITHIN GROUP (ORDER BY inquiries),
+		       count(*) FILTER (WHERE inquiries < $6), count(*) FILTER (WHERE inquiries = $6)
+		FROM rates`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	args := []interface{}{
+		benchmark.PropertyID,
+		benchmark.From,
+		benchmark.To,
+		scope,
+		benchmark.Views.PerDay,
+		benchmark.Inquiries.PerDay,
+		SimilarPriceBand,
+	}
+
+	var viewsBelow, viewsEqual, inquiriesBelow, inquiriesEqual int
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&benchmark.Comparables,
+		&benchmark.Views.P25,
+		&benchmark.Views.Median,
+		&benchmark.Views.P75,
+		&viewsBelow,
+		&viewsEqual,
+		&benchmark.Inquiries.P25,
+		&benchmark.Inquiries.Median,
+		&benchmark.Inquiries.P75,
+		&inquiriesBelow,
+		&inquiriesEqual,
+	)
+	if err != nil {
+		return err
+	}
+
+	benchmark.Views.Percentile = benchmarkPercentile(viewsBelow, viewsEqual, benchmark.Comparables)
+	benchmark.Inquiries.Percentile = benchmarkPercentile(inquiriesBelow, inquiriesEqual, benchmark.Comparables)
+
+	return nil
+}
+
+// benchmarkPercentile is the percentile rank of a value among total others, counting
+// ties as half below; it is null when there is nothing to compare with
+func benchmarkPercentile(below, equal, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	percentile := 100 * (float64(below) + float64(equal)/2) / float64(total)
+	return &percentile
+}
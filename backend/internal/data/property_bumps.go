@@ -0,0 +1,88 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBumpNotLive is returned when bumping a listing that is expired or not yet published
+var ErrBumpNotLive = errors.New("only live listings can be bumped")
+
+// BumpCooldownError is returned when a listing was bumped too recently to bump again
+type BumpCooldownError struct {
+	NextBumpAt time.Time
+}
+
+func (e *BumpCooldownError) Error() string {
+	return fmt.Sprintf("listing can be bumped again after %s", e.NextBumpAt.UTC().Format(time.RFC3339))
+}
+
+// Bump moves one of the agent's listings to the top of the newest sort and records it in
+// the bump history. A listing can only be bumped once per cooldown.
+func (p PropertyModel) Bump(id, agentID int64, cooldown time.Duration) (*time.Time, error) {
+	if id < 1 {
+		return nil, ErrPropertyNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the listing so concurrent bumps cannot both pass the cooldown check
+	var live bool
+	var lastBumpedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT p.status <> 'scheduled' AND (p.expires_at IS NULL OR p.expires_at > NOW()),
+		       (SELECT MAX(b.created_at) FROM property_bumps b WHERE b.property_id = p.id)
+		FROM properties p
+		WHERE p.id = $1 AND p.agent_id = $2 AND p.deleted_at IS NULL
+		FOR UPDATE`, id, agentID).Scan(&live, &lastBumpedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPropertyNotFound
+		}
+		return nil, err
+	}
+
+	if !live {
+		return nil, ErrBumpNotLive
+	}
+
+	if lastBumpedAt.Valid {
+		nextBumpAt := lastBumpedAt.Time.Add(cooldown)
+		if time.Now().Before(nextBumpAt) {
+			return nil, &BumpCooldownError{NextBumpAt: nextBumpAt}
+		}
+	}
+
+	var bumpedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO property_bumps (property_id, agent_id)
+		VALUES ($1, $2)
+		RETURNING created_at`, id, agentID).Scan(&bumpedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE properties
+		SET bumped_at = $1, version = version + 1
+		WHERE id = $2`, bumpedAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &bumpedAt, nil
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ExpiringListing is a listing due to expire soon, with the owning agent's contact details
+type ExpiringListing struct {
+	PropertyID int64
+	Title      string
+	ExpiresAt  time.Time
+	AgentID    int64
+	AgentName  string
+	AgentEmail string
+}
+
+// Renew extends an agent's listing by lifetime, counting from now if it has already expired
+func (p PropertyModel) Renew(id, agentID int64, lifetime time.Duration) (*time.Time, error) {
+	if id < 1 {
+		return nil, ErrPropertyNotFound
+	}
+
+	query := `
+		UPDATE properties
+		SET expires_at = GREATEST(COALESCE(expires_at, NOW()), NOW()) + make_interval(secs => $3),
+		    expiry_reminded_at = NULL,
+		    version = version + 1
+		WHERE id = $1 AND agent_id = $2 AND deleted_at IS NULL
+		RETURNING expires_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var expiresAt time.Time
+	err := p.DB.QueryRowContext(ctx, query, id, agentID, lifetime.Seconds()).Scan(&expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPropertyNotFound
+		}
+		return nil, err
+	}
+
+	return &expiresAt, nil
+}
+
+// GetExpiringForReminder returns live listings expiring within the window whose agent has not been reminded
+func (p PropertyModel) GetExpiringForReminder(within time.Duration) ([]*ExpiringListing, error) {
+	query := `
+		SELECT p.id, p.title, p.expires_at, u.id, u.name, u.email
+		FROM properties p
+		INNER JOIN users u ON u.id = p.agent_id
+		WHERE p.expires_at > NOW()
+		AND p.expires_at <= NOW() + make_interval(secs => $1)
+		AND p.expiry_reminded_at IS NULL
+		AND p.deleted_at IS NULL
+		ORDER BY p.expires_at ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, within.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := []*ExpiringListing{}
+
+	for rows.Next() {
+		var listing ExpiringListing
+		err := rows.Scan(
+			&listing.PropertyID,
+			&listing.Title,
+			&listing.ExpiresAt,
+			&listing.AgentID,
+			&listing.AgentName,
+			&listing.AgentEmail,
+		)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, &listing)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listings, nil
+}
+
+// MarkExpiryReminded records that the agent has been told the listing is about to expire
+func (p PropertyModel) MarkExpiryReminded(id int64) error {
+	query := `UPDATE properties SET expiry_reminded_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := p.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// CountExpiredSince returns how many listings expired in the given period
+func (p PropertyModel) CountExpiredSince(since time.Time) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM properties
+		WHERE expires_at > $1 AND expires_at <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int64
+	err := p.DB.QueryRowContext(ctx, query, since).Scan(&count)
+	return count, err
+}
@@ -21,6 +21,7 @@ type Favourite struct {
 type FavouriteProperty struct {
 	UserID       int64     `json:"user_id"`
 	PropertyID   int64     `json:"property_id"`
+	CollectionID *int64    `json:"collection_id"` // nil when only in "All"
 	SavedAt      time.Time `json:"saved_at"`
 	Property     *Property `json:"property"`
 	IsFavourited bool      `json:"is_favourited"` // Helper field for client
@@ -117,8 +118,39 @@ func (m FavouriteModel) IsFavourite(userID, propertyID int64) (bool, error) {
 	return exists, nil
 }
 
-// GetAllForUser retrieves all favourited properties for a user with full property details
-func (m FavouriteModel) GetAllForUser(userID int64, filters Filters) ([]*FavouriteProperty, Metadata, error) {
+// SetCollection moves a favourite into one of the user's collections, or back to "All" when collectionID is nil
+func (m FavouriteModel) SetCollection(userID, propertyID int64, collectionID *int64) error {
+	query := `
+		UPDATE user_favourites
+		SET collection_id = $3
+		WHERE user_id = $1 AND property_id = $2
+		AND ($3::bigint IS NULL OR EXISTS (
+			SELECT 1 FROM favourite_collections c WHERE c.id = $3 AND c.user_id = $1
+		))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, propertyID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrFavouriteNotFound
+	}
+
+	return nil
+}
+
+// GetAllForUser retrieves favourited properties for a user with full property details,
+// limited to one collection when collectionID is positive
+func (m FavouriteModel) GetAllForUser(userID, collectionID int64, filters Filters) ([]*FavouriteProperty, Metadata, error) {
 	// Map sort columns to their proper table-qualified names
 	sortColumn := filters.sortColumn()
 	switch sortColumn {
@@ -134,20 +166,20 @@ func (m FavouriteModel) GetAllForUser(userID int64, filters Filters) ([]*Favouri
 
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(),
-		       uf.user_id, uf.property_id, uf.created_at,
-		       p.id, p.created_at, p.title, p.year_built, p.area, p.bedrooms, 
-		       p.bathrooms, p.floor, p.price, p.location, p.property_type, 
-		       p.features, p.images, p.featured_at, p.agent_id, p.version
+		       uf.user_id, uf.property_id, uf.collection_id, uf.created_at,
+		       %s
 		FROM user_favourites uf
 		INNER JOIN properties p ON uf.property_id = p.id
 		WHERE uf.user_id = $1
+		AND ($2 = 0 OR uf.collection_id = $2)
+		AND p.deleted_at IS NULL
 		ORDER BY %s %s
-		LIMIT $2 OFFSET $3`, sortColumn, filters.sortDirection())
+		LIMIT $3 OFFSET $4`, propertyColumns("p"), sortColumn, filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{userID, filters.limit(), filters.offset()}
+	args := []interface{}{userID, collectionID, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -162,28 +194,7 @@ func (m FavouriteModel) GetAllForUser(userID int64, filters Filters) ([]*Favouri
 		var fav FavouriteProperty
 		fav.Property = &Property{}
 
-		err := rows.Scan(
-			&totalRecords,
-			&fav.UserID,
-			&fav.PropertyID,
-			&fav.SavedAt,
-			&fav.Property.ID,
-			&fav.Property.CreatedAt,
-			&fav.Property.Title,
-			&fav.Property.YearBuilt,
-			&fav.Property.Area,
-			&fav.Property.Bedrooms,
-			&fav.Property.Bathrooms,
-			&fav.Property.Floor,
-			&fav.Property.Price,
-			&fav.Property.Location,
-			&fav.Property.PropertyType,
-			pq.Array(&fav.Property.Features),
-			pq.Array(&fav.Property.Images),
-			&fav.Property.FeaturedAt,
-			&fav.Property.AgentID,
-			&fav.Property.Version,
-		)
+		err := rows.Scan(append([]interface{}{&totalRecords, &fav.UserID, &fav.PropertyID, &fav.CollectionID, &fav.SavedAt}, fav.Property.scanDest()...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -233,6 +244,38 @@ func (m FavouriteModel) GetPropertyIDsForUser(userID int64) ([]int64, error) {
 	return propertyIDs, nil
 }
 
+// GetFavouritedAmong returns which of the given properties the user has favourited
+func (m FavouriteModel) GetFavouritedAmong(userID int64, propertyIDs []int64) ([]int64, error) {
+	query := `
+		SELECT property_id
+		FROM user_favourites
+		WHERE user_id = $1 AND property_id = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var favourited []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		favourited = append(favourited, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return favourited, nil
+}
+
 // GetStatsForUser returns favourite statistics for a user
 func (m FavouriteModel) GetStatsForUser(userID int64) (*FavouriteStats, error) {
 	query := `
@@ -242,7 +285,7 @@ func (m FavouriteModel) GetStatsForUser(userID int64) (*FavouriteStats, error) {
 			COALESCE(SUM(p.price), 0) as total_value
 		FROM user_favourites uf
 		INNER JOIN properties p ON uf.property_id = p.id
-		WHERE uf.user_id = $1`
+		WHERE uf.user_id = $1 AND p.deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -281,24 +324,24 @@ func (m FavouriteModel) GetFavouriteCount(propertyID int64) (int, error) {
 	return count, nil
 }
 
-// GetMostFavouritedProperties returns properties sorted by favourite count
-func (m FavouriteModel) GetMostFavouritedProperties(filters Filters) ([]*Property, Metadata, error) {
-	query := `
-		SELECT count(*) OVER(),
-		       p.id, p.created_at, p.title, p.year_built, p.area, p.bedrooms, 
-		       p.bathrooms, p.floor, p.price, p.location, p.property_type, 
-		       p.features, p.images, p.featured_at, p.agent_id, p.version,
-		       COUNT(uf.user_id) as favourite_count
-		FROM properties p
-		LEFT JOIN user_favourites uf ON p.id = uf.property_id
-		GROUP BY p.id
-		ORDER BY favourite_count DESC, p.id DESC
-		LIMIT $1 OFFSET $2`
+// FavouriteWindows maps the accepted most-favourited windows to their length in days
+var FavouriteWindows = map[string]int{
+	"7d":  7,
+	"30d": 30,
+	"90d": 90,
+	"1y":  365,
+}
+
+// GetMostFavouritedProperties returns properties sorted by the filters' sort, where
+// "favourites" is the favourite count. When since is set only favourites added
+// after it are counted, so the favourites table is not scanned in full.
+func (m FavouriteModel) GetMostFavouritedProperties(since *time.Time, filters Filters) ([]*Property, Metadata, error) {
+	query := mostFavouritedQuery(filters)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{filters.limit(), filters.offset()}
+	args := []interface{}{since, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -313,26 +356,8 @@ func (m FavouriteModel) GetMostFavouritedProperties(filters Filters) ([]*Propert
 		var property Property
 		var favouriteCount int
 
-		err := rows.Scan(
-			&totalRecords,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
-			&favouriteCount,
-		)
+		dest := append([]interface{}{&totalRecords}, property.scanDest()...)
+		err := rows.Scan(append(dest, &favouriteCount)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -348,6 +373,27 @@ func (m FavouriteModel) GetMostFavouritedProperties(filters Filters) ([]*Propert
 	return properties, metadata, nil
 }
 
+// mostFavouritedQuery selects live listings with their favourite counts since $1, in the
+// filters' sort order with id as the final tiebreaker, paged by $2 and $3
+func mostFavouritedQuery(filters Filters) string {
+	return fmt.Sprintf(`
+		WITH counts AS (
+			SELECT property_id, COUNT(*) AS favourite_count
+			FROM user_favourites
+			WHERE ($1::timestamptz IS NULL OR created_at >= $1)
+			GROUP BY property_id
+		)
+		SELECT count(*) OVER(), %s, favourite_count
+		FROM (
+			SELECT properties.*, COALESCE(counts.favourite_count, 0) AS favourite_count
+			FROM properties
+			LEFT JOIN counts ON counts.property_id = properties.id
+			WHERE %s
+		) properties
+		ORDER BY %s, id DESC
+		LIMIT $2 OFFSET $3`, propertyColumns(""), publicPropertyCondition("properties"), filters.orderBy())
+}
+
 // RemoveAllForProperty removes all favourites for a property (useful when deleting property)
 func (m FavouriteModel) RemoveAllForProperty(propertyID int64) error {
 	query := `DELETE FROM user_favourites WHERE property_id = $1`
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMostFavouritedQuerySort(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"-favourites", "ORDER BY favourite_count DESC, id DESC"},
+		{"favourites", "ORDER BY favourite_count ASC, id DESC"},
+		{"price", "ORDER BY price ASC, id DESC"},
+		{"-favourites,-price", "ORDER BY favourite_count DESC, price DESC, id DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			filters := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortSafelist: FavouritedPropertySortSafelist}
+
+			query := mostFavouritedQuery(filters)
+			if !strings.Contains(query, tt.want) {
+				t.Errorf("query is missing %q:\n%s", tt.want, query)
+			}
+		})
+	}
+}
+
+func TestMostFavouritedPagination(t *testing.T) {
+	filters := Filters{Page: 3, PageSize: 10, Sort: "-favourites", SortSafelist: FavouritedPropertySortSafelist}
+
+	if !strings.Contains(mostFavouritedQuery(filters), "LIMIT $2 OFFSET $3") {
+		t.Fatal("query is not paged by its limit and offset arguments")
+	}
+	if filters.limit() != 10 || filters.offset() != 20 {
+		t.Errorf("got limit %d offset %d; want 10 and 20", filters.limit(), filters.offset())
+	}
+
+	// The total comes from the window count over every matching row, not the page
+	metadata := calculateMetadata(45, filters.Page, filters.PageSize)
+	want := Metadata{CurrentPage: 3, PageSize: 10, FirstPage: 1, LastPage: 5, TotalListings: 45}
+	if metadata != want {
+		t.Errorf("got metadata %+v; want %+v", metadata, want)
+	}
+}
+
+func TestGetFavouritedAmong(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	user := seedTestUser(t, models, "user")
+	other := seedTestUser(t, models, "user")
+
+	liked := seedTestProperty(t, models, agent.ID)
+	notLiked := seedTestProperty(t, models, agent.ID)
+	likedByOther := seedTestProperty(t, models, agent.ID)
+	unasked := seedTestProperty(t, models, agent.ID)
+
+	for _, favourite := range []struct{ userID, propertyID int64 }{
+		{user.ID, liked.ID},
+		{user.ID, unasked.ID},
+		{other.ID, likedByOther.ID},
+	} {
+		if err := models.Favourites.Add(favourite.userID, favourite.propertyID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := models.Favourites.GetFavouritedAmong(user.ID, []int64{liked.ID, notLiked.ID, likedByOther.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the user's own favourites among the requested ids come back
+	if want := []int64{liked.ID}; !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
@@ -12,27 +12,31 @@ import (
 
 // Inquiry represents a property inquiry from a potential buyer/renter
 type Inquiry struct {
-	ID                     int64      `json:"id"`
-	PropertyID             int64      `json:"property_id"`
-	UserID                 int64      `json:"user_id"`
-	AgentID                int64      `json:"agent_id"`
-	Name                   string     `json:"name"`
-	Email                  string     `json:"email"`
-	Phone                  string     `json:"phone,omitempty"`
-	Message                string     `json:"message"`
-	InquiryType            string     `json:"inquiry_type"`
-	PreferredContactMethod string     `json:"preferred_contact_method"`
-	PreferredViewingDate   *time.Time `json:"preferred_viewing_date,omitempty"`
-	Status                 string     `json:"status"`
-	Priority               string     `json:"priority"`
-	AgentNotes             string     `json:"agent_notes,omitempty"` // Changed from sql.NullString
-	RespondedAt            *time.Time `json:"responded_at,omitempty"`
-	CreatedAt              time.Time  `json:"created_at"`
-	UpdatedAt              time.Time  `json:"updated_at"`
-	Version                int32      `json:"version"`
+	ID                     int64             `json:"id"`
+	PropertyID             int64             `json:"property_id"`
+	UserID                 int64             `json:"user_id"`
+	AgentID                int64             `json:"agent_id"`
+	Name                   string            `json:"name"`
+	Email                  string            `json:"email"`
+	Phone                  string            `json:"phone,omitempty"`
+	Message                string            `json:"message"`
+	InquiryType            string            `json:"inquiry_type"`
+	PreferredContactMethod string            `json:"preferred_contact_method"`
+	PreferredViewingDate   *time.Time        `json:"preferred_viewing_date,omitempty"`
+	Status                 string            `json:"status"`
+	Priority               string            `json:"priority"`
+	AgentNotes             string            `json:"agent_notes,omitempty"` // Changed from sql.NullString
+	ResponseMessage        string            `json:"response_message,omitempty"`
+	ContactAttempts        []*ContactAttempt `json:"contact_attempts,omitempty"`
+	RespondedAt            *time.Time        `json:"responded_at,omitempty"`
+	IsOverdue              bool              `json:"is_overdue"`
+	CreatedAt              time.Time         `json:"created_at"`
+	UpdatedAt              time.Time         `json:"updated_at"`
+	Version                int32             `json:"version"`
 	// Joined fields
 	PropertyTitle string `json:"property_title,omitempty"`
 	UserName      string `json:"user_name,omitempty"`
+	AgentName     string `json:"agent_name,omitempty"`
 }
 
 // InquiryStats holds statistics about inquiries
@@ -42,10 +46,18 @@ type InquiryStats struct {
 	ContactedCount      int     `json:"contacted_count"`
 	ScheduledCount      int     `json:"scheduled_count"`
 	ClosedCount         int     `json:"closed_count"`
+	AutoClosedCount     int     `json:"auto_closed_count"`
 	ResponseRate        float64 `json:"response_rate"`
 	AverageResponseTime string  `json:"average_response_time"`
 }
 
+// Allowed inquiry status, priority and type values
+var (
+	InquiryStatuses   = []string{"new", "contacted", "scheduled", "closed", "spam"}
+	InquiryPriorities = []string{"low", "normal", "high", "urgent"}
+	InquiryTypes      = []string{"general", "viewing", "purchase", "rent", "more_info"}
+)
+
 // ValidateInquiry checks that all fields of an Inquiry are valid
 func ValidateInquiry(v *validator.Validator, inquiry *Inquiry) {
 	// Validate required fields
@@ -67,8 +79,7 @@ func ValidateInquiry(v *validator.Validator, inquiry *Inquiry) {
 	v.Check(len(inquiry.Message) <= 2000, "message", "must not exceed 2000 characters")
 
 	// Validate inquiry type
-	validTypes := []string{"general", "viewing", "purchase", "rent", "more_info"}
-	v.Check(validator.In(inquiry.InquiryType, validTypes...), "inquiry_type",
+	v.Check(validator.In(inquiry.InquiryType, InquiryTypes...), "inquiry_type",
 		"must be one of: general, viewing, purchase, rent, more_info")
 
 	// Validate contact method
@@ -77,13 +88,11 @@ func ValidateInquiry(v *validator.Validator, inquiry *Inquiry) {
 		"preferred_contact_method", "must be one of: email, phone, any")
 
 	// Validate status
-	validStatuses := []string{"new", "contacted", "scheduled", "closed", "spam"}
-	v.Check(validator.In(inquiry.Status, validStatuses...), "status",
+	v.Check(validator.In(inquiry.Status, InquiryStatuses...), "status",
 		"must be one of: new, contacted, scheduled, closed, spam")
 
 	// Validate priority
-	validPriorities := []string{"low", "normal", "high", "urgent"}
-	v.Check(validator.In(inquiry.Priority, validPriorities...), "priority",
+	v.Check(validator.In(inquiry.Priority, InquiryPriorities...), "priority",
 		"must be one of: low, normal, high, urgent")
 
 	// Validate viewing date if provided
@@ -93,6 +102,16 @@ func ValidateInquiry(v *validator.Validator, inquiry *Inquiry) {
 	}
 }
 
+// maxInquiryResponseLength caps a reply to the inquirer, free text or rendered from a template
+const maxInquiryResponseLength = 5000
+
+// ValidateInquiryResponse checks the response an agent sends to the inquirer
+func ValidateInquiryResponse(v *validator.Validator, message string) {
+	v.Check(message != "", "message", "must be provided")
+	v.Check(len(message) >= 10, "message", "must be at least 10 characters")
+	v.Check(len(message) <= maxInquiryResponseLength, "message", fmt.Sprintf("must not exceed %d characters", maxInquiryResponseLength))
+}
+
 // InquiryModel wraps the database connection for inquiry operations
 type InquiryModel struct {
 	DB *sql.DB
@@ -144,6 +163,7 @@ func (m InquiryModel) Get(id int64) (*Inquiry, error) {
 		       i.message, i.inquiry_type, i.preferred_contact_method, 
 		       i.preferred_viewing_date, i.status, i.priority, 
 		       COALESCE(i.agent_notes, '') as agent_notes,
+		       i.response_message,
 		       i.responded_at, i.created_at, i.updated_at, i.version,
 		       p.title as property_title, u.name as user_name
 		FROM inquiries i
@@ -171,6 +191,7 @@ func (m InquiryModel) Get(id int64) (*Inquiry, error) {
 		&inquiry.Status,
 		&inquiry.Priority,
 		&inquiry.AgentNotes,
+		&inquiry.ResponseMessage,
 		&inquiry.RespondedAt,
 		&inquiry.CreatedAt,
 		&inquiry.UpdatedAt,
@@ -191,28 +212,32 @@ func (m InquiryModel) Get(id int64) (*Inquiry, error) {
 	return &inquiry, nil
 }
 
-// GetAllForAgent retrieves all inquiries for a specific agent
-func (m InquiryModel) GetAllForAgent(agentID int64, status string, filters Filters) ([]*Inquiry, Metadata, error) {
+// GetAllForAgent retrieves all inquiries for a specific agent, flagging new inquiries
+// older than the response SLA as overdue
+func (m InquiryModel) GetAllForAgent(agentID int64, status string, sla time.Duration, filters Filters) ([]*Inquiry, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), 
 		       i.id, i.property_id, i.user_id, i.agent_id, i.name, i.email, i.phone,
 		       i.message, i.inquiry_type, i.preferred_contact_method, 
 		       i.preferred_viewing_date, i.status, i.priority, 
 		       COALESCE(i.agent_notes, '') as agent_notes,
-		       i.responded_at, i.created_at, i.updated_at, i.version,
+		       i.response_message,
+		       i.responded_at,
+		       (i.status = 'new' AND i.created_at < NOW() - make_interval(secs => $5)) AS is_overdue,
+		       i.created_at, i.updated_at, i.version,
 		       p.title as property_title, u.name as user_name
 		FROM inquiries i
 		INNER JOIN properties p ON i.property_id = p.id
 		INNER JOIN users u ON i.user_id = u.id
 		WHERE i.agent_id = $1
 		AND (i.status = $2 OR $2 = '')
-		ORDER BY %s %s, i.id DESC
+		ORDER BY i.%s %s, i.id DESC
 		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{agentID, status, filters.limit(), filters.offset()}
+	args := []interface{}{agentID, status, filters.limit(), filters.offset(), sla.Seconds()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -241,7 +266,9 @@ func (m InquiryModel) GetAllForAgent(agentID int64, status string, filters Filte
 			&inquiry.Status,
 			&inquiry.Priority,
 			&inquiry.AgentNotes,
+			&inquiry.ResponseMessage,
 			&inquiry.RespondedAt,
+			&inquiry.IsOverdue,
 			&inquiry.CreatedAt,
 			&inquiry.UpdatedAt,
 			&inquiry.Version,
@@ -263,21 +290,101 @@ func (m InquiryModel) GetAllForAgent(agentID int64, status string, filters Filte
 	return inquiries, metadata, nil
 }
 
-// GetAllForUser retrieves all inquiries made by a specific user
-func (m InquiryModel) GetAllForUser(userID int64, filters Filters) ([]*Inquiry, Metadata, error) {
+// GetOverdueForAgent retrieves an agent's new inquiries that have waited longer than
+// the response SLA
+func (m InquiryModel) GetOverdueForAgent(agentID int64, sla time.Duration, filters Filters) ([]*Inquiry, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), 
 		       i.id, i.property_id, i.user_id, i.agent_id, i.name, i.email, i.phone,
 		       i.message, i.inquiry_type, i.preferred_contact_method, 
 		       i.preferred_viewing_date, i.status, i.priority, 
 		       COALESCE(i.agent_notes, '') as agent_notes,
+		       i.response_message,
+		       i.responded_at,
+		       true AS is_overdue,
+		       i.created_at, i.updated_at, i.version,
+		       p.title as property_title, u.name as user_name
+		FROM inquiries i
+		INNER JOIN properties p ON i.property_id = p.id
+		INNER JOIN users u ON i.user_id = u.id
+		WHERE i.agent_id = $1
+		AND i.status = 'new'
+		AND i.created_at < NOW() - make_interval(secs => $2)
+		ORDER BY i.%s %s, i.id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{agentID, sla.Seconds(), filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	inquiries := []*Inquiry{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var inquiry Inquiry
+		err := rows.Scan(
+			&totalRecords,
+			&inquiry.ID,
+			&inquiry.PropertyID,
+			&inquiry.UserID,
+			&inquiry.AgentID,
+			&inquiry.Name,
+			&inquiry.Email,
+			&inquiry.Phone,
+			&inquiry.Message,
+			&inquiry.InquiryType,
+			&inquiry.PreferredContactMethod,
+			&inquiry.PreferredViewingDate,
+			&inquiry.Status,
+			&inquiry.Priority,
+			&inquiry.AgentNotes,
+			&inquiry.ResponseMessage,
+			&inquiry.RespondedAt,
+			&inquiry.IsOverdue,
+			&inquiry.CreatedAt,
+			&inquiry.UpdatedAt,
+			&inquiry.Version,
+			&inquiry.PropertyTitle,
+			&inquiry.UserName,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		inquiries = append(inquiries, &inquiry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return inquiries, metadata, nil
+}
+
+// GetAllForUser retrieves all inquiries made by a specific user, without the agent's private notes
+func (m InquiryModel) GetAllForUser(userID int64, filters Filters) ([]*Inquiry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), 
+		       i.id, i.property_id, i.user_id, i.agent_id, i.name, i.email, i.phone,
+		       i.message, i.inquiry_type, i.preferred_contact_method, 
+		       i.preferred_viewing_date, i.status, i.priority, 
+		       '' as agent_notes, -- agent notes are private to the agent
+		       i.response_message,
 		       i.responded_at, i.created_at, i.updated_at, i.version,
 		       p.title as property_title, u.name as user_name
 		FROM inquiries i
 		INNER JOIN properties p ON i.property_id = p.id
 		INNER JOIN users u ON i.user_id = u.id
 		WHERE i.user_id = $1
-		ORDER BY %s %s, i.id DESC
+		ORDER BY i.%s %s, i.id DESC
 		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -312,6 +419,7 @@ func (m InquiryModel) GetAllForUser(userID int64, filters Filters) ([]*Inquiry,
 			&inquiry.Status,
 			&inquiry.Priority,
 			&inquiry.AgentNotes,
+			&inquiry.ResponseMessage,
 			&inquiry.RespondedAt,
 			&inquiry.CreatedAt,
 			&inquiry.UpdatedAt,
@@ -368,6 +476,122 @@ func (m InquiryModel) Update(inquiry *Inquiry) error {
 	return nil
 }
 
+// Reassign hands the inquiry to another agent using optimistic locking. Stats and
+// listings follow agent_id, so the inquiry moves to the new agent's figures at once.
+func (m InquiryModel) Reassign(id, newAgentID int64, version int32) error {
+	query := `
+		UPDATE inquiries
+		SET agent_id = $1, updated_at = NOW(), version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var newVersion int32
+	err := m.DB.QueryRowContext(ctx, query, newAgentID, id, version).Scan(&newVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEditConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UpdateBulk applies a new status and/or priority to each of the agent's inquiries in a
+// single transaction. Inquiries the agent doesn't own are reported as not found and
+// left untouched, inquiries already in the requested state are skipped, and each
+// resulting state is checked with ValidateInquiry.
+func (m InquiryModel) UpdateBulk(agentID int64, ids []int64, status, priority *string) (*BulkResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	selectQuery := `
+		SELECT id, property_id, agent_id, name, email, COALESCE(phone, ''), message, inquiry_type,
+		       preferred_contact_method, preferred_viewing_date, status, priority, responded_at, version
+		FROM inquiries
+		WHERE id = $1 AND agent_id = $2
+		FOR UPDATE`
+
+	updateQuery := `
+		UPDATE inquiries
+		SET status = $1, priority = $2, responded_at = $3, version = version + 1
+		WHERE id = $4
+		RETURNING version`
+
+	results := NewBulkResult(len(ids))
+
+	for _, id := range ids {
+		var inquiry Inquiry
+		err := tx.QueryRowContext(ctx, selectQuery, id, agentID).Scan(
+			&inquiry.ID,
+			&inquiry.PropertyID,
+			&inquiry.AgentID,
+			&inquiry.Name,
+			&inquiry.Email,
+			&inquiry.Phone,
+			&inquiry.Message,
+			&inquiry.InquiryType,
+			&inquiry.PreferredContactMethod,
+			&inquiry.PreferredViewingDate,
+			&inquiry.Status,
+			&inquiry.Priority,
+			&inquiry.RespondedAt,
+			&inquiry.Version,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				results.Fail(id, BulkErrorNotFound, "inquiry not found")
+				continue
+			}
+			return nil, err
+		}
+
+		if (status == nil || *status == inquiry.Status) && (priority == nil || *priority == inquiry.Priority) {
+			results.Skip(id)
+			continue
+		}
+
+		if status != nil {
+			inquiry.Status = *status
+			// Match single updates: the first move away from 'new' counts as the response
+			if inquiry.RespondedAt == nil && *status != "new" {
+				now := time.Now()
+				inquiry.RespondedAt = &now
+			}
+		}
+		if priority != nil {
+			inquiry.Priority = *priority
+		}
+
+		v := validator.New()
+		if ValidateInquiry(v, &inquiry); !v.Valid() {
+			results.FailWithErrors(id, BulkErrorInvalid, "the resulting inquiry failed validation", v.Errors)
+			continue
+		}
+
+		err = tx.QueryRowContext(ctx, updateQuery, inquiry.Status, inquiry.Priority, inquiry.RespondedAt, id).Scan(&inquiry.Version)
+		if err != nil {
+			return nil, err
+		}
+		results.Succeed(id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // Delete removes an inquiry
 func (m InquiryModel) Delete(id int64) error {
 	if id < 1 {
@@ -396,7 +620,8 @@ func (m InquiryModel) Delete(id int64) error {
 	return nil
 }
 
-// GetStatsForAgent returns inquiry statistics for a specific agent
+// GetStatsForAgent returns inquiry statistics for a specific agent. A logged contact
+// attempt counts as a response, as does a reply.
 func (m InquiryModel) GetStatsForAgent(agentID int64) (*InquiryStats, error) {
 	query := `
 		SELECT 
@@ -405,17 +630,27 @@ func (m InquiryModel) GetStatsForAgent(agentID int64) (*InquiryStats, error) {
 			COUNT(CASE WHEN status = 'contacted' THEN 1 END) as contacted,
 			COUNT(CASE WHEN status = 'scheduled' THEN 1 END) as scheduled,
 			COUNT(CASE WHEN status = 'closed' THEN 1 END) as closed,
+			COUNT(CASE WHEN auto_closed_at IS NOT NULL THEN 1 END) as auto_closed,
+			-- Inquiries closed for inactivity don't count against the response rate
 			CASE 
-				WHEN COUNT(*) > 0 THEN 
-					ROUND((COUNT(CASE WHEN responded_at IS NOT NULL THEN 1 END)::numeric / COUNT(*)::numeric) * 100, 2)
+				WHEN COUNT(CASE WHEN auto_closed_at IS NULL OR contacted_at IS NOT NULL THEN 1 END) > 0 THEN 
+					ROUND((COUNT(CASE WHEN contacted_at IS NOT NULL THEN 1 END)::numeric /
+					       COUNT(CASE WHEN auto_closed_at IS NULL OR contacted_at IS NOT NULL THEN 1 END)::numeric) * 100, 2)
 				ELSE 0 
 			END as response_rate,
 			COALESCE(
-				EXTRACT(EPOCH FROM AVG(responded_at - created_at)) / 3600, 
+				EXTRACT(EPOCH FROM AVG(contacted_at - created_at)) / 3600, 
 				0
 			) as avg_response_hours
-		FROM inquiries
-		WHERE agent_id = $1`
+		FROM (
+			-- An inquiry is contacted by the first reply or the first logged contact attempt
+			SELECT i.status, i.auto_closed_at, i.created_at,
+			       LEAST(i.responded_at, (
+			           SELECT MIN(a.created_at) FROM inquiry_contact_attempts a WHERE a.inquiry_id = i.id
+			       )) AS contacted_at
+			FROM inquiries i
+			WHERE i.agent_id = $1
+		) inquiries`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -429,6 +664,7 @@ func (m InquiryModel) GetStatsForAgent(agentID int64) (*InquiryStats, error) {
 		&stats.ContactedCount,
 		&stats.ScheduledCount,
 		&stats.ClosedCount,
+		&stats.AutoClosedCount,
 		&stats.ResponseRate,
 		&avgResponseHours,
 	)
@@ -447,22 +683,46 @@ func (m InquiryModel) GetStatsForAgent(agentID int64) (*InquiryStats, error) {
 	return &stats, nil
 }
 
-// MarkAsResponded updates the inquiry to mark it as responded
-func (m InquiryModel) MarkAsResponded(id int64) error {
+// CountRecentForUserProperty counts the inquiries a user has opened about a property since the given time
+func (m InquiryModel) CountRecentForUserProperty(userID, propertyID int64, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM inquiries
+		WHERE user_id = $1 AND property_id = $2 AND created_at >= $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, userID, propertyID, since).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Respond stores the agent's latest response to the inquirer; the first response marks
+// the inquiry as contacted and records when it was sent. Uses optimistic locking.
+func (m InquiryModel) Respond(inquiry *Inquiry) error {
 	query := `
 		UPDATE inquiries
-		SET responded_at = NOW(), status = 'contacted', version = version + 1
-		WHERE id = $1 AND responded_at IS NULL
-		RETURNING version`
+		SET response_message = $1,
+		    status = CASE WHEN responded_at IS NULL THEN 'contacted' ELSE status END,
+		    responded_at = COALESCE(responded_at, NOW()),
+		    updated_at = NOW(), version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING status, responded_at, updated_at, version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var newVersion int32
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(&newVersion)
+	args := []interface{}{inquiry.ResponseMessage, inquiry.ID, inquiry.Version}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&inquiry.Status, &inquiry.RespondedAt, &inquiry.UpdatedAt, &inquiry.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return ErrPropertyNotFound
+			return ErrEditConflict
 		}
 		return err
 	}
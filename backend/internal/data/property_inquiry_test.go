@@ -0,0 +1,33 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func TestValidateInquiryResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		valid   bool
+	}{
+		{"typical reply", "Thanks for asking, the flat is still available.", true},
+		{"empty", "", false},
+		{"too short", "Yes", false},
+		{"longest allowed", strings.Repeat("a", maxInquiryResponseLength), true},
+		{"too long", strings.Repeat("a", maxInquiryResponseLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateInquiryResponse(v, tt.message)
+
+			if v.Valid() != tt.valid {
+				t.Errorf("got valid %v (errors %v); want %v", v.Valid(), v.Errors, tt.valid)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
 )
 
 // PropertyMedia represents a media file associated with a property
@@ -23,6 +24,12 @@ type PropertyMedia struct {
 	IsPrimary    bool      `json:"is_primary"`
 	CreatedAt    time.Time `json:"created_at"`
 	Version      int32     `json:"version"`
+
+	// Pixel dimensions, only known for decodable images
+	OriginalWidth  *int `json:"original_width,omitempty"`
+	OriginalHeight *int `json:"original_height,omitempty"`
+	Width          *int `json:"width,omitempty"`
+	Height         *int `json:"height,omitempty"`
 }
 
 // MediaModel wraps the database connection for property media operations
@@ -44,12 +51,39 @@ func ValidateMedia(v *validator.Validator, media *PropertyMedia) {
 	v.Check(len(media.Caption) <= 500, "caption", "must not exceed 500 characters")
 }
 
-// Insert adds a new media file to the database
-func (m MediaModel) Insert(media *PropertyMedia) error {
+// ErrMediaLimitReached is returned when a property already has its maximum number of media items
+var ErrMediaLimitReached = errors.New("property has reached its media limit")
+
+// Insert adds a new media file to the database unless the property already has
+// maxPerProperty items. The property row is locked while counting, so concurrent
+// uploads can't both pass the check and exceed the limit.
+func (m MediaModel) Insert(media *PropertyMedia, maxPerProperty int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(pm.id)
+		FROM (SELECT id FROM properties WHERE id = $1 FOR UPDATE) p
+		LEFT JOIN property_media pm ON pm.property_id = p.id`, media.PropertyID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count >= maxPerProperty {
+		return ErrMediaLimitReached
+	}
+
 	query := `
 		INSERT INTO property_media 
-		(property_id, media_type, file_path, file_name, file_size, mime_type, display_order, caption, is_primary)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		(property_id, media_type, file_path, file_name, file_size, mime_type, display_order, caption, is_primary,
+		 original_width, original_height, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING id, created_at, version`
 
 	args := []interface{}{
@@ -62,23 +96,30 @@ func (m MediaModel) Insert(media *PropertyMedia) error {
 		media.DisplayOrder,
 		media.Caption,
 		media.IsPrimary,
+		media.OriginalWidth,
+		media.OriginalHeight,
+		media.Width,
+		media.Height,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
 		&media.ID,
 		&media.CreatedAt,
 		&media.Version,
 	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // GetAllForProperty retrieves all media files for a specific property
 func (m MediaModel) GetAllForProperty(propertyID int64) ([]*PropertyMedia, error) {
 	query := `
 		SELECT id, property_id, media_type, file_path, file_name, file_size, 
-		       mime_type, display_order, caption, is_primary, created_at, version
+		       mime_type, display_order, caption, is_primary, created_at, version,
+		       original_width, original_height, width, height
 		FROM property_media
 		WHERE property_id = $1
 		ORDER BY display_order ASC, created_at ASC`
@@ -109,6 +150,10 @@ func (m MediaModel) GetAllForProperty(propertyID int64) ([]*PropertyMedia, error
 			&media.IsPrimary,
 			&media.CreatedAt,
 			&media.Version,
+			&media.OriginalWidth,
+			&media.OriginalHeight,
+			&media.Width,
+			&media.Height,
 		)
 		if err != nil {
 			return nil, err
@@ -131,7 +176,8 @@ func (m MediaModel) Get(id int64) (*PropertyMedia, error) {
 
 	query := `
 		SELECT id, property_id, media_type, file_path, file_name, file_size, 
-		       mime_type, display_order, caption, is_primary, created_at, version
+		       mime_type, display_order, caption, is_primary, created_at, version,
+		       original_width, original_height, width, height
 		FROM property_media
 		WHERE id = $1`
 
@@ -153,6 +199,10 @@ func (m MediaModel) Get(id int64) (*PropertyMedia, error) {
 		&media.IsPrimary,
 		&media.CreatedAt,
 		&media.Version,
+		&media.OriginalWidth,
+		&media.OriginalHeight,
+		&media.Width,
+		&media.Height,
 	)
 
 	if err != nil {
@@ -284,3 +334,105 @@ func (m MediaModel) SetPrimary(id int64) error {
 
 	return tx.Commit()
 }
+
+// EnsurePrimary makes sure a property with images has exactly one primary image,
+// keeping the current primary if there is one and otherwise promoting the first image
+func (m MediaModel) EnsurePrimary(propertyID int64) error {
+	query := `
+		WITH cover AS (
+			SELECT id FROM property_media
+			WHERE property_id = $1 AND media_type = 'image'
+			ORDER BY is_primary DESC, display_order ASC, id ASC
+			LIMIT 1
+		)
+		UPDATE property_media
+		SET is_primary = (id = (SELECT id FROM cover)), version = version + 1
+		WHERE property_id = $1 AND media_type = 'image'
+		AND is_primary IS DISTINCT FROM (id = (SELECT id FROM cover))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, propertyID)
+	return err
+}
+
+// CountForProperty returns how many media items of any type the property has
+func (m MediaModel) CountForProperty(propertyID int64) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM property_media
+		WHERE property_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, propertyID).Scan(&count)
+	return count, err
+}
+
+// ErrMediaNotInProperty is returned when a reorder names media of another property
+var ErrMediaNotInProperty = errors.New("media does not belong to the property")
+
+// MaxMediaReorder caps how many media IDs a single reorder may list
+const MaxMediaReorder = 100
+
+// Reorder sets the display order of the property's media in one transaction: the
+// listed media first, in the given order, then any unlisted media in their current
+// order. Orders are renumbered from zero so none are duplicated.
+func (m MediaModel) Reorder(propertyID int64, mediaIDs []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the property's media so concurrent edits can't interleave with the renumbering
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM property_media WHERE property_id = $1 FOR UPDATE`, propertyID)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		owned[id] = true
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range mediaIDs {
+		if !owned[id] {
+			return ErrMediaNotInProperty
+		}
+	}
+
+	query := `
+		WITH ordered AS (
+			SELECT id, row_number() OVER (
+				ORDER BY array_position($2::bigint[], id) ASC NULLS LAST, display_order ASC, created_at ASC, id ASC
+			) - 1 AS position
+			FROM property_media
+			WHERE property_id = $1
+		)
+		UPDATE property_media pm
+		SET display_order = ordered.position, version = pm.version + 1
+		FROM ordered
+		WHERE pm.id = ordered.id AND pm.display_order <> ordered.position`
+
+	_, err = tx.ExecContext(ctx, query, propertyID, pq.Array(mediaIDs))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
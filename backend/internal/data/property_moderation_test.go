@@ -0,0 +1,180 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// seedTestListing inserts a new listing for the agent as an agent would create it,
+// removed again when the test ends
+func seedTestListing(t *testing.T, models Models, agentID int64, autoApprove bool) *Property {
+	t.Helper()
+
+	property := &Property{
+		Title:        "Corner house",
+		YearBuilt:    2015,
+		Area:         120,
+		Bedrooms:     3,
+		Bathrooms:    2,
+		Price:        250000,
+		Location:     "Karen",
+		PropertyType: "house",
+		Features:     []string{"garden"},
+		Images:       []string{"https://example.com/1.jpg"},
+		AgentID:      sql.NullInt64{Int64: agentID, Valid: true},
+		AutoApprove:  autoApprove,
+	}
+	if err := models.Properties.Insert(property); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { models.Properties.DB.Exec(`DELETE FROM properties WHERE id = $1`, property.ID) })
+
+	return property
+}
+
+// seedTestCoverImage gives the listing a cover image
+func seedTestCoverImage(t *testing.T, models Models, propertyID int64) {
+	t.Helper()
+
+	media := &PropertyMedia{
+		PropertyID: propertyID,
+		MediaType:  "image",
+		FilePath:   "uploads/test/cover.jpg",
+		FileName:   "cover.jpg",
+		FileSize:   1024,
+		MimeType:   "image/jpeg",
+	}
+	if err := models.Media.Insert(media, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Media.EnsurePrimary(propertyID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApproveTrustedNeedsCoverImage(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	trusted := seedTestListing(t, models, agent.ID, true)
+	untrusted := seedTestListing(t, models, agent.ID, false)
+
+	if trusted.Status != PropertyStatusPending {
+		t.Fatalf("got new trusted listing status %q; want %q", trusted.Status, PropertyStatusPending)
+	}
+
+	approved, err := models.Properties.ApproveTrusted(trusted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved {
+		t.Error("approved a trusted listing without a cover image")
+	}
+
+	seedTestCoverImage(t, models, trusted.ID)
+	seedTestCoverImage(t, models, untrusted.ID)
+
+	approved, err = models.Properties.ApproveTrusted(trusted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approved {
+		t.Error("did not approve a trusted listing with a cover image")
+	}
+
+	approved, err = models.Properties.ApproveTrusted(untrusted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved {
+		t.Error("approved a listing left for moderation")
+	}
+
+	property, err := models.Properties.Get(trusted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !property.IsPublic() {
+		t.Errorf("got trusted listing status %q after its cover image; want it public", property.Status)
+	}
+}
+
+func TestPublishDueHonoursTrust(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	trustedWithCover := seedTestListing(t, models, agent.ID, true)
+	trustedWithoutCover := seedTestListing(t, models, agent.ID, true)
+	untrusted := seedTestListing(t, models, agent.ID, false)
+
+	seedTestCoverImage(t, models, trustedWithCover.ID)
+	seedTestCoverImage(t, models, untrusted.ID)
+
+	ids := []int64{trustedWithCover.ID, trustedWithoutCover.ID, untrusted.ID}
+	_, err := db.Exec(`UPDATE properties SET status = 'scheduled', publish_at = NOW() - INTERVAL '1 minute' WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	published, err := models.Properties.PublishDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int64]string{
+		trustedWithCover.ID:    PropertyStatusApproved,
+		trustedWithoutCover.ID: PropertyStatusPending,
+		untrusted.ID:           PropertyStatusPending,
+	}
+	for id, status := range want {
+		if !slices.Contains(published, id) {
+			t.Errorf("listing %d was not published", id)
+		}
+
+		property, err := models.Properties.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if property.Status != status {
+			t.Errorf("listing %d: got status %q; want %q", id, property.Status, status)
+		}
+	}
+
+	// The trusted listing still waiting for its cover image is approved once it has one
+	seedTestCoverImage(t, models, trustedWithoutCover.ID)
+
+	approved, err := models.Properties.ApproveTrusted(trustedWithoutCover.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approved {
+		t.Error("did not approve a published trusted listing once it had a cover image")
+	}
+}
+
+func TestModeratingDeletedListing(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	admin := seedTestUser(t, models, "admin")
+	agent := seedTestUser(t, models, "agent")
+	property := seedTestListing(t, models, agent.ID, false)
+	seedTestCoverImage(t, models, property.ID)
+
+	if _, err := models.Properties.Delete(property.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := models.Properties.ApproveProperty(property.ID, admin.ID); !errors.Is(err, ErrPropertyNotFound) {
+		t.Errorf("approve: got error %v; want %v", err, ErrPropertyNotFound)
+	}
+	if err := models.Properties.RejectProperty(property.ID, admin.ID, "duplicate"); !errors.Is(err, ErrPropertyNotFound) {
+		t.Errorf("reject: got error %v; want %v", err, ErrPropertyNotFound)
+	}
+}
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// Kinds of property note; rejection and approval notes are written by the moderation flow
+const (
+	PropertyNoteComment   = "note"
+	PropertyNoteRejection = "rejection"
+	PropertyNoteApproval  = "approval"
+)
+
+// PropertyNote is a private message on a listing between its agent and admins
+type PropertyNote struct {
+	ID         int64     `json:"id"`
+	PropertyID int64     `json:"property_id"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorName string    `json:"author_name,omitempty"`
+	AuthorRole string    `json:"author_role"`
+	Kind       string    `json:"kind"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ValidatePropertyNote validates property note fields
+func ValidatePropertyNote(v *validator.Validator, note *PropertyNote) {
+	v.Check(note.Note != "", "note", "must be provided")
+	v.Check(len(note.Note) <= 2000, "note", "must not exceed 2000 characters")
+	v.Check(validator.In(note.AuthorRole, "agent", "admin"), "author_role", "must be agent or admin")
+	v.Check(validator.In(note.Kind, PropertyNoteComment, PropertyNoteRejection, PropertyNoteApproval), "kind", "must be note, rejection or approval")
+}
+
+// PropertyNoteModel wraps database operations for property notes
+type PropertyNoteModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a note to a listing's thread
+func (m PropertyNoteModel) Insert(note *PropertyNote) error {
+	query := `
+		INSERT INTO property_notes (property_id, author_id, author_role, kind, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{note.PropertyID, note.AuthorID, note.AuthorRole, note.Kind, note.Note}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&note.ID, &note.CreatedAt)
+}
+
+// GetAllForProperty retrieves a page of a listing's notes
+func (m PropertyNoteModel) GetAllForProperty(propertyID int64, filters Filters) ([]*PropertyNote, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), n.id, n.property_id, COALESCE(n.author_id, 0), COALESCE(u.name, ''),
+		       n.author_role, n.kind, n.note, n.created_at
+		FROM property_notes n
+		LEFT JOIN users u ON u.id = n.author_id
+		WHERE n.property_id = $1
+		ORDER BY n.%s %s, n.id ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, propertyID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	notes := []*PropertyNote{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var note PropertyNote
+		err := rows.Scan(
+			&totalRecords,
+			&note.ID,
+			&note.PropertyID,
+			&note.AuthorID,
+			&note.AuthorName,
+			&note.AuthorRole,
+			&note.Kind,
+			&note.Note,
+			&note.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return notes, metadata, nil
+}
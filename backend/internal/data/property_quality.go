@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// Listing quality signals, each worth a configurable weight of the 0-100 score
+const (
+	QualityPrimaryImage = "primary_image"
+	QualityImages       = "images"
+	QualityDescription  = "description"
+	QualityFeatures     = "features"
+	QualityCoordinates  = "coordinates"
+	QualityYearBuilt    = "year_built"
+	QualityAttributes   = "attributes"
+)
+
+// DefaultQualityWeights is the default weighting of the listing quality signals
+const DefaultQualityWeights = "primary_image=20,images=20,description=20,features=15,coordinates=10,year_built=5,attributes=10"
+
+// Targets at which the scaled signals earn their full weight
+const (
+	QualityTargetImages            = 8
+	QualityTargetDescriptionLength = 300
+	QualityTargetFeatures          = 5
+)
+
+// qualitySignals lists the signals in the order they are reported
+var qualitySignals = []string{
+	QualityPrimaryImage, QualityImages, QualityDescription, QualityFeatures,
+	QualityCoordinates, QualityYearBuilt, QualityAttributes,
+}
+
+// ParseQualityWeights parses weights written as "signal=weight,..." Signals left out
+// weigh nothing; at least one weight must be positive.
+func ParseQualityWeights(s string) (map[string]int, error) {
+	weights := make(map[string]int)
+	total := 0
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		signal, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("quality weight %q must be written as signal=weight", part)
+		}
+
+		signal = strings.TrimSpace(signal)
+		known := false
+		for _, candidate := range qualitySignals {
+			known = known || candidate == signal
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown quality signal %q", signal)
+		}
+
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("quality weight for %q must be a non-negative integer", signal)
+		}
+
+		weights[signal] = weight
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("at least one quality weight must be positive")
+	}
+
+	return weights, nil
+}
+
+// MediaSummary counts a property's uploaded images
+type MediaSummary struct {
+	ImageCount      int
+	HasPrimaryImage bool
+}
+
+// QualityCheck is one signal of the quality score: its share of the score, how much
+// of it the listing earned and, when not all of it, how to earn the rest
+type QualityCheck struct {
+	Signal     string `json:"signal"`
+	Weight     int    `json:"weight"`
+	Earned     int    `json:"earned"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// ListingQuality is a 0-100 score of how complete a listing is, with its breakdown
+type ListingQuality struct {
+	Score     int            `json:"score"`
+	Breakdown []QualityCheck `json:"breakdown"`
+}
+
+// ComputeListingQuality scores a listing from its fields and uploaded media. Each
+// signal earns a fraction of its weight, and the total is scaled to 0-100.
+func ComputeListingQuality(property *Property, media MediaSummary, weights map[string]int) *ListingQuality {
+	quality := &ListingQuality{Breakdown: []QualityCheck{}}
+	total, earned := 0.0, 0.0
+
+	for _, signal := range qualitySignals {
+		weight := weights[signal]
+		if weight == 0 {
+			continue
+		}
+
+		fraction, suggestion := qualitySignal(signal, property, media)
+
+		check := QualityCheck{
+			Signal: signal,
+			Weight: weight,
+			Earned: int(math.Round(float64(weight) * fraction)),
+		}
+		if fraction < 1 {
+			check.Suggestion = suggestion
+		}
+
+		quality.Breakdown = append(quality.Breakdown, check)
+		total += float64(weight)
+		earned += float64(weight) * fraction
+	}
+
+	if total > 0 {
+		quality.Score = int(math.Round(earned / total * 100))
+	}
+
+	return quality
+}
+
+// qualitySignal returns the fraction of a signal the listing earns and the
+// suggestion shown when it falls short
+func qualitySignal(signal string, property *Property, media MediaSummary) (float64, string) {
+	switch signal {
+	case QualityPrimaryImage:
+		if media.HasPrimaryImage {
+			return 1, ""
+		}
+		return 0, "Upload a photo and set it as the primary image; listings need one to be approved"
+	case QualityImages:
+		count := max(len(property.Images), media.ImageCount)
+		return scaledSignal(count, QualityTargetImages), fmt.Sprintf("Add more photos; listings with at least %d stand out (%d so far)", QualityTargetImages, count)
+	case QualityDescription:
+		length := utf8.RuneCountInString(strings.TrimSpace(property.Description))
+		return scaledSignal(length, QualityTargetDescriptionLength), fmt.Sprintf("Write a description of at least %d characters (%d so far)", QualityTargetDescriptionLength, length)
+	case QualityFeatures:
+		return scaledSignal(len(property.Features), QualityTargetFeatures), fmt.Sprintf("List at least %d features (%d so far)", QualityTargetFeatures, len(property.Features))
+	case QualityCoordinates:
+		if property.Latitude != nil && property.Longitude != nil {
+			return 1, ""
+		}
+		return 0, "Add the latitude and longitude so the listing shows on the map and in radius searches"
+	case QualityYearBuilt:
+		if property.YearBuilt != 0 {
+			return 1, ""
+		}
+		return 0, "Add the year the property was built"
+	case QualityAttributes:
+		filled := 0
+		if property.EnergyRating != nil {
+			filled++
+		}
+		if property.ParkingSpaces != nil {
+			filled++
+		}
+		if property.LotSize != nil {
+			filled++
+		}
+		if property.HeatingType != nil {
+			filled++
+		}
+		if property.Furnished != nil {
+			filled++
+		}
+		return scaledSignal(filled, 5), "Fill in the energy rating, parking spaces, lot size, heating type and furnished status"
+	}
+
+	return 0, ""
+}
+
+// scaledSignal is the fraction of target reached by n, capped at 1
+func scaledSignal(n, target int) float64 {
+	return math.Min(float64(n)/float64(target), 1)
+}
+
+// GetImageSummaries counts the uploaded images of each property and whether one is
+// primary. Properties without images are left out of the map.
+func (m MediaModel) GetImageSummaries(propertyIDs []int64) (map[int64]MediaSummary, error) {
+	summaries := make(map[int64]MediaSummary)
+	if len(propertyIDs) == 0 {
+		return summaries, nil
+	}
+
+	query := `
+		SELECT property_id, COUNT(*), bool_or(is_primary)
+		FROM property_media
+		WHERE property_id = ANY($1) AND media_type = 'image'
+		GROUP BY property_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var summary MediaSummary
+		if err := rows.Scan(&id, &summary.ImageCount, &summary.HasPrimaryImage); err != nil {
+			return nil, err
+		}
+		summaries[id] = summary
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
@@ -24,7 +24,54 @@ type Schedule struct {
 	OriginalScheduledAt *time.Time `json:"original_scheduled_at,omitempty"`
 	LastRescheduledAt   *time.Time `json:"last_rescheduled_at,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 	Version             int        `json:"version"`
+	Timezone            string     `json:"timezone"`
+
+	// AgentLocation is the agent's timezone; when set, the viewing must fall within working hours
+	AgentLocation *time.Location `json:"-"`
+
+	// SlotMinutes is the agent's booking granularity; zero skips alignment checks
+	SlotMinutes int `json:"-"`
+
+	// WorkingHours bound the viewing in AgentLocation; the zero value skips the check, as
+	// for agents whose configured availability replaces the standard hours
+	WorkingHours WorkingHours `json:"-"`
+
+	// MaxDurationMinutes is the longest viewing that may be booked, set from configuration
+	MaxDurationMinutes int `json:"-"`
+}
+
+// scheduleColumns is the select list for a full Schedule, in the order scanned by
+// Schedule.scanDest. notes is nullable but scanned into a string.
+const scheduleColumns = `id, property_id, user_id, agent_id, scheduled_at, duration_minutes, status,
+	COALESCE(notes, ''), reschedule_count, original_scheduled_at, last_rescheduled_at,
+	created_at, updated_at, version, timezone`
+
+// scheduleColumnsS is scheduleColumns for queries that alias schedules as s
+const scheduleColumnsS = `s.id, s.property_id, s.user_id, s.agent_id, s.scheduled_at, s.duration_minutes, s.status,
+	COALESCE(s.notes, ''), s.reschedule_count, s.original_scheduled_at, s.last_rescheduled_at,
+	s.created_at, s.updated_at, s.version, s.timezone`
+
+// scanDest returns the scan destinations matching scheduleColumns
+func (s *Schedule) scanDest() []interface{} {
+	return []interface{}{
+		&s.ID,
+		&s.PropertyID,
+		&s.UserID,
+		&s.AgentID,
+		&s.ScheduledAt,
+		&s.DurationMinutes,
+		&s.Status,
+		&s.Notes,
+		&s.RescheduleCount,
+		&s.OriginalScheduledAt,
+		&s.LastRescheduledAt,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&s.Version,
+		&s.Timezone,
+	}
 }
 
 // ScheduleWithDetails includes property and user information
@@ -45,11 +92,30 @@ type AgentScheduleStats struct {
 	CancelledSchedules int `json:"cancelled_schedules"`
 }
 
+// ScheduleTrendPoint holds one day of an agent's schedule activity. Confirmed, completed
+// and cancelled count schedules by the day they last changed into that status.
+type ScheduleTrendPoint struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Confirmed int    `json:"confirmed"`
+	Completed int    `json:"completed"`
+	Cancelled int    `json:"cancelled"`
+}
+
+// ScheduleTrendPeriods maps the accepted trend periods to their length in days
+var ScheduleTrendPeriods = map[string]int{
+	"7d":  7,
+	"30d": 30,
+	"90d": 90,
+	"1y":  365,
+}
+
 var (
-	ErrScheduleNotFound    = errors.New("schedule not found")
-	ErrScheduleConflict    = errors.New("schedule conflict - time slot already booked")
-	ErrInvalidScheduleTime = errors.New("invalid schedule time - must be in the future")
-	ErrScheduleNotEditable = errors.New("schedule cannot be edited in current status")
+	ErrScheduleNotFound     = errors.New("schedule not found")
+	ErrScheduleConflict     = errors.New("schedule conflict - time slot already booked")
+	ErrUserScheduleConflict = errors.New("schedule conflict - user already has a viewing at this time")
+	ErrInvalidScheduleTime  = errors.New("invalid schedule time - must be in the future")
+	ErrScheduleNotEditable  = errors.New("schedule cannot be edited in current status")
 )
 
 // ValidateSchedule validates schedule fields
@@ -62,12 +128,101 @@ func ValidateSchedule(v *validator.Validator, schedule *Schedule) {
 	v.Check(schedule.ScheduledAt.After(time.Now()), "scheduled_at", "must be in the future")
 
 	v.Check(schedule.DurationMinutes > 0, "duration_minutes", "must be positive")
-	v.Check(schedule.DurationMinutes <= 480, "duration_minutes", "must not exceed 8 hours")
+	v.Check(schedule.DurationMinutes <= schedule.MaxDurationMinutes, "duration_minutes", fmt.Sprintf("must not exceed %d minutes", schedule.MaxDurationMinutes))
+
+	validateSlotAlignment(v, schedule.ScheduledAt, schedule.DurationMinutes, schedule.SlotMinutes, schedule.AgentLocation)
+	validateWorkingHours(v, schedule.ScheduledAt, schedule.DurationMinutes, schedule.WorkingHours, schedule.AgentLocation)
+
+	if schedule.Timezone != "" {
+		ValidateTimezone(v, schedule.Timezone)
+	}
 
 	validStatuses := []string{"pending", "confirmed", "cancelled", "completed"}
 	v.Check(validator.In(schedule.Status, validStatuses...), "status", "must be pending, confirmed, cancelled, or completed")
 
-	v.Check(len(schedule.Notes) <= 1000, "notes", "must not exceed 1000 characters")
+	ValidateScheduleNotes(v, schedule.Notes)
+}
+
+// ValidateScheduleNotes checks the length of a schedule's notes
+func ValidateScheduleNotes(v *validator.Validator, notes string) {
+	v.Check(len(notes) <= 1000, "notes", "must not exceed 1000 characters")
+}
+
+// Default local working hours that viewings must fall within, in the agent's timezone,
+// for agents who haven't configured their own availability
+const (
+	DefaultWorkdayStartHour = 8
+	DefaultWorkdayEndHour   = 20
+)
+
+// WorkingHours is the part of each local day, in whole hours, in which viewings may be
+// booked. An EndHour of 24 runs to midnight.
+type WorkingHours struct {
+	StartHour int
+	EndHour   int
+}
+
+// ValidateWorkingHours checks that the hours describe a non-empty part of one day
+func ValidateWorkingHours(v *validator.Validator, hours WorkingHours) {
+	v.Check(hours.StartHour >= 0 && hours.StartHour <= 23, "workday_start", "must be between 0 and 23")
+	v.Check(hours.EndHour >= 1 && hours.EndHour <= 24, "workday_end", "must be between 1 and 24")
+	v.Check(hours.EndHour > hours.StartHour, "workday_end", "must be after workday_start")
+}
+
+// IsZero reports whether no working hours are set
+func (h WorkingHours) IsZero() bool {
+	return h == WorkingHours{}
+}
+
+// Contains reports whether a viewing starting at start and lasting durationMinutes falls
+// within the working day of its local date in loc
+func (h WorkingHours) Contains(start time.Time, durationMinutes int, loc *time.Location) bool {
+	start = start.In(loc)
+	end := start.Add(time.Duration(durationMinutes) * time.Minute)
+
+	// Bounds are built from the local date so DST changes shift them correctly
+	dayStart := time.Date(start.Year(), start.Month(), start.Day(), h.StartHour, 0, 0, 0, loc)
+	dayEnd := time.Date(start.Year(), start.Month(), start.Day(), h.EndHour, 0, 0, 0, loc)
+
+	return !start.Before(dayStart) && !end.After(dayEnd)
+}
+
+// String formats the hours as "HH:00 to HH:00"
+func (h WorkingHours) String() string {
+	return fmt.Sprintf("%02d:00 to %02d:00", h.StartHour, h.EndHour)
+}
+
+// validateWorkingHours checks that the viewing starts and ends within the agent's local working day
+func validateWorkingHours(v *validator.Validator, scheduledAt time.Time, durationMinutes int, hours WorkingHours, loc *time.Location) {
+	if loc == nil || hours.IsZero() {
+		return
+	}
+
+	v.Check(hours.Contains(scheduledAt, durationMinutes, loc), "scheduled_at",
+		fmt.Sprintf("must be between %s in the agent's timezone (%s)", hours, loc.String()))
+}
+
+// validateSlotAlignment checks that start time and duration sit on the agent's slot grid.
+// The grid runs from local midnight in loc (UTC when nil), so bookings in half-hour
+// offset timezones align to the agent's clock rather than to UTC.
+func validateSlotAlignment(v *validator.Validator, scheduledAt time.Time, durationMinutes, slotMinutes int, loc *time.Location) {
+	if slotMinutes <= 0 {
+		return
+	}
+
+	v.Check(onSlotGrid(scheduledAt, slotMinutes, loc), "scheduled_at", fmt.Sprintf("must start on a %d minute boundary", slotMinutes))
+	v.Check(durationMinutes%slotMinutes == 0, "duration_minutes", fmt.Sprintf("must be a multiple of %d minutes", slotMinutes))
+}
+
+// onSlotGrid reports whether t falls a whole number of slots after midnight by the local
+// clock in loc (UTC when nil)
+func onSlotGrid(t time.Time, slotMinutes int, loc *time.Location) bool {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+
+	return local.Second() == 0 && local.Nanosecond() == 0 && (local.Hour()*60+local.Minute())%slotMinutes == 0
 }
 
 // Add validation for rescheduling
@@ -91,7 +246,10 @@ func ValidateReschedule(v *validator.Validator, schedule *Schedule, newScheduled
 
 	// Validate new duration
 	v.Check(newDuration > 0, "duration_minutes", "must be positive")
-	v.Check(newDuration <= 480, "duration_minutes", "must not exceed 8 hours")
+	v.Check(newDuration <= schedule.MaxDurationMinutes, "duration_minutes", fmt.Sprintf("must not exceed %d minutes", schedule.MaxDurationMinutes))
+
+	validateSlotAlignment(v, newScheduledAt, newDuration, schedule.SlotMinutes, schedule.AgentLocation)
+	validateWorkingHours(v, newScheduledAt, newDuration, schedule.WorkingHours, schedule.AgentLocation)
 }
 
 // ScheduleModel wraps database operations for schedules
@@ -105,6 +263,15 @@ func (m ScheduleModel) Insert(schedule *Schedule) error {
 	// Calculate end time in Go
 	endTime := schedule.ScheduledAt.Add(time.Duration(schedule.DurationMinutes) * time.Minute)
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// Viewings must fall inside the agent's weekly availability, when they have set any
+	err := checkAvailability(ctx, m.DB, schedule.AgentID, schedule.ScheduledAt, endTime, schedule.AgentLocation)
+	if err != nil {
+		return err
+	}
+
 	// Check for scheduling conflicts using make_interval
 	conflictQuery := `
 		SELECT COUNT(*) 
@@ -115,11 +282,8 @@ func (m ScheduleModel) Insert(schedule *Schedule) error {
 			(scheduled_at < $3 AND scheduled_at + make_interval(mins => duration_minutes) > $2)
 		)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
 	var count int
-	err := m.DB.QueryRowContext(ctx, conflictQuery,
+	err = m.DB.QueryRowContext(ctx, conflictQuery,
 		schedule.AgentID,
 		schedule.ScheduledAt,
 		endTime,
@@ -133,12 +297,21 @@ func (m ScheduleModel) Insert(schedule *Schedule) error {
 		return ErrScheduleConflict
 	}
 
+	err = checkUserConflict(ctx, m.DB, schedule.UserID, 0, schedule.ScheduledAt, endTime)
+	if err != nil {
+		return err
+	}
+
 	// Insert the schedule with reschedule tracking fields
 	query := `
 		INSERT INTO schedules (property_id, user_id, agent_id, scheduled_at, duration_minutes, 
-		                       status, notes, reschedule_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, 0)
-		RETURNING id, created_at, version, reschedule_count`
+		                       status, notes, reschedule_count, timezone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 0, $8)
+		RETURNING id, created_at, updated_at, version, reschedule_count`
+
+	if schedule.Timezone == "" {
+		schedule.Timezone = DefaultTimezone
+	}
 
 	args := []interface{}{
 		schedule.PropertyID,
@@ -148,16 +321,46 @@ func (m ScheduleModel) Insert(schedule *Schedule) error {
 		schedule.DurationMinutes,
 		schedule.Status,
 		schedule.Notes,
+		schedule.Timezone,
 	}
 
 	return m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&schedule.ID,
 		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
 		&schedule.Version,
 		&schedule.RescheduleCount,
 	)
 }
 
+// checkUserConflict returns ErrUserScheduleConflict if the user already has a pending or
+// confirmed viewing, with any agent, overlapping start to end. excludeID leaves out the
+// schedule being moved, or is 0.
+func checkUserConflict(ctx context.Context, db *sql.DB, userID, excludeID int64, start, end time.Time) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM schedules
+			WHERE user_id = $1
+			AND id <> $2
+			AND status IN ('pending', 'confirmed')
+			AND scheduled_at < $4
+			AND scheduled_at + make_interval(mins => duration_minutes) > $3
+		)`
+
+	var conflict bool
+	err := db.QueryRowContext(ctx, query, userID, excludeID, start, end).Scan(&conflict)
+	if err != nil {
+		return err
+	}
+
+	if conflict {
+		return ErrUserScheduleConflict
+	}
+
+	return nil
+}
+
 // Get retrieves a schedule by ID
 // Update Get method to include reschedule fields
 func (m ScheduleModel) Get(id int64) (*Schedule, error) {
@@ -166,9 +369,7 @@ func (m ScheduleModel) Get(id int64) (*Schedule, error) {
 	}
 
 	query := `
-		SELECT id, property_id, user_id, agent_id, scheduled_at, duration_minutes, 
-		       status, notes, reschedule_count, original_scheduled_at, last_rescheduled_at,
-		       created_at, version
+		SELECT ` + scheduleColumns + `
 		FROM schedules
 		WHERE id = $1`
 
@@ -177,21 +378,7 @@ func (m ScheduleModel) Get(id int64) (*Schedule, error) {
 
 	var schedule Schedule
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&schedule.ID,
-		&schedule.PropertyID,
-		&schedule.UserID,
-		&schedule.AgentID,
-		&schedule.ScheduledAt,
-		&schedule.DurationMinutes,
-		&schedule.Status,
-		&schedule.Notes,
-		&schedule.RescheduleCount,
-		&schedule.OriginalScheduledAt,
-		&schedule.LastRescheduledAt,
-		&schedule.CreatedAt,
-		&schedule.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(schedule.scanDest()...)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -208,17 +395,15 @@ func (m ScheduleModel) Get(id int64) (*Schedule, error) {
 func (m ScheduleModel) GetAllForAgent(agentID int64, status string, filters Filters) ([]*ScheduleWithDetails, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), 
-		       s.id, s.property_id, s.user_id, s.agent_id, s.scheduled_at, 
-		       s.duration_minutes, s.status, s.notes, s.reschedule_count,
-		       s.original_scheduled_at, s.last_rescheduled_at, s.created_at, s.version,
+		       %s,
 		       p.title, p.location, u.name, u.email
 		FROM schedules s
 		INNER JOIN properties p ON s.property_id = p.id
 		INNER JOIN users u ON s.user_id = u.id
 		WHERE s.agent_id = $1
 		AND (s.status = $2 OR $2 = '')
-		ORDER BY %s %s, s.id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+		ORDER BY s.%s %s, s.id ASC
+		LIMIT $3 OFFSET $4`, scheduleColumnsS, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -236,26 +421,56 @@ func (m ScheduleModel) GetAllForAgent(agentID int64, status string, filters Filt
 
 	for rows.Next() {
 		var schedule ScheduleWithDetails
-		err := rows.Scan(
-			&totalRecords,
-			&schedule.ID,
-			&schedule.PropertyID,
-			&schedule.UserID,
-			&schedule.AgentID,
-			&schedule.ScheduledAt,
-			&schedule.DurationMinutes,
-			&schedule.Status,
-			&schedule.Notes,
-			&schedule.RescheduleCount,
-			&schedule.OriginalScheduledAt,
-			&schedule.LastRescheduledAt,
-			&schedule.CreatedAt,
-			&schedule.Version,
-			&schedule.PropertyTitle,
-			&schedule.PropertyAddr,
-			&schedule.UserName,
-			&schedule.UserEmail,
-		)
+		dest := append([]interface{}{&totalRecords}, schedule.scanDest()...)
+		err := rows.Scan(append(dest, &schedule.PropertyTitle, &schedule.PropertyAddr, &schedule.UserName, &schedule.UserEmail)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return schedules, metadata, nil
+}
+
+// GetAllForProperty retrieves the schedules booked for a property, with the booking
+// user's details, optionally filtered by status
+func (m ScheduleModel) GetAllForProperty(propertyID int64, status string, filters Filters) ([]*ScheduleWithDetails, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(),
+		       %s,
+		       p.title, p.location, u.name, u.email
+		FROM schedules s
+		INNER JOIN properties p ON s.property_id = p.id
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.property_id = $1
+		AND (s.status = $2 OR $2 = '')
+		ORDER BY s.%s %s, s.id ASC
+		LIMIT $3 OFFSET $4`, scheduleColumnsS, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{propertyID, status, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	schedules := []*ScheduleWithDetails{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var schedule ScheduleWithDetails
+		dest := append([]interface{}{&totalRecords}, schedule.scanDest()...)
+		err := rows.Scan(append(dest, &schedule.PropertyTitle, &schedule.PropertyAddr, &schedule.UserName, &schedule.UserEmail)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -271,21 +486,61 @@ func (m ScheduleModel) GetAllForAgent(agentID int64, status string, filters Filt
 	return schedules, metadata, nil
 }
 
+// GetAllForAgentInRange retrieves an agent's pending and confirmed schedules overlapping the given range
+func (m ScheduleModel) GetAllForAgentInRange(agentID int64, from, to time.Time) ([]*ScheduleWithDetails, error) {
+	query := `
+		SELECT ` + scheduleColumnsS + `,
+		       p.title, p.location, u.name, u.email
+		FROM schedules s
+		INNER JOIN properties p ON s.property_id = p.id
+		INNER JOIN users u ON s.user_id = u.id
+		WHERE s.agent_id = $1
+		AND s.status IN ('pending', 'confirmed')
+		AND s.scheduled_at < $3
+		AND s.scheduled_at + make_interval(mins => s.duration_minutes) > $2
+		ORDER BY s.scheduled_at ASC, s.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []*ScheduleWithDetails{}
+
+	for rows.Next() {
+		var schedule ScheduleWithDetails
+		dest := schedule.scanDest()
+		err := rows.Scan(append(dest, &schedule.PropertyTitle, &schedule.PropertyAddr, &schedule.UserName, &schedule.UserEmail)...)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
 // GetAllForUser retrieves all schedules for a user
 // Update GetAllForUser to include reschedule fields
 func (m ScheduleModel) GetAllForUser(userID int64, filters Filters) ([]*ScheduleWithDetails, Metadata, error) {
 	query := fmt.Sprintf(`
 		SELECT count(*) OVER(), 
-		       s.id, s.property_id, s.user_id, s.agent_id, s.scheduled_at, 
-		       s.duration_minutes, s.status, s.notes, s.reschedule_count,
-		       s.original_scheduled_at, s.last_rescheduled_at, s.created_at, s.version,
+		       %s,
 		       p.title, p.location, u.name, u.email
 		FROM schedules s
 		INNER JOIN properties p ON s.property_id = p.id
 		INNER JOIN users u ON s.agent_id = u.id
 		WHERE s.user_id = $1
-		ORDER BY %s %s, s.id ASC
-		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+		ORDER BY s.%s %s, s.id ASC
+		LIMIT $2 OFFSET $3`, scheduleColumnsS, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -303,26 +558,8 @@ func (m ScheduleModel) GetAllForUser(userID int64, filters Filters) ([]*Schedule
 
 	for rows.Next() {
 		var schedule ScheduleWithDetails
-		err := rows.Scan(
-			&totalRecords,
-			&schedule.ID,
-			&schedule.PropertyID,
-			&schedule.UserID,
-			&schedule.AgentID,
-			&schedule.ScheduledAt,
-			&schedule.DurationMinutes,
-			&schedule.Status,
-			&schedule.Notes,
-			&schedule.RescheduleCount,
-			&schedule.OriginalScheduledAt,
-			&schedule.LastRescheduledAt,
-			&schedule.CreatedAt,
-			&schedule.Version,
-			&schedule.PropertyTitle,
-			&schedule.PropertyAddr,
-			&schedule.UserName,
-			&schedule.UserEmail,
-		)
+		dest := append([]interface{}{&totalRecords}, schedule.scanDest()...)
+		err := rows.Scan(append(dest, &schedule.PropertyTitle, &schedule.PropertyAddr, &schedule.UserName, &schedule.UserEmail)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -338,11 +575,47 @@ func (m ScheduleModel) GetAllForUser(userID int64, filters Filters) ([]*Schedule
 	return schedules, metadata, nil
 }
 
-// UpdateStatus updates the status of a schedule
+// UpdateNotes replaces a schedule's notes. Notes don't affect the booking, so this
+// neither checks nor bumps the version and can't conflict with other edits.
+func (m ScheduleModel) UpdateNotes(id int64, notes string) error {
+	if id < 1 {
+		return ErrScheduleNotFound
+	}
+
+	query := `
+		UPDATE schedules
+		SET notes = $1
+		WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, notes, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// UpdateStatus updates the status of a schedule, recording when it was confirmed,
+// completed or cancelled
 func (m ScheduleModel) UpdateStatus(id int64, status string, version int) error {
 	query := `
 		UPDATE schedules
-		SET status = $1, version = version + 1
+		SET status = $1, version = version + 1,
+		    confirmed_at = CASE WHEN $1 = 'confirmed' THEN NOW() ELSE confirmed_at END,
+		    completed_at = CASE WHEN $1 = 'completed' THEN NOW() ELSE completed_at END,
+		    cancelled_at = CASE WHEN $1 = 'cancelled' THEN NOW() ELSE cancelled_at END
 		WHERE id = $2 AND version = $3
 		RETURNING version`
 
@@ -362,6 +635,50 @@ func (m ScheduleModel) UpdateStatus(id int64, status string, version int) error
 	return nil
 }
 
+// CompletePastConfirmed marks confirmed schedules that ended more than grace ago as completed.
+// An agentID of 0 applies to every agent. The completed schedules are returned with the count.
+func (m ScheduleModel) CompletePastConfirmed(agentID int64, grace time.Duration) (int64, []*Schedule, error) {
+	query := `
+		UPDATE schedules
+		SET status = 'completed', completed_at = NOW(), version = version + 1
+		WHERE status = 'confirmed'
+		AND scheduled_at + make_interval(mins => duration_minutes) + make_interval(secs => $1) < NOW()
+		AND (agent_id = $2 OR $2 = 0)
+		RETURNING ` + scheduleColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, grace.Seconds(), agentID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	schedules := []*Schedule{}
+
+	for rows.Next() {
+		var schedule Schedule
+		err := rows.Scan(schedule.scanDest()...)
+		if err != nil {
+			return 0, nil, err
+		}
+		schedules = append(schedules, &schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return int64(len(schedules)), schedules, nil
+}
+
+// MarkPastAsCompleted marks every agent's confirmed schedules that ended more than grace
+// ago as completed. Completed schedules are left alone, so running it again is a no-op.
+func (m ScheduleModel) MarkPastAsCompleted(grace time.Duration) (int64, []*Schedule, error) {
+	return m.CompletePastConfirmed(0, grace)
+}
+
 // Delete removes a schedule
 func (m ScheduleModel) Delete(id int64) error {
 	if id < 1 {
@@ -422,8 +739,55 @@ func (m ScheduleModel) GetStatsForAgent(agentID int64) (*AgentScheduleStats, err
 	return &stats, nil
 }
 
-// Reschedule updates the scheduled time and duration of an appointment
-func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuration int, version int) error {
+// GetTrendForAgent returns one point per day between from and to with the agent's
+// schedule activity, including days with no activity. Each transition is counted on the
+// day it happened, so a viewing confirmed one day and completed the next shows on both.
+func (m ScheduleModel) GetTrendForAgent(agentID int64, from, to time.Time) ([]*ScheduleTrendPoint, error) {
+	query := `
+		SELECT d.day,
+			(SELECT COUNT(*) FROM schedules
+			 WHERE agent_id = $1 AND created_at >= d.day AND created_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM schedules
+			 WHERE agent_id = $1 AND confirmed_at >= d.day AND confirmed_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM schedules
+			 WHERE agent_id = $1 AND completed_at >= d.day AND completed_at < d.day + interval '1 day'),
+			(SELECT COUNT(*) FROM schedules
+			 WHERE agent_id = $1 AND cancelled_at >= d.day AND cancelled_at < d.day + interval '1 day')
+		FROM generate_series(date_trunc('day', $2::timestamptz), $3::timestamptz - interval '1 second', interval '1 day') AS d(day)
+		ORDER BY d.day ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []*ScheduleTrendPoint{}
+
+	for rows.Next() {
+		var point ScheduleTrendPoint
+		var day time.Time
+		err := rows.Scan(&day, &point.Created, &point.Confirmed, &point.Completed, &point.Cancelled)
+		if err != nil {
+			return nil, err
+		}
+		point.Date = day.Format("2006-01-02")
+		points = append(points, &point)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// Reschedule updates the scheduled time and duration of an appointment. The new time
+// must fall inside the agent's weekly availability, read in agentLocation.
+func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuration int, version int, agentLocation *time.Location) error {
 	// First, get the current schedule
 	schedule, err := m.Get(id)
 	if err != nil {
@@ -433,6 +797,14 @@ func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuratio
 	// Calculate new end time
 	newEndTime := newScheduledAt.Add(time.Duration(newDuration) * time.Minute)
 
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = checkAvailability(ctx, m.DB, schedule.AgentID, newScheduledAt, newEndTime, agentLocation)
+	if err != nil {
+		return err
+	}
+
 	// Check for scheduling conflicts with the new time
 	conflictQuery := `
 		SELECT COUNT(*) 
@@ -444,9 +816,6 @@ func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuratio
 			(scheduled_at < $4 AND scheduled_at + make_interval(mins => duration_minutes) > $3)
 		)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
 	var count int
 	err = m.DB.QueryRowContext(ctx, conflictQuery,
 		schedule.AgentID,
@@ -463,6 +832,11 @@ func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuratio
 		return ErrScheduleConflict
 	}
 
+	err = checkUserConflict(ctx, m.DB, schedule.UserID, id, newScheduledAt, newEndTime)
+	if err != nil {
+		return err
+	}
+
 	// Update the schedule with new time, increment reschedule count
 	query := `
 		UPDATE schedules
@@ -471,6 +845,7 @@ func (m ScheduleModel) Reschedule(id int64, newScheduledAt time.Time, newDuratio
 		    reschedule_count = reschedule_count + 1,
 		    original_scheduled_at = COALESCE(original_scheduled_at, $3),
 		    last_rescheduled_at = NOW(),
+		    reminder_sent_at = NULL,
 		    version = version + 1
 		WHERE id = $4 AND version = $5
 		RETURNING version, reschedule_count`
@@ -0,0 +1,406 @@
+package data
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestValidateSlotAlignment(t *testing.T) {
+	kolkata := mustLoadLocation(t, "Asia/Kolkata")
+
+	tests := []struct {
+		name        string
+		scheduledAt time.Time
+		duration    int
+		slotMinutes int
+		loc         *time.Location
+		wantErrors  []string
+	}{
+		{
+			name:        "aligned start and duration",
+			scheduledAt: time.Date(2030, 3, 4, 10, 30, 0, 0, time.UTC),
+			duration:    60,
+			slotMinutes: 30,
+		},
+		{
+			name:        "start one minute past the boundary",
+			scheduledAt: time.Date(2030, 3, 4, 10, 31, 0, 0, time.UTC),
+			duration:    60,
+			slotMinutes: 30,
+			wantErrors:  []string{"scheduled_at"},
+		},
+		{
+			name:        "start with stray seconds",
+			scheduledAt: time.Date(2030, 3, 4, 10, 30, 15, 0, time.UTC),
+			duration:    60,
+			slotMinutes: 30,
+			wantErrors:  []string{"scheduled_at"},
+		},
+		{
+			name:        "duration off the grid",
+			scheduledAt: time.Date(2030, 3, 4, 10, 30, 0, 0, time.UTC),
+			duration:    45,
+			slotMinutes: 30,
+			wantErrors:  []string{"duration_minutes"},
+		},
+		{
+			name:        "zero slot size skips the check",
+			scheduledAt: time.Date(2030, 3, 4, 10, 17, 0, 0, time.UTC),
+			duration:    7,
+			slotMinutes: 0,
+		},
+		{
+			name:        "hour slot on the local hour in a half-hour offset zone",
+			scheduledAt: time.Date(2030, 3, 4, 10, 0, 0, 0, kolkata),
+			duration:    60,
+			slotMinutes: 60,
+			loc:         kolkata,
+		},
+		{
+			name:        "hour slot on the UTC hour in a half-hour offset zone",
+			scheduledAt: time.Date(2030, 3, 4, 10, 30, 0, 0, kolkata),
+			duration:    60,
+			slotMinutes: 60,
+			loc:         kolkata,
+			wantErrors:  []string{"scheduled_at"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			validateSlotAlignment(v, tt.scheduledAt, tt.duration, tt.slotMinutes, tt.loc)
+
+			if len(v.Errors) != len(tt.wantErrors) {
+				t.Fatalf("got errors %v; want errors for %v", v.Errors, tt.wantErrors)
+			}
+			for _, key := range tt.wantErrors {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("missing error for %q; got %v", key, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkingHoursAcrossDST(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	london := mustLoadLocation(t, "Europe/London")
+	hours := WorkingHours{StartHour: 8, EndHour: 20}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		duration int
+		loc      *time.Location
+		want     bool
+	}{
+		// Clocks go forward at 02:00 on 10 March 2030 in New York (UTC-5 to UTC-4)
+		{"first hour after spring forward", time.Date(2030, 3, 10, 12, 0, 0, 0, time.UTC), 60, newYork, true},
+		{"UTC hour that was 08:00 the day before", time.Date(2030, 3, 10, 13, 0, 0, 0, time.UTC), 60, newYork, true},
+		{"07:30 local after spring forward", time.Date(2030, 3, 10, 11, 30, 0, 0, time.UTC), 60, newYork, false},
+		{"07:00 local the day before spring forward", time.Date(2030, 3, 9, 12, 0, 0, 0, time.UTC), 60, newYork, false},
+		{"last hour before spring forward day ends", time.Date(2030, 3, 10, 23, 0, 0, 0, time.UTC), 60, newYork, true},
+
+		// Clocks go back at 02:00 on 3 November 2030 in New York (UTC-4 to UTC-5)
+		{"19:00 local after fall back", time.Date(2030, 11, 4, 0, 0, 0, 0, time.UTC), 60, newYork, true},
+		{"running past 20:00 local after fall back", time.Date(2030, 11, 4, 0, 30, 0, 0, time.UTC), 60, newYork, false},
+		{"08:00 local after fall back", time.Date(2030, 11, 3, 13, 0, 0, 0, time.UTC), 30, newYork, true},
+		{"07:00 local after fall back", time.Date(2030, 11, 3, 12, 0, 0, 0, time.UTC), 30, newYork, false},
+
+		// Clocks go forward at 01:00 UTC on 31 March 2030 in London (GMT to BST)
+		{"08:00 BST", time.Date(2030, 3, 31, 7, 0, 0, 0, time.UTC), 60, london, true},
+		{"07:00 BST", time.Date(2030, 3, 31, 6, 0, 0, 0, time.UTC), 60, london, false},
+		{"08:00 GMT the day before", time.Date(2030, 3, 30, 8, 0, 0, 0, time.UTC), 60, london, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hours.Contains(tt.start, tt.duration, tt.loc); got != tt.want {
+				t.Errorf("Contains(%s, %d) = %v; want %v", tt.start.In(tt.loc), tt.duration, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWorkingHoursConfig(t *testing.T) {
+	tests := []struct {
+		hours WorkingHours
+		valid bool
+	}{
+		{WorkingHours{StartHour: 8, EndHour: 20}, true},
+		{WorkingHours{StartHour: 0, EndHour: 24}, true},
+		{WorkingHours{StartHour: 18, EndHour: 22}, true},
+		{WorkingHours{StartHour: 20, EndHour: 8}, false},
+		{WorkingHours{StartHour: 9, EndHour: 9}, false},
+		{WorkingHours{StartHour: -1, EndHour: 20}, false},
+		{WorkingHours{StartHour: 8, EndHour: 25}, false},
+	}
+
+	for _, tt := range tests {
+		v := validator.New()
+		if ValidateWorkingHours(v, tt.hours); v.Valid() != tt.valid {
+			t.Errorf("%+v: got errors %v; want valid=%v", tt.hours, v.Errors, tt.valid)
+		}
+	}
+}
+
+func TestValidateScheduleUsesConfiguredWorkingHours(t *testing.T) {
+	nairobi := mustLoadLocation(t, "Africa/Nairobi")
+
+	schedule := &Schedule{
+		PropertyID:         1,
+		UserID:             2,
+		AgentID:            3,
+		ScheduledAt:        time.Date(2099, 6, 1, 21, 0, 0, 0, nairobi),
+		DurationMinutes:    60,
+		Status:             "pending",
+		AgentLocation:      nairobi,
+		WorkingHours:       WorkingHours{StartHour: 18, EndHour: 23},
+		MaxDurationMinutes: 480,
+	}
+
+	v := validator.New()
+	if ValidateSchedule(v, schedule); !v.Valid() {
+		t.Fatalf("21:00 within 18:00 to 23:00: got errors %v", v.Errors)
+	}
+
+	schedule.WorkingHours = WorkingHours{StartHour: 8, EndHour: 20}
+	v = validator.New()
+	if ValidateSchedule(v, schedule); v.Valid() {
+		t.Fatal("21:00 outside 08:00 to 20:00: got no errors")
+	}
+}
+
+func TestScheduleColumnsMatchScanDest(t *testing.T) {
+	want := len((&Schedule{}).scanDest())
+
+	for name, columns := range map[string]string{"scheduleColumns": scheduleColumns, "scheduleColumnsS": scheduleColumnsS} {
+		if got := countColumns(columns); got != want {
+			t.Errorf("%s has %d columns; scanDest has %d destinations", name, got, want)
+		}
+	}
+}
+
+// countColumns counts the entries of a select list, ignoring commas inside parentheses
+func countColumns(list string) int {
+	count, depth := 1, 0
+	for _, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestValidateScheduleUsesConfiguredMaxDuration(t *testing.T) {
+	schedule := &Schedule{
+		PropertyID:         1,
+		UserID:             2,
+		AgentID:            3,
+		ScheduledAt:        time.Now().Add(24 * time.Hour),
+		DurationMinutes:    90,
+		Status:             "pending",
+		MaxDurationMinutes: 120,
+	}
+
+	v := validator.New()
+	if ValidateSchedule(v, schedule); !v.Valid() {
+		t.Fatalf("90 minutes with a 120 minute cap: got errors %v", v.Errors)
+	}
+
+	schedule.MaxDurationMinutes = 60
+	v = validator.New()
+	if ValidateSchedule(v, schedule); v.Errors["duration_minutes"] == "" {
+		t.Errorf("90 minutes with a 60 minute cap: got errors %v; want a duration_minutes error", v.Errors)
+	}
+}
+
+func TestBookingWindowsReplaceWorkingHours(t *testing.T) {
+	hours := WorkingHours{StartHour: 8, EndHour: 20}
+
+	tests := []struct {
+		name    string
+		windows []availabilityWindow
+		day     time.Weekday
+		want    []availabilityWindow
+	}{
+		{
+			name: "no availability uses working hours",
+			day:  time.Monday,
+			want: []availabilityWindow{{day: time.Monday, start: 8 * 60, end: 20 * 60}},
+		},
+		{
+			name:    "availability outside working hours is kept whole",
+			windows: []availabilityWindow{{day: time.Saturday, start: 6 * 60, end: 22 * 60}},
+			day:     time.Saturday,
+			want:    []availabilityWindow{{day: time.Saturday, start: 6 * 60, end: 22 * 60}},
+		},
+		{
+			name:    "no window on the day",
+			windows: []availabilityWindow{{day: time.Saturday, start: 9 * 60, end: 12 * 60}},
+			day:     time.Monday,
+			want:    []availabilityWindow{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bookingWindows(tt.windows, tt.day, hours)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got windows %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateScheduleWithoutWorkingHoursAllowsEveningViewings(t *testing.T) {
+	// Agents with configured availability have no fixed working hours; their windows
+	// are checked when the viewing is stored
+	schedule := &Schedule{
+		PropertyID:         1,
+		UserID:             2,
+		AgentID:            3,
+		ScheduledAt:        time.Date(2099, 6, 1, 21, 0, 0, 0, time.UTC),
+		DurationMinutes:    60,
+		Status:             "pending",
+		AgentLocation:      time.UTC,
+		MaxDurationMinutes: 480,
+	}
+
+	v := validator.New()
+	if ValidateSchedule(v, schedule); !v.Valid() {
+		t.Fatalf("got errors %v; want none", v.Errors)
+	}
+}
+
+func TestCompletePastConfirmed(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	viewer := seedTestUser(t, models, "user")
+	property := seedTestProperty(t, models, agent.ID)
+
+	seed := func(status string, startsIn time.Duration) int64 {
+		var id int64
+		err := db.QueryRow(`
+			INSERT INTO schedules (property_id, user_id, agent_id, scheduled_at, duration_minutes, status)
+			VALUES ($1, $2, $3, $4, 60, $5)
+			RETURNING id`, property.ID, viewer.ID, agent.ID, time.Now().Add(startsIn), status).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	past := seed("confirmed", -3*time.Hour)
+	upcoming := seed("confirmed", 3*time.Hour)
+	pending := seed("pending", -3*time.Hour)
+
+	count, completed, err := models.Schedules.CompletePastConfirmed(agent.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || len(completed) != 1 || completed[0].ID != past {
+		t.Fatalf("got %d completed %v; want only schedule %d", count, completed, past)
+	}
+
+	for id, want := range map[int64]string{past: "completed", upcoming: "confirmed", pending: "pending"} {
+		schedule, err := models.Schedules.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schedule.Status != want {
+			t.Errorf("schedule %d: got status %q; want %q", id, schedule.Status, want)
+		}
+	}
+
+	var completedAt *time.Time
+	var version int
+	err = db.QueryRow(`SELECT completed_at, version FROM schedules WHERE id = $1`, past).Scan(&completedAt, &version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completedAt == nil {
+		t.Error("completed schedule has no completed_at")
+	}
+	if version != 2 {
+		t.Errorf("got version %d; want 2", version)
+	}
+
+	// Running again changes nothing
+	count, _, err = models.Schedules.CompletePastConfirmed(agent.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("second run completed %d schedules; want 0", count)
+	}
+}
+
+func TestMarkPastAsCompleted(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	viewer := seedTestUser(t, models, "user")
+	property := seedTestProperty(t, models, agent.ID)
+
+	var id int64
+	err := db.QueryRow(`
+		INSERT INTO schedules (property_id, user_id, agent_id, scheduled_at, duration_minutes, status)
+		VALUES ($1, $2, $3, $4, 60, 'confirmed')
+		RETURNING id`, property.ID, viewer.ID, agent.ID, time.Now().Add(-3*time.Hour)).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, completed, err := models.Schedules.MarkPastAsCompleted(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(completed, func(s *Schedule) bool { return s.ID == id }) {
+		t.Fatalf("schedule %d was not among the completed schedules", id)
+	}
+
+	schedule, err := models.Schedules.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Status != "completed" {
+		t.Errorf("got status %q; want %q", schedule.Status, "completed")
+	}
+	if schedule.Version != 2 {
+		t.Errorf("got version %d; want 2", schedule.Version)
+	}
+
+	// Running again leaves the completed schedule alone
+	_, completed, err = models.Schedules.MarkPastAsCompleted(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(completed, func(s *Schedule) bool { return s.ID == id }) {
+		t.Errorf("schedule %d was completed twice", id)
+	}
+}
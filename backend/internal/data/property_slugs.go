@@ -0,0 +1,98 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSlugBaseLength caps the title part of a slug
+const maxSlugBaseLength = 80
+
+// slugBase turns a title into lowercase ASCII words joined by hyphens,
+// eg "3 Bed House, Karen!" becomes "3-bed-house-karen"
+func slugBase(title string) string {
+	var sb strings.Builder
+	pendingHyphen := false
+
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingHyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			pendingHyphen = false
+			continue
+		}
+		pendingHyphen = true
+	}
+
+	base := sb.String()
+	if len(base) > maxSlugBaseLength {
+		base = strings.TrimRight(base[:maxSlugBaseLength], "-")
+	}
+	return base
+}
+
+// PropertySlug returns the slug for a listing. The id suffix keeps slugs unique
+// even when titles repeat.
+func PropertySlug(title string, id int64) string {
+	base := slugBase(title)
+	if base == "" {
+		return strconv.FormatInt(id, 10)
+	}
+	return base + "-" + strconv.FormatInt(id, 10)
+}
+
+// GetBySlug retrieves a property by its current slug or by one it had before being
+// renamed. Callers compare the returned property's Slug with the requested one to
+// detect a stale slug.
+func (p PropertyModel) GetBySlug(slug string) (*Property, error) {
+	if slug == "" {
+		return nil, ErrPropertyNotFound
+	}
+
+	query := `
+	SELECT ` + propertyColumns("p") + `
+	FROM properties p
+	WHERE p.slug = $1 AND p.deleted_at IS NULL
+	UNION ALL
+	SELECT ` + propertyColumns("p") + `
+	FROM properties p
+	INNER JOIN property_slug_history h ON h.property_id = p.id
+	WHERE h.slug = $1 AND p.deleted_at IS NULL
+	LIMIT 1`
+
+	var property Property
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := p.DB.QueryRowContext(ctx, query, slug).Scan(property.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrPropertyNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &property, nil
+}
+
+// recordSlugChange keeps a renamed listing's previous slug so old links still resolve
+func recordSlugChange(ctx context.Context, tx *sql.Tx, propertyID int64, previous, current string) error {
+	if previous == "" || previous == current {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO property_slug_history (slug, property_id)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING`, previous, propertyID)
+	return err
+}
@@ -0,0 +1,133 @@
+package data
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestPropertyIsPublic(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		property Property
+		want     bool
+	}{
+		{"approved", Property{Status: PropertyStatusApproved}, true},
+		{"approved and not yet expired", Property{Status: PropertyStatusApproved, ExpiresAt: &future}, true},
+		{"approved but expired", Property{Status: PropertyStatusApproved, ExpiresAt: &past}, false},
+		{"approved but deleted", Property{Status: PropertyStatusApproved, DeletedAt: &past}, false},
+		{"pending", Property{Status: PropertyStatusPending}, false},
+		{"scheduled", Property{Status: PropertyStatusScheduled}, false},
+		{"rejected", Property{Status: "rejected"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.property.IsPublic(); got != tt.want {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicPropertyCondition(t *testing.T) {
+	want := "(p.status = 'approved' AND (p.expires_at IS NULL OR p.expires_at > NOW()) AND p.deleted_at IS NULL)"
+	if got := publicPropertyCondition("p"); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	if got := publicPropertyCondition(""); strings.Contains(got, ".") {
+		t.Errorf("unqualified condition %q names a table", got)
+	}
+}
+
+func TestPublicQueriesUsePublicPropertyCondition(t *testing.T) {
+	searchQuery, _ := advancedSearchQuery(PropertySearchCriteria{}, testSearchFilters(), LocationSearch{})
+	favouritedQuery := mostFavouritedQuery(Filters{Page: 1, PageSize: 20, Sort: "-favourites", SortSafelist: FavouritedPropertySortSafelist})
+
+	tests := []struct {
+		name      string
+		query     string
+		condition string
+	}{
+		{"advanced search", searchQuery, publicPropertyCondition("")},
+		{"most favourited", favouritedQuery, publicPropertyCondition("properties")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(tt.query, tt.condition) {
+				t.Errorf("query is missing %q:\n%s", tt.condition, tt.query)
+			}
+			if strings.Contains(tt.query, "<> 'scheduled'") {
+				t.Errorf("query only hides scheduled listings:\n%s", tt.query)
+			}
+		})
+	}
+}
+
+func TestPublicQueriesHideUnapprovedListings(t *testing.T) {
+	db := newTestDB(t)
+	models := NewModels(db)
+
+	agent := seedTestUser(t, models, "agent")
+	approved := seedTestProperty(t, models, agent.ID)
+	pending := seedTestProperty(t, models, agent.ID)
+	expired := seedTestProperty(t, models, agent.ID)
+
+	// A location of their own keeps other listings in the database out of the results
+	location := fmt.Sprintf("Visibility %d", time.Now().UnixNano())
+	_, err := db.Exec(`UPDATE properties SET location = $1 WHERE id = ANY($2)`, location, pq.Array([]int64{approved.ID, pending.ID, expired.ID}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec(`UPDATE properties SET status = $1 WHERE id = $2`, PropertyStatusPending, pending.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec(`UPDATE properties SET expires_at = NOW() - INTERVAL '1 day' WHERE id = $1`, expired.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: PropertySortSafelist}
+	want := []int64{approved.ID}
+
+	listed, _, _, err := models.Properties.GetAll("", location, nil, nil, PriceRange{}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := propertyIDs(listed); !slices.Equal(got, want) {
+		t.Errorf("listings: got %v; want %v", got, want)
+	}
+
+	searched, _, err := models.Properties.AdvancedSearch(PropertySearchCriteria{Locations: []string{location}}, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := propertyIDs(searched); !slices.Equal(got, want) {
+		t.Errorf("search: got %v; want %v", got, want)
+	}
+
+	similar, err := models.Properties.GetSimilar(pending.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := propertyIDs(similar); !slices.Equal(got, want) {
+		t.Errorf("similar: got %v; want %v", got, want)
+	}
+}
+
+// propertyIDs returns the ids of properties in order
+func propertyIDs(properties []*Property) []int64 {
+	ids := make([]int64, len(properties))
+	for i, property := range properties {
+		ids[i] = property.ID
+	}
+	return ids
+}
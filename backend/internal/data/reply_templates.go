@@ -0,0 +1,345 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+)
+
+// ReplyTemplate is an agent's reusable inquiry reply with {{placeholder}} fields
+type ReplyTemplate struct {
+	ID        int64     `json:"id"`
+	AgentID   int64     `json:"-"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int       `json:"version"`
+}
+
+// ReplyTemplatePlaceholders are the only names a template body may reference
+var ReplyTemplatePlaceholders = []string{"name", "property", "agent", "inquiry_type"}
+
+// DefaultReplyTemplates are given to each agent the first time they use templates
+var DefaultReplyTemplates = []ReplyTemplate{
+	{
+		Title: "Thanks for your inquiry",
+		Body:  "Hi {{name}},\n\nThank you for your interest in {{property}}. I'll get back to you shortly with more details.\n\nRegards,\n{{agent}}",
+	},
+	{
+		Title: "Viewing availability",
+		Body:  "Hi {{name}},\n\nI'd be happy to arrange a viewing of {{property}}. Please let me know which days and times suit you best.\n\nRegards,\n{{agent}}",
+	},
+	{
+		Title: "No longer available",
+		Body:  "Hi {{name}},\n\nThank you for asking about {{property}}. Unfortunately it is no longer available, but I'd be glad to suggest similar listings.\n\nRegards,\n{{agent}}",
+	},
+}
+
+var (
+	ErrReplyTemplateNotFound  = errors.New("reply template not found")
+	ErrDuplicateReplyTemplate = errors.New("duplicate reply template title")
+)
+
+// ReplyTemplateData holds the values substituted into a template's placeholders
+type ReplyTemplateData struct {
+	Name        string
+	Property    string
+	Agent       string
+	InquiryType string
+}
+
+// ValidateReplyTemplate validates reply template fields, including its placeholders
+func ValidateReplyTemplate(v *validator.Validator, replyTemplate *ReplyTemplate) {
+	v.Check(strings.TrimSpace(replyTemplate.Title) != "", "title", "must be provided")
+	v.Check(len(replyTemplate.Title) <= 100, "title", "must not exceed 100 characters")
+	v.Check(strings.TrimSpace(replyTemplate.Body) != "", "body", "must be provided")
+	v.Check(len(replyTemplate.Body) <= 2000, "body", "must not exceed 2000 characters")
+
+	if replyTemplate.Body != "" {
+		if _, err := parseReplyTemplate(replyTemplate.Body, ReplyTemplateData{}); err != nil {
+			v.AddError("body", err.Error())
+		}
+	}
+}
+
+// RenderReplyTemplate substitutes the inquiry's values into a template body
+func RenderReplyTemplate(body string, values ReplyTemplateData) (string, error) {
+	tmpl, err := parseReplyTemplate(body, values)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, nil); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// parseReplyTemplate parses body with each placeholder bound to its value. Anything
+// other than plain text and bare {{placeholder}} actions is rejected.
+func parseReplyTemplate(body string, values ReplyTemplateData) (*template.Template, error) {
+	bound := map[string]string{
+		"name":         values.Name,
+		"property":     values.Property,
+		"agent":        values.Agent,
+		"inquiry_type": values.InquiryType,
+	}
+
+	funcs := template.FuncMap{}
+	for _, placeholder := range ReplyTemplatePlaceholders {
+		value := bound[placeholder]
+		funcs[placeholder] = func() string { return value }
+	}
+
+	tmpl, err := template.New("reply").Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("must only use the placeholders: {{%s}}", strings.Join(ReplyTemplatePlaceholders, "}}, {{"))
+	}
+	if tmpl.Tree == nil {
+		return tmpl, nil
+	}
+
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+		case *parse.ActionNode:
+			if !isReplyPlaceholder(n) {
+				return nil, fmt.Errorf("unsupported placeholder %s", n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported template syntax %s", n)
+		}
+	}
+
+	return tmpl, nil
+}
+
+// isReplyPlaceholder reports whether an action is a single known placeholder, eg {{name}}
+func isReplyPlaceholder(n *parse.ActionNode) bool {
+	if len(n.Pipe.Decl) != 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	ident, ok := n.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && validator.In(ident.Ident, ReplyTemplatePlaceholders...)
+}
+
+// ReplyTemplateModel wraps database operations for reply templates
+type ReplyTemplateModel struct {
+	DB *sql.DB
+}
+
+// Insert creates a new reply template
+func (m ReplyTemplateModel) Insert(replyTemplate *ReplyTemplate) error {
+	query := `
+		INSERT INTO reply_templates (agent_id, title, body)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{replyTemplate.AgentID, replyTemplate.Title, replyTemplate.Body}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&replyTemplate.ID, &replyTemplate.CreatedAt, &replyTemplate.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "reply_templates_agent_title_key"`:
+			return ErrDuplicateReplyTemplate
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retrieves one of the agent's reply templates
+func (m ReplyTemplateModel) Get(id, agentID int64) (*ReplyTemplate, error) {
+	if id < 1 {
+		return nil, ErrReplyTemplateNotFound
+	}
+
+	query := `
+		SELECT id, agent_id, title, body, created_at, version
+		FROM reply_templates
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var replyTemplate ReplyTemplate
+
+	err := m.DB.QueryRowContext(ctx, query, id, agentID).Scan(
+		&replyTemplate.ID,
+		&replyTemplate.AgentID,
+		&replyTemplate.Title,
+		&replyTemplate.Body,
+		&replyTemplate.CreatedAt,
+		&replyTemplate.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReplyTemplateNotFound
+		}
+		return nil, err
+	}
+
+	return &replyTemplate, nil
+}
+
+// GetAllForAgent lists the agent's reply templates by title
+func (m ReplyTemplateModel) GetAllForAgent(agentID int64) ([]*ReplyTemplate, error) {
+	query := `
+		SELECT id, agent_id, title, body, created_at, version
+		FROM reply_templates
+		WHERE agent_id = $1
+		ORDER BY title ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	replyTemplates := []*ReplyTemplate{}
+
+	for rows.Next() {
+		var replyTemplate ReplyTemplate
+		err := rows.Scan(
+			&replyTemplate.ID,
+			&replyTemplate.AgentID,
+			&replyTemplate.Title,
+			&replyTemplate.Body,
+			&replyTemplate.CreatedAt,
+			&replyTemplate.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		replyTemplates = append(replyTemplates, &replyTemplate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return replyTemplates, nil
+}
+
+// SeedDefaults gives the agent the default templates, once. Agents who have been
+// seeded before are left alone even if they have since deleted every template.
+func (m ReplyTemplateModel) SeedDefaults(agentID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO reply_template_seeds (agent_id)
+		VALUES ($1)
+		ON CONFLICT (agent_id) DO NOTHING`, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return nil
+	}
+
+	for _, replyTemplate := range DefaultReplyTemplates {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO reply_templates (agent_id, title, body)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (agent_id, title) DO NOTHING`, agentID, replyTemplate.Title, replyTemplate.Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Update saves a reply template using optimistic locking
+func (m ReplyTemplateModel) Update(replyTemplate *ReplyTemplate) error {
+	query := `
+		UPDATE reply_templates
+		SET title = $1, body = $2, version = version + 1
+		WHERE id = $3 AND agent_id = $4 AND version = $5
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{
+		replyTemplate.Title,
+		replyTemplate.Body,
+		replyTemplate.ID,
+		replyTemplate.AgentID,
+		replyTemplate.Version,
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&replyTemplate.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "reply_templates_agent_title_key"`:
+			return ErrDuplicateReplyTemplate
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes one of the agent's reply templates
+func (m ReplyTemplateModel) Delete(id, agentID int64) error {
+	if id < 1 {
+		return ErrReplyTemplateNotFound
+	}
+
+	query := `
+		DELETE FROM reply_templates
+		WHERE id = $1 AND agent_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, agentID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrReplyTemplateNotFound
+	}
+
+	return nil
+}
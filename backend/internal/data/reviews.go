@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/codercollo/property/backend/internal/validator"
@@ -22,6 +23,41 @@ type Review struct {
 	ApprovedAt *time.Time `json:"approved_at,omitempty"`
 	ApprovedBy *int64     `json:"approved_by,omitempty"`
 	Version    int32      `json:"version"`
+
+	// PropertyTitle is only filled in on agent review listings
+	PropertyTitle string `json:"property_title,omitempty"`
+}
+
+// DeletedUserName is shown as the author of reviews whose account has been deleted
+const DeletedUserName = "Deleted user"
+
+// reviewAuthorColumns selects a review's user_id and user_name. Reviews outlive their
+// author's account, so they are joined to users with a LEFT JOIN and a deleted author
+// reads as user 0 named DeletedUserName.
+const reviewAuthorColumns = `COALESCE(r.user_id, 0), COALESCE(u.name, '` + DeletedUserName + `') AS user_name`
+
+// PublicReview is a review with moderation details removed, for display to buyers
+type PublicReview struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	PropertyID    int64     `json:"property_id"`
+	PropertyTitle string    `json:"property_title"`
+	UserName      string    `json:"user_name"`
+	Rating        int32     `json:"rating"`
+	Comment       string    `json:"comment"`
+}
+
+// Public returns the review without its moderation fields
+func (r *Review) Public() *PublicReview {
+	return &PublicReview{
+		ID:            r.ID,
+		CreatedAt:     r.CreatedAt,
+		PropertyID:    r.PropertyID,
+		PropertyTitle: r.PropertyTitle,
+		UserName:      r.UserName,
+		Rating:        r.Rating,
+		Comment:       r.Comment,
+	}
 }
 
 // ValidateReview checks that all fields of a Review are valid
@@ -88,10 +124,10 @@ func (r ReviewModel) Get(id int64) (*Review, error) {
 
 	//SQL query to select review and associated user name
 	query := `
-		SELECT r.id, r.created_at, r.property_id, r.user_id, u.name as user_name,
+		SELECT r.id, r.created_at, r.property_id, ` + reviewAuthorColumns + `,
 		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version
 		FROM reviews r
-		INNER JOIN users u ON r.user_id = u.id
+		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.id = $1`
 
 	var review Review
@@ -129,16 +165,18 @@ func (r ReviewModel) Get(id int64) (*Review, error) {
 
 // GetAllForProperty retrieves approved reviews for a specific property with pagination
 // and returns metadata
-func (r ReviewModel) GetAllForProperty(propertyID int64, filters Filters) ([]*Review, Metadata, error) {
-	//SQL query to select approved reviews with total count for metadata
-	query := `
-		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, r.user_id, u.name as user_name,
+func (r ReviewModel) GetAllForProperty(propertyID int64, minRating int, filters Filters) ([]*Review, Metadata, error) {
+	//SQL query to select approved reviews with total count for metadata; ties on
+	//rating are broken newest first
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, `+reviewAuthorColumns+`,
 		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version
 		FROM reviews r
-		INNER JOIN users u ON r.user_id = u.id
+		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.property_id = $1 AND r.status = 'approved'
-		ORDER BY r.created_at DESC
-		LIMIT $2 OFFSET $3`
+		AND r.rating >= $2
+		ORDER BY r.%s %s, r.created_at DESC, r.id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
 	//Create context with 3-second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -146,6 +184,7 @@ func (r ReviewModel) GetAllForProperty(propertyID int64, filters Filters) ([]*Re
 
 	args := []interface{}{
 		propertyID,
+		minRating,
 		filters.limit(),
 		filters.offset(),
 	}
@@ -197,10 +236,10 @@ func (r ReviewModel) GetAllForProperty(propertyID int64, filters Filters) ([]*Re
 func (r ReviewModel) GetAllPending(filters Filters) ([]*Review, Metadata, error) {
 	//SQL query to select pending reviews with total count
 	query := `
-		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, r.user_id, u.name as user_name,
+		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, ` + reviewAuthorColumns + `,
 		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version
 		FROM reviews r
-		INNER JOIN users u ON r.user_id = u.id
+		LEFT JOIN users u ON r.user_id = u.id
 		WHERE r.status = 'pending'
 		ORDER BY r.created_at DESC
 		LIMIT $1 OFFSET $2`
@@ -378,22 +417,48 @@ func (r ReviewModel) GetAverageRating(propertyID int64) (float64, int, error) {
 	return avgRating, count, nil
 }
 
-// GetAllForAgent retrieves all reviews for properties belonging to a specific agent
-func (m ReviewModel) GetAllForAgent(agentID int64, filters Filters) ([]*Review, Metadata, error) {
+// GetAverageRatingForAgent calculates the average rating and count of approved reviews across an agent's properties
+func (m ReviewModel) GetAverageRatingForAgent(agentID int64) (float64, int, error) {
 	query := `
-		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, r.user_id, u.name as user_name,
-		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version
+		SELECT COALESCE(AVG(r.rating), 0), COUNT(r.id)
 		FROM reviews r
-		INNER JOIN users u ON r.user_id = u.id
+		INNER JOIN properties p ON r.property_id = p.id
+		WHERE p.agent_id = $1 AND r.status = 'approved'
+		AND p.deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var avgRating float64
+	var count int
+
+	err := m.DB.QueryRowContext(ctx, query, agentID).Scan(&avgRating, &count)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return avgRating, count, nil
+}
+
+// GetAllForAgent retrieves reviews for properties belonging to a specific agent,
+// optionally limited to one status. Reviews of deleted properties are left out.
+func (m ReviewModel) GetAllForAgent(agentID int64, status string, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, `+reviewAuthorColumns+`,
+		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version, p.title
+		FROM reviews r
+		LEFT JOIN users u ON r.user_id = u.id
 		INNER JOIN properties p ON r.property_id = p.id
 		WHERE p.agent_id = $1
-		ORDER BY r.created_at DESC
-		LIMIT $2 OFFSET $3`
+		AND p.deleted_at IS NULL
+		AND (r.status = $2 OR $2 = '')
+		ORDER BY r.%s %s, r.id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{agentID, filters.limit(), filters.offset()}
+	args := []interface{}{agentID, status, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -419,6 +484,7 @@ func (m ReviewModel) GetAllForAgent(agentID int64, filters Filters) ([]*Review,
 			&review.ApprovedAt,
 			&review.ApprovedBy,
 			&review.Version,
+			&review.PropertyTitle,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -438,10 +504,10 @@ func (m ReviewModel) GetAllForAgent(agentID int64, filters Filters) ([]*Review,
 // GetPendingForAgent retrieves pending reviews for properties belonging to a specific agent
 func (m ReviewModel) GetPendingForAgent(agentID int64, filters Filters) ([]*Review, Metadata, error) {
 	query := `
-		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, r.user_id, u.name as user_name,
+		SELECT count(*) OVER(), r.id, r.created_at, r.property_id, ` + reviewAuthorColumns + `,
 		       r.rating, r.comment, r.status, r.approved_at, r.approved_by, r.version
 		FROM reviews r
-		INNER JOIN users u ON r.user_id = u.id
+		LEFT JOIN users u ON r.user_id = u.id
 		INNER JOIN properties p ON r.property_id = p.id
 		WHERE p.agent_id = $1 AND r.status = 'pending'
 		ORDER BY r.created_at DESC
@@ -0,0 +1,391 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// SavedSearch is a user's stored property search. New listings matching it are
+// emailed to the user at the chosen frequency.
+type SavedSearch struct {
+	ID        int64                  `json:"id"`
+	UserID    int64                  `json:"-"`
+	Name      string                 `json:"name"`
+	Query     string                 `json:"query"`
+	Criteria  PropertySearchCriteria `json:"criteria"`
+	Frequency string                 `json:"frequency"`
+	LastRunAt time.Time              `json:"last_run_at"`
+	CreatedAt time.Time              `json:"created_at"`
+	Version   int                    `json:"version"`
+}
+
+// SavedSearchFrequencies maps each alert frequency to the time between alerts
+var SavedSearchFrequencies = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// MaxSavedSearches caps how many saved searches a single user may keep
+const MaxSavedSearches = 20
+
+var (
+	ErrSavedSearchNotFound      = errors.New("saved search not found")
+	ErrDuplicateSavedSearch     = errors.New("duplicate saved search name")
+	ErrSavedSearchLimitExceeded = errors.New("saved search limit reached")
+)
+
+// ValidateSavedSearch validates saved search fields; the criteria are validated when
+// the query is parsed
+func ValidateSavedSearch(v *validator.Validator, search *SavedSearch) {
+	v.Check(search.Name != "", "name", "must be provided")
+	v.Check(len(search.Name) <= 100, "name", "must not exceed 100 characters")
+	v.Check(len(search.Query) <= 2000, "query", "must not exceed 2000 characters")
+
+	_, ok := SavedSearchFrequencies[search.Frequency]
+	v.Check(ok, "frequency", "must be daily or weekly")
+}
+
+// SavedSearchAlert is a saved search due to run, with the owner's contact details
+type SavedSearchAlert struct {
+	SavedSearch
+	UserName  string
+	UserEmail string
+}
+
+// SavedSearchModel wraps database operations for saved searches
+type SavedSearchModel struct {
+	DB *sql.DB
+}
+
+// Insert stores a saved search, failing once the user has MaxSavedSearches
+func (m SavedSearchModel) Insert(search *SavedSearch) error {
+	criteria, err := json.Marshal(search.Criteria)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saved_searches (user_id, name, query, criteria, frequency)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (SELECT COUNT(*) FROM saved_searches WHERE user_id = $1) < $6
+		RETURNING id, last_run_at, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{search.UserID, search.Name, search.Query, criteria, search.Frequency, MaxSavedSearches}
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&search.ID, &search.LastRunAt, &search.CreatedAt, &search.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrSavedSearchLimitExceeded
+		case err.Error() == `pq: duplicate key value violates unique constraint "saved_searches_user_name_key"`:
+			return ErrDuplicateSavedSearch
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// savedSearchColumns is the select list scanned by scanSavedSearch
+const savedSearchColumns = `s.id, s.user_id, s.name, s.query, s.criteria, s.frequency, s.last_run_at, s.created_at, s.version`
+
+// scanSavedSearch scans savedSearchColumns followed by any extra destinations
+func scanSavedSearch(row interface{ Scan(...interface{}) error }, search *SavedSearch, extra ...interface{}) error {
+	var criteria []byte
+
+	dest := []interface{}{
+		&search.ID,
+		&search.UserID,
+		&search.Name,
+		&search.Query,
+		&criteria,
+		&search.Frequency,
+		&search.LastRunAt,
+		&search.CreatedAt,
+		&search.Version,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(criteria, &search.Criteria)
+}
+
+// Get retrieves one of the user's saved searches
+func (m SavedSearchModel) Get(id, userID int64) (*SavedSearch, error) {
+	if id < 1 {
+		return nil, ErrSavedSearchNotFound
+	}
+
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches s
+		WHERE s.id = $1 AND s.user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var search SavedSearch
+
+	err := scanSavedSearch(m.DB.QueryRowContext(ctx, query, id, userID), &search)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+// GetAllForUser lists the user's saved searches by name
+func (m SavedSearchModel) GetAllForUser(userID int64) ([]*SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches s
+		WHERE s.user_id = $1
+		ORDER BY lower(s.name) ASC, s.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []*SavedSearch{}
+
+	for rows.Next() {
+		var search SavedSearch
+		if err := scanSavedSearch(rows, &search); err != nil {
+			return nil, err
+		}
+		searches = append(searches, &search)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+// Update changes a saved search using optimistic locking
+func (m SavedSearchModel) Update(search *SavedSearch) error {
+	criteria, err := json.Marshal(search.Criteria)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE saved_searches
+		SET name = $1, query = $2, criteria = $3, frequency = $4, version = version + 1
+		WHERE id = $5 AND user_id = $6 AND version = $7
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{search.Name, search.Query, criteria, search.Frequency, search.ID, search.UserID, search.Version}
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&search.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "saved_searches_user_name_key"`:
+			return ErrDuplicateSavedSearch
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete removes one of the user's saved searches
+func (m SavedSearchModel) Delete(id, userID int64) error {
+	if id < 1 {
+		return ErrSavedSearchNotFound
+	}
+
+	query := `
+		DELETE FROM saved_searches
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSavedSearchNotFound
+	}
+
+	return nil
+}
+
+// GetDue returns the saved searches whose alert interval has passed, oldest run first,
+// along with their owners' names and emails
+func (m SavedSearchModel) GetDue(now time.Time) ([]*SavedSearchAlert, error) {
+	frequencies := make([]string, 0, len(SavedSearchFrequencies))
+	cutoffs := make([]time.Time, 0, len(SavedSearchFrequencies))
+	for frequency, interval := range SavedSearchFrequencies {
+		frequencies = append(frequencies, frequency)
+		cutoffs = append(cutoffs, now.Add(-interval))
+	}
+
+	query := `
+		SELECT ` + savedSearchColumns + `, u.name, u.email
+		FROM saved_searches s
+		INNER JOIN users u ON u.id = s.user_id
+		INNER JOIN unnest($1::text[], $2::timestamptz[]) AS due(frequency, cutoff) ON due.frequency = s.frequency
+		WHERE s.last_run_at <= due.cutoff AND u.activated = true
+		ORDER BY s.last_run_at ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(frequencies), pq.Array(formatTimes(cutoffs)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []*SavedSearchAlert{}
+
+	for rows.Next() {
+		var alert SavedSearchAlert
+		if err := scanSavedSearch(rows, &alert.SavedSearch, &alert.UserName, &alert.UserEmail); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// formatTimes renders times for a timestamptz[] parameter
+func formatTimes(times []time.Time) []string {
+	formatted := make([]string, len(times))
+	for i, t := range times {
+		formatted[i] = t.UTC().Format(time.RFC3339Nano)
+	}
+	return formatted
+}
+
+// UnsentMatches returns the listings that have not yet been sent for the saved search
+func (m SavedSearchModel) UnsentMatches(searchID int64, propertyIDs []int64) ([]int64, error) {
+	unsent := []int64{}
+	if len(propertyIDs) == 0 {
+		return unsent, nil
+	}
+
+	query := `
+		SELECT id FROM unnest($2::bigint[]) AS id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM saved_search_matches
+			WHERE saved_search_id = $1 AND property_id = id
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, searchID, pq.Array(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		unsent = append(unsent, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return unsent, nil
+}
+
+// RecordMatches marks the listings as sent for the saved search, so a listing is never
+// emailed twice for one search. It returns the IDs that had not been recorded before.
+func (m SavedSearchModel) RecordMatches(searchID int64, propertyIDs []int64) ([]int64, error) {
+	newIDs := []int64{}
+	if len(propertyIDs) == 0 {
+		return newIDs, nil
+	}
+
+	query := `
+		INSERT INTO saved_search_matches (saved_search_id, property_id)
+		SELECT $1, unnest($2::bigint[])
+		ON CONFLICT DO NOTHING
+		RETURNING property_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, searchID, pq.Array(propertyIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		newIDs = append(newIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newIDs, nil
+}
+
+// MarkRun records when the saved search last ran; the next run looks for listings published after it
+func (m SavedSearchModel) MarkRun(id int64, ranAt time.Time) error {
+	query := `
+		UPDATE saved_searches
+		SET last_run_at = $1
+		WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, ranAt, id)
+	return err
+}
@@ -0,0 +1,93 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// ScheduleReminder is an upcoming viewing with the contact details of both parties
+type ScheduleReminder struct {
+	ScheduleID      int64
+	ScheduledAt     time.Time
+	DurationMinutes int
+	Status          string
+	PropertyID      int64
+	PropertyTitle   string
+	Location        string
+	UserName        string
+	UserEmail       string
+	UserTimezone    string
+	AgentName       string
+	AgentEmail      string
+	AgentTimezone   string
+}
+
+// GetUpcomingNeedingReminder returns pending and confirmed viewings starting within the
+// lead time that neither party has been reminded of yet, soonest first
+func (m ScheduleModel) GetUpcomingNeedingReminder(lead time.Duration) ([]*ScheduleReminder, error) {
+	query := `
+		SELECT s.id, s.scheduled_at, s.duration_minutes, s.status,
+		       p.id, p.title, p.location,
+		       u.name, u.email, u.timezone,
+		       a.name, a.email, a.timezone
+		FROM schedules s
+		INNER JOIN properties p ON p.id = s.property_id
+		INNER JOIN users u ON u.id = s.user_id
+		INNER JOIN users a ON a.id = s.agent_id
+		WHERE s.status IN ('pending', 'confirmed')
+		AND s.reminder_sent_at IS NULL
+		AND s.scheduled_at > NOW()
+		AND s.scheduled_at <= NOW() + make_interval(secs => $1)
+		ORDER BY s.scheduled_at ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, lead.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*ScheduleReminder{}
+
+	for rows.Next() {
+		var reminder ScheduleReminder
+		err := rows.Scan(
+			&reminder.ScheduleID,
+			&reminder.ScheduledAt,
+			&reminder.DurationMinutes,
+			&reminder.Status,
+			&reminder.PropertyID,
+			&reminder.PropertyTitle,
+			&reminder.Location,
+			&reminder.UserName,
+			&reminder.UserEmail,
+			&reminder.UserTimezone,
+			&reminder.AgentName,
+			&reminder.AgentEmail,
+			&reminder.AgentTimezone,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, &reminder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// MarkReminderSent records that the viewing's reminders have gone out
+func (m ScheduleModel) MarkReminderSent(id int64) error {
+	query := `UPDATE schedules SET reminder_sent_at = NOW() WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
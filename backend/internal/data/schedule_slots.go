@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// busyInterval is a period in which the agent can't take a viewing
+type busyInterval struct {
+	start, end time.Time
+}
+
+// GetAvailableSlots returns the start times on the given date at which a viewing of
+// durationMinutes could be booked with the agent, earliest first. The date is read in
+// its own location, which should be the agent's timezone. Candidates sit on the agent's
+// slotMinutes grid inside their availability windows for that weekday, or within the
+// standard working hours when they have none. Times already booked by pending or
+// confirmed viewings, blacked out, or in the past are left out.
+func (m ScheduleModel) GetAvailableSlots(agentID int64, date time.Time, durationMinutes, slotMinutes int, hours WorkingHours) ([]time.Time, error) {
+	loc := date.Location()
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	windows, err := loadAvailabilityWindows(ctx, m.DB, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	windows = bookingWindows(windows, dayStart.Weekday(), hours)
+
+	query := `
+		SELECT scheduled_at, scheduled_at + make_interval(mins => duration_minutes)
+		FROM schedules
+		WHERE agent_id = $1
+		AND status IN ('pending', 'confirmed')
+		AND scheduled_at < $3
+		AND scheduled_at + make_interval(mins => duration_minutes) > $2
+		UNION ALL
+		SELECT starts_at, ends_at
+		FROM agent_blackouts
+		WHERE agent_id = $1
+		AND starts_at < $3
+		AND ends_at > $2`
+
+	rows, err := m.DB.QueryContext(ctx, query, agentID, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	busy := []busyInterval{}
+
+	for rows.Next() {
+		var interval busyInterval
+		if err := rows.Scan(&interval.start, &interval.end); err != nil {
+			return nil, err
+		}
+		busy = append(busy, interval)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	duration := time.Duration(durationMinutes) * time.Minute
+	slot := time.Duration(slotMinutes) * time.Minute
+	now := time.Now()
+
+	slots := []time.Time{}
+
+	for _, window := range windows {
+		// Bounds are built from the local date so DST changes shift them correctly
+		from, to := window.start, window.end
+		windowStart := time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day(), from/60, from%60, 0, 0, loc)
+		windowEnd := time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day(), to/60, to%60, 0, 0, loc)
+
+		// Start on the slot grid bookings are checked against, which runs from local midnight
+		start := windowStart
+		if rem := from % slotMinutes; rem != 0 {
+			start = start.Add(time.Duration(slotMinutes-rem) * time.Minute)
+		}
+
+		for ; !start.Add(duration).After(windowEnd); start = start.Add(slot) {
+			if !start.After(now) {
+				continue
+			}
+			if overlapsBusy(start, start.Add(duration), busy) {
+				continue
+			}
+			slots = append(slots, start)
+		}
+	}
+
+	// Windows are stored in no particular order
+	slices.SortFunc(slots, time.Time.Compare)
+
+	return slots, nil
+}
+
+// bookingWindows returns the windows on day in which viewings may be booked. Configured
+// availability replaces the standard working hours; agents without any can be booked
+// throughout the working day.
+func bookingWindows(windows []availabilityWindow, day time.Weekday, hours WorkingHours) []availabilityWindow {
+	if len(windows) == 0 {
+		return []availabilityWindow{{day: day, start: hours.StartHour * 60, end: hours.EndHour * 60}}
+	}
+
+	onDay := []availabilityWindow{}
+	for _, window := range windows {
+		if window.day == day {
+			onDay = append(onDay, window)
+		}
+	}
+	return onDay
+}
+
+// overlapsBusy reports whether start to end overlaps any of the busy intervals
+func overlapsBusy(start, end time.Time, busy []busyInterval) bool {
+	for _, interval := range busy {
+		if start.Before(interval.end) && end.After(interval.start) {
+			return true
+		}
+	}
+	return false
+}
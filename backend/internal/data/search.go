@@ -4,26 +4,100 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/codercollo/property/backend/internal/validator"
 	"github.com/lib/pq"
 )
 
-// PropertySearchCriteria holds all possible search filters
+// PropertySearchCriteria holds all possible search filters. Saved searches store
+// the criteria as JSON, so the tags must stay stable.
 type PropertySearchCriteria struct {
-	Location     string
-	PropertyType string
-	Status       string // featured, standard, all
-	MinPrice     float64
-	MaxPrice     float64
-	MinBedrooms  int32
-	MaxBedrooms  int32
-	MinBathrooms int32
-	MaxBathrooms int32
-	MinArea      int32
-	MaxArea      int32
-	Features     []string
+	Locations     []string     `json:"locations,omitempty"`      // any of, partial match
+	PropertyTypes []string     `json:"property_types,omitempty"` // any of, partial match
+	Status        string       `json:"status,omitempty"`         // featured, standard, all
+	MinPrice      float64      `json:"min_price,omitempty"`
+	MaxPrice      float64      `json:"max_price,omitempty"`
+	MinBedrooms   int32        `json:"min_bedrooms,omitempty"`
+	MaxBedrooms   int32        `json:"max_bedrooms,omitempty"`
+	MinBathrooms  int32        `json:"min_bathrooms,omitempty"`
+	MaxBathrooms  int32        `json:"max_bathrooms,omitempty"`
+	MinArea       int32        `json:"min_area,omitempty"`
+	MaxArea       int32        `json:"max_area,omitempty"`
+	Features      []string     `json:"features,omitempty"`
+	MinParking    int32        `json:"min_parking,omitempty"`
+	Furnished     string       `json:"furnished,omitempty"`
+	BBox          *BoundingBox `json:"bbox,omitempty"`
+	MappableOnly  bool         `json:"-"` // only properties with coordinates
+	ListedAfter   *time.Time   `json:"-"` // only listings published after this time
+
+	// Radius search: properties within RadiusKm of the point. Zero RadiusKm disables it.
+	Latitude  float64 `json:"lat,omitempty"`
+	Longitude float64 `json:"lng,omitempty"`
+	RadiusKm  float64 `json:"radius_km,omitempty"`
+}
+
+// MaxSearchRadiusKm caps the radius of a radius search
+const MaxSearchRadiusKm = 200
+
+// kmPerDegreeLatitude is the approximate length of one degree of latitude
+const kmPerDegreeLatitude = 111.045
+
+// HasRadius reports whether the criteria include a radius search
+func (c PropertySearchCriteria) HasRadius() bool {
+	return c.RadiusKm > 0
+}
+
+// ValidateRadius checks the radius search point and distance
+func ValidateRadius(v *validator.Validator, criteria PropertySearchCriteria) {
+	v.Check(criteria.Latitude >= -90 && criteria.Latitude <= 90, "lat", "must be between -90 and 90")
+	v.Check(criteria.Longitude >= -180 && criteria.Longitude <= 180, "lng", "must be between -180 and 180")
+	v.Check(criteria.RadiusKm > 0, "radius_km", "must be greater than zero")
+	v.Check(criteria.RadiusKm <= MaxSearchRadiusKm, "radius_km", fmt.Sprintf("must not be more than %d", MaxSearchRadiusKm))
+}
+
+// distanceExpression is the great-circle (haversine) distance in km from the point at
+// the given latitude and longitude placeholders to each property
+func distanceExpression(latArg, lngArg int) string {
+	return fmt.Sprintf(`6371 * 2 * asin(sqrt(
+		power(sin(radians(latitude - $%[1]d) / 2), 2) +
+		cos(radians($%[1]d)) * cos(radians(latitude)) * power(sin(radians(longitude - $%[2]d) / 2), 2)))`, latArg, lngArg)
+}
+
+// longitudeRangeClause matches longitudes from west to east starting at placeholder
+// argPosition. A range crossing the antimeridian is split into its two sides, and one
+// spanning the whole globe isn't filtered at all.
+func longitudeRangeClause(argPosition int, west, east float64) (string, []interface{}) {
+	switch {
+	case east-west >= 360:
+		return "", nil
+	case west < -180:
+		west += 360
+	case east > 180:
+		east -= 360
+	default:
+		return fmt.Sprintf("longitude BETWEEN $%d AND $%d", argPosition, argPosition+1), []interface{}{west, east}
+	}
+
+	return fmt.Sprintf("(longitude BETWEEN $%d AND 180 OR longitude BETWEEN -180 AND $%d)", argPosition, argPosition+1), []interface{}{west, east}
+}
+
+// BoundingBox bounds a search area, in GeoJSON order (west, south, east, north)
+type BoundingBox struct {
+	MinLng float64 `json:"min_lng"`
+	MinLat float64 `json:"min_lat"`
+	MaxLng float64 `json:"max_lng"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// ValidateBoundingBox checks that the box has valid coordinates and a positive area
+func ValidateBoundingBox(v *validator.Validator, bbox *BoundingBox) {
+	v.Check(bbox.MinLng >= -180 && bbox.MaxLng <= 180, "bbox", "longitudes must be between -180 and 180")
+	v.Check(bbox.MinLat >= -90 && bbox.MaxLat <= 90, "bbox", "latitudes must be between -90 and 90")
+	v.Check(bbox.MinLng < bbox.MaxLng, "bbox", "min longitude must be less than max longitude")
+	v.Check(bbox.MinLat < bbox.MaxLat, "bbox", "min latitude must be less than max latitude")
 }
 
 // AvailableFilters represents all available filter options
@@ -35,6 +109,8 @@ type AvailableFilters struct {
 	BedroomRange  RangeInfo `json:"bedroom_range"`
 	BathroomRange RangeInfo `json:"bathroom_range"`
 	AreaRange     RangeInfo `json:"area_range"`
+	ParkingRange  RangeInfo `json:"parking_range"`
+	Furnished     []string  `json:"furnished"`
 }
 
 type PriceInfo struct {
@@ -49,22 +125,66 @@ type RangeInfo struct {
 
 // AdvancedSearch performs a comprehensive property search with multiple filters
 func (p PropertyModel) AdvancedSearch(criteria PropertySearchCriteria, filters Filters) ([]*Property, Metadata, error) {
+	query, args := advancedSearchQuery(criteria, filters, p.Locations)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	properties := []*Property{}
+	totalRecords := 0
+
+	err := p.queryLocations(ctx, len(criteria.Locations) > 0, query, args, func(rows *sql.Rows) error {
+		for rows.Next() {
+			var property Property
+			err := rows.Scan(append([]interface{}{&totalRecords}, property.scanDest()...)...)
+			if err != nil {
+				return err
+			}
+			properties = append(properties, &property)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return properties, metadata, nil
+}
+
+// advancedSearchQuery builds the AdvancedSearch query and its arguments, matching
+// location filters as configured by locationSearch
+func advancedSearchQuery(criteria PropertySearchCriteria, filters Filters, locationSearch LocationSearch) (string, []interface{}) {
 	// Build dynamic WHERE clauses
 	var whereClauses []string
 	var args []interface{}
 	argPosition := 1
 
-	// Location filter (case-insensitive partial match)
-	if criteria.Location != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("location ILIKE $%d", argPosition))
-		args = append(args, "%"+criteria.Location+"%")
+	// Location filter (case-insensitive partial match on any of the values, or a close
+	// spelling of one when fuzzy matching is on, with the closest matches first)
+	locationRank := ""
+	if len(criteria.Locations) > 0 {
+		patterns := fmt.Sprintf("$%d", argPosition)
+		args = append(args, pq.Array(likePatterns(criteria.Locations)))
 		argPosition++
+
+		if locationSearch.Fuzzy {
+			locations := fmt.Sprintf("$%d", argPosition)
+			args = append(args, pq.Array(criteria.Locations))
+			argPosition++
+
+			whereClauses = append(whereClauses, locationSearch.matchAny(locations, patterns))
+			locationRank = locationSearch.orderAny(locations)
+		} else {
+			whereClauses = append(whereClauses, fmt.Sprintf("location ILIKE ANY(%s)", patterns))
+		}
 	}
 
-	// Property type filter (case-insensitive partial match)
-	if criteria.PropertyType != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("property_type ILIKE $%d", argPosition))
-		args = append(args, "%"+criteria.PropertyType+"%")
+	// Property type filter (case-insensitive partial match on any of the values)
+	if len(criteria.PropertyTypes) > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("property_type ILIKE ANY($%d)", argPosition))
+		args = append(args, pq.Array(likePatterns(criteria.PropertyTypes)))
 		argPosition++
 	}
 
@@ -131,77 +251,96 @@ func (p PropertyModel) AdvancedSearch(criteria PropertySearchCriteria, filters F
 		argPosition++
 	}
 
-	// Combine WHERE clauses
-	whereSQL := "TRUE" // Default to no filters
-	if len(whereClauses) > 0 {
-		whereSQL = strings.Join(whereClauses, " AND ")
+	// Parking filter (at least the given number of spaces)
+	if criteria.MinParking > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("parking_spaces >= $%d", argPosition))
+		args = append(args, criteria.MinParking)
+		argPosition++
+	}
+
+	// Furnished status filter (exact match)
+	if criteria.Furnished != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf("furnished = $%d", argPosition))
+		args = append(args, criteria.Furnished)
+		argPosition++
+	}
+
+	// Only properties that can be placed on a map
+	if criteria.MappableOnly {
+		whereClauses = append(whereClauses, "latitude IS NOT NULL AND longitude IS NOT NULL")
+	}
+
+	// Only listings first published after the given time, eg since a saved search last ran,
+	// so listings that were scheduled or waited in moderation are still picked up
+	if criteria.ListedAfter != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("published_at > $%d", argPosition))
+		args = append(args, *criteria.ListedAfter)
+		argPosition++
+	}
+
+	// Bounding box filter
+	if criteria.BBox != nil {
+		whereClauses = append(whereClauses, fmt.Sprintf("longitude BETWEEN $%d AND $%d AND latitude BETWEEN $%d AND $%d",
+			argPosition, argPosition+1, argPosition+2, argPosition+3))
+		args = append(args, criteria.BBox.MinLng, criteria.BBox.MaxLng, criteria.BBox.MinLat, criteria.BBox.MaxLat)
+		argPosition += 4
+	}
+
+	// Radius filter. A bounding box around the point narrows the rows using the coordinate
+	// index before the exact distance is checked; near the poles only latitude is boxed.
+	// The distance is exposed as a column so results can also be sorted by it.
+	source := "properties"
+	if criteria.HasRadius() {
+		source = fmt.Sprintf("(SELECT *, %s AS distance FROM properties WHERE latitude IS NOT NULL) properties",
+			distanceExpression(argPosition, argPosition+1))
+		args = append(args, criteria.Latitude, criteria.Longitude)
+		argPosition += 2
+
+		latDelta := criteria.RadiusKm / kmPerDegreeLatitude
+		whereClauses = append(whereClauses, fmt.Sprintf("latitude BETWEEN $%d AND $%d", argPosition, argPosition+1))
+		args = append(args, criteria.Latitude-latDelta, criteria.Latitude+latDelta)
+		argPosition += 2
+
+		if cos := math.Cos(criteria.Latitude * math.Pi / 180); cos > 0.01 {
+			lngDelta := criteria.RadiusKm / (kmPerDegreeLatitude * cos)
+			if clause, lngArgs := longitudeRangeClause(argPosition, criteria.Longitude-lngDelta, criteria.Longitude+lngDelta); clause != "" {
+				whereClauses = append(whereClauses, clause)
+				args = append(args, lngArgs...)
+				argPosition += len(lngArgs)
+			}
+		}
+
+		whereClauses = append(whereClauses, fmt.Sprintf("distance <= $%d", argPosition))
+		args = append(args, criteria.RadiusKm)
+		argPosition++
 	}
 
+	// Only public listings appear in search
+	whereClauses = append(whereClauses, publicPropertyCondition(""))
+
+	// Combine WHERE clauses
+	whereSQL := strings.Join(whereClauses, " AND ")
+
 	// Add pagination arguments
 	args = append(args, filters.limit(), filters.offset())
 
 	// Build complete query
 	query := fmt.Sprintf(`
-		SELECT count(*) OVER(), id, created_at, title, year_built, area, bedrooms, 
-		       bathrooms, floor, price, location, property_type, features, images, 
-		       featured_at, agent_id, version
-		FROM properties
+		SELECT count(*) OVER(), %s
+		FROM %s
 		WHERE %s
-		ORDER BY %s %s, id ASC
+		ORDER BY %s%s, id ASC
 		LIMIT $%d OFFSET $%d`,
+		propertyColumns(""),
+		source,
 		whereSQL,
-		filters.sortColumn(),
-		filters.sortDirection(),
+		locationRank,
+		filters.orderBy(),
 		argPosition,
 		argPosition+1,
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	rows, err := p.DB.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, Metadata{}, err
-	}
-	defer rows.Close()
-
-	properties := []*Property{}
-	totalRecords := 0
-
-	for rows.Next() {
-		var property Property
-		err := rows.Scan(
-			&totalRecords,
-			&property.ID,
-			&property.CreatedAt,
-			&property.Title,
-			&property.YearBuilt,
-			&property.Area,
-			&property.Bedrooms,
-			&property.Bathrooms,
-			&property.Floor,
-			&property.Price,
-			&property.Location,
-			&property.PropertyType,
-			pq.Array(&property.Features),
-			pq.Array(&property.Images),
-			&property.FeaturedAt,
-			&property.AgentID,
-			&property.Version,
-		)
-		if err != nil {
-			return nil, Metadata{}, err
-		}
-		properties = append(properties, &property)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, Metadata{}, err
-	}
-
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
-
-	return properties, metadata, nil
+	return query, args
 }
 
 // GetAvailableFilters returns all available filter options from the database
@@ -212,7 +351,7 @@ func (p PropertyModel) GetAvailableFilters() (*AvailableFilters, error) {
 	filters := &AvailableFilters{}
 
 	// Get distinct property types
-	typeQuery := `SELECT DISTINCT property_type FROM properties WHERE property_type != '' ORDER BY property_type`
+	typeQuery := `SELECT DISTINCT property_type FROM properties WHERE property_type != '' AND deleted_at IS NULL ORDER BY property_type`
 	typeRows, err := p.DB.QueryContext(ctx, typeQuery)
 	if err != nil {
 		return nil, err
@@ -228,7 +367,7 @@ func (p PropertyModel) GetAvailableFilters() (*AvailableFilters, error) {
 	}
 
 	// Get distinct locations
-	locQuery := `SELECT DISTINCT location FROM properties WHERE location != '' ORDER BY location`
+	locQuery := `SELECT DISTINCT location FROM properties WHERE location != '' AND deleted_at IS NULL ORDER BY location`
 	locRows, err := p.DB.QueryContext(ctx, locQuery)
 	if err != nil {
 		return nil, err
@@ -244,7 +383,7 @@ func (p PropertyModel) GetAvailableFilters() (*AvailableFilters, error) {
 	}
 
 	// Get all unique features
-	featureQuery := `SELECT DISTINCT unnest(features) as feature FROM properties ORDER BY feature`
+	featureQuery := `SELECT DISTINCT unnest(features) as feature FROM properties WHERE deleted_at IS NULL ORDER BY feature`
 	featureRows, err := p.DB.QueryContext(ctx, featureQuery)
 	if err != nil {
 		return nil, err
@@ -260,32 +399,64 @@ func (p PropertyModel) GetAvailableFilters() (*AvailableFilters, error) {
 	}
 
 	// Get price range
-	priceQuery := `SELECT MIN(price), MAX(price) FROM properties WHERE price > 0`
+	priceQuery := `SELECT MIN(price), MAX(price) FROM properties WHERE price > 0 AND deleted_at IS NULL`
 	err = p.DB.QueryRowContext(ctx, priceQuery).Scan(&filters.PriceRange.Min, &filters.PriceRange.Max)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
 
 	// Get bedroom range
-	bedroomQuery := `SELECT MIN(bedrooms), MAX(bedrooms) FROM properties WHERE bedrooms > 0`
+	bedroomQuery := `SELECT MIN(bedrooms), MAX(bedrooms) FROM properties WHERE bedrooms > 0 AND deleted_at IS NULL`
 	err = p.DB.QueryRowContext(ctx, bedroomQuery).Scan(&filters.BedroomRange.Min, &filters.BedroomRange.Max)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
 
 	// Get bathroom range
-	bathroomQuery := `SELECT MIN(bathrooms), MAX(bathrooms) FROM properties WHERE bathrooms >= 0`
+	bathroomQuery := `SELECT MIN(bathrooms), MAX(bathrooms) FROM properties WHERE bathrooms >= 0 AND deleted_at IS NULL`
 	err = p.DB.QueryRowContext(ctx, bathroomQuery).Scan(&filters.BathroomRange.Min, &filters.BathroomRange.Max)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
 
 	// Get area range
-	areaQuery := `SELECT MIN(area), MAX(area) FROM properties WHERE area > 0`
+	areaQuery := `SELECT MIN(area), MAX(area) FROM properties WHERE area > 0 AND deleted_at IS NULL`
 	err = p.DB.QueryRowContext(ctx, areaQuery).Scan(&filters.AreaRange.Min, &filters.AreaRange.Max)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
 
+	// Get parking range
+	parkingQuery := `SELECT COALESCE(MIN(parking_spaces), 0), COALESCE(MAX(parking_spaces), 0) FROM properties WHERE parking_spaces IS NOT NULL AND deleted_at IS NULL`
+	err = p.DB.QueryRowContext(ctx, parkingQuery).Scan(&filters.ParkingRange.Min, &filters.ParkingRange.Max)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Get distinct furnished states
+	furnishedQuery := `SELECT DISTINCT furnished FROM properties WHERE furnished IS NOT NULL AND deleted_at IS NULL ORDER BY furnished`
+	furnishedRows, err := p.DB.QueryContext(ctx, furnishedQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer furnishedRows.Close()
+
+	for furnishedRows.Next() {
+		var furnished string
+		if err := furnishedRows.Scan(&furnished); err != nil {
+			return nil, err
+		}
+		filters.Furnished = append(filters.Furnished, furnished)
+	}
+
 	return filters, nil
 }
+
+// likePatterns wraps each value in % wildcards for a partial ILIKE match
+func likePatterns(values []string) []string {
+	patterns := make([]string, len(values))
+	for i, value := range values {
+		patterns[i] = "%" + value + "%"
+	}
+	return patterns
+}
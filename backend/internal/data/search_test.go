@@ -0,0 +1,136 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func testSearchFilters() Filters {
+	return Filters{Page: 1, PageSize: 20, Sort: "id", SortSafelist: PropertySortSafelist}
+}
+
+func TestAdvancedSearchQueryMultiValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		locations     []string
+		propertyTypes []string
+		wantLocations []string
+		wantTypes     []string
+	}{
+		{
+			name:          "single values",
+			locations:     []string{"Kilimani"},
+			propertyTypes: []string{"apartment"},
+			wantLocations: []string{"%Kilimani%"},
+			wantTypes:     []string{"%apartment%"},
+		},
+		{
+			name:          "several values",
+			locations:     []string{"Kilimani", "Lavington"},
+			propertyTypes: []string{"apartment", "townhouse"},
+			wantLocations: []string{"%Kilimani%", "%Lavington%"},
+			wantTypes:     []string{"%apartment%", "%townhouse%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria := PropertySearchCriteria{Locations: tt.locations, PropertyTypes: tt.propertyTypes}
+			query, args := advancedSearchQuery(criteria, testSearchFilters(), LocationSearch{})
+
+			for _, clause := range []string{"location ILIKE ANY($1)", "property_type ILIKE ANY($2)"} {
+				if !strings.Contains(query, clause) {
+					t.Errorf("query is missing %q:\n%s", clause, query)
+				}
+			}
+
+			if got := stringArrayArg(t, args[0]); !slices.Equal(got, tt.wantLocations) {
+				t.Errorf("location patterns: got %v; want %v", got, tt.wantLocations)
+			}
+			if got := stringArrayArg(t, args[1]); !slices.Equal(got, tt.wantTypes) {
+				t.Errorf("property type patterns: got %v; want %v", got, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestAdvancedSearchQueryWithoutMultiValueFilters(t *testing.T) {
+	query, _ := advancedSearchQuery(PropertySearchCriteria{}, testSearchFilters(), LocationSearch{})
+
+	for _, column := range []string{"location ILIKE", "property_type ILIKE"} {
+		if strings.Contains(query, column) {
+			t.Errorf("query filters on %q with no values given:\n%s", column, query)
+		}
+	}
+}
+
+// stringArrayArg returns the values of a query argument built with pq.Array
+func stringArrayArg(t *testing.T, arg interface{}) []string {
+	t.Helper()
+
+	array, ok := arg.(*pq.StringArray)
+	if !ok {
+		t.Fatalf("got argument of type %T; want *pq.StringArray", arg)
+	}
+	return []string(*array)
+}
+
+func TestLongitudeRangeClause(t *testing.T) {
+	tests := []struct {
+		name       string
+		west, east float64
+		wantClause string
+		wantArgs   []interface{}
+	}{
+		{"inside the range", 36.5, 37.1, "longitude BETWEEN $3 AND $4", []interface{}{36.5, 37.1}},
+		{"crossing 180 eastwards", 179.5, 180.5, "(longitude BETWEEN $3 AND 180 OR longitude BETWEEN -180 AND $4)", []interface{}{179.5, -179.5}},
+		{"crossing -180 westwards", -180.5, -179.5, "(longitude BETWEEN $3 AND 180 OR longitude BETWEEN -180 AND $4)", []interface{}{179.5, -179.5}},
+		{"spanning the globe", -200, 200, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := longitudeRangeClause(3, tt.west, tt.east)
+			if clause != tt.wantClause {
+				t.Errorf("got clause %q; want %q", clause, tt.wantClause)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("got args %v; want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestAdvancedSearchQueryRadiusAcrossAntimeridian(t *testing.T) {
+	// 50km around Taveuni, Fiji, which straddles 180
+	criteria := PropertySearchCriteria{Latitude: -16.8, Longitude: 179.9, RadiusKm: 50}
+	query, args := advancedSearchQuery(criteria, testSearchFilters(), LocationSearch{})
+
+	if !strings.Contains(query, "longitude BETWEEN -180 AND") {
+		t.Fatalf("query does not cover the far side of the antimeridian:\n%s", query)
+	}
+	for _, arg := range args {
+		if lng, ok := arg.(float64); ok && (lng > 180 || lng < -180) {
+			t.Errorf("got out of range longitude argument %v", lng)
+		}
+	}
+}
+
+func TestAdvancedSearchQueryListedAfter(t *testing.T) {
+	since := time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+	query, args := advancedSearchQuery(PropertySearchCriteria{ListedAfter: &since}, testSearchFilters(), LocationSearch{})
+
+	if !strings.Contains(query, "published_at > $1") {
+		t.Fatalf("query does not match on publish time:\n%s", query)
+	}
+	if strings.Contains(query, "created_at >") {
+		t.Errorf("query matches on creation time:\n%s", query)
+	}
+	if got, ok := args[0].(time.Time); !ok || !got.Equal(since) {
+		t.Errorf("got first argument %v; want %v", args[0], since)
+	}
+}
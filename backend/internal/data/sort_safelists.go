@@ -0,0 +1,113 @@
+package data
+
+// Sort safelists for each list endpoint. Filters.Sort is validated against one of
+// these by ValidateFilters, and only listed keys ever reach an ORDER BY clause, so
+// every key must name a sortable column of the resource's query.
+
+// PropertySortSafelist lists the sort keys accepted by the public property listings
+var PropertySortSafelist = []string{
+	"id", "title", "year_built", "price", "bedrooms", "bathrooms", "area", "created_at", "updated_at", "featured", "freshness",
+	"-id", "-title", "-year_built", "-price", "-bedrooms", "-bathrooms", "-area", "-created_at", "-updated_at", "-featured", "-freshness",
+}
+
+// FavouritedPropertySortSafelist adds the favourite count to the property sort keys
+var FavouritedPropertySortSafelist = append([]string{"favourites", "-favourites"}, PropertySortSafelist...)
+
+// AgentPropertySortSafelist lists the sort keys for an agent's own listings
+var AgentPropertySortSafelist = []string{
+	"id", "title", "year_built", "price", "updated_at",
+	"-id", "-title", "-year_built", "-price", "-updated_at",
+}
+
+// AdminPropertySortSafelist lists the sort keys for the admin property listing
+var AdminPropertySortSafelist = []string{
+	"id", "title", "price", "created_at", "updated_at",
+	"-id", "-title", "-price", "-created_at", "-updated_at",
+}
+
+// FavouriteSortSafelist lists the sort keys for a user's favourites
+var FavouriteSortSafelist = []string{
+	"id", "title", "price", "created_at",
+	"-id", "-title", "-price", "-created_at",
+}
+
+// ScheduleSortSafelist lists the sort keys for user and agent viewing schedules
+var ScheduleSortSafelist = []string{
+	"id", "scheduled_at", "updated_at",
+	"-id", "-scheduled_at", "-updated_at",
+}
+
+// AgentInquirySortSafelist lists the sort keys for an agent's inquiries
+var AgentInquirySortSafelist = []string{
+	"id", "created_at", "updated_at", "priority", "status",
+	"-id", "-created_at", "-updated_at", "-priority", "-status",
+}
+
+// AdminInquirySortSafelist lists the sort keys for the admin inquiry listing
+var AdminInquirySortSafelist = []string{
+	"id", "created_at", "updated_at", "priority", "status",
+	"-id", "-created_at", "-updated_at", "-priority", "-status",
+}
+
+// UserInquirySortSafelist lists the sort keys for a user's own inquiries
+var UserInquirySortSafelist = []string{
+	"id", "created_at", "updated_at",
+	"-id", "-created_at", "-updated_at",
+}
+
+// PropertyReviewSortSafelist lists the sort keys for a property's reviews
+var PropertyReviewSortSafelist = []string{
+	"created_at", "rating",
+	"-created_at", "-rating",
+}
+
+// AgentReviewSortSafelist lists the sort keys for the reviews of an agent's listings
+var AgentReviewSortSafelist = []string{
+	"id", "rating", "created_at",
+	"-id", "-rating", "-created_at",
+}
+
+// PendingReviewSortSafelist lists the sort keys for the admin review moderation queue
+var PendingReviewSortSafelist = []string{
+	"created_at",
+	"-created_at",
+}
+
+// AgentPendingReviewSortSafelist lists the sort keys for an agent's reviews awaiting moderation
+var AgentPendingReviewSortSafelist = []string{
+	"id", "created_at",
+	"-id", "-created_at",
+}
+
+// PaymentSortSafelist lists the sort keys for an agent's payment history
+var PaymentSortSafelist = []string{
+	"id", "amount", "created_at", "updated_at",
+	"-id", "-amount", "-created_at", "-updated_at",
+}
+
+// PropertyNoteSortSafelist lists the sort keys for the notes on a property
+var PropertyNoteSortSafelist = []string{
+	"created_at",
+	"-created_at",
+}
+
+// WebhookDeliverySortSafelist lists the sort keys for a webhook's delivery log
+var WebhookDeliverySortSafelist = []string{
+	"id", "created_at", "next_attempt_at",
+	"-id", "-created_at", "-next_attempt_at",
+}
+
+// UserSortSafelist lists the sort keys for the admin user listing
+var UserSortSafelist = []string{
+	"id", "name", "email", "created_at",
+	"-id", "-name", "-email", "-created_at",
+}
+
+// AgentSortSafelist lists the sort keys for the admin agent listing
+var AgentSortSafelist = []string{
+	"id", "name", "created_at",
+	"-id", "-name", "-created_at",
+}
+
+// AgentSearchSortSafelist lists the sort keys for the public agent search
+var AgentSearchSortSafelist = []string{"id"}
@@ -0,0 +1,221 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// migrationSchema is the tables, columns and indexed columns built up by the up
+// migrations. Only btree indexes are counted, as the others can't serve an ORDER BY;
+// indexed holds both plain columns and expressions.
+type migrationSchema struct {
+	columns map[string]map[string]bool
+	indexed map[string]map[string]bool
+}
+
+var (
+	sqlCommentRe     = regexp.MustCompile(`--[^\n]*`)
+	createTableRe    = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+)\s*\((.*)\)$`)
+	alterTableRe     = regexp.MustCompile(`(?is)^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?(\w+)\s+(.*)$`)
+	addColumnRe      = regexp.MustCompile(`(?i)ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+	createIndexRe    = regexp.MustCompile(`(?is)^CREATE (?:UNIQUE )?INDEX (?:CONCURRENTLY )?(?:IF NOT EXISTS )?\w+\s+ON (\w+)\s*(?:USING (\w+)\s*)?\((.*?)\)\s*(?:WHERE .*)?$`)
+	indexOrderingRe  = regexp.MustCompile(`(?i)\s+(ASC|DESC|NULLS FIRST|NULLS LAST)\b`)
+	identifierRe     = regexp.MustCompile(`\w+`)
+	tableConstraints = []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE"}
+)
+
+// loadMigrationSchema reads every up migration in order
+func loadMigrationSchema(t *testing.T) migrationSchema {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.up.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+
+	schema := migrationSchema{columns: map[string]map[string]bool{}, indexed: map[string]map[string]bool{}}
+	mark := func(set map[string]map[string]bool, table, column string) {
+		if set[table] == nil {
+			set[table] = map[string]bool{}
+		}
+		set[table][column] = true
+	}
+
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sql := sqlCommentRe.ReplaceAllString(string(contents), "")
+		for _, statement := range strings.Split(sql, ";") {
+			statement = strings.TrimSpace(statement)
+
+			if m := createTableRe.FindStringSubmatch(statement); m != nil {
+				table := strings.ToLower(m[1])
+				for _, definition := range splitTopLevel(m[2]) {
+					words := identifierRe.FindAllString(definition, -1)
+					if len(words) == 0 {
+						continue
+					}
+					upper := strings.ToUpper(definition)
+					if isTableConstraint(words[0]) {
+						// PRIMARY KEY (...) and UNIQUE (...) are backed by an index
+						if strings.HasPrefix(upper, "PRIMARY") || strings.HasPrefix(upper, "UNIQUE") {
+							for _, column := range identifierRe.FindAllString(definition[strings.Index(definition, "("):], -1) {
+								mark(schema.indexed, table, strings.ToLower(column))
+							}
+						}
+						continue
+					}
+					column := strings.ToLower(words[0])
+					mark(schema.columns, table, column)
+					if strings.Contains(upper, "PRIMARY KEY") || strings.Contains(upper, "UNIQUE") {
+						mark(schema.indexed, table, column)
+					}
+				}
+				continue
+			}
+
+			if m := alterTableRe.FindStringSubmatch(statement); m != nil {
+				for _, add := range addColumnRe.FindAllStringSubmatch(m[2], -1) {
+					mark(schema.columns, strings.ToLower(m[1]), strings.ToLower(add[1]))
+				}
+				continue
+			}
+
+			if m := createIndexRe.FindStringSubmatch(statement); m != nil {
+				if m[2] != "" && !strings.EqualFold(m[2], "btree") {
+					continue
+				}
+				for _, element := range splitTopLevel(m[3]) {
+					mark(schema.indexed, strings.ToLower(m[1]), normalizeIndexElement(element))
+				}
+			}
+		}
+	}
+
+	return schema
+}
+
+// normalizeIndexElement reduces an index column or expression to a comparable form,
+// dropping its ordering, whitespace and any outer parentheses
+func normalizeIndexElement(element string) string {
+	element = strings.ToLower(indexOrderingRe.ReplaceAllString(element, ""))
+	element = strings.Join(strings.Fields(element), "")
+	for strings.HasPrefix(element, "(") && strings.HasSuffix(element, ")") {
+		element = element[1 : len(element)-1]
+	}
+	return element
+}
+
+// splitTopLevel splits a CREATE TABLE body on the commas that aren't inside parentheses
+func splitTopLevel(body string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(body[start:]))
+}
+
+func isTableConstraint(word string) bool {
+	for _, keyword := range tableConstraints {
+		if strings.EqualFold(word, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSortSafelistsUseIndexedColumns(t *testing.T) {
+	schema := loadMigrationSchema(t)
+
+	// Sort keys computed by the query rather than read from a column
+	computed := map[string]bool{"favourites": true}
+
+	tests := []struct {
+		name     string
+		safelist []string
+		table    string
+		// tables for keys that come from a joined table
+		joined map[string]string
+	}{
+		{"PropertySortSafelist", PropertySortSafelist, "properties", nil},
+		{"FavouritedPropertySortSafelist", FavouritedPropertySortSafelist, "properties", nil},
+		{"AgentPropertySortSafelist", AgentPropertySortSafelist, "properties", nil},
+		{"AdminPropertySortSafelist", AdminPropertySortSafelist, "properties", nil},
+		{"FavouriteSortSafelist", FavouriteSortSafelist, "properties", map[string]string{"created_at": "user_favourites"}},
+		{"ScheduleSortSafelist", ScheduleSortSafelist, "schedules", nil},
+		{"AgentInquirySortSafelist", AgentInquirySortSafelist, "inquiries", nil},
+		{"AdminInquirySortSafelist", AdminInquirySortSafelist, "inquiries", nil},
+		{"UserInquirySortSafelist", UserInquirySortSafelist, "inquiries", nil},
+		{"PropertyReviewSortSafelist", PropertyReviewSortSafelist, "reviews", nil},
+		{"AgentReviewSortSafelist", AgentReviewSortSafelist, "reviews", nil},
+		{"PendingReviewSortSafelist", PendingReviewSortSafelist, "reviews", nil},
+		{"AgentPendingReviewSortSafelist", AgentPendingReviewSortSafelist, "reviews", nil},
+		{"PaymentSortSafelist", PaymentSortSafelist, "payments", nil},
+		{"PropertyNoteSortSafelist", PropertyNoteSortSafelist, "property_notes", nil},
+		{"WebhookDeliverySortSafelist", WebhookDeliverySortSafelist, "webhook_deliveries", nil},
+		{"UserSortSafelist", UserSortSafelist, "users", nil},
+		{"AgentSortSafelist", AgentSortSafelist, "users", nil},
+		{"AgentSearchSortSafelist", AgentSearchSortSafelist, "users", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range tt.safelist {
+				key = strings.TrimPrefix(key, "-")
+				if computed[key] {
+					continue
+				}
+
+				table := tt.table
+				if joined, ok := tt.joined[key]; ok {
+					table = joined
+				}
+
+				expr, isExpression := sortExpressions[key]
+				if !isExpression {
+					if !schema.columns[table][key] {
+						t.Errorf("sort key %q: %s has no column %q", key, table, key)
+					} else if !schema.indexed[table][key] {
+						t.Errorf("sort key %q: %s.%s is not indexed", key, table, key)
+					}
+					continue
+				}
+
+				// An expression is indexed as a whole, or through every column it reads
+				if schema.indexed[table][normalizeIndexElement(expr)] {
+					continue
+				}
+				columns := 0
+				for _, word := range identifierRe.FindAllString(strings.ToLower(expr), -1) {
+					if !schema.columns[table][word] {
+						continue
+					}
+					columns++
+					if !schema.indexed[table][word] {
+						t.Errorf("sort key %q: %s.%s is not indexed", key, table, word)
+					}
+				}
+				if columns == 0 {
+					t.Errorf("sort key %q: expression %q reads no column of %s", key, expr, table)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDBEnv names the database used by tests that need one. It must already have the
+// migrations applied; those tests are skipped when it isn't set.
+const testDBEnv = "PROPERTY_TEST_DB_DSN"
+
+// newTestDB opens the test database, skipping the test when none is configured
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDBEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDBEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seedTestUser inserts a user with the given role, removed again when the test ends
+func seedTestUser(t *testing.T, models Models, role string) *User {
+	t.Helper()
+
+	user := &User{
+		Name:      "Test " + role,
+		Email:     fmt.Sprintf("%s-%d@example.com", role, time.Now().UnixNano()),
+		Activated: true,
+		Role:      role,
+	}
+	if err := user.Password.Set("pa55word1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { models.Users.DB.Exec(`DELETE FROM users WHERE id = $1`, user.ID) })
+
+	return user
+}
+
+// seedTestProperty inserts an approved listing for the agent, removed again when the test ends
+func seedTestProperty(t *testing.T, models Models, agentID int64) *Property {
+	t.Helper()
+
+	property := &Property{
+		Title:        "Garden flat",
+		YearBuilt:    2010,
+		Area:         80,
+		Bedrooms:     2,
+		Bathrooms:    1,
+		Price:        100000,
+		Location:     "Kilimani",
+		PropertyType: "apartment",
+		Features:     []string{"garden"},
+		Images:       []string{"https://example.com/1.jpg"},
+		AgentID:      sql.NullInt64{Int64: agentID, Valid: true},
+	}
+	if err := models.Properties.Insert(property); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { models.Properties.DB.Exec(`DELETE FROM properties WHERE id = $1`, property.ID) })
+
+	// New listings wait for moderation, so approve it directly
+	_, err := models.Properties.DB.Exec(`UPDATE properties SET status = $1, published_at = NOW() WHERE id = $2`, PropertyStatusApproved, property.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	property.Status = PropertyStatusApproved
+
+	return property
+}
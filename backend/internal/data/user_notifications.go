@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// UserNotification is an in-app notification shown to a user until they read it
+type UserNotification struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Kind       string     `json:"kind"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	PropertyID *int64     `json:"property_id,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Kinds of in-app notification
+const (
+	NotificationPaymentCompleted = "payment.completed"
+	NotificationPaymentFailed    = "payment.failed"
+)
+
+// MaxListedNotifications caps how many notifications are returned at once, newest first
+const MaxListedNotifications = 50
+
+// ErrNotificationNotFound is returned when a notification doesn't exist or belongs to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// UserNotificationModel wraps database operations for in-app notifications
+type UserNotificationModel struct {
+	DB *sql.DB
+}
+
+// Insert stores a new notification for its user
+func (m UserNotificationModel) Insert(notification *UserNotification) error {
+	query := `
+		INSERT INTO notifications (user_id, kind, title, body, property_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{notification.UserID, notification.Kind, notification.Title, notification.Body, notification.PropertyID}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&notification.ID, &notification.CreatedAt)
+}
+
+// GetAllForUser returns the user's most recent notifications, newest first, only the
+// unread ones when unreadOnly is set, along with the number still unread
+func (m UserNotificationModel) GetAllForUser(userID int64, unreadOnly bool) ([]*UserNotification, int, error) {
+	query := `
+		SELECT id, user_id, kind, title, body, property_id, read_at, created_at,
+		       (SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1 AND (read_at IS NULL OR NOT $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, unreadOnly, MaxListedNotifications)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []*UserNotification{}
+	unread := 0
+
+	for rows.Next() {
+		var notification UserNotification
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserID,
+			&notification.Kind,
+			&notification.Title,
+			&notification.Body,
+			&notification.PropertyID,
+			&notification.ReadAt,
+			&notification.CreatedAt,
+			&unread,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkRead marks one of the user's notifications as read; reading it again is a no-op
+func (m UserNotificationModel) MarkRead(id, userID int64) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
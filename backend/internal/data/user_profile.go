@@ -26,7 +26,8 @@ var (
 	ErrFileTooLarge    = errors.New("file size exceeds maximum allowed")
 )
 
-// AllowedProfilePhotoTypes defines acceptable image MIME types
+// AllowedProfilePhotoTypes defines acceptable image MIME types. JPEG and PNG photos are
+// cropped and resized on upload; WebP photos are stored as uploaded.
 var AllowedProfilePhotoTypes = map[string]bool{
 	"image/jpeg": true,
 	"image/jpg":  true,
@@ -40,6 +41,7 @@ type ProfilePhotoUpload struct {
 	Header   *multipart.FileHeader
 	UserID   int64
 	PhotoURL string
+	MaxSize  int64 // defaults to MaxProfilePhotoSize
 }
 
 // ValidateProfilePhotoUpload validates the uploaded profile photo
@@ -51,8 +53,12 @@ func ValidateProfilePhotoUpload(v *validator.Validator, upload *ProfilePhotoUplo
 	}
 
 	// Check file size
-	if upload.Header.Size > MaxProfilePhotoSize {
-		v.AddError("photo", fmt.Sprintf("file size must not exceed %d MB", MaxProfilePhotoSize/(1024*1024)))
+	maxSize := upload.MaxSize
+	if maxSize <= 0 {
+		maxSize = MaxProfilePhotoSize
+	}
+	if upload.Header.Size > maxSize {
+		v.AddError("photo", fmt.Sprintf("file size must not exceed %d KB", maxSize/1024))
 	}
 
 	// Check file type
@@ -67,15 +73,14 @@ func ValidateProfilePhotoUpload(v *validator.Validator, upload *ProfilePhotoUplo
 	}
 }
 
-// SaveProfilePhoto saves the uploaded file to disk and returns the file path
-func SaveProfilePhoto(file multipart.File, header *multipart.FileHeader, userID int64) (string, error) {
+// SaveProfilePhoto saves the processed photo to disk with the given extension and returns the file path
+func SaveProfilePhoto(file io.Reader, ext string, userID int64) (string, error) {
 	// Create uploads directory if it doesn't exist
 	if err := os.MkdirAll(ProfilePhotosDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create uploads directory: %w", err)
 	}
 
 	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
 	filename := fmt.Sprintf("%d_%s%s", userID, uuid.New().String(), ext)
 	filepath := filepath.Join(ProfilePhotosDir, filename)
 
@@ -29,9 +29,25 @@ type User struct {
 	Activated    bool      `json:"activated"`
 	Role         string    `json:"role"`
 	ProfilePhoto string    `json:"profile_photo,omitempty"`
+	Timezone     string    `json:"timezone"`
 	Version      int       `json:"-"`
 }
 
+// DefaultTimezone is used for users who have not chosen a timezone
+const DefaultTimezone = "UTC"
+
+// Location returns the user's timezone, falling back to UTC if it cannot be loaded
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // password holds the plaintext(optional) and hashed password
 type password struct {
 	plaintext *string
@@ -84,6 +100,13 @@ func ValidateRole(v *validator.Validator, role string) {
 	v.Check(validator.In(role, validRoles...), "role", "must be one of: user, agent, admin")
 }
 
+// ValidateTimezone checks that the timezone is a known IANA name
+func ValidateTimezone(v *validator.Validator, timezone string) {
+	v.Check(timezone != "", "timezone", "must be provided")
+	_, err := time.LoadLocation(timezone)
+	v.Check(err == nil, "timezone", "must be a valid IANA timezone name")
+}
+
 // ValidateUser validates name, email, password, and role
 func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "name", "must be provided")
@@ -100,21 +123,30 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 
 	ValidateRole(v, user.Role)
+
+	if user.Timezone != "" {
+		ValidateTimezone(v, user.Timezone)
+	}
 }
 
 // Insert adds a new user and populates ID, CreatedAt, and Version
 func (m UserModel) Insert(user *User) error {
 	query := `
-INSERT INTO users (name, email, password_hash, activated, role)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO users (name, email, password_hash, activated, role, timezone)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, created_at, version`
 
+	if user.Timezone == "" {
+		user.Timezone = DefaultTimezone
+	}
+
 	args := []interface{}{
 		user.Name,
 		user.Email,
 		user.Password.hash,
 		user.Activated,
 		user.Role,
+		user.Timezone,
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -134,7 +166,7 @@ RETURNING id, created_at, version`
 // GetByEmail fetches a user by email
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-SELECT id, created_at, name, email, password_hash, activated, role, version
+SELECT id, created_at, name, email, password_hash, activated, role, timezone, version
 FROM users
 WHERE email = $1`
 
@@ -151,6 +183,7 @@ WHERE email = $1`
 		&user.Password.hash,
 		&user.Activated,
 		&user.Role,
+		&user.Timezone,
 		&user.Version,
 	)
 
@@ -170,8 +203,9 @@ WHERE email = $1`
 func (m UserModel) Update(user *User) error {
 	query := `
 UPDATE users
-SET name = $1, email = $2, password_hash = $3, activated = $4, role = $5, version = version + 1
-WHERE id = $6 AND version = $7
+SET name = $1, email = $2, password_hash = $3, activated = $4, role = $5,
+    timezone = COALESCE(NULLIF($6, ''), timezone), version = version + 1
+WHERE id = $7 AND version = $8
 RETURNING version`
 
 	args := []interface{}{
@@ -180,6 +214,7 @@ RETURNING version`
 		user.Password.hash,
 		user.Activated,
 		user.Role,
+		user.Timezone,
 		user.ID,
 		user.Version,
 	}
@@ -207,7 +242,7 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 
 	query := `
 SELECT users.id, users.created_at, users.name, users.email, users.password_hash,
-       users.activated, users.role, users.version
+       users.activated, users.role, users.timezone, users.version
 FROM users
 INNER JOIN tokens ON users.id = tokens.user_id
 WHERE tokens.hash = $1
@@ -228,6 +263,7 @@ WHERE tokens.hash = $1
 		&user.Password.hash,
 		&user.Activated,
 		&user.Role,
+		&user.Timezone,
 		&user.Version,
 	)
 
@@ -251,7 +287,7 @@ func (u *User) IsAnonymous() bool {
 // Get retrieves a user by ID from the database
 func (m UserModel) Get(id int64) (*User, error) {
 	query := `
-SELECT id, created_at, name, email, password_hash, activated, role, version
+SELECT id, created_at, name, email, password_hash, activated, role, timezone, version
 FROM users
 WHERE id = $1`
 
@@ -270,6 +306,7 @@ WHERE id = $1`
 		&user.Password.hash,
 		&user.Activated,
 		&user.Role,
+		&user.Timezone,
 		&user.Version,
 	)
 	if err != nil {
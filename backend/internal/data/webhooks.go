@@ -0,0 +1,529 @@
+package data
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/codercollo/property/backend/internal/validator"
+	"github.com/lib/pq"
+)
+
+// MaxWebhookAttempts is the number of delivery attempts before a delivery is marked dead
+const MaxWebhookAttempts = 8
+
+// WebhookEvents lists the event names a webhook may subscribe to
+var WebhookEvents = []string{"inquiry.created", "schedule.created", "schedule.rescheduled", "schedule.completed", "payment.completed", "payment.failed"}
+
+// Webhook represents an outbound callback registered by a user
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery represents a single persisted attempt to deliver an event
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Signature      string          `json:"signature"`
+	Status         string          `json:"status"`
+	AttemptCount   int             `json:"attempt_count"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	// Populated when loading due deliveries for the worker
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// ValidateWebhook validates webhook fields
+func ValidateWebhook(v *validator.Validator, webhook *Webhook) {
+	v.Check(webhook.URL != "", "url", "must be provided")
+	v.Check(len(webhook.URL) <= 2000, "url", "must not exceed 2000 characters")
+
+	u, err := url.Parse(webhook.URL)
+	v.Check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "", "url", "must be a valid http or https URL")
+	if err == nil && u.Host != "" {
+		v.Check(u.User == nil, "url", "must not contain credentials")
+		v.Check(publicWebhookHost(u.Hostname()), "url", "must not point at a private or loopback address")
+	}
+
+	v.Check(len(webhook.Events) >= 1, "events", "must contain at least 1 event")
+	v.Check(validator.Unique(webhook.Events), "events", "must not contain duplicate values")
+	for _, event := range webhook.Events {
+		v.Check(validator.In(event, WebhookEvents...), "events", fmt.Sprintf("unknown event %q", event))
+	}
+}
+
+// cgnatRange is the carrier-grade NAT block, which net.IP.IsPrivate doesn't cover
+var cgnatRange = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// PublicWebhookIP reports whether deliveries may be sent to ip. Loopback, private,
+// link-local, multicast and unspecified addresses are refused so a webhook can't be
+// used to reach services inside the network.
+func PublicWebhookIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || cgnatRange.Contains(ip))
+}
+
+// publicWebhookHost rejects localhost names and non-public IP literals. Names are only
+// resolved when a delivery is sent, where the dialer checks the address again.
+func publicWebhookHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return PublicWebhookIP(ip)
+	}
+
+	return true
+}
+
+// SignWebhookPayload returns the HMAC-SHA256 signature for a payload
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookModel wraps database operations for webhooks and their deliveries
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+// Insert registers a new webhook
+func (m WebhookModel) Insert(webhook *Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{webhook.UserID, webhook.URL, webhook.Secret, pq.Array(webhook.Events)}
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&webhook.ID, &webhook.Active, &webhook.CreatedAt)
+}
+
+// Get retrieves a webhook by ID
+func (m WebhookModel) Get(id int64) (*Webhook, error) {
+	if id < 1 {
+		return nil, ErrWebhookNotFound
+	}
+
+	query := `
+		SELECT id, user_id, url, secret, events, active, created_at
+		FROM webhooks
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var webhook Webhook
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		pq.Array(&webhook.Events),
+		&webhook.Active,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetAllForUser retrieves all webhooks registered by a user
+func (m WebhookModel) GetAllForUser(userID int64) ([]*Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, active, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY id ASC`
+
+	return m.query(query, userID)
+}
+
+// GetActiveForEvent retrieves a user's active webhooks subscribed to an event
+func (m WebhookModel) GetActiveForEvent(userID int64, event string) ([]*Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, active, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		AND active = true
+		AND $2 = ANY(events)
+		ORDER BY id ASC`
+
+	return m.query(query, userID, event)
+}
+
+// query runs a webhook SELECT and scans the results
+func (m WebhookModel) query(query string, args ...interface{}) ([]*Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+
+	for rows.Next() {
+		var webhook Webhook
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.UserID,
+			&webhook.URL,
+			&webhook.Secret,
+			pq.Array(&webhook.Events),
+			&webhook.Active,
+			&webhook.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Delete removes a webhook belonging to the given user
+func (m WebhookModel) Delete(id, userID int64) error {
+	if id < 1 {
+		return ErrWebhookNotFound
+	}
+
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// =============================================================================
+// DELIVERIES
+// =============================================================================
+
+// InsertDelivery persists a pending delivery, signing the payload with the webhook secret.
+// The payload is stored byte for byte, so the signature matches the body that is sent.
+func (m WebhookModel) InsertDelivery(webhook *Webhook, event string, payload []byte) (*WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, signature)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, attempt_count, next_attempt_at, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	delivery := &WebhookDelivery{
+		WebhookID: webhook.ID,
+		Event:     event,
+		Payload:   payload,
+		Signature: SignWebhookPayload(webhook.Secret, payload),
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, delivery.WebhookID, delivery.Event, []byte(delivery.Payload), delivery.Signature).Scan(
+		&delivery.ID,
+		&delivery.Status,
+		&delivery.AttemptCount,
+		&delivery.NextAttemptAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// GetDueDeliveries retrieves pending deliveries whose next attempt is due
+func (m WebhookModel) GetDueDeliveries(limit int) ([]*WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.webhook_id, d.event, d.payload, d.signature, d.status, d.attempt_count,
+		       d.next_attempt_at, d.last_attempt_at, d.last_error, d.response_status,
+		       d.delivered_at, d.created_at, w.url, w.secret
+		FROM webhook_deliveries d
+		INNER JOIN webhooks w ON d.webhook_id = w.id
+		WHERE d.status = 'pending'
+		AND d.next_attempt_at <= NOW()
+		AND w.active = true
+		ORDER BY d.next_attempt_at ASC, d.id ASC
+		LIMIT $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Signature,
+			&delivery.Status,
+			&delivery.AttemptCount,
+			&delivery.NextAttemptAt,
+			&delivery.LastAttemptAt,
+			&delivery.LastError,
+			&delivery.ResponseStatus,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDeliveriesForWebhook retrieves deliveries for a webhook, optionally filtered by status
+func (m WebhookModel) GetDeliveriesForWebhook(webhookID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, webhook_id, event, payload, signature, status, attempt_count,
+		       next_attempt_at, last_attempt_at, last_error, response_status,
+		       delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		AND (status = $2 OR $2 = '')
+		ORDER BY %s %s, id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{webhookID, status, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var delivery WebhookDelivery
+		err := rows.Scan(
+			&totalRecords,
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Signature,
+			&delivery.Status,
+			&delivery.AttemptCount,
+			&delivery.NextAttemptAt,
+			&delivery.LastAttemptAt,
+			&delivery.LastError,
+			&delivery.ResponseStatus,
+			&delivery.DeliveredAt,
+			&delivery.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return deliveries, metadata, nil
+}
+
+// GetDelivery retrieves a delivery by ID
+func (m WebhookModel) GetDelivery(id int64) (*WebhookDelivery, error) {
+	if id < 1 {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+
+	query := `
+		SELECT id, webhook_id, event, payload, signature, status, attempt_count,
+		       next_attempt_at, last_attempt_at, last_error, response_status,
+		       delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var delivery WebhookDelivery
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&delivery.Payload,
+		&delivery.Signature,
+		&delivery.Status,
+		&delivery.AttemptCount,
+		&delivery.NextAttemptAt,
+		&delivery.LastAttemptAt,
+		&delivery.LastError,
+		&delivery.ResponseStatus,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// MarkDelivered records a successful delivery attempt
+func (m WebhookModel) MarkDelivered(id int64, responseStatus int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered',
+		    attempt_count = attempt_count + 1,
+		    last_attempt_at = NOW(),
+		    delivered_at = NOW(),
+		    response_status = $1,
+		    last_error = ''
+		WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, responseStatus, id)
+	return err
+}
+
+// MarkFailed records a failed attempt, scheduling a retry or marking the delivery dead
+func (m WebhookModel) MarkFailed(id int64, responseStatus int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := "pending"
+	if dead {
+		status = "dead"
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1,
+		    attempt_count = attempt_count + 1,
+		    last_attempt_at = NOW(),
+		    next_attempt_at = $2,
+		    response_status = $3,
+		    last_error = $4
+		WHERE id = $5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, status, nextAttemptAt, responseStatus, lastError, id)
+	return err
+}
+
+// Redeliver resets a delivery so the worker picks it up again immediately
+func (m WebhookModel) Redeliver(id int64) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending',
+		    attempt_count = 0,
+		    next_attempt_at = NOW(),
+		    last_error = ''
+		WHERE id = $1 AND status <> 'pending'`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
@@ -16,24 +16,63 @@ var templateFS embed.FS
 
 // Mailer wraps the SMTP dialer and sender info
 type Mailer struct {
-	dialer *mail.Dialer
-	sender string
+	dialer  *mail.Dialer
+	sender  string
+	replyTo string
 }
 
-// New returns a Mailer configured with SMTP settings
-func New(host string, port int, username, password, sender string) Mailer {
+// Option overrides a header on a single message
+type Option func(*message)
+
+// message holds the per-message headers that options may override
+type message struct {
+	from    string
+	replyTo string
+}
+
+// WithFrom sends the message from address instead of the configured sender
+func WithFrom(address string) Option {
+	return func(msg *message) {
+		if address != "" {
+			msg.from = address
+		}
+	}
+}
+
+// WithReplyTo directs replies to address, e.g. the agent a notification is about
+func WithReplyTo(address string) Option {
+	return func(msg *message) {
+		if address != "" {
+			msg.replyTo = address
+		}
+	}
+}
+
+// New returns a Mailer configured with SMTP settings. Replies go to replyTo,
+// or to the sender when replyTo is empty.
+func New(host string, port int, username, password, sender, replyTo string) Mailer {
 	dialer := mail.NewDialer(host, port, username, password)
 	dialer.Timeout = 5 * time.Second
 
+	if replyTo == "" {
+		replyTo = sender
+	}
+
 	return Mailer{
-		dialer: dialer,
-		sender: sender,
+		dialer:  dialer,
+		sender:  sender,
+		replyTo: replyTo,
 	}
 
 }
 
 // Send composes and sends an email using the given template and data
-func (m Mailer) Send(recipient, templateFile string, data interface{}) error {
+func (m Mailer) Send(recipient, templateFile string, data interface{}, opts ...Option) error {
+	headers := message{from: m.sender, replyTo: m.replyTo}
+	for _, opt := range opts {
+		opt(&headers)
+	}
+
 	//Load templates form embedded FS
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
@@ -62,7 +101,8 @@ func (m Mailer) Send(recipient, templateFile string, data interface{}) error {
 	//Build the email message
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipient)
-	msg.SetHeader("From", m.sender)
+	msg.SetHeader("From", headers.from)
+	msg.SetHeader("Reply-To", headers.replyTo)
 	msg.SetHeader("Subject", subject.String())
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
@@ -0,0 +1,271 @@
+// Package pdf writes simple single-page PDF documents: text in the standard
+// Helvetica fonts, filled rectangles and raster images. It has no dependencies
+// beyond the standard library. Coordinates are in points, measured from the
+// top-left corner of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font selects one of the built-in Helvetica faces
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Page is a single PDF page built up from drawing operations
+type Page struct {
+	Title string
+
+	width   float64
+	height  float64
+	content bytes.Buffer
+	images  []pageImage
+}
+
+// pageImage is a JPEG-encoded image drawn on the page
+type pageImage struct {
+	data          []byte
+	width, height int
+}
+
+// NewPage creates an empty page of the given size in points
+func NewPage(width, height float64) *Page {
+	return &Page{width: width, height: height}
+}
+
+// SetFill sets the colour used by later text and rectangles; components are 0 to 1
+func (p *Page) SetFill(r, g, b float64) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", r, g, b)
+}
+
+// Rect fills a rectangle whose top-left corner is at x, y
+func (p *Page) Rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re f\n", x, p.height-y-h, w, h)
+}
+
+// Text draws a single line of text with its baseline at y. Characters outside the
+// Windows-1252 character set are shown as "?".
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, p.height-y, escape(encode(s)))
+}
+
+// Image draws img scaled to fill the w x h box whose top-left corner is at x, y.
+// Transparent areas are drawn on white.
+func (p *Page) Image(img image.Image, x, y, w, h float64) error {
+	bounds := img.Bounds()
+
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, p.height-y-h, len(p.images))
+	p.images = append(p.images, pageImage{data: buf.Bytes(), width: bounds.Dx(), height: bounds.Dy()})
+
+	return nil
+}
+
+// WriteTo writes the page as a complete PDF document
+func (p *Page) WriteTo(w io.Writer) (int64, error) {
+	var doc bytes.Buffer
+	offsets := []int{}
+
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, doc.Len())
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			doc.WriteString("stream\n")
+			doc.Write(stream)
+			doc.WriteString("\nendstream\n")
+		}
+		doc.WriteString("endobj\n")
+	}
+
+	var content bytes.Buffer
+	zw := zlib.NewWriter(&content)
+	if _, err := zw.Write(p.content.Bytes()); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	// Objects 1-6 are fixed; images follow from object 7
+	var xobjects strings.Builder
+	for i := range p.images {
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i, 7+i)
+	}
+
+	doc.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	object("<< /Type /Pages /Kids [3 0 R] /Count 1 >>", nil)
+	object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+		"/Resources << /Font << /F1 4 0 R /F2 5 0 R >> /XObject <<%s >> >> /Contents 6 0 R >>",
+		p.width, p.height, xobjects.String()), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>", content.Len()), content.Bytes())
+
+	for _, img := range p.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 /Filter /DCTDecode /Length %d >>", img.width, img.height, len(img.data)), img.data)
+	}
+
+	object(fmt.Sprintf("<< /Title (%s) /Producer (PropertyOwn) >>", escape(encode(p.Title))), nil)
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, len(offsets), xref)
+
+	n, err := w.Write(doc.Bytes())
+	return int64(n), err
+}
+
+// =============================================================================
+// TEXT
+// =============================================================================
+
+// winAnsi maps the characters of Windows-1252 outside Latin-1 to their codes
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encode converts s to Windows-1252, replacing unsupported characters with "?"
+// and control characters with spaces
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case unicode.IsControl(r):
+			out = append(out, ' ')
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsi[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// escape quotes the delimiters of a PDF literal string
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '\\' || c == '(' || c == ')' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// Glyph widths of printable ASCII, from space to tilde, in 1/1000 em
+var (
+	regularWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	boldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// TextWidth returns the width of s in points. Characters outside printable ASCII
+// are measured as a typical lowercase letter.
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := &regularWidths
+	if font == Bold {
+		widths = &boldWidths
+	}
+
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += widths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WrapText breaks s into lines no wider than width, keeping paragraph breaks.
+// Words too long for a line are split.
+func WrapText(font Font, size, width float64, s string) []string {
+	lines := []string{}
+
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if TextWidth(font, size, candidate) <= width {
+				line = candidate
+				continue
+			}
+
+			if line != "" {
+				lines = append(lines, line)
+			}
+
+			// Split a word that doesn't fit on a line of its own
+			line = ""
+			for _, r := range word {
+				if line != "" && TextWidth(font, size, line+string(r)) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
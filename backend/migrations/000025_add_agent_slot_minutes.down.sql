@@ -0,0 +1,5 @@
+ALTER TABLE agent_profiles
+DROP CONSTRAINT IF EXISTS agent_profiles_slot_minutes_check;
+
+ALTER TABLE agent_profiles
+DROP COLUMN IF EXISTS slot_minutes;
@@ -0,0 +1,9 @@
+-- Add booking slot granularity to agent profiles
+ALTER TABLE agent_profiles
+ADD COLUMN IF NOT EXISTS slot_minutes INTEGER NOT NULL DEFAULT 30;
+
+ALTER TABLE agent_profiles
+ADD CONSTRAINT agent_profiles_slot_minutes_check
+CHECK (slot_minutes IN (5, 10, 15, 20, 30, 60));
+
+COMMENT ON COLUMN agent_profiles.slot_minutes IS 'Granularity in minutes that viewing start times and durations must align to';
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS agent_blackouts (
+    id bigserial PRIMARY KEY,
+    agent_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at timestamp(0) with time zone NOT NULL,
+    ends_at timestamp(0) with time zone NOT NULL,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT agent_blackouts_range_check CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS agent_blackouts_agent_id_idx ON agent_blackouts(agent_id);
+CREATE INDEX IF NOT EXISTS agent_blackouts_range_idx ON agent_blackouts(agent_id, starts_at, ends_at);
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_properties_furnished;
+DROP INDEX IF EXISTS idx_properties_parking_spaces;
+
+ALTER TABLE properties
+DROP CONSTRAINT IF EXISTS properties_furnished_check,
+DROP CONSTRAINT IF EXISTS properties_heating_type_check,
+DROP CONSTRAINT IF EXISTS properties_lot_size_check,
+DROP CONSTRAINT IF EXISTS properties_parking_spaces_check,
+DROP CONSTRAINT IF EXISTS properties_energy_rating_check;
+
+ALTER TABLE properties
+DROP COLUMN IF EXISTS furnished,
+DROP COLUMN IF EXISTS heating_type,
+DROP COLUMN IF EXISTS lot_size,
+DROP COLUMN IF EXISTS parking_spaces,
+DROP COLUMN IF EXISTS energy_rating;
@@ -0,0 +1,26 @@
+-- Add optional structured attributes to properties
+ALTER TABLE properties
+ADD COLUMN IF NOT EXISTS energy_rating text,
+ADD COLUMN IF NOT EXISTS parking_spaces integer,
+ADD COLUMN IF NOT EXISTS lot_size integer,
+ADD COLUMN IF NOT EXISTS heating_type text,
+ADD COLUMN IF NOT EXISTS furnished text;
+
+ALTER TABLE properties ADD CONSTRAINT properties_energy_rating_check
+CHECK (energy_rating IS NULL OR energy_rating IN ('A', 'B', 'C', 'D', 'E', 'F', 'G'));
+
+ALTER TABLE properties ADD CONSTRAINT properties_parking_spaces_check
+CHECK (parking_spaces IS NULL OR parking_spaces >= 0);
+
+ALTER TABLE properties ADD CONSTRAINT properties_lot_size_check
+CHECK (lot_size IS NULL OR lot_size > 0);
+
+ALTER TABLE properties ADD CONSTRAINT properties_heating_type_check
+CHECK (heating_type IS NULL OR heating_type IN ('none', 'gas', 'electric', 'oil', 'heat_pump', 'solar', 'district', 'other'));
+
+ALTER TABLE properties ADD CONSTRAINT properties_furnished_check
+CHECK (furnished IS NULL OR furnished IN ('furnished', 'semi_furnished', 'unfurnished'));
+
+-- Indexes for the filterable attributes
+CREATE INDEX IF NOT EXISTS idx_properties_parking_spaces ON properties(parking_spaces);
+CREATE INDEX IF NOT EXISTS idx_properties_furnished ON properties(furnished);
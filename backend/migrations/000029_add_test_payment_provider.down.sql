@@ -0,0 +1,4 @@
+DELETE FROM payments WHERE payment_provider = 'test';
+
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payment_provider;
+ALTER TABLE payments ADD CONSTRAINT check_payment_provider CHECK (payment_provider IN ('mpesa', 'bank', 'card'));
@@ -0,0 +1,2 @@
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS check_payment_provider;
+ALTER TABLE payments ADD CONSTRAINT check_payment_provider CHECK (payment_provider IN ('mpesa', 'bank', 'card', 'test'));
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS property_views (
+    id bigserial PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    user_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    viewed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS property_views_property_viewed_idx ON property_views(property_id, viewed_at);
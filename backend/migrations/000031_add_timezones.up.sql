@@ -0,0 +1,6 @@
+-- IANA timezone names for users and the timezone a viewing was booked in
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+
+COMMENT ON COLUMN users.timezone IS 'IANA timezone name used to render and validate local times';
+COMMENT ON COLUMN schedules.timezone IS 'IANA timezone the viewing was booked in; scheduled_at itself is stored in UTC';
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_properties_coordinates;
+
+ALTER TABLE properties DROP CONSTRAINT IF EXISTS properties_coordinates_check;
+ALTER TABLE properties DROP CONSTRAINT IF EXISTS properties_longitude_check;
+ALTER TABLE properties DROP CONSTRAINT IF EXISTS properties_latitude_check;
+
+ALTER TABLE properties
+DROP COLUMN IF EXISTS longitude,
+DROP COLUMN IF EXISTS latitude;
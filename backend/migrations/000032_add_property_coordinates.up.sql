@@ -0,0 +1,17 @@
+-- Optional map coordinates for properties
+ALTER TABLE properties
+ADD COLUMN IF NOT EXISTS latitude double precision,
+ADD COLUMN IF NOT EXISTS longitude double precision;
+
+ALTER TABLE properties ADD CONSTRAINT properties_latitude_check
+CHECK (latitude IS NULL OR latitude BETWEEN -90 AND 90);
+
+ALTER TABLE properties ADD CONSTRAINT properties_longitude_check
+CHECK (longitude IS NULL OR longitude BETWEEN -180 AND 180);
+
+-- Coordinates are set together or not at all
+ALTER TABLE properties ADD CONSTRAINT properties_coordinates_check
+CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- Index for bounding box lookups
+CREATE INDEX IF NOT EXISTS idx_properties_coordinates ON properties(latitude, longitude);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id bigserial PRIMARY KEY,
+    actor_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    action text NOT NULL,
+    entity_type text NOT NULL,
+    entity_id bigint,
+    details jsonb NOT NULL DEFAULT '{}',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_id_idx ON audit_log(actor_id);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log(created_at);
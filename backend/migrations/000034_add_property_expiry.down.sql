@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_properties_expires_at;
+
+ALTER TABLE properties
+DROP COLUMN IF EXISTS expiry_reminded_at,
+DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,10 @@
+-- Listing lifetime: properties drop out of public results once expires_at passes
+ALTER TABLE properties
+ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone,
+ADD COLUMN IF NOT EXISTS expiry_reminded_at timestamp(0) with time zone;
+
+-- Give existing listings a full lifetime from now, so none expire before their agent
+-- has been reminded to renew
+UPDATE properties SET expires_at = GREATEST(created_at, NOW()) + INTERVAL '90 days' WHERE expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_properties_expires_at ON properties(expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE agent_profiles
+DROP COLUMN IF EXISTS show_email,
+DROP COLUMN IF EXISTS show_phone,
+DROP COLUMN IF EXISTS phone;
@@ -0,0 +1,5 @@
+-- Agent contact details shown on listings, each gated by the agent's own opt-in
+ALTER TABLE agent_profiles
+ADD COLUMN IF NOT EXISTS phone text,
+ADD COLUMN IF NOT EXISTS show_phone boolean NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS show_email boolean NOT NULL DEFAULT false;
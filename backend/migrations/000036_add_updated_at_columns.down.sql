@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trigger_set_schedules_updated_at ON schedules;
+DROP INDEX IF EXISTS idx_schedules_updated_at;
+ALTER TABLE schedules DROP COLUMN IF EXISTS updated_at;
+
+DROP TRIGGER IF EXISTS trigger_set_properties_updated_at ON properties;
+DROP INDEX IF EXISTS idx_properties_updated_at;
+ALTER TABLE properties DROP COLUMN IF EXISTS updated_at;
+
+DROP FUNCTION IF EXISTS set_updated_at();
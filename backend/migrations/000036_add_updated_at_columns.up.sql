@@ -0,0 +1,36 @@
+-- Shared trigger function that stamps updated_at on every row update
+CREATE OR REPLACE FUNCTION set_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Properties
+ALTER TABLE properties
+ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+UPDATE properties SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_properties_updated_at ON properties(updated_at);
+
+CREATE TRIGGER trigger_set_properties_updated_at
+    BEFORE UPDATE ON properties
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+-- Schedules
+ALTER TABLE schedules
+ADD COLUMN IF NOT EXISTS updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
+
+UPDATE schedules SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_schedules_updated_at ON schedules(updated_at);
+
+CREATE TRIGGER trigger_set_schedules_updated_at
+    BEFORE UPDATE ON schedules
+    FOR EACH ROW
+    EXECUTE FUNCTION set_updated_at();
+
+COMMENT ON FUNCTION set_updated_at() IS 'Automatically updates the updated_at timestamp when a row is modified';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS open_house_attendees;
+DROP TABLE IF EXISTS open_houses;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS open_houses (
+    id bigserial PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    agent_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at timestamp(0) with time zone NOT NULL,
+    ends_at timestamp(0) with time zone NOT NULL,
+    capacity integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT open_houses_range_check CHECK (ends_at > starts_at),
+    CONSTRAINT open_houses_capacity_check CHECK (capacity > 0)
+);
+
+CREATE INDEX IF NOT EXISTS open_houses_property_starts_idx ON open_houses(property_id, starts_at);
+CREATE INDEX IF NOT EXISTS open_houses_agent_starts_idx ON open_houses(agent_id, starts_at);
+
+-- One RSVP per user per open house
+CREATE TABLE IF NOT EXISTS open_house_attendees (
+    open_house_id bigint NOT NULL REFERENCES open_houses(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (open_house_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS open_house_attendees_user_idx ON open_house_attendees(user_id);
@@ -0,0 +1,14 @@
+-- Private moderation thread between a listing's agent and admins
+CREATE TABLE IF NOT EXISTS property_notes (
+    id bigserial PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    author_id bigint REFERENCES users(id) ON DELETE SET NULL,
+    author_role text NOT NULL,
+    kind text NOT NULL DEFAULT 'note',
+    note text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT property_notes_author_role_check CHECK (author_role IN ('agent', 'admin')),
+    CONSTRAINT property_notes_kind_check CHECK (kind IN ('note', 'rejection', 'approval'))
+);
+
+CREATE INDEX IF NOT EXISTS property_notes_property_created_idx ON property_notes(property_id, created_at);
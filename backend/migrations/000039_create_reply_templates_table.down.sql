@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reply_template_seeds;
+DROP TABLE IF EXISTS reply_templates;
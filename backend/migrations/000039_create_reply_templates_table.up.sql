@@ -0,0 +1,16 @@
+-- Quick-reply templates agents use to answer inquiries
+CREATE TABLE IF NOT EXISTS reply_templates (
+    id bigserial PRIMARY KEY,
+    agent_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title text NOT NULL,
+    body text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT reply_templates_agent_title_key UNIQUE (agent_id, title)
+);
+
+-- Agents that have already been given the default templates
+CREATE TABLE IF NOT EXISTS reply_template_seeds (
+    agent_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    seeded_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
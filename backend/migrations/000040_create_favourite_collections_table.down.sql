@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_favourites_collection;
+ALTER TABLE user_favourites DROP COLUMN IF EXISTS collection_id;
+DROP TABLE IF EXISTS favourite_collections;
@@ -0,0 +1,16 @@
+-- Named folders a user can sort their favourites into
+CREATE TABLE IF NOT EXISTS favourite_collections (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS favourite_collections_user_name_key ON favourite_collections(user_id, lower(name));
+
+-- A NULL collection leaves the favourite only in the implicit "All" collection
+ALTER TABLE user_favourites
+    ADD COLUMN IF NOT EXISTS collection_id bigint REFERENCES favourite_collections(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_favourites_collection ON user_favourites(collection_id) WHERE collection_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Each time a signed-in user reveals an agent's contact details on a listing
+CREATE TABLE IF NOT EXISTS contact_reveals (
+    id bigserial PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    agent_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    revealed_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS contact_reveals_property_revealed_idx ON contact_reveals(property_id, revealed_at);
+CREATE INDEX IF NOT EXISTS contact_reveals_user_revealed_idx ON contact_reveals(user_id, revealed_at);
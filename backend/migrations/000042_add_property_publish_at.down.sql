@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS properties_scheduled_publish_idx;
+UPDATE properties SET status = 'pending' WHERE status = 'scheduled';
+ALTER TABLE properties DROP COLUMN IF EXISTS publish_at;
@@ -0,0 +1,14 @@
+-- status drives moderation; make sure it exists before scheduled listings rely on it.
+-- Listings already live when the column is added are backfilled as approved so they stay
+-- public; new listings default to pending review.
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'approved';
+
+ALTER TABLE properties
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+-- Listings with a future publish_at stay 'scheduled' until the publishing job releases them
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS properties_scheduled_publish_idx ON properties(publish_at) WHERE status = 'scheduled';
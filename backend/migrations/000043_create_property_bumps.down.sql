@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS property_bumps;
+DROP INDEX IF EXISTS properties_freshness_idx;
+ALTER TABLE properties DROP COLUMN IF EXISTS bumped_at;
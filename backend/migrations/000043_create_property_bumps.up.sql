@@ -0,0 +1,14 @@
+-- bumped_at moves a listing back to the top of the newest-first sort without editing it
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS bumped_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS properties_freshness_idx ON properties(COALESCE(bumped_at, created_at));
+
+-- Bump history, used to enforce the per-listing cooldown
+CREATE TABLE IF NOT EXISTS property_bumps (
+    id bigserial PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties ON DELETE CASCADE,
+    agent_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS property_bumps_property_created_idx ON property_bumps(property_id, created_at DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE property_media
+    DROP COLUMN IF EXISTS original_width,
+    DROP COLUMN IF EXISTS original_height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS height;
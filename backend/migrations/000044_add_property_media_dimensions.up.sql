@@ -0,0 +1,6 @@
+-- Pixel dimensions of uploaded images, as uploaded and as stored after any downscaling
+ALTER TABLE property_media
+    ADD COLUMN IF NOT EXISTS original_width integer,
+    ADD COLUMN IF NOT EXISTS original_height integer,
+    ADD COLUMN IF NOT EXISTS width integer,
+    ADD COLUMN IF NOT EXISTS height integer;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS property_slug_history;
+DROP INDEX IF EXISTS properties_slug_key;
+ALTER TABLE properties DROP COLUMN IF EXISTS slug;
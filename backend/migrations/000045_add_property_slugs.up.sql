@@ -0,0 +1,17 @@
+-- Slugs are the slugified title followed by the id, so they are unique by construction
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS slug text NOT NULL DEFAULT '';
+
+UPDATE properties
+SET slug = COALESCE(NULLIF(trim(both '-' from left(lower(regexp_replace(title, '[^a-zA-Z0-9]+', '-', 'g')), 80)), '') || '-', '') || id
+WHERE slug = '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS properties_slug_key ON properties(slug) WHERE slug <> '';
+
+-- Previous slugs of renamed listings, so stale links can be redirected
+CREATE TABLE IF NOT EXISTS property_slug_history (
+    slug text PRIMARY KEY,
+    property_id bigint NOT NULL REFERENCES properties ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS property_slug_history_property_idx ON property_slug_history(property_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_inquiries_open_updated_at;
+ALTER TABLE inquiries
+    DROP COLUMN IF EXISTS auto_closed_at,
+    DROP COLUMN IF EXISTS stale_prompted_at;
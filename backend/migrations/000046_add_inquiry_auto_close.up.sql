@@ -0,0 +1,6 @@
+-- Inquiries closed by the stale inquiry job, and when the inquirer was asked if still interested
+ALTER TABLE inquiries
+    ADD COLUMN IF NOT EXISTS auto_closed_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS stale_prompted_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_inquiries_open_updated_at ON inquiries(updated_at) WHERE status IN ('new', 'contacted');
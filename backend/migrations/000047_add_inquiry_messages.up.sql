@@ -0,0 +1,11 @@
+-- Messages exchanged on an inquiry, through the platform or the masked email relay
+CREATE TABLE IF NOT EXISTS inquiry_messages (
+    id bigserial PRIMARY KEY,
+    inquiry_id bigint NOT NULL REFERENCES inquiries ON DELETE CASCADE,
+    sender text NOT NULL CHECK (sender IN ('agent', 'inquirer')),
+    body text NOT NULL,
+    via text NOT NULL DEFAULT 'platform' CHECK (via IN ('platform', 'email')),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inquiry_messages_inquiry_id ON inquiry_messages(inquiry_id, created_at);
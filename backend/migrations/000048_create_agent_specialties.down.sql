@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS agent_service_areas;
+DROP TABLE IF EXISTS agent_specialties;
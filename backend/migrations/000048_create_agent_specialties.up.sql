@@ -0,0 +1,15 @@
+-- Property types an agent specialises in and the areas they serve
+CREATE TABLE IF NOT EXISTS agent_specialties (
+    agent_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    property_type text NOT NULL,
+    PRIMARY KEY (agent_id, property_type)
+);
+
+CREATE TABLE IF NOT EXISTS agent_service_areas (
+    agent_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    area text NOT NULL,
+    PRIMARY KEY (agent_id, area)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_specialties_type ON agent_specialties(lower(property_type));
+CREATE INDEX IF NOT EXISTS idx_agent_service_areas_area ON agent_service_areas(lower(area));
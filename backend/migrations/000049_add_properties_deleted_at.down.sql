@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_properties_deleted_at;
+ALTER TABLE properties DROP COLUMN IF EXISTS deleted_at;
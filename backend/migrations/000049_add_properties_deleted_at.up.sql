@@ -0,0 +1,4 @@
+-- Deleted listings are kept, hidden from every normal read, so admins can restore them
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_properties_deleted_at ON properties(deleted_at) WHERE deleted_at IS NOT NULL;
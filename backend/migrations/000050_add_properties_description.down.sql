@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS properties_title_description_idx;
+ALTER TABLE properties DROP COLUMN IF EXISTS description;
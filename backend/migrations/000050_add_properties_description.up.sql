@@ -0,0 +1,6 @@
+-- Long-form listing description, optional and searchable alongside the title
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS description text NOT NULL DEFAULT '';
+
+-- GIN index for full-text search on the title and description together
+CREATE INDEX IF NOT EXISTS properties_title_description_idx
+ON properties USING GIN ((to_tsvector('simple', title) || to_tsvector('simple', description)));
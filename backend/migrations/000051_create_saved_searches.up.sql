@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    query text NOT NULL DEFAULT '',
+    criteria jsonb NOT NULL DEFAULT '{}',
+    frequency text NOT NULL DEFAULT 'daily',
+    last_run_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT saved_searches_user_name_key UNIQUE (user_id, name),
+    CONSTRAINT saved_searches_frequency_check CHECK (frequency IN ('daily', 'weekly'))
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_due_idx ON saved_searches(frequency, last_run_at);
+
+-- Listings already emailed for a saved search, so each is only sent once
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    saved_search_id bigint NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    property_id bigint NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    notified_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (saved_search_id, property_id)
+);
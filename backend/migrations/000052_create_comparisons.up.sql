@@ -0,0 +1,14 @@
+-- Saved property comparisons, viewable by anyone holding the share token.
+-- Anonymous comparisons expire; comparisons saved by a signed-in user are kept.
+CREATE TABLE IF NOT EXISTS comparisons (
+    id bigserial PRIMARY KEY,
+    user_id bigint REFERENCES users(id) ON DELETE CASCADE,
+    property_ids bigint[] NOT NULL,
+    share_token text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    expires_at timestamp(0) with time zone,
+    CONSTRAINT comparisons_share_token_key UNIQUE (share_token)
+);
+
+CREATE INDEX IF NOT EXISTS comparisons_user_id_idx ON comparisons(user_id);
+CREATE INDEX IF NOT EXISTS comparisons_expires_at_idx ON comparisons(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,11 @@
+-- An agent's out-of-office delegation: until the given time the delegate may view
+-- and act on the agent's inquiries and schedules. An agent has at most one delegate.
+CREATE TABLE IF NOT EXISTS agent_delegations (
+    agent_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    until timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT agent_delegations_not_self CHECK (agent_id <> delegate_id)
+);
+
+CREATE INDEX IF NOT EXISTS agent_delegations_delegate_id_idx ON agent_delegations(delegate_id);
@@ -0,0 +1 @@
+ALTER TABLE inquiries DROP COLUMN IF EXISTS response_message;
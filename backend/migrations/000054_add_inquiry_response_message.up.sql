@@ -0,0 +1,2 @@
+-- The agent's latest response to the inquirer. Unlike agent_notes, it is shown to the inquirer.
+ALTER TABLE inquiries ADD COLUMN IF NOT EXISTS response_message text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS inquiry_contact_attempts;
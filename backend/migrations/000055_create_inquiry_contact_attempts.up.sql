@@ -0,0 +1,13 @@
+-- Contact attempts an agent made outside the message thread, eg phone calls.
+-- Any attempt counts as contacting the inquirer in the agent's response rate.
+CREATE TABLE IF NOT EXISTS inquiry_contact_attempts (
+    id bigserial PRIMARY KEY,
+    inquiry_id bigint NOT NULL REFERENCES inquiries ON DELETE CASCADE,
+    agent_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    method text NOT NULL CHECK (method IN ('phone', 'sms', 'whatsapp', 'email', 'in_person')),
+    outcome text NOT NULL CHECK (outcome IN ('reached', 'no_answer', 'voicemail', 'busy', 'wrong_number')),
+    note text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inquiry_contact_attempts_inquiry_id ON inquiry_contact_attempts(inquiry_id, created_at);
@@ -0,0 +1,13 @@
+-- How an agent is emailed about new inquiries: one email per inquiry, or an hourly or
+-- daily digest. With urgent_immediately set, high and urgent priority inquiries are
+-- still emailed at once. last_digest_at marks the end of the last digest sent.
+CREATE TABLE IF NOT EXISTS agent_notification_preferences (
+    agent_id bigint PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    inquiry_emails text NOT NULL DEFAULT 'immediate',
+    urgent_immediately boolean NOT NULL DEFAULT true,
+    last_digest_at timestamp with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT agent_notification_preferences_inquiry_emails_check CHECK (inquiry_emails IN ('immediate', 'hourly', 'daily'))
+);
+
+CREATE INDEX IF NOT EXISTS agent_notification_preferences_digest_idx ON agent_notification_preferences(inquiry_emails, last_digest_at);
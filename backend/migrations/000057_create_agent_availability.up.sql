@@ -0,0 +1,15 @@
+-- An agent's weekly working hours in their own timezone. Viewings may only be booked
+-- inside one of these windows; agents with none can be booked at any time.
+-- day_of_week runs from 0 (Sunday) to 6 (Saturday).
+CREATE TABLE IF NOT EXISTS agent_availability (
+    id bigserial PRIMARY KEY,
+    agent_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day_of_week smallint NOT NULL,
+    start_time time NOT NULL,
+    end_time time NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    CONSTRAINT agent_availability_day_check CHECK (day_of_week BETWEEN 0 AND 6),
+    CONSTRAINT agent_availability_range_check CHECK (end_time > start_time)
+);
+
+CREATE INDEX IF NOT EXISTS agent_availability_agent_day_idx ON agent_availability(agent_id, day_of_week);
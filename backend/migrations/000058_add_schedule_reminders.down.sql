@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_schedules_reminder_due;
+
+ALTER TABLE schedules DROP COLUMN IF EXISTS reminder_sent_at;
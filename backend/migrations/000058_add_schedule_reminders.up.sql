@@ -0,0 +1,5 @@
+-- When the user and agent were reminded of an upcoming viewing; cleared when it is rescheduled
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS reminder_sent_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_schedules_reminder_due ON schedules(scheduled_at)
+WHERE reminder_sent_at IS NULL AND status IN ('pending', 'confirmed');
@@ -0,0 +1,10 @@
+-- Anonymized reviews have no author to restore, so they are removed
+DELETE FROM reviews WHERE user_id IS NULL;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_user_id_fkey;
+
+ALTER TABLE reviews
+ADD CONSTRAINT reviews_user_id_fkey
+FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE reviews ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,10 @@
+-- Reviews outlive their author: deleting a user keeps the rating and comment but drops
+-- the link to the account, and they are shown as written by a deleted user. Inquiries
+-- and schedules hold the user's contact details and are still removed with them.
+ALTER TABLE reviews ALTER COLUMN user_id DROP NOT NULL;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_user_id_fkey;
+
+ALTER TABLE reviews
+ADD CONSTRAINT reviews_user_id_fkey
+FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
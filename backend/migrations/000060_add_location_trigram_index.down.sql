@@ -0,0 +1,2 @@
+-- The extension is left in place as other objects may have come to depend on it
+DROP INDEX IF EXISTS idx_properties_location_trgm;
@@ -0,0 +1,20 @@
+-- Fuzzy location matching compares spellings with pg_trgm. Creating the extension needs
+-- elevated privileges on some hosts; where it can't be created the API falls back to
+-- plain ILIKE location filters, so the migration carries on without it.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION
+    WHEN insufficient_privilege OR undefined_file THEN
+        RAISE NOTICE 'pg_trgm is unavailable, fuzzy location matching will be disabled';
+END
+$$;
+
+-- Speeds up ILIKE substring filters and word similarity matches on location
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS idx_properties_location_trgm ON properties USING gin (location gin_trgm_ops);
+    END IF;
+END
+$$;
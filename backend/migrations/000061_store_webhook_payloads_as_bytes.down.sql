@@ -0,0 +1,2 @@
+ALTER TABLE webhook_deliveries
+ALTER COLUMN payload TYPE jsonb USING convert_from(payload, 'UTF8')::jsonb;
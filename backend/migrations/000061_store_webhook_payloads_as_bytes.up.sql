@@ -0,0 +1,4 @@
+-- Deliveries are signed over the exact bytes that are sent. jsonb reorders keys and
+-- normalises whitespace, so the payload is kept as the raw bytes that were signed.
+ALTER TABLE webhook_deliveries
+ALTER COLUMN payload TYPE bytea USING convert_to(payload::text, 'UTF8');
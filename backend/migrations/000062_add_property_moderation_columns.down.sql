@@ -0,0 +1,4 @@
+ALTER TABLE properties
+    DROP COLUMN IF EXISTS rejection_reason,
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by;
@@ -0,0 +1,5 @@
+-- Who last approved or rejected a listing, when, and why it was rejected
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS moderated_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS moderated_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS rejection_reason text;
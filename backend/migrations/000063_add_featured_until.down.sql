@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS properties_featured_until_idx;
+
+ALTER TABLE properties DROP COLUMN IF EXISTS featured_until;
@@ -0,0 +1,4 @@
+-- When a featured listing drops back to standard; NULL keeps it featured until unfeatured
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS featured_until timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS properties_featured_until_idx ON properties (featured_until) WHERE featured_until IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS schedules_agent_created_idx;
+
+ALTER TABLE schedules
+    DROP COLUMN IF EXISTS confirmed_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS cancelled_at;
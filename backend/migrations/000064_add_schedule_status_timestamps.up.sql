@@ -0,0 +1,13 @@
+-- When each schedule was confirmed, completed or cancelled, for agent schedule trends.
+-- Transitions before this migration weren't recorded; completed viewings are backfilled
+-- with the time they ended, the others are left NULL.
+ALTER TABLE schedules
+    ADD COLUMN IF NOT EXISTS confirmed_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS completed_at timestamp(0) with time zone,
+    ADD COLUMN IF NOT EXISTS cancelled_at timestamp(0) with time zone;
+
+UPDATE schedules
+SET completed_at = scheduled_at + make_interval(mins => duration_minutes)
+WHERE status = 'completed' AND completed_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS schedules_agent_created_idx ON schedules (agent_id, created_at);
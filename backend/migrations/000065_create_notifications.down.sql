@@ -0,0 +1,3 @@
+ALTER TABLE agent_notification_preferences DROP COLUMN IF EXISTS payment_emails;
+
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,18 @@
+-- In-app notifications shown to a user until read, eg the outcome of a payment
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    property_id bigint REFERENCES properties(id) ON DELETE SET NULL,
+    read_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON notifications(user_id, created_at DESC);
+
+-- Whether an agent is emailed when a payment completes or fails; the in-app
+-- notification is always created
+ALTER TABLE agent_notification_preferences
+    ADD COLUMN IF NOT EXISTS payment_emails boolean NOT NULL DEFAULT true;
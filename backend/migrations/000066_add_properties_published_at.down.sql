@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS properties_published_at_idx;
+
+ALTER TABLE properties DROP COLUMN IF EXISTS published_at;
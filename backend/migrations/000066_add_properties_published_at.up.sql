@@ -0,0 +1,11 @@
+-- When each listing first went public, so saved-search alerts pick up listings that
+-- were scheduled or waited in moderation rather than only newly created ones.
+-- Listings approved before this migration use their moderation or creation time.
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS published_at timestamp(0) with time zone;
+
+UPDATE properties
+SET published_at = COALESCE(moderated_at, created_at)
+WHERE status = 'approved' AND published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS properties_published_at_idx ON properties (published_at) WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_name;
+DROP INDEX IF EXISTS webhook_deliveries_webhook_created_idx;
+DROP INDEX IF EXISTS idx_payments_agent_updated_at;
+DROP INDEX IF EXISTS idx_payments_agent_amount;
+DROP INDEX IF EXISTS reviews_property_rating_idx;
+DROP INDEX IF EXISTS idx_properties_year_built;
+DROP INDEX IF EXISTS idx_properties_title;
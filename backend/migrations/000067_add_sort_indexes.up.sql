@@ -0,0 +1,11 @@
+-- Indexes for the sort keys in the list safelists that had none, so every accepted
+-- ORDER BY can be served from an index. Lists scoped to an owner index the sort
+-- column after the owner.
+CREATE INDEX IF NOT EXISTS idx_properties_title ON properties (title);
+CREATE INDEX IF NOT EXISTS idx_properties_year_built ON properties (year_built);
+CREATE INDEX IF NOT EXISTS reviews_property_rating_idx ON reviews (property_id, rating);
+CREATE INDEX IF NOT EXISTS idx_payments_agent_amount ON payments (agent_id, amount);
+CREATE INDEX IF NOT EXISTS idx_payments_agent_updated_at ON payments (agent_id, updated_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_created_idx ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_users_name ON users (name);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at);
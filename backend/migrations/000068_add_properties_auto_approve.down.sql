@@ -0,0 +1 @@
+ALTER TABLE properties DROP COLUMN IF EXISTS auto_approve;
@@ -0,0 +1,4 @@
+-- Whether a listing skips moderation, decided from its agent's verification when it is
+-- created. Such listings are approved once they have a cover image.
+ALTER TABLE properties
+    ADD COLUMN IF NOT EXISTS auto_approve boolean NOT NULL DEFAULT false;